@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"go/token"
 	"os"
+	"strings"
 
 	"github.com/arneph/toph/builder"
+	"github.com/arneph/toph/checker"
 	c "github.com/arneph/toph/config"
 	"github.com/arneph/toph/ir"
 	irAnalyzer "github.com/arneph/toph/ir/analyzer"
 	irOptimizer "github.com/arneph/toph/ir/optimizer"
+	"github.com/arneph/toph/trace"
 	"github.com/arneph/toph/translator"
 	"github.com/arneph/toph/uppaal"
 	uppaalOptimizer "github.com/arneph/toph/uppaal/optimizer"
@@ -34,23 +37,72 @@ const (
 	// RunFailedWritingOutputFiles indicates that the Run function failed
 	// writing the generated Uppaal files to disk.
 	RunFailedWritingOutputFiles
+	// RunFailedWithChecker indicates that the Run function failed while the
+	// built-in model checker was working.
+	RunFailedWithChecker
+	// RunFoundViolations indicates that the Run function completed
+	// successfully but the built-in model checker found queries that are not
+	// satisfied.
+	RunFoundViolations
 )
 
+func (r Result) String() string {
+	switch r {
+	case RunSuccessful:
+		return "successful"
+	case RunSuccessfulButWithWarnings:
+		return "successful with warnings"
+	case RunFailedWithBuilder:
+		return "failed with builder"
+	case RunFailedWithTranslator:
+		return "failed with translator"
+	case RunFailedWritingOutputFiles:
+		return "failed writing output files"
+	case RunFailedWithChecker:
+		return "failed with checker"
+	case RunFoundViolations:
+		return "found violations"
+	default:
+		panic(fmt.Errorf("unexpected result: %d", r))
+	}
+}
+
 // Run translates the packages at the given paths and returns whether it was
-// successful or failed.
+// successful or failed. Warnings get printed to stderr and results of the
+// built-in model checker to stdout.
 func Run(paths []string, config *c.Config) Result {
+	report := Analyze(paths, config)
+	for _, w := range report.Warnings {
+		fmt.Fprintln(os.Stderr, w)
+	}
+	if report.Result != RunFailedWithBuilder && report.NoEntryFuncs {
+		fmt.Fprintf(os.Stderr, "found no entry functions (main or tests)\n")
+	}
+	for _, entry := range report.Entries {
+		if entry.CheckResult != nil {
+			outputCheckResult(entry.CheckResult, entry.OutName)
+		}
+	}
+	return report.Result
+}
+
+// Analyze translates the packages at the given paths, writes the output files,
+// and returns a report of the results without printing anything besides
+// errors writing output files.
+func Analyze(paths []string, config *c.Config) *Report {
+	report := new(Report)
 	warnings := false
+	violations := false
 
 	// Builder
 	program, entryFuncs, errs := builder.BuildProgram(paths, config)
 	warnings = warnings || len(errs) > 0
-	for _, err := range errs {
-		fmt.Fprintln(os.Stderr, err)
-	}
+	report.addWarnings(errs)
 	if program == nil {
-		return RunFailedWithBuilder
+		report.Result = RunFailedWithBuilder
+		return report
 	} else if len(entryFuncs) == 0 {
-		fmt.Fprintf(os.Stderr, "found no entry functions (main or tests)\n")
+		report.NoEntryFuncs = true
 	}
 
 	initStmts := program.InitFunc().Body().Stmts()
@@ -79,8 +131,15 @@ func Run(paths []string, config *c.Config) Result {
 	}
 
 	if config.OptimizeIR {
-		// Dead Code Eliminator
+		// Dead Code Eliminator (with all entry funcs called, to keep calls of
+		// closures defined in entry funcs):
+		program.InitFunc().Body().SetStmts(initStmts)
+		for _, entryFunc := range entryFuncs {
+			callStmt := ir.NewCallStmt(entryFunc, entryFunc.Signature(), ir.Call, token.NoPos, token.NoPos)
+			program.InitFunc().Body().AddStmt(callStmt)
+		}
 		irOptimizer.EliminateDeadCode(program, config)
+		program.InitFunc().Body().SetStmts(initStmts)
 
 		if config.Debug {
 			if len(entryFuncs) == 0 {
@@ -103,46 +162,78 @@ func Run(paths []string, config *c.Config) Result {
 		program.InitFunc().Body().SetStmts(initStmts)
 		program.InitFunc().Body().AddStmt(callStmt)
 
+		entry := &EntryReport{
+			EntryFunc: entryFunc.Name(),
+			Handle:    entryFunc.Handle(),
+			OutName:   outNames[i],
+		}
+		report.Entries = append(report.Entries, entry)
+
 		// Translator
 		sys, errs := translator.TranslateProg(program, config)
 		warnings = warnings || len(errs) > 0
-		for _, err := range errs {
-			fmt.Fprintln(os.Stderr, err)
-		}
+		report.addWarnings(errs)
 		if sys == nil {
-			return RunFailedWithTranslator
+			report.Result = RunFailedWithTranslator
+			return report
 		}
 
 		if config.OptimizeUppaalSystem {
 			if config.Debug {
-				ok := outputUppaalSystem(sys, outNames[i]+".init", config.OutFormats)
+				files, ok := outputUppaalSystem(sys, outNames[i]+".init", config.OutFormats)
 				if !ok {
-					return RunFailedWritingOutputFiles
+					report.Result = RunFailedWritingOutputFiles
+					return report
 				}
+				entry.OutFiles = append(entry.OutFiles, files...)
 			}
 
 			uppaalOptimizer.ReduceStates(sys)
 			uppaalOptimizer.ReduceTransitions(sys)
 
 			if config.Debug {
-				ok := outputUppaalSystem(sys, outNames[i]+".opt", config.OutFormats)
+				files, ok := outputUppaalSystem(sys, outNames[i]+".opt", config.OutFormats)
 				if !ok {
-					return RunFailedWritingOutputFiles
+					report.Result = RunFailedWritingOutputFiles
+					return report
 				}
+				entry.OutFiles = append(entry.OutFiles, files...)
 			}
 		}
+		if config.Timed {
+			translator.MakeInternalTransitionsUrgent(sys)
+		}
 		if !config.Debug {
-			ok := outputUppaalSystem(sys, outNames[i], config.OutFormats)
+			files, ok := outputUppaalSystem(sys, outNames[i], config.OutFormats)
 			if !ok {
-				return RunFailedWritingOutputFiles
+				report.Result = RunFailedWritingOutputFiles
+				return report
 			}
+			entry.OutFiles = append(entry.OutFiles, files...)
+		}
+		entry.Queries = sys.AllQueries()
+
+		// Checker
+		if config.Check {
+			result, err := checker.CheckSystem(sys, config)
+			if err != nil {
+				report.addWarnings([]error{err})
+				report.Result = RunFailedWithChecker
+				return report
+			}
+			entry.CheckResult = result
+			violations = violations || len(result.NotSatisfied()) > 0
 		}
 	}
 
-	if warnings {
-		return RunSuccessfulButWithWarnings
+	if violations {
+		report.Result = RunFoundViolations
+	} else if warnings {
+		report.Result = RunSuccessfulButWithWarnings
+	} else {
+		report.Result = RunSuccessful
 	}
-	return RunSuccessful
+	return report
 }
 
 func outputIRProgram(program *ir.Program, outName string, stepName string, config *c.Config) {
@@ -227,7 +318,8 @@ func outputIRProgram(program *ir.Program, outName string, stepName string, confi
 	viFile.WriteString(vi.String())
 }
 
-func outputUppaalSystem(sys *uppaal.System, outName string, outFormats map[string]bool) bool {
+func outputUppaalSystem(sys *uppaal.System, outName string, outFormats map[string]bool) ([]string, bool) {
+	var files []string
 	for _, ffmt := range []string{"xml", "xta", "ugi", "q"} {
 		if !outFormats[ffmt] {
 			continue
@@ -236,9 +328,10 @@ func outputUppaalSystem(sys *uppaal.System, outName string, outFormats map[strin
 		sysFile, err := os.Create(outName + "." + ffmt)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write %s file: %v\n", ffmt, err)
-			return false
+			return nil, false
 		}
 		defer sysFile.Close()
+		files = append(files, sysFile.Name())
 
 		switch ffmt {
 		case "xml":
@@ -252,5 +345,64 @@ func outputUppaalSystem(sys *uppaal.System, outName string, outFormats map[strin
 		}
 	}
 
-	return true
+	return files, true
+}
+
+func outputCheckResult(result *checker.Result, outName string) {
+	fmt.Printf("%s: explored %d states", outName, result.ExploredStates)
+	if !result.Complete {
+		fmt.Printf(" (state limit reached)")
+	}
+	fmt.Println()
+
+	categories := make(map[uppaal.QueryCategory][]int)
+	for i, qr := range result.QueryResults {
+		category := qr.Query.Category()
+		categories[category] = append(categories[category], i)
+	}
+	for category := uppaal.ResourceBoundUnreached; category <= uppaal.ReachabilityRequirements; category++ {
+		indices := categories[category]
+		if len(indices) == 0 {
+			continue
+		}
+		verdict := checker.Satisfied
+		for _, i := range indices {
+			if v := result.QueryResults[i].Verdict; v == checker.NotSatisfied {
+				verdict = v
+				break
+			} else if v == checker.Unknown {
+				verdict = v
+			}
+		}
+		fmt.Printf("%s %s\n", verdictString(verdict), category)
+		for _, i := range indices {
+			qr := result.QueryResults[i]
+			fmt.Printf("\t%03d %s %s\n", i+1, verdictString(qr.Verdict), qr.Query.Query())
+			if qr.Query.SourceLocation() != "" {
+				fmt.Printf("\t                   %s\n", qr.Query.SourceLocation())
+			}
+			if qr.Verdict == checker.NotSatisfied && qr.Trace != nil {
+				outputTrace(qr.Trace)
+			}
+		}
+	}
+}
+
+// outputTrace prints the given trace, leading to a violation of its query,
+// below the query results.
+func outputTrace(t *trace.Trace) {
+	for _, line := range strings.Split(strings.TrimSuffix(t.String(), "\n"), "\n") {
+		fmt.Printf("\t\t%s\n", line)
+	}
+}
+
+func verdictString(v checker.Verdict) string {
+	switch v {
+	case checker.Satisfied:
+		return "    \x1b[32msatisfied:\x1b[0m"
+	case checker.NotSatisfied:
+		return "\x1b[31mnot satisfied:\x1b[0m"
+	default:
+		return "      \x1b[33munknown:\x1b[0m"
+	}
 }
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/arneph/toph/checker"
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/uppaal"
+)
+
+// Report holds the results of the Analyze function.
+type Report struct {
+	Result Result
+	// Warnings holds all warnings of the builder, translator, and checker.
+	Warnings []*diag.Warning
+	// NoEntryFuncs indicates that the packages contain no entry functions
+	// (main or tests).
+	NoEntryFuncs bool
+	// Entries holds the outputs for each entry function.
+	Entries []*EntryReport
+}
+
+func (r *Report) addWarnings(errs []error) {
+	for _, err := range errs {
+		r.Warnings = append(r.Warnings, diag.FromError(err, diag.General))
+	}
+}
+
+// EntryReport holds the outputs for an entry function.
+type EntryReport struct {
+	// EntryFunc is the name of the entry function.
+	EntryFunc string
+	// Handle is the unique handle of the entry function in the program.
+	Handle string
+	// OutName is the file name of all output files for the entry function.
+	OutName string
+	// OutFiles lists the paths of all written output files.
+	OutFiles []string
+	// Queries holds all queries of the generated system, in the order of the
+	// q file.
+	Queries []*uppaal.Query
+	// CheckResult holds the results of the built-in model checker, if
+	// enabled. Its query results have the same order as Queries.
+	CheckResult *checker.Result
+}
+
+type jsonPosition struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column,omitempty"`
+}
+
+type jsonWarning struct {
+	Kind     string        `json:"kind"`
+	Position *jsonPosition `json:"position,omitempty"`
+	Snippet  string        `json:"snippet,omitempty"`
+	Message  string        `json:"message"`
+}
+
+type jsonQuery struct {
+	Number         int    `json:"number"`
+	Query          string `json:"query"`
+	Description    string `json:"description"`
+	Category       string `json:"category"`
+	SourceLocation string `json:"sourceLocation,omitempty"`
+	Verdict        string `json:"verdict,omitempty"`
+}
+
+type jsonEntry struct {
+	EntryFunc      string       `json:"entryFunc"`
+	Handle         string       `json:"handle"`
+	OutName        string       `json:"outName"`
+	OutFiles       []string     `json:"outFiles"`
+	Queries        []*jsonQuery `json:"queries"`
+	ExploredStates int          `json:"exploredStates,omitempty"`
+	Complete       *bool        `json:"complete,omitempty"`
+}
+
+type jsonReport struct {
+	Result       string         `json:"result"`
+	Warnings     []*jsonWarning `json:"warnings"`
+	NoEntryFuncs bool           `json:"noEntryFuncs,omitempty"`
+	Entries      []*jsonEntry   `json:"entries"`
+}
+
+// JSON returns the JSON representation of the report.
+func (r *Report) JSON() ([]byte, error) {
+	jr := &jsonReport{
+		Result:       r.Result.String(),
+		Warnings:     []*jsonWarning{},
+		NoEntryFuncs: r.NoEntryFuncs,
+		Entries:      []*jsonEntry{},
+	}
+	for _, w := range r.Warnings {
+		jw := &jsonWarning{
+			Kind:    w.Kind.String(),
+			Snippet: w.Snippet,
+			Message: w.Msg,
+		}
+		if w.Pos.IsValid() {
+			jw.Position = &jsonPosition{
+				File:   w.Pos.Filename,
+				Line:   w.Pos.Line,
+				Column: w.Pos.Column,
+			}
+		}
+		jr.Warnings = append(jr.Warnings, jw)
+	}
+	for _, entry := range r.Entries {
+		je := &jsonEntry{
+			EntryFunc: entry.EntryFunc,
+			Handle:    entry.Handle,
+			OutName:   entry.OutName,
+			OutFiles:  entry.OutFiles,
+			Queries:   []*jsonQuery{},
+		}
+		if je.OutFiles == nil {
+			je.OutFiles = []string{}
+		}
+		for i, q := range entry.Queries {
+			jq := &jsonQuery{
+				Number:         i + 1,
+				Query:          q.Query(),
+				Description:    q.Description(),
+				Category:       q.Category().String(),
+				SourceLocation: q.SourceLocation(),
+			}
+			if entry.CheckResult != nil {
+				jq.Verdict = entry.CheckResult.QueryResults[i].Verdict.String()
+			}
+			je.Queries = append(je.Queries, jq)
+		}
+		if entry.CheckResult != nil {
+			je.ExploredStates = entry.CheckResult.ExploredStates
+			je.Complete = &entry.CheckResult.Complete
+		}
+		jr.Entries = append(jr.Entries, je)
+	}
+	return json.MarshalIndent(jr, "", "  ")
+}
+
+type sarifLog struct {
+	Version string      `json:"version"`
+	Schema  string      `json:"$schema"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	Level     string           `json:"level"`
+	Message   sarifMessage     `json:"message"`
+	Locations []*sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// SARIF returns the SARIF 2.1.0 representation of the report. Warnings
+// become results with the "warning" level and queries that the built-in
+// model checker found to be not satisfied become results with the "error"
+// level.
+func (r *Report) SARIF() ([]byte, error) {
+	run := &sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "toph",
+			InformationURI: "https://github.com/arneph/toph",
+			Rules:          []*sarifRule{},
+		}},
+		Results: []*sarifResult{},
+	}
+	rules := make(map[string]bool)
+	addRule := func(id, description string) {
+		if rules[id] {
+			return
+		}
+		rules[id] = true
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: description},
+		})
+	}
+
+	for _, w := range r.Warnings {
+		id := w.Kind.String()
+		addRule(id, strings.ReplaceAll(id, "-", " "))
+		result := &sarifResult{
+			RuleID:  id,
+			Level:   "warning",
+			Message: sarifMessage{Text: w.Msg},
+		}
+		if loc := sarifLocationFor(w.Pos.Filename, w.Pos.Line, w.Pos.Column, w.Snippet); loc != nil {
+			result.Locations = []*sarifLocation{loc}
+		}
+		run.Results = append(run.Results, result)
+	}
+	for _, entry := range r.Entries {
+		if entry.CheckResult == nil {
+			continue
+		}
+		for _, qr := range entry.CheckResult.NotSatisfied() {
+			q := qr.Query
+			id := strings.ReplaceAll(q.Category().String(), " ", "-")
+			addRule(id, q.Category().String())
+			result := &sarifResult{
+				RuleID:  id,
+				Level:   "error",
+				Message: sarifMessage{Text: q.Description() + ": " + q.Query()},
+			}
+			pos := diag.ParsePosition(q.SourceLocation())
+			if loc := sarifLocationFor(pos.Filename, pos.Line, pos.Column, ""); loc != nil {
+				result.Locations = []*sarifLocation{loc}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	log := &sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []*sarifRun{run},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+func sarifLocationFor(file string, line, column int, snippet string) *sarifLocation {
+	if file == "" || line <= 0 {
+		return nil
+	}
+	uri := filepath.ToSlash(file)
+	if filepath.IsAbs(file) {
+		u := url.URL{Scheme: "file", Path: uri}
+		if !strings.HasPrefix(u.Path, "/") {
+			u.Path = "/" + u.Path
+		}
+		uri = u.String()
+	}
+	loc := &sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: uri},
+			Region: sarifRegion{
+				StartLine:   line,
+				StartColumn: column,
+			},
+		},
+	}
+	if snippet != "" {
+		loc.PhysicalLocation.Region.Snippet = &sarifMessage{Text: snippet}
+	}
+	return loc
+}
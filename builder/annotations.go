@@ -1,65 +1,330 @@
 package builder
 
 import (
+	"fmt"
 	"go/ast"
+	"go/types"
 	"strconv"
 	"strings"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
-func (b *builder) findIterationBoundsFromAnnotation(stmt ast.Stmt, ctx *context) (min, max int) {
-	min, max = -1, -1
+// Annotations are comments of the form "toph: <annotation>, <annotation>"
+// attached to a declaration or statement, either on the line above or at the
+// end of the line. Each annotation is either a key or a key=value pair:
+//
+//	min_iter=<n>, max_iter=<n>    loops: bounds on the number of iterations
+//	check=reachable|unreachable   select cases: expected reachability
+//	assume=true|false             if statements: only the if (true) or the
+//	                              else (false) branch gets modeled
+//	ignore                        statements: not modeled;
+//	                              functions: modeled as no-ops
+//	max_instances=<n>             go statements and functions: number of
+//	                              concurrent instances of the (called)
+//	                              function, overriding the call graph
+//	capacity=<n>                  make calls of channels: buffer capacity
+//	entry                         functions: analyzed as entry function
+//	track                         variable definitions and for statements:
+//	                              int and bool variables modeled as bounded
+//	                              ints, such that conditions become guards
+//	shared                        variables and fields: checked for data races
+//	substitute=<name>             substitutes files: substituted package,
+//	                              function, method, or type
+//
+// Annotations with unknown keys or invalid values get reported as warnings.
+var annotationSyntaxes = map[string]annotationSyntax{
+	"min_iter":      intAnnotation,
+	"max_iter":      intAnnotation,
+	"check":         enumAnnotation("reachable", "unreachable"),
+	"assume":        enumAnnotation("true", "false"),
+	"ignore":        flagAnnotation,
+	"max_instances": positiveIntAnnotation,
+	"capacity":      intAnnotation,
+	"entry":         flagAnnotation,
+	"track":         flagAnnotation,
+	"shared":        flagAnnotation,
+	"substitute":    nameAnnotation,
+}
+
+// annotationSyntax checks the value of an annotation and returns an error
+// describing the problem if the value is invalid. The value is empty for
+// annotations without '='.
+type annotationSyntax func(value string, hasValue bool) error
+
+func flagAnnotation(value string, hasValue bool) error {
+	if hasValue {
+		return fmt.Errorf("unexpected value")
+	}
+	return nil
+}
+
+func intAnnotation(value string, hasValue bool) error {
+	if !hasValue {
+		return fmt.Errorf("missing value")
+	} else if n, err := strconv.Atoi(value); err != nil || n < 0 {
+		return fmt.Errorf("expected non-negative integer value")
+	}
+	return nil
+}
+
+func positiveIntAnnotation(value string, hasValue bool) error {
+	if !hasValue {
+		return fmt.Errorf("missing value")
+	} else if n, err := strconv.Atoi(value); err != nil || n < 1 {
+		return fmt.Errorf("expected positive integer value")
+	}
+	return nil
+}
+
+func nameAnnotation(value string, hasValue bool) error {
+	if !hasValue || value == "" {
+		return fmt.Errorf("missing value")
+	}
+	return nil
+}
 
-	for _, info := range b.findAnnotations(stmt, ctx) {
-		if strings.HasPrefix(info, "min_iter=") {
-			r, err := strconv.Atoi(info[9:])
-			if err == nil {
-				min = r
+func enumAnnotation(values ...string) annotationSyntax {
+	return func(value string, hasValue bool) error {
+		if !hasValue {
+			return fmt.Errorf("missing value")
+		}
+		for _, v := range values {
+			if value == v {
+				return nil
 			}
-		} else if strings.HasPrefix(info, "max_iter=") {
-			r, err := strconv.Atoi(info[9:])
-			if err == nil {
-				max = r
+		}
+		return fmt.Errorf("expected %s", strings.Join(values, " or "))
+	}
+}
+
+// splitAnnotation returns the key and value of the given annotation.
+func splitAnnotation(info string) (key, value string, hasValue bool) {
+	i := strings.Index(info, "=")
+	if i == -1 {
+		return info, "", false
+	}
+	return strings.TrimSpace(info[:i]), strings.TrimSpace(info[i+1:]), true
+}
+
+// checkAnnotationsInFile adds warnings for all annotations in the file with
+// unknown keys or invalid values.
+func (b *builder) checkAnnotationsInFile(file *ast.File) {
+	for _, commentGroup := range file.Comments {
+		for _, info := range b.findAnnotationsInCommentGroup(commentGroup) {
+			key, value, hasValue := splitAnnotation(info)
+			p := b.fset.Position(commentGroup.Pos())
+			syntax, ok := annotationSyntaxes[key]
+			if !ok {
+				b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, info, "unknown annotation: %s", info))
+			} else if err := syntax(value, hasValue); err != nil {
+				b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, info, "invalid annotation: %s: %v", info, err))
 			}
 		}
 	}
-	return
+}
+
+// findAnnotation returns the value of the annotation with the given key for
+// the given node and whether the node has the annotation.
+func (b *builder) findAnnotation(node ast.Node, key string, ctx *context) (value string, ok bool) {
+	return findAnnotationInInfos(b.findAnnotations(node, ctx), key)
+}
+
+// findIntAnnotation returns the integer value of the annotation with the
+// given key for the given node or -1 if the node has no such annotation.
+func (b *builder) findIntAnnotation(node ast.Node, key string, ctx *context) int {
+	value, ok := b.findAnnotation(node, key, ctx)
+	if !ok {
+		return -1
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func findAnnotationInInfos(infos []string, key string) (value string, ok bool) {
+	for _, info := range infos {
+		k, v, _ := splitAnnotation(info)
+		if k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (b *builder) findIterationBoundsFromAnnotation(stmt ast.Stmt, ctx *context) (min, max int) {
+	return b.findIntAnnotation(stmt, "min_iter", ctx), b.findIntAnnotation(stmt, "max_iter", ctx)
 }
 
 func (b *builder) findReachabilityRequirementFromAnnotation(stmt ast.Stmt, ctx *context) ir.ReachabilityRequirement {
-	for _, info := range b.findAnnotations(stmt, ctx) {
-		if strings.HasPrefix(info, "check=") {
-			if info[6:] == "reachable" {
-				return ir.Reachable
-			} else if info[6:] == "unreachable" {
-				return ir.Unreachable
-			}
+	value, _ := b.findAnnotation(stmt, "check", ctx)
+	switch value {
+	case "reachable":
+		return ir.Reachable
+	case "unreachable":
+		return ir.Unreachable
+	default:
+		return ir.NoReachabilityRequirement
+	}
+}
+
+func (b *builder) findAnnotations(node ast.Node, ctx *context) (infos []string) {
+	for _, commentGroup := range ctx.cmap[node] {
+		infos = append(infos, b.findAnnotationsInCommentGroup(commentGroup)...)
+	}
+	return
+}
+
+// findAnnotationsForExpr returns the annotations in comments at the end of
+// the line of the given expression or on the line above it. This allows
+// annotating expressions, for example make calls, which comments do not get
+// associated with.
+func (b *builder) findAnnotationsForExpr(expr ast.Expr, ctx *context) (infos []string) {
+	line := b.fset.Position(expr.Pos()).Line
+	for _, commentGroup := range ctx.cmap.Comments() {
+		startLine := b.fset.Position(commentGroup.Pos()).Line
+		endLine := b.fset.Position(commentGroup.End()).Line
+		if startLine == line || endLine == line-1 {
+			infos = append(infos, b.findAnnotationsInCommentGroup(commentGroup)...)
 		}
 	}
-	return ir.NoReachabilityRequirement
+	return
 }
 
-func (b *builder) findAnnotations(stmt ast.Stmt, ctx *context) (infos []string) {
-	for _, commentGroup := range ctx.cmap[stmt] {
-		text := commentGroup.Text()
-		for {
-			i := strings.Index(text, "toph:")
-			if i == -1 {
-				break
-			}
-			j := strings.Index(text, "\n")
-			if j == -1 {
-				j = len(text)
-			}
-			infs := text[i+5 : j]
-			text = text[j:]
+func (b *builder) findAnnotationsInCommentGroup(commentGroup *ast.CommentGroup) (infos []string) {
+	if commentGroup == nil {
+		return nil
+	}
+	text := commentGroup.Text()
+	for {
+		i := strings.Index(text, "toph:")
+		if i == -1 {
+			break
+		}
+		j := strings.Index(text[i:], "\n")
+		if j == -1 {
+			j = len(text)
+		} else {
+			j += i
+		}
+		infs := text[i+5 : j]
+		text = text[j:]
 
-			for _, info := range strings.Split(infs, ",") {
-				info = strings.TrimSpace(info)
-				infos = append(infos, info)
+		for _, info := range strings.Split(infs, ",") {
+			info = strings.TrimSpace(info)
+			if info == "" {
+				continue
 			}
+			infos = append(infos, info)
 		}
 	}
 	return
 }
+
+// findFuncDeclAnnotations returns the annotations for the given function
+// declaration in the file with the given comment map.
+func (b *builder) findFuncDeclAnnotations(funcDecl *ast.FuncDecl, cmap ast.CommentMap) (infos []string) {
+	for _, commentGroup := range cmap[funcDecl] {
+		infos = append(infos, b.findAnnotationsInCommentGroup(commentGroup)...)
+	}
+	return
+}
+
+// processFuncDeclAnnotations applies the max_instances and entry annotations
+// of the given function declaration in the file with the given comment map.
+func (b *builder) processFuncDeclAnnotations(funcDecl *ast.FuncDecl, irFunc *ir.Func, cmap ast.CommentMap) {
+	infos := b.findFuncDeclAnnotations(funcDecl, cmap)
+	if value, ok := findAnnotationInInfos(infos, "max_instances"); ok {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			irFunc.SetMaxInstances(n)
+		}
+	}
+	if _, ok := findAnnotationInInfos(infos, "entry"); ok {
+		b.annotatedEntryFuncs[irFunc] = true
+	}
+}
+
+// annotatedEntryFunc returns a func calling the given func annotated with
+// entry with fresh values for its receiver and parameters. Channel parameters
+// get new unbuffered channels. Returns the func itself if it has no receiver
+// and parameters.
+func (b *builder) annotatedEntryFunc(irFunc *ir.Func) *ir.Func {
+	sig := irFunc.Signature()
+	if sig == nil || (sig.Recv() == nil && sig.Params().Len() == 0) {
+		return irFunc
+	}
+	entrySig := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+	entryFunc := b.program.AddOuterFunc(irFunc.Name(), entrySig, irFunc.Pos(), irFunc.End())
+	callStmt := ir.NewCallStmt(irFunc, sig, ir.Call, irFunc.Pos(), irFunc.End())
+	if sig.Recv() != nil {
+		if recvVal := b.makeFreshValue(sig.Recv().Type(), entryFunc.Body(), irFunc.Pos(), irFunc.Pos()); recvVal != nil {
+			callStmt.AddArg(-1, recvVal, false)
+		}
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		paramType := sig.Params().At(i).Type()
+		var argVal ir.RValue
+		if b.typesTypeToIrType(paramType) == ir.ChanType {
+			chanVar := b.program.NewVariable("", ir.ChanType.InitializedValue())
+			entryFunc.Body().Scope().AddVariable(chanVar)
+			makeStmt := ir.NewMakeChanStmt(chanVar, ir.MakeValue(0, ir.IntType), irFunc.Pos(), irFunc.Pos())
+			entryFunc.Body().AddStmt(makeStmt)
+			argVal = chanVar
+		} else {
+			argVal = b.makeFreshValue(paramType, entryFunc.Body(), irFunc.Pos(), irFunc.Pos())
+		}
+		if argVal != nil {
+			callStmt.AddArg(i, argVal, false)
+		}
+	}
+	entryFunc.Body().AddStmt(callStmt)
+	return entryFunc
+}
+
+// isIgnoredFuncDecl returns whether the given function declaration in the
+// file with the given comment map is annotated with ignore.
+func (b *builder) isIgnoredFuncDecl(funcDecl *ast.FuncDecl, cmap ast.CommentMap) bool {
+	_, ok := findAnnotationInInfos(b.findFuncDeclAnnotations(funcDecl, cmap), "ignore")
+	return ok
+}
+
+// processGoStmtAnnotations applies the max_instances annotation of the given
+// go statement to the function called by the last go call in the body.
+func (b *builder) processGoStmtAnnotations(stmt *ast.GoStmt, ctx *context) {
+	n := b.findIntAnnotation(stmt, "max_instances", ctx)
+	if n < 1 {
+		return
+	}
+	stmts := ctx.body.Stmts()
+	for i := len(stmts) - 1; i >= 0; i-- {
+		callStmt, ok := stmts[i].(*ir.CallStmt)
+		if !ok || callStmt.CallKind() != ir.Go {
+			continue
+		}
+		if callee, ok := callStmt.Callee().(*ir.Func); ok {
+			callee.SetMaxInstances(n)
+			return
+		}
+		break
+	}
+	p := b.fset.Position(stmt.Pos())
+	stmtStr := b.nodeToString(stmt)
+	b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, stmtStr, "max_instances annotation requires statically known callee: %s", stmtStr))
+}
+
+// findCapacityFromAnnotation returns the value of the capacity annotation for
+// the given make call or nil if it has none.
+func (b *builder) findCapacityFromAnnotation(callExpr *ast.CallExpr, ctx *context) ir.RValue {
+	value, ok := findAnnotationInInfos(b.findAnnotationsForExpr(callExpr, ctx), "capacity")
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil
+	}
+	return ir.MakeValue(int64(n), ir.IntType)
+}
@@ -1,14 +1,24 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
 func (b *builder) processAssignStmt(stmt *ast.AssignStmt, ctx *context) {
+	if stmt.Tok != token.ASSIGN && stmt.Tok != token.DEFINE {
+		if v := b.findTrackedVar(stmt.Lhs[0], ctx); v != nil {
+			b.processTrackedVarUpdate(stmt.Lhs[0], v, stmt.Tok, stmt.Rhs[0], ctx)
+			return
+		}
+		b.processExprs(stmt.Rhs, ctx)
+		b.processArithmeticUpdate(stmt.Lhs[0], ctx)
+		return
+	}
+
 	// Create newly defined variables:
 	if stmt.Tok == token.DEFINE {
 		for _, expr := range stmt.Lhs {
@@ -24,13 +34,60 @@ func (b *builder) processAssignStmt(stmt *ast.AssignStmt, ctx *context) {
 }
 
 func (b *builder) processAssignments(lhsExprs []ast.Expr, rhsExprs []ast.Expr, ctx *context) {
+	if b.processCondLockerAssignment(lhsExprs, rhsExprs, ctx) {
+		return
+	}
+
+	// Tracked variables:
+	tracked := make(map[int]*ir.Variable)
+	for i, expr := range lhsExprs {
+		if v := b.findTrackedVar(expr, ctx); v != nil {
+			tracked[i] = v
+		}
+	}
+
 	// Handle Rhs expressions:
-	rhs := b.processExprs(rhsExprs, ctx)
+	var rhs map[int]ir.RValue
+	if receiveExpr := receiveOkExpr(lhsExprs, rhsExprs); receiveExpr != nil && tracked[1] != nil {
+		rhs = make(map[int]ir.RValue)
+		if receiveStmt := b.processReceiveExpr(receiveExpr, true, true, ctx); receiveStmt != nil {
+			if receiveStmt.Payload() != nil {
+				rhs[0] = receiveStmt.Payload()
+			}
+			rhs[1] = b.bindReceiveOk(receiveStmt, ctx)
+		}
+	} else if len(tracked) > 0 && len(lhsExprs) == len(rhsExprs) {
+		rhs = make(map[int]ir.RValue)
+		for i, expr := range rhsExprs {
+			var v ir.RValue
+			if _, ok := tracked[i]; ok {
+				v = b.processIntExpr(expr, ctx)
+			} else {
+				v = b.processExpr(expr, ctx)
+			}
+			if v != nil {
+				rhs[i] = v
+			}
+		}
+	} else {
+		rhs = b.processExprs(rhsExprs, ctx)
+	}
+	if len(lhsExprs) == len(rhsExprs) {
+		for i, expr := range rhsExprs {
+			if v := b.processImplicitConversion(rhs[i], expr, ctx.typesInfo.TypeOf(lhsExprs[i]), ctx); v != nil {
+				rhs[i] = v
+			}
+		}
+	}
 
 	// Handle Lhs expressions:
 	lhs := make(map[int]ir.LValue)
 	requiresCopy := make(map[int]bool)
 	for i, expr := range lhsExprs {
+		if v, ok := tracked[i]; ok {
+			lhs[i] = v
+			continue
+		}
 		irVal := b.processExpr(expr, ctx)
 		if irVal == nil {
 			continue
@@ -39,17 +96,19 @@ func (b *builder) processAssignments(lhsExprs []ast.Expr, rhsExprs []ast.Expr, c
 		}
 		irVar := irVal.(ir.LValue)
 		irType := irVar.Type()
-		if irType == ir.MutexType {
+		typesType := ctx.typesInfo.TypeOf(expr)
+		if (irType == ir.MutexType || irType == ir.RWMutexType) && !b.isPointer(typesType) {
 			p := b.fset.Position(expr.Pos())
-			b.addWarning(fmt.Errorf("%v: can not assign sync.Mutex or sync.RWMutex", p))
+			exprStr := b.nodeToString(expr)
+			b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, exprStr, "can not assign sync.Mutex or sync.RWMutex"))
 			continue
-		} else if irType == ir.WaitGroupType {
+		} else if irType == ir.WaitGroupType && !b.isPointer(typesType) {
 			p := b.fset.Position(expr.Pos())
-			b.addWarning(fmt.Errorf("%v: can not assign sync.WaitGroup", p))
+			exprStr := b.nodeToString(expr)
+			b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, exprStr, "can not assign sync.WaitGroup"))
 			continue
 		}
 		lhs[i] = irVar
-		typesType := ctx.typesInfo.TypeOf(expr)
 		if _, ok := irType.(*ir.StructType); ok {
 			requiresCopy[i] = !b.isPointer(typesType)
 		} else if irContainerType, ok := irType.(*ir.ContainerType); ok && irContainerType.Kind() == ir.Array {
@@ -70,6 +129,9 @@ func (b *builder) processAssignments(lhsExprs []ast.Expr, rhsExprs []ast.Expr, c
 		}
 		l := lhs[i]
 		r := rhs[i]
+		if _, ok := tracked[i]; ok && r == nil {
+			r = b.unknownTrackedValue(ctx.typesInfo.TypeOf(lhsExpr))
+		}
 		if l == nil && r == nil {
 			continue
 		} else if l == nil && r == ir.Nil {
@@ -80,13 +142,12 @@ func (b *builder) processAssignments(lhsExprs []ast.Expr, rhsExprs []ast.Expr, c
 			}
 			p := b.fset.Position(lhsExpr.Pos())
 			lhsExprStr := b.nodeToString(lhsExpr)
-			b.addWarning(fmt.Errorf("%v: could not handle lhs of assignment: %s", p, lhsExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, lhsExprStr, "could not handle lhs of assignment: %s", lhsExprStr))
 			continue
 		} else if r == nil {
 			p := b.fset.Position(rhsExpr.Pos())
 			rhsExprStr := b.nodeToString(rhsExpr)
-			b.addWarning(
-				fmt.Errorf("%v: could not handle rhs of assignment: %s", p, rhsExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, rhsExprStr, "could not handle rhs of assignment: %s", rhsExprStr))
 			continue
 		}
 		if r == ir.Nil {
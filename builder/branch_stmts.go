@@ -1,11 +1,11 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
@@ -14,13 +14,31 @@ func (b *builder) processIfStmt(stmt *ast.IfStmt, ctx *context) {
 		b.processStmt(stmt.Init, ctx)
 	}
 
-	b.processExpr(stmt.Cond, ctx)
+	b.processSharedVarReads(stmt.Cond, ctx)
+	cond, negated := b.processCondExpr(stmt.Cond, ctx)
+
+	// The assume annotation restricts the model to one of the branches:
+	if assumption, ok := b.findAnnotation(stmt, "assume", ctx); ok {
+		switch assumption {
+		case "true":
+			b.processStmt(stmt.Body, ctx)
+			return
+		case "false":
+			if stmt.Else != nil {
+				b.processStmt(stmt.Else, ctx)
+			}
+			return
+		}
+	}
 
 	elsePos := stmt.End()
 	if stmt.Else != nil {
 		elsePos = stmt.Else.Pos()
 	}
 	ifStmt := ir.NewIfStmt(ctx.body.Scope(), stmt.Pos(), stmt.End(), stmt.Pos(), elsePos)
+	if cond != nil {
+		ifStmt.SetCond(cond, negated)
+	}
 	ctx.body.AddStmt(ifStmt)
 
 	b.processStmt(stmt.Body, ctx.subContextForBody(ifStmt, "", ifStmt.IfBranch()))
@@ -30,11 +48,39 @@ func (b *builder) processIfStmt(stmt *ast.IfStmt, ctx *context) {
 	}
 }
 
+// processCondExpr processes the given branch condition and returns the
+// modeled int value (zero for false) the condition consists of, if any, and
+// whether the condition negates it. Modeled values only result from
+// operations like sync.Mutex.TryLock and from conditions over tracked
+// variables, all other conditions return nil.
+func (b *builder) processCondExpr(expr ast.Expr, ctx *context) (cond ir.RValue, negated bool) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return b.processCondExpr(e.X, ctx)
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			cond, negated = b.processCondExpr(e.X, ctx)
+			return cond, !negated
+		}
+	}
+	if b.usesTrackedVars(expr, ctx) {
+		return b.processIntExpr(expr, ctx), false
+	}
+	cond = b.processExpr(expr, ctx)
+	if cond == nil || cond.Type() != ir.IntType {
+		return nil, false
+	} else if typesType := ctx.typesInfo.TypeOf(expr); typesType == nil || typesType.Underlying() != types.Typ[types.Bool] {
+		return nil, false
+	}
+	return cond, false
+}
+
 func (b *builder) processSwitchStmt(stmt *ast.SwitchStmt, label string, ctx *context) {
 	if stmt.Init != nil {
 		b.processStmt(stmt.Init, ctx)
 	}
 	if stmt.Tag != nil {
+		b.processSharedVarReads(stmt.Tag, ctx)
 		b.processExpr(stmt.Tag, ctx)
 	}
 
@@ -116,8 +162,7 @@ func (b *builder) processLabeledStmt(labeledStmt *ast.LabeledStmt, ctx *context)
 		b.processTypeSwitchStmt(stmt, label, ctx)
 	default:
 		p := b.fset.Position(labeledStmt.Pos())
-		b.addWarning(
-			fmt.Errorf("%v: ignoring label: %q", p, label))
+		b.addWarning(diag.Warningf(diag.UnsupportedStatement, p, label, "ignoring label: %q", label))
 
 		b.processStmt(stmt, ctx)
 	}
@@ -132,7 +177,15 @@ func (b *builder) processForStmt(stmt *ast.ForStmt, label string, ctx *context)
 	ctx.body.AddStmt(forStmt)
 
 	if stmt.Cond != nil {
-		b.processExpr(stmt.Cond, ctx.subContextForBody(forStmt, "", forStmt.Cond()))
+		condCtx := ctx.subContextForBody(forStmt, "", forStmt.Cond())
+		b.processSharedVarReads(stmt.Cond, condCtx)
+		if b.usesTrackedVars(stmt.Cond, condCtx) {
+			if cond, negated := b.processCondExpr(stmt.Cond, condCtx); cond != nil {
+				forStmt.SetCondValue(cond, negated)
+			}
+		} else {
+			b.processExpr(stmt.Cond, condCtx)
+		}
 	}
 	forStmt.SetIsInfinite(stmt.Cond == nil)
 
@@ -141,8 +194,7 @@ func (b *builder) processForStmt(stmt *ast.ForStmt, label string, ctx *context)
 	if minAnn != -1 || maxAnn != -1 {
 		if iters != -1 {
 			p := b.fset.Position(stmt.Pos())
-			b.addWarning(
-				fmt.Errorf("%v: unnecessary loop iter annotation", p))
+			b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, "", "unnecessary loop iter annotation"))
 		}
 		forStmt.SetMinIterations(minAnn)
 		forStmt.SetMaxIterations(maxAnn)
@@ -158,7 +210,20 @@ func (b *builder) processForStmt(stmt *ast.ForStmt, label string, ctx *context)
 }
 
 func (b *builder) processRangeStmt(stmt *ast.RangeStmt, label string, ctx *context) {
-	typesType := ctx.typesInfo.TypeOf(stmt.X)
+	b.processSharedVarReads(stmt.X, ctx)
+
+	typesType := b.typeArgs.substitute(ctx.typesInfo.TypeOf(stmt.X))
+	switch underlyingTypesType := typesType.Underlying().(type) {
+	case *types.Basic:
+		if underlyingTypesType.Info()&types.IsInteger != 0 {
+			b.processRangeIntStmt(stmt, label, ctx)
+			return
+		}
+	case *types.Signature:
+		if b.processRangeFuncStmt(stmt, underlyingTypesType, label, ctx) {
+			return
+		}
+	}
 	irType := b.typesTypeToIrType(typesType)
 
 	if irType == ir.ChanType {
@@ -167,6 +232,30 @@ func (b *builder) processRangeStmt(stmt *ast.RangeStmt, label string, ctx *conte
 			rangeStmt := ir.NewChanRangeStmt(chanVar, ctx.body.Scope(), stmt.Pos(), stmt.End())
 			ctx.body.AddStmt(rangeStmt)
 
+			var valueVal ir.LValue
+			payloadType, requiresCopy := b.chanPayloadType(typesType)
+			if payloadType != nil && stmt.Key != nil {
+				if valueVarIdent, ok := stmt.Key.(*ast.Ident); ok && stmt.Tok == token.DEFINE {
+					b.processVarDefinitionInScope(valueVarIdent, ctx.body.Scope(), false, ctx)
+				}
+				rv := b.processExpr(stmt.Key, ctx)
+				if lv, ok := rv.(ir.LValue); ok {
+					valueVal = lv
+				}
+				if irContainerAccess, ok := rv.(*ir.ContainerAccess); ok {
+					irContainerAccess.SetKind(ir.Write)
+				}
+			}
+			if valueVal != nil && requiresCopy {
+				receivedVar := b.program.NewVariable("", payloadType.UninitializedValue())
+				ctx.body.Scope().AddVariable(receivedVar)
+				rangeStmt.SetPayload(payloadType, receivedVar)
+				assignStmt := ir.NewAssignStmt(receivedVar, valueVal, true, stmt.Key.Pos(), stmt.Key.End())
+				rangeStmt.Body().AddStmt(assignStmt)
+			} else {
+				rangeStmt.SetPayload(payloadType, valueVal)
+			}
+
 			b.processStmt(stmt.Body, ctx.subContextForBody(rangeStmt, label, rangeStmt.Body()))
 			return
 		}
@@ -214,6 +303,29 @@ func (b *builder) processRangeStmt(stmt *ast.RangeStmt, label string, ctx *conte
 	b.processStmt(stmt.Body, ctx.subContextForBody(forStmt, label, forStmt.Body()))
 }
 
+func (b *builder) processRangeIntStmt(stmt *ast.RangeStmt, label string, ctx *context) {
+	b.processExpr(stmt.X, ctx)
+
+	forStmt := ir.NewForStmt(ctx.body.Scope(), stmt.Pos(), stmt.End())
+	ctx.body.AddStmt(forStmt)
+
+	minAnn, maxAnn := b.findIterationBoundsFromAnnotation(stmt, ctx)
+	iters := b.staticRangeLoopBoundsEval(stmt, ctx)
+	if minAnn != -1 || maxAnn != -1 {
+		if iters != -1 {
+			p := b.fset.Position(stmt.Pos())
+			b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, "", "unnecessary loop iter annotation"))
+		}
+		forStmt.SetMinIterations(minAnn)
+		forStmt.SetMaxIterations(maxAnn)
+	} else if iters != -1 {
+		forStmt.SetMinIterations(iters)
+		forStmt.SetMaxIterations(iters)
+	}
+
+	b.processStmt(stmt.Body, ctx.subContextForBody(forStmt, label, forStmt.Body()))
+}
+
 func (b *builder) processBranchStmt(stmt *ast.BranchStmt, ctx *context) {
 	label := ""
 	if stmt.Label != nil {
@@ -230,8 +342,12 @@ func (b *builder) processBranchStmt(stmt *ast.BranchStmt, ctx *context) {
 		targetStmt = ctx.findContinuable(label)
 	default:
 		p := b.fset.Position(stmt.Pos())
-		b.addWarning(
-			fmt.Errorf("%v: unsuported branch statement: %s", p, stmt.Tok))
+		stmtStr := b.nodeToString(stmt)
+		b.addWarning(diag.Warningf(diag.UnsupportedStatement, p, stmtStr, "unsuported branch statement: %s", stmt.Tok))
+		return
+	}
+	if targetStmt == nil && ctx.rangeFuncLoop != nil {
+		b.processRangeFuncBranchStmt(stmt, label, ctx)
 		return
 	}
 	branchStmt := ir.NewBranchStmt(targetStmt, kind, stmt.Pos(), stmt.End())
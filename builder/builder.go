@@ -1,7 +1,6 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/build"
 	"go/format"
@@ -14,6 +13,7 @@ import (
 	"strings"
 
 	c "github.com/arneph/toph/config"
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 
 	"github.com/arneph/toph/builder/packages"
@@ -40,7 +40,7 @@ func BuildProgram(paths []string, config *c.Config) (program *ir.Program, entryF
 	for i, path := range paths {
 		absPath, err := filepath.Abs(path)
 		if err != nil {
-			b.addWarning(fmt.Errorf("could not find absolute path for %q: %v", path, err))
+			b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, path, "could not find absolute path for %q: %v", path, err))
 		} else {
 			absPaths[i] = absPath
 		}
@@ -67,12 +67,17 @@ func BuildProgram(paths []string, config *c.Config) (program *ir.Program, entryF
 	}
 	rootPackages, err := packages.Load(packagesConfig, absPaths...)
 	if err != nil {
-		b.addWarning(err)
-		return
+		b.addWarning(diag.FromError(err, diag.LoadFailure))
+		return nil, nil, b.warnings
 	}
 	packages.Visit(rootPackages, nil, func(pkg *packages.Package) {
 		for _, err := range pkg.Errors {
-			b.addWarning(err)
+			p := diag.ParsePosition(err.Pos)
+			if !p.IsValid() {
+				b.addWarning(diag.FromError(err, diag.LoadFailure))
+				continue
+			}
+			b.addWarning(diag.Warningf(diag.LoadFailure, p, "", "%s", err.Msg))
 		}
 	})
 	packages.Visit(rootPackages, func(pkg *packages.Package) bool {
@@ -91,10 +96,10 @@ func BuildProgram(paths []string, config *c.Config) (program *ir.Program, entryF
 		if pkg.Name == "unsafe" {
 			return
 		} else if len(pkg.GoFiles) == 0 {
-			b.addWarning(fmt.Errorf("no files in package: %s", pkg.PkgPath))
+			b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, pkg.PkgPath, "no files in package: %s", pkg.PkgPath))
 			return
 		} else if pkg.IllTyped {
-			b.addWarning(fmt.Errorf("skipped due to incomplete type information: %s", pkg.PkgPath))
+			b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, pkg.PkgPath, "skipped due to incomplete type information: %s", pkg.PkgPath))
 			return
 		} else if strings.HasPrefix(pkg.GoFiles[0], config.BuildContext.GOROOT) {
 			return
@@ -102,14 +107,14 @@ func BuildProgram(paths []string, config *c.Config) (program *ir.Program, entryF
 			return
 		}
 		if config.Debug {
-			b.addWarning(fmt.Errorf("translating package: %s", pkg.PkgPath))
+			b.addWarning(diag.Warningf(diag.General, token.Position{}, pkg.PkgPath, "translating package: %s", pkg.PkgPath))
 		}
 		b.pkgs = append(b.pkgs, pkg)
 	})
 
 	subsFile, err := parser.ParseFile(b.fset, "substitutes.go", substitutesCode, parserMode)
 	if err != nil {
-		b.addWarning(fmt.Errorf("parsing substitutes failed: %v", err))
+		b.addWarning(diag.Warningf(diag.Internal, token.Position{}, "", "parsing substitutes failed: %v", err))
 		return nil, nil, b.warnings
 	}
 	subsTypesInfo := &types.Info{
@@ -119,37 +124,59 @@ func BuildProgram(paths []string, config *c.Config) (program *ir.Program, entryF
 		Implicits:  make(map[ast.Node]types.Object),
 		Selections: make(map[*ast.SelectorExpr]*types.Selection),
 		Scopes:     make(map[ast.Node]*types.Scope),
+		Instances:  make(map[*ast.Ident]types.Instance),
 	}
 	subsTypesConfig := &types.Config{
 		Importer: importer.ForCompiler(b.fset, "source", nil),
 	}
-	_, err = subsTypesConfig.Check("subs", b.fset, []*ast.File{subsFile}, subsTypesInfo)
+	b.subsPkg, err = subsTypesConfig.Check("subs", b.fset, []*ast.File{subsFile}, subsTypesInfo)
 	if err != nil {
-		b.addWarning(fmt.Errorf("type checking substitutes failed: %v", err))
+		b.addWarning(diag.Warningf(diag.Internal, token.Position{}, "", "type checking substitutes failed: %v", err))
 		return nil, nil, b.warnings
 	}
+	b.typesPkgs[b.subsPkg] = struct{}{}
+	userSubsFiles, userSubsTypesInfo := b.loadUserSubstitutes()
 
 	// Types:
 	b.funcs = make(map[*types.Func]*ir.Func)
+	b.genericFuncs = make(map[*types.Func]*genericFunc)
+	b.funcInstances = make(map[funcInstanceKey]*ir.Func)
 	b.vars = make(map[*types.Var]*ir.Variable)
 	b.fields = make(map[*types.Var]*ir.Field)
+	b.excludedMembers = make(map[string]struct{})
+	b.annotatedEntryFuncs = make(map[*ir.Func]bool)
 
 	// Comment maps:
 	b.cmaps = make(map[*ast.File]ast.CommentMap)
 	for _, pkg := range b.pkgs {
 		for _, astFile := range pkg.Syntax {
 			b.cmaps[astFile] = ast.NewCommentMap(b.fset, astFile, astFile.Comments)
+			b.checkAnnotationsInFile(astFile)
 		}
 	}
+	for _, userSubsFile := range userSubsFiles {
+		b.checkAnnotationsInFile(userSubsFile)
+	}
 
 	// IR setup:
 	b.program = ir.NewProgram(b.fset)
-	b.liftedSpecialOpFuncs = make(map[ir.SpecialOp]*ir.Func)
+	b.liftedSpecialOpFuncs = make(map[liftedSpecialOp]*ir.Func)
+	b.findSharedVars()
+	b.findTrackedVars()
 
 	// Substitures processing:
 	b.processFuncDeclsInFile(subsFile, subsTypesInfo)
 	b.processGenDeclsInFile(subsFile, subsTypesInfo)
 	b.processFuncDefsInFile(subsFile, subsTypesInfo)
+	for _, userSubsFile := range userSubsFiles {
+		b.processFuncDeclsInFile(userSubsFile, userSubsTypesInfo)
+	}
+	for _, userSubsFile := range userSubsFiles {
+		b.processGenDeclsInFile(userSubsFile, userSubsTypesInfo)
+	}
+	for _, userSubsFile := range userSubsFiles {
+		b.processFuncDefsInFile(userSubsFile, userSubsTypesInfo)
+	}
 
 	// AST processing:
 	for _, pkg := range b.pkgs {
@@ -192,43 +219,67 @@ func BuildProgram(paths []string, config *c.Config) (program *ir.Program, entryF
 			b.processFuncDefsInFile(astFile, pkg.TypesInfo)
 		}
 	}
+	b.processFuncInstances()
 
 	// Entry funcs:
 	for _, irFunc := range b.program.Funcs() {
-		if irFunc.Name() == "main" &&
+		if irFunc.EnclosingFunc() != nil {
+			continue
+		} else if b.annotatedEntryFuncs[irFunc] {
+			entryFuncs = append(entryFuncs, b.annotatedEntryFunc(irFunc))
+		} else if irFunc.Name() == "main" &&
 			irFunc.Signature() != nil &&
 			irFunc.Signature().String() == "func()" {
 			entryFuncs = append(entryFuncs, irFunc)
 		} else if strings.HasPrefix(irFunc.Name(), "Test") &&
 			irFunc.Signature() != nil &&
 			irFunc.Signature().String() == "func(t *testing.T)" {
-			entryFuncs = append(entryFuncs, irFunc)
+			entryFuncs = append(entryFuncs, b.testEntryFunc(irFunc))
 		}
 	}
 
+	b.addExcludedMembersSummary()
+
 	return b.program, entryFuncs, b.warnings
 }
 
 type builder struct {
-	fset      *token.FileSet
-	pkgs      []*packages.Package
-	typesPkgs map[*types.Package]struct{}
-	funcs     map[*types.Func]*ir.Func
-	vars      map[*types.Var]*ir.Variable
-	fields    map[*types.Var]*ir.Field
-	types     typeutil.Map
-	cmaps     map[*ast.File]ast.CommentMap
+	fset       *token.FileSet
+	pkgs       []*packages.Package
+	typesPkgs  map[*types.Package]struct{}
+	subsPkg    *types.Package
+	userSubs   userSubstitutes
+	funcs      map[*types.Func]*ir.Func
+	vars       map[*types.Var]*ir.Variable
+	fields     map[*types.Var]*ir.Field
+	sharedVars map[*types.Var]*ir.SharedVar
+	types      typeutil.Map
+	cmaps      map[*ast.File]ast.CommentMap
+
+	excludedMembers map[string]struct{}
+	// trackedVars holds the int and bool variables modeled as Uppaal ints.
+	trackedVars map[*types.Var]bool
+	// sharedVarCounters holds the access counters of the instances of shared
+	// variables that are currently being built.
+	sharedVarCounters map[*types.Var]*ir.Variable
+	// annotatedEntryFuncs holds the functions annotated with entry.
+	annotatedEntryFuncs map[*ir.Func]bool
+
+	genericFuncs         map[*types.Func]*genericFunc
+	funcInstances        map[funcInstanceKey]*ir.Func
+	pendingFuncInstances []*funcInstance
+	typeArgs             typeArgs
 
 	program              *ir.Program
-	liftedSpecialOpFuncs map[ir.SpecialOp]*ir.Func
+	liftedSpecialOpFuncs map[liftedSpecialOp]*ir.Func
 
 	config *c.Config
 
 	warnings []error
 }
 
-func (b *builder) addWarning(err error) {
-	b.warnings = append(b.warnings, err)
+func (b *builder) addWarning(w *diag.Warning) {
+	b.warnings = append(b.warnings, w)
 }
 
 func (b *builder) nodeToString(node ast.Node) string {
@@ -248,11 +299,16 @@ func (b *builder) processFuncDeclsInFile(file *ast.File, typesInfo *types.Info)
 		}
 		name := funcDecl.Name.Name
 		typesFunc := typesInfo.Defs[funcDecl.Name].(*types.Func)
+		if b.isGenericFunc(typesFunc) {
+			b.genericFuncs[typesFunc] = &genericFunc{funcDecl, b.cmaps[file], typesInfo}
+			continue
+		}
 		typesSig := typesFunc.Type().(*types.Signature)
 		irFunc := b.program.AddOuterFunc(name, typesSig, decl.Pos(), decl.End())
 		ctx := newContext(b.cmaps[file], typesInfo, irFunc)
 		b.processFuncReceiver(funcDecl.Recv, ctx)
 		b.processFuncType(funcDecl.Type, ctx)
+		b.processFuncDeclAnnotations(funcDecl, irFunc, b.cmaps[file])
 		b.funcs[typesFunc] = irFunc
 	}
 }
@@ -264,7 +320,10 @@ func (b *builder) processFuncDefsInFile(file *ast.File, typesInfo *types.Info) {
 			continue
 		}
 		typesFunc := typesInfo.Defs[funcDecl.Name].(*types.Func)
-		irFunc := b.funcs[typesFunc]
+		irFunc, ok := b.funcs[typesFunc]
+		if !ok || b.isExcludedFunc(typesFunc) || b.isIgnoredFuncDecl(funcDecl, b.cmaps[file]) {
+			continue
+		}
 		ctx := newContext(b.cmaps[file], typesInfo, irFunc)
 		b.processFuncBody(funcDecl.Body, ctx)
 	}
@@ -321,6 +380,9 @@ func (b *builder) processInitializer(typesInfo *types.Info, init *types.Initiali
 		}
 	} else {
 		rhs[0] = b.processExpr(init.Rhs, initCtx)
+		if len(init.Lhs) == 1 {
+			rhs[0] = b.processImplicitConversion(rhs[0], init.Rhs, init.Lhs[0].Type(), initCtx)
+		}
 	}
 
 	// Create assignment statements:
@@ -335,13 +397,12 @@ func (b *builder) processInitializer(typesInfo *types.Info, init *types.Initiali
 		} else if l == nil {
 			p := b.fset.Position(init.Lhs[i].Pos())
 			rhsExprStr := b.nodeToString(rhsExpr)
-			b.addWarning(fmt.Errorf("%v: could not handle lhs of assignment: %s", p, rhsExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, rhsExprStr, "could not handle lhs of assignment: %s", rhsExprStr))
 			continue
 		} else if r == nil {
 			p := b.fset.Position(rhsExpr.Pos())
 			rhsExprStr := b.nodeToString(rhsExpr)
-			b.addWarning(
-				fmt.Errorf("%v: could not handle rhs of assignment: %s", p, rhsExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, rhsExprStr, "could not handle rhs of assignment: %s", rhsExprStr))
 			continue
 		}
 		requiresCopy := false
@@ -1,10 +1,11 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
@@ -14,7 +15,7 @@ func (b *builder) findChannel(chanExpr ast.Expr, ctx *context) ir.LValue {
 	if !ok || lv == nil {
 		p := b.fset.Position(chanExpr.Pos())
 		chanExprStr := b.nodeToString(chanExpr)
-		b.addWarning(fmt.Errorf("%v: could not resolve channel expr: %s", p, chanExprStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, chanExprStr, "could not resolve channel expr: %s", chanExprStr))
 		return nil
 	}
 	return lv
@@ -22,7 +23,9 @@ func (b *builder) findChannel(chanExpr ast.Expr, ctx *context) ir.LValue {
 
 func (b *builder) processMakeChanExpr(callExpr *ast.CallExpr, ctx *context) *ir.Variable {
 	var bufferSize ir.RValue = ir.MakeValue(0, ir.IntType)
-	if len(callExpr.Args) > 1 {
+	if res := b.findCapacityFromAnnotation(callExpr, ctx); res != nil {
+		bufferSize = res
+	} else if len(callExpr.Args) > 1 {
 		bufferSizeExpr := callExpr.Args[1]
 
 		if res := b.processContainerLength(bufferSizeExpr, ctx); res != nil {
@@ -32,7 +35,7 @@ func (b *builder) processMakeChanExpr(callExpr *ast.CallExpr, ctx *context) *ir.
 		} else {
 			p := b.fset.Position(bufferSizeExpr.Pos())
 			aStr := b.nodeToString(bufferSizeExpr)
-			b.addWarning(fmt.Errorf("%v: can not process buffer size: %s", p, aStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, aStr, "can not process buffer size: %s", aStr))
 		}
 	}
 
@@ -44,13 +47,55 @@ func (b *builder) processMakeChanExpr(callExpr *ast.CallExpr, ctx *context) *ir.
 	return result
 }
 
-func (b *builder) processReceiveExpr(expr *ast.UnaryExpr, addToCtx bool, ctx *context) *ir.ChanCommOpStmt {
+// chanPayloadType returns the type of the values transferred through channels
+// of the given type and whether received values need to be copied. The
+// payload type is nil if the element type of the channel is not modeled.
+func (b *builder) chanPayloadType(typesType types.Type) (payloadType ir.Type, requiresCopy bool) {
+	typesChan, ok := typesType.Underlying().(*types.Chan)
+	if !ok {
+		return nil, false
+	}
+	elementTypesType := typesChan.Elem()
+	switch irType := b.typesTypeToIrType(elementTypesType).(type) {
+	case nil:
+		return nil, false
+	case ir.BasicType:
+		switch irType {
+		case ir.MutexType, ir.RWMutexType, ir.WaitGroupType, ir.OnceType:
+			if !b.isPointer(elementTypesType) {
+				return nil, false
+			}
+		}
+		return irType, false
+	case *ir.StructType:
+		return irType, !b.isPointer(elementTypesType)
+	case *ir.ContainerType:
+		return irType, irType.Kind() == ir.Array && !b.isPointer(elementTypesType)
+	default:
+		return nil, false
+	}
+}
+
+// processReceiveExpr processes the given receive expression. If bindValue is
+// set and the element type of the channel is modeled, the received value gets
+// stored in a new variable, which is the payload of the returned statement.
+func (b *builder) processReceiveExpr(expr *ast.UnaryExpr, addToCtx, bindValue bool, ctx *context) *ir.ChanCommOpStmt {
 	chanVar := b.findChannel(expr.X, ctx)
 	if chanVar == nil {
 		return nil
 	}
 
 	receiveStmt := ir.NewChanCommOpStmt(chanVar, ir.Receive, expr.Pos(), expr.End())
+	if payloadType, _ := b.chanPayloadType(ctx.typesInfo.TypeOf(expr.X)); payloadType != nil {
+		var receivedVar *ir.Variable
+		if bindValue {
+			receivedVar = b.program.NewVariable("", payloadType.UninitializedValue())
+			ctx.body.Scope().AddVariable(receivedVar)
+			receiveStmt.SetPayload(payloadType, receivedVar)
+		} else {
+			receiveStmt.SetPayload(payloadType, nil)
+		}
+	}
 	if addToCtx {
 		ctx.body.AddStmt(receiveStmt)
 	}
@@ -58,7 +103,10 @@ func (b *builder) processReceiveExpr(expr *ast.UnaryExpr, addToCtx bool, ctx *co
 }
 
 func (b *builder) processSendStmt(stmt *ast.SendStmt, addToCtx bool, ctx *context) *ir.ChanCommOpStmt {
-	b.processExpr(stmt.Value, ctx)
+	value := b.processExpr(stmt.Value, ctx)
+	if typesChan, ok := ctx.typesInfo.TypeOf(stmt.Chan).Underlying().(*types.Chan); ok {
+		value = b.processImplicitConversion(value, stmt.Value, typesChan.Elem(), ctx)
+	}
 
 	chanVar := b.findChannel(stmt.Chan, ctx)
 	if chanVar == nil {
@@ -66,6 +114,17 @@ func (b *builder) processSendStmt(stmt *ast.SendStmt, addToCtx bool, ctx *contex
 	}
 
 	sendStmt := ir.NewChanCommOpStmt(chanVar, ir.Send, stmt.Pos(), stmt.End())
+	if payloadType, _ := b.chanPayloadType(ctx.typesInfo.TypeOf(stmt.Chan)); payloadType != nil {
+		if value == ir.Nil {
+			value = payloadType.UninitializedValue()
+		} else if value == nil || value.Type() != payloadType {
+			p := b.fset.Position(stmt.Value.Pos())
+			valueStr := b.nodeToString(stmt.Value)
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, valueStr, "could not resolve sent value: %s", valueStr))
+			value = payloadType.UninitializedValue()
+		}
+		sendStmt.SetPayload(payloadType, value)
+	}
 	if addToCtx {
 		ctx.body.AddStmt(sendStmt)
 	}
@@ -92,7 +151,7 @@ func (b *builder) processSelectStmt(stmt *ast.SelectStmt, label string, ctx *con
 			body = selectCase.Body()
 
 		case *ast.ExprStmt:
-			receiveStmt := b.processReceiveExpr(stmt.X.(*ast.UnaryExpr), false, ctx)
+			receiveStmt := b.processReceiveExpr(stmt.X.(*ast.UnaryExpr), false, false, ctx)
 			if receiveStmt == nil {
 				continue
 			}
@@ -102,7 +161,8 @@ func (b *builder) processSelectStmt(stmt *ast.SelectStmt, label string, ctx *con
 			body = selectCase.Body()
 
 		case *ast.AssignStmt:
-			receiveStmt := b.processReceiveExpr(stmt.Rhs[0].(*ast.UnaryExpr), false, ctx)
+			receiveExpr := stmt.Rhs[0].(*ast.UnaryExpr)
+			receiveStmt := b.processReceiveExpr(receiveExpr, false, true, ctx)
 			if receiveStmt == nil {
 				continue
 			}
@@ -126,19 +186,36 @@ func (b *builder) processSelectStmt(stmt *ast.SelectStmt, label string, ctx *con
 			// Handle Lhs expressions:
 			lhs := b.processExprs(stmt.Lhs, subCtx)
 			for i, expr := range stmt.Lhs {
-				if _, ok := lhs[i]; !ok {
+				if i == 1 {
+					if okVar := b.findTrackedVar(expr, subCtx); okVar != nil {
+						receivedOk := b.bindReceiveOk(receiveStmt, ctx)
+						body.AddStmt(ir.NewAssignStmt(receivedOk, okVar, false, expr.Pos(), expr.End()))
+						continue
+					}
+				}
+				l, ok := lhs[i].(ir.LValue)
+				if !ok {
 					continue
 				}
-				p := b.fset.Position(expr.Pos())
-				b.addWarning(
-					fmt.Errorf("%v: can not model value passing via channel", p))
-				continue
+				if i > 0 || receiveStmt.Payload() == nil {
+					p := b.fset.Position(expr.Pos())
+					exprStr := b.nodeToString(expr)
+					b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, exprStr, "can not model value passing via channel"))
+					continue
+				}
+				if irContainerAccess, ok := l.(*ir.ContainerAccess); ok {
+					irContainerAccess.SetKind(ir.Write)
+				}
+				_, requiresCopy := b.chanPayloadType(ctx.typesInfo.TypeOf(receiveExpr.X))
+				assignStmt := ir.NewAssignStmt(receiveStmt.Payload(), l, requiresCopy, expr.Pos(), expr.End())
+				body.AddStmt(assignStmt)
 			}
 
 		default:
 			if stmt != nil {
 				p := b.fset.Position(stmt.Pos())
-				b.addWarning(fmt.Errorf("%v: unexpected %T communcation clause", p, stmt))
+				stmtStr := b.nodeToString(stmt)
+				b.addWarning(diag.Warningf(diag.UnsupportedStatement, p, stmtStr, "unexpected %T communcation clause", stmt))
 			}
 
 			selectStmt.SetHasDefault(true)
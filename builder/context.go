@@ -16,6 +16,8 @@ type context struct {
 
 	enclosingStmts      []ir.Stmt
 	enclosingStmtLabels map[string]ir.Stmt
+
+	rangeFuncLoop *rangeFuncLoop
 }
 
 func newContext(cmap ast.CommentMap, typesInfo *types.Info, f *ir.Func) *context {
@@ -95,6 +97,7 @@ func (c *context) subContextForBody(stmt ir.Stmt, label string, containedBody *i
 	if label != "" {
 		ctx.enclosingStmtLabels[label] = stmt
 	}
+	ctx.rangeFuncLoop = c.rangeFuncLoop
 
 	return ctx
 }
@@ -0,0 +1,106 @@
+package builder
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/ir"
+)
+
+// maxDuration is the largest duration (in time units) that can be represented
+// by an Uppaal int.
+const maxDuration = 32767
+
+// unknownDuration represents durations that could not be determined.
+var unknownDuration = ir.MakeValue(-1, ir.IntType)
+
+// isDuration returns whether the given type is time.Duration and durations
+// get modeled, which is the case in timed mode only.
+func (b *builder) isDuration(typesType types.Type) bool {
+	return b.config.Timed && typesType != nil && b.typeArgs.substitute(typesType).String() == "time.Duration"
+}
+
+// processDurationExpr returns the value of the given time.Duration expression
+// measured in the time unit of the configuration. Constant durations get
+// evaluated directly, durations held by modeled variables or returned by
+// modeled functions get passed on, and all other durations are unknown.
+func (b *builder) processDurationExpr(expr ast.Expr, ctx *context) ir.RValue {
+	if typeAndValue := ctx.typesInfo.Types[expr]; typeAndValue.Value != nil {
+		return b.durationValue(typeAndValue.Value, expr)
+	}
+
+	var result ir.RValue
+	switch e := expr.(type) {
+	case *ast.Ident:
+		result = b.processIdent(e, ctx)
+	case *ast.ParenExpr:
+		return b.processDurationExpr(e.X, ctx)
+	case *ast.SelectorExpr:
+		result = b.processSelectorExpr(e, ctx)
+	case *ast.IndexExpr:
+		result = b.processIndexExpr(e, ctx)
+	case *ast.CallExpr:
+		if resultVar := b.processCallExpr(e, ctx)[0]; resultVar != nil {
+			result = resultVar
+		}
+	case *ast.BinaryExpr:
+		b.processExpr(e.X, ctx)
+		b.processExpr(e.Y, ctx)
+	case *ast.UnaryExpr:
+		if e.Op == token.ARROW {
+			if receiveStmt := b.processReceiveExpr(e, true, true, ctx); receiveStmt != nil && receiveStmt.Payload() != nil {
+				result = receiveStmt.Payload()
+			}
+		} else {
+			b.processExpr(e.X, ctx)
+		}
+	case *ast.StarExpr:
+		b.processExpr(e.X, ctx)
+	case *ast.TypeAssertExpr:
+		b.processExpr(e.X, ctx)
+	}
+	if result == nil || result.Type() != ir.IntType {
+		return unknownDuration
+	}
+	return result
+}
+
+func (b *builder) durationValue(val constant.Value, expr ast.Expr) ir.Value {
+	val = constant.ToInt(val)
+	if val.Kind() != constant.Int {
+		return unknownDuration
+	}
+	ns, _ := constant.Int64Val(val)
+	d := ns / int64(b.config.TimeUnit)
+	if d < 0 {
+		d = 0
+	} else if d > maxDuration {
+		p := b.fset.Position(expr.Pos())
+		exprStr := b.nodeToString(expr)
+		b.addWarning(diag.Warningf(diag.IgnoredExpression, p, exprStr, "duration exceeds %d time units, using maximum: %s", maxDuration, exprStr))
+		d = maxDuration
+	}
+	return ir.MakeValue(d, ir.IntType)
+}
+
+// processArithmeticUpdate processes the target of a compound assignment or an
+// increment or decrement statement. Arithmetic is not modeled, therefore
+// modeled durations become unknown.
+func (b *builder) processArithmeticUpdate(expr ast.Expr, ctx *context) {
+	irVal := b.processExpr(expr, ctx)
+	if !b.isDuration(ctx.typesInfo.TypeOf(expr)) {
+		return
+	}
+	irVar, ok := irVal.(ir.LValue)
+	if !ok {
+		return
+	}
+	if irContainerAccess, ok := irVar.(*ir.ContainerAccess); ok {
+		irContainerAccess.SetKind(ir.Write)
+	}
+	assignStmt := ir.NewAssignStmt(unknownDuration, irVar, false, expr.Pos(), expr.End())
+	ctx.body.AddStmt(assignStmt)
+}
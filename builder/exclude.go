@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/arneph/toph/diag"
+)
+
+// isExcludedFunc returns whether the given function or method is excluded
+// from translation, either by name or because its receiver type is excluded.
+// Excluded functions get translated as no-ops.
+func (b *builder) isExcludedFunc(typesFunc *types.Func) bool {
+	if b.shouldExcludeMember(typesFunc.FullName(), typesFunc.Pkg()) {
+		b.excludedMembers[typesFunc.FullName()] = struct{}{}
+		return true
+	}
+	recv := typesFunc.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	recvType := recv.Type()
+	if pointer, ok := recvType.(*types.Pointer); ok {
+		recvType = pointer.Elem()
+	}
+	if typesNamed, ok := recvType.(*types.Named); ok && b.isExcludedType(typesNamed) {
+		b.excludedMembers[typesFunc.FullName()] = struct{}{}
+		return true
+	}
+	return false
+}
+
+// isExcludedType returns whether the given type is excluded from
+// translation. Excluded types do not get modeled.
+func (b *builder) isExcludedType(typesNamed *types.Named) bool {
+	if typesNamed.Obj().Pkg() == nil {
+		return false
+	}
+	name := typesNamed.Obj().Pkg().Path() + "." + typesNamed.Obj().Name()
+	if b.shouldExcludeMember(name, typesNamed.Obj().Pkg()) {
+		b.excludedMembers[name] = struct{}{}
+		return true
+	}
+	return false
+}
+
+// shouldExcludeMember returns whether the member of the given package with the
+// given fully qualified name, using the package path, is excluded. Patterns
+// can qualify members with the package path or the package name, for example
+// "(*github.com/acme/server.T).Method" or "(*server.T).Method".
+func (b *builder) shouldExcludeMember(name string, pkg *types.Package) bool {
+	if b.config.ShouldExcludeMember(name) {
+		return true
+	} else if pkg == nil {
+		return false
+	}
+	return b.config.ShouldExcludeMember(strings.Replace(name, pkg.Path()+".", pkg.Name()+".", 1))
+}
+
+// addExcludedMembersSummary adds a warning listing all excluded functions,
+// methods, and types encountered while building the program.
+func (b *builder) addExcludedMembersSummary() {
+	if len(b.excludedMembers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(b.excludedMembers))
+	for name := range b.excludedMembers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.addWarning(diag.Warningf(diag.General, token.Position{}, "", "excluded %d functions, methods, and types from translation: %s", len(names), strings.Join(names, ", ")))
+}
@@ -1,11 +1,11 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
@@ -33,6 +33,10 @@ func (b *builder) processExprs(exprs []ast.Expr, ctx *context) map[int]ir.RValue
 }
 
 func (b *builder) processExpr(expr ast.Expr, ctx *context) ir.RValue {
+	if typeAndValue, ok := ctx.typesInfo.Types[expr]; ok && !typeAndValue.IsType() && b.isDuration(typeAndValue.Type) {
+		return b.processDurationExpr(expr, ctx)
+	}
+
 	switch e := expr.(type) {
 	case *ast.BasicLit:
 		return nil
@@ -60,14 +64,19 @@ func (b *builder) processExpr(expr ast.Expr, ctx *context) ir.RValue {
 	case *ast.Ident:
 		return b.processIdent(e, ctx)
 	case *ast.IndexExpr:
+		if ctx.typesInfo.Types[e.Index].IsType() {
+			// Explicit instantiation of a generic function:
+			return b.processExpr(e.X, ctx)
+		}
 		return b.processIndexExpr(e, ctx)
+	case *ast.IndexListExpr:
+		return b.processExpr(e.X, ctx)
 	case *ast.KeyValueExpr:
 		b.processExpr(e.Key, ctx)
 		b.processExpr(e.Value, ctx)
 		return nil
 	case *ast.ParenExpr:
-		b.processExpr(e.X, ctx)
-		return nil
+		return b.processExpr(e.X, ctx)
 	case *ast.SelectorExpr:
 		return b.processSelectorExpr(e, ctx)
 	case *ast.SliceExpr:
@@ -85,7 +94,7 @@ func (b *builder) processExpr(expr ast.Expr, ctx *context) ir.RValue {
 			if e.Low != nil || e.High != nil || e.Max != nil {
 				p := b.fset.Position(e.Pos())
 				eStr := b.nodeToString(e)
-				b.addWarning(fmt.Errorf("%v: ignoring indices of slice expression: %s", p, eStr))
+				b.addWarning(diag.Warningf(diag.IgnoredExpression, p, eStr, "ignoring indices of slice expression: %s", eStr))
 			}
 		}
 		return result
@@ -98,8 +107,11 @@ func (b *builder) processExpr(expr ast.Expr, ctx *context) ir.RValue {
 	case *ast.UnaryExpr:
 		switch e.Op {
 		case token.ARROW:
-			b.processReceiveExpr(e, true, ctx)
-			return nil
+			receiveStmt := b.processReceiveExpr(e, true, true, ctx)
+			if receiveStmt == nil || receiveStmt.Payload() == nil {
+				return nil
+			}
+			return receiveStmt.Payload()
 		case token.AND:
 			return b.processExpr(e.X, ctx)
 		default:
@@ -120,7 +132,7 @@ func (b *builder) processExpr(expr ast.Expr, ctx *context) ir.RValue {
 		}
 		p := b.fset.Position(e.Pos())
 		eStr := b.nodeToString(e)
-		b.addWarning(fmt.Errorf("%v: ignoring %T expression: %s", p, e, eStr))
+		b.addWarning(diag.Warningf(diag.IgnoredExpression, p, eStr, "ignoring %T expression: %s", e, eStr))
 		return nil
 	}
 }
@@ -135,13 +147,13 @@ func (b *builder) processIdent(ident *ast.Ident, ctx *context) ir.RValue {
 	usedTypesObj := ctx.typesInfo.ObjectOf(ident)
 	if usedTypesObj == nil {
 		p := b.fset.Position(ident.Pos())
-		b.addWarning(fmt.Errorf("%v: types.Object for identifier is nil: %s", p, ident.Name))
+		b.addWarning(diag.Warningf(diag.Internal, p, ident.Name, "types.Object for identifier is nil: %s", ident.Name))
 		return nil
 	}
 	typesType := usedTypesObj.Type()
 	if typesType == nil {
 		p := b.fset.Position(ident.Pos())
-		b.addWarning(fmt.Errorf("%v: types.Type for identifier is nil: %s", p, ident.Name))
+		b.addWarning(diag.Warningf(diag.Internal, p, ident.Name, "types.Type for identifier is nil: %s", ident.Name))
 		return nil
 	}
 	irType := b.typesTypeToIrType(typesType)
@@ -166,7 +178,7 @@ func (b *builder) processIdent(ident *ast.Ident, ctx *context) ir.RValue {
 		}
 		return v
 	case *types.Func:
-		f := b.funcs[usedTypesObj]
+		f := b.findFunc(usedTypesObj, ident, ctx)
 		if f == nil {
 			return nil
 		}
@@ -175,7 +187,7 @@ func (b *builder) processIdent(ident *ast.Ident, ctx *context) ir.RValue {
 		return nil
 	default:
 		p := b.fset.Position(usedTypesObj.Pos())
-		b.addWarning(fmt.Errorf("%v: unexpected types.Object type: %T", p, usedTypesObj))
+		b.addWarning(diag.Warningf(diag.Internal, p, "", "unexpected types.Object type: %T", usedTypesObj))
 		return nil
 	}
 }
@@ -191,11 +203,11 @@ func (b *builder) processSelectorExpr(selExpr *ast.SelectorExpr, ctx *context) i
 		return b.processIdent(selExpr.Sel, ctx)
 	}
 
-	if typesSelection.Kind() != types.FieldVal {
-		p := b.fset.Position(selExpr.Pos())
-		selExprStr := b.nodeToString(selExpr)
-		b.addWarning(fmt.Errorf("%v: method expressions or method values are not supported: %s", p, selExprStr))
-		return nil
+	switch typesSelection.Kind() {
+	case types.MethodVal:
+		return b.processMethodValue(selExpr, ctx)
+	case types.MethodExpr:
+		return b.processMethodExpr(selExpr, ctx)
 	}
 
 	xVal := b.processExpr(selExpr.X, ctx)
@@ -208,7 +220,7 @@ func (b *builder) processSelectorExpr(selExpr *ast.SelectorExpr, ctx *context) i
 	if xVal == nil || !ok {
 		p := b.fset.Position(selExpr.X.Pos())
 		xStr := b.nodeToString(selExpr.X)
-		b.addWarning(fmt.Errorf("%v: could not resolve struct variable expression: %s", p, xStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, xStr, "could not resolve struct variable expression: %s", xStr))
 		return nil
 	}
 	irStructType := irStructVal.Type().(*ir.StructType)
@@ -219,11 +231,11 @@ func (b *builder) processSelectorExpr(selExpr *ast.SelectorExpr, ctx *context) i
 	if fieldIrType == nil {
 		return nil
 	}
-	irField, ok := b.fields[fieldTypesVar]
+	irField, ok := b.findField(fieldTypesVar, irStructType)
 	if !ok {
 		p := b.fset.Position(selExpr.Sel.Pos())
 		selStr := b.nodeToString(selExpr.Sel)
-		b.addWarning(fmt.Errorf("%v: could not resolve field expression: %s", p, selStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, selStr, "could not resolve field expression: %s", selStr))
 		return nil
 	}
 
@@ -233,7 +245,7 @@ func (b *builder) processSelectorExpr(selExpr *ast.SelectorExpr, ctx *context) i
 		if !ok {
 			p := b.fset.Position(selExpr.Sel.Pos())
 			selStr := b.nodeToString(selExpr.Sel)
-			b.addWarning(fmt.Errorf("%v: could not resolve field expression: %s", p, selStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, selStr, "could not resolve field expression: %s", selStr))
 			return nil
 		}
 		for _, irField := range embeddedFields {
@@ -263,7 +275,7 @@ func (b *builder) processIndexExpr(indexExpr *ast.IndexExpr, ctx *context) ir.RV
 	if iIrType != nil {
 		p := b.fset.Position(iExpr.Pos())
 		iStr := b.nodeToString(iExpr)
-		b.addWarning(fmt.Errorf("%v: ignoring index value: %s", p, iStr))
+		b.addWarning(diag.Warningf(diag.IgnoredExpression, p, iStr, "ignoring index value: %s", iStr))
 	}
 	irContainerType := xIrType.(*ir.ContainerType)
 	irContainerIndex := ir.RValue(ir.RandomIndex)
@@ -1,11 +1,11 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 	"golang.org/x/tools/go/ast/astutil"
 )
@@ -34,7 +34,7 @@ func (b *builder) findCallee(funcExpr ast.Expr, ctx *context) (callee ir.Callabl
 		default:
 			p := b.fset.Position(funcExpr.Pos())
 			funcExprStr := b.nodeToString(funcExpr)
-			b.addWarning(fmt.Errorf("%v: could not resolve func expr: %v", p, funcExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedCallee, p, funcExprStr, "could not resolve func expr: %v", funcExprStr))
 		}
 		return nil, nil
 	}
@@ -51,12 +51,12 @@ func (b *builder) findCallee(funcExpr ast.Expr, ctx *context) (callee ir.Callabl
 		if callee == nil {
 			p := b.fset.Position(funcExpr.Pos())
 			funcExprStr := b.nodeToString(funcExpr)
-			b.addWarning(fmt.Errorf("%v: could not resolve func index in ir.Program: %v", p, funcExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedCallee, p, funcExprStr, "could not resolve func index in ir.Program: %v", funcExprStr))
 			return nil, nil
 		}
 	}
 
-	calleeSignature = calleeTypesType.Underlying().(*types.Signature)
+	calleeSignature = b.typeArgs.substitute(calleeTypesType).Underlying().(*types.Signature)
 
 	return callee, calleeSignature
 }
@@ -66,15 +66,33 @@ func (b *builder) processCallExpr(callExpr *ast.CallExpr, ctx *context) map[int]
 }
 
 func (b *builder) processDeferStmt(stmt *ast.DeferStmt, ctx *context) {
+	if ctx.rangeFuncLoop != nil {
+		p := b.fset.Position(stmt.Pos())
+		stmtStr := b.nodeToString(stmt)
+		b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, stmtStr, "deferred call in range-over-func loop runs at the end of the iteration: %s", stmtStr))
+	}
 	b.processCallExprWithCallKind(stmt.Call, ir.Defer, ctx)
 }
 
 func (b *builder) processGoStmt(stmt *ast.GoStmt, ctx *context) {
 	b.processCallExprWithCallKind(stmt.Call, ir.Go, ctx)
+	b.processGoStmtAnnotations(stmt, ctx)
 }
 
 func (b *builder) processReturnStmt(stmt *ast.ReturnStmt, ctx *context) {
+	if ctx.rangeFuncLoop != nil {
+		b.processRangeFuncReturnStmt(stmt, ctx)
+		return
+	}
 	resultVals := b.processExprs(stmt.Results, ctx)
+	if sig := ctx.currentFunc().Signature(); sig != nil && len(stmt.Results) == sig.Results().Len() {
+		for i, resultExpr := range stmt.Results {
+			resultTypesType := sig.Results().At(i).Type()
+			if v := b.processImplicitConversion(resultVals[i], resultExpr, resultTypesType, ctx); v != nil {
+				resultVals[i] = v
+			}
+		}
+	}
 
 	returnStmt := ir.NewReturnStmt(false, stmt.Pos(), stmt.End())
 	ctx.body.AddStmt(returnStmt)
@@ -97,7 +115,7 @@ func (b *builder) processReturnStmt(stmt *ast.ReturnStmt, ctx *context) {
 				}
 				resultExpr := stmt.Results[i]
 				resultExprStr := b.nodeToString(resultExpr)
-				b.addWarning(fmt.Errorf("%v: could not resolve return value: %s", p, resultExprStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, resultExprStr, "could not resolve return value: %s", resultExprStr))
 			}
 		}
 	} else {
@@ -127,7 +145,15 @@ func (b *builder) processRecoverCall(callExpr *ast.CallExpr, ctx *context) {
 	ctx.body.AddStmt(recoverStmt)
 }
 
+func (b *builder) processGoexitCall(callExpr *ast.CallExpr, ctx *context) {
+	goexitStmt := ir.NewGoexitStmt(callExpr.Pos(), callExpr.End())
+	ctx.body.AddStmt(goexitStmt)
+}
+
 func (b *builder) processCallExprWithCallKind(callExpr *ast.CallExpr, callKind ir.CallKind, ctx *context) map[int]*ir.Variable {
+	if typeAndValue, ok := ctx.typesInfo.Types[callExpr.Fun]; ok && typeAndValue.IsType() {
+		return b.processConversionExpr(callExpr, ctx)
+	}
 	if b.canIgnoreCall(callExpr, ctx) {
 		b.processExprs(callExpr.Args, ctx)
 		return map[int]*ir.Variable{}
@@ -136,7 +162,8 @@ func (b *builder) processCallExprWithCallKind(callExpr *ast.CallExpr, callKind i
 	if name, ok := b.isKnownBuiltin(callExpr, ctx); ok {
 		if callKind != ir.Call {
 			p := b.fset.Position(callExpr.Pos())
-			b.addWarning(fmt.Errorf("%v: only direct calls to %s are supported", p, name))
+			callExprStr := b.nodeToString(callExpr)
+			b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, callExprStr, "only direct calls to %s are supported", name))
 			return nil
 		}
 		switch name {
@@ -163,6 +190,15 @@ func (b *builder) processCallExprWithCallKind(callExpr *ast.CallExpr, callKind i
 			b.processPanicCall(callExpr, ctx)
 		case "recover":
 			b.processRecoverCall(callExpr, ctx)
+		case "runtime.Goexit":
+			b.processGoexitCall(callExpr, ctx)
+		case "sync.OnceFunc", "sync.OnceValue", "sync.OnceValues":
+			resultVar := b.processOnceFuncExpr(callExpr, ctx)
+			if resultVar != nil {
+				return map[int]*ir.Variable{0: resultVar}
+			}
+		case "(*testing.T).Run":
+			b.processTestRunExpr(callExpr, ctx)
 		}
 		return map[int]*ir.Variable{}
 	}
@@ -175,15 +211,37 @@ func (b *builder) processCallExprWithCallKind(callExpr *ast.CallExpr, callKind i
 		return map[int]*ir.Variable{}
 	}
 
+	if method := b.findInterfaceMethod(callExpr.Fun, ctx); method != nil {
+		return b.processInterfaceMethodCallExprWithCallKind(callExpr, method, callKind, ctx)
+	}
+
 	return b.processRegularCallExprWithCallKind(callExpr, callKind, ctx)
 }
 
+func (b *builder) processConversionExpr(callExpr *ast.CallExpr, ctx *context) map[int]*ir.Variable {
+	val := b.processExpr(callExpr.Args[0], ctx)
+	val = b.processImplicitConversion(val, callExpr.Args[0], ctx.typesInfo.TypeOf(callExpr), ctx)
+	irType := b.typesTypeToIrType(ctx.typesInfo.TypeOf(callExpr))
+	if val == nil || irType == nil || val.Type() != irType {
+		return map[int]*ir.Variable{}
+	}
+	irVar := b.program.NewVariable("", irType.UninitializedValue())
+	ctx.body.Scope().AddVariable(irVar)
+	assignStmt := ir.NewAssignStmt(val, irVar, false, callExpr.Pos(), callExpr.End())
+	ctx.body.AddStmt(assignStmt)
+	return map[int]*ir.Variable{0: irVar}
+}
+
 func (b *builder) processCallReceiverVal(callExpr *ast.CallExpr, calleeSignature *types.Signature, ctx *context) (recvVal ir.RValue, requiresCopy, ok bool) {
 	recv := calleeSignature.Recv()
 	if recv == nil {
 		return nil, false, true
 	}
 	recvExpr := callExpr.Fun.(*ast.SelectorExpr).X
+	return b.processReceiverVal(recvExpr, recv, ctx)
+}
+
+func (b *builder) processReceiverVal(recvExpr ast.Expr, recv *types.Var, ctx *context) (recvVal ir.RValue, requiresCopy, ok bool) {
 	recvVal = b.processExpr(recvExpr, ctx)
 	recvTypesType := recv.Type()
 	irType := b.typesTypeToIrType(recvTypesType)
@@ -193,7 +251,7 @@ func (b *builder) processCallReceiverVal(callExpr *ast.CallExpr, calleeSignature
 	if recvVal == nil {
 		recvExprStr := b.nodeToString(recvExpr)
 		p := b.fset.Position(recvExpr.Pos())
-		b.addWarning(fmt.Errorf("%v: could not resolve receiver: %s", p, recvExprStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, recvExprStr, "could not resolve receiver: %s", recvExprStr))
 		return nil, false, false
 	}
 	if recvLV, ok := recvVal.(ir.LValue); ok && recvLV.Type() != irType {
@@ -202,7 +260,7 @@ func (b *builder) processCallReceiverVal(callExpr *ast.CallExpr, calleeSignature
 		if !ok {
 			recvExprStr := b.nodeToString(recvExpr)
 			p := b.fset.Position(recvExpr.Pos())
-			b.addWarning(fmt.Errorf("%v: could not resolve receiver: %s", p, recvExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, recvExprStr, "could not resolve receiver: %s", recvExprStr))
 			return nil, false, false
 		}
 		for _, field := range embeddedFields {
@@ -227,6 +285,19 @@ func (b *builder) processCallArgVals(callExpr *ast.CallExpr, calleeSignature *ty
 	if calleeSignature.Variadic() {
 		regularParamN--
 	}
+	for i, argExpr := range callExpr.Args {
+		var paramTypesType types.Type
+		if i < regularParamN {
+			paramTypesType = calleeSignature.Params().At(i).Type()
+		} else if calleeSignature.Variadic() && callExpr.Ellipsis == token.NoPos {
+			paramTypesType = calleeSignature.Params().At(regularParamN).Type().(*types.Slice).Elem()
+		} else {
+			continue
+		}
+		if argVal := b.processImplicitConversion(argVals[i], argExpr, paramTypesType, ctx); argVal != nil {
+			argVals[i] = argVal
+		}
+	}
 	for i := 0; i < regularParamN; i++ {
 		param := calleeSignature.Params().At(i)
 		paramTypesType := param.Type()
@@ -242,7 +313,7 @@ func (b *builder) processCallArgVals(callExpr *ast.CallExpr, calleeSignature *ty
 			argExpr := callExpr.Args[i]
 			argExprStr := b.nodeToString(argExpr)
 			p := b.fset.Position(argExpr.Pos())
-			b.addWarning(fmt.Errorf("%v: could not resolve argument: %s", p, argExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, argExprStr, "could not resolve argument: %s", argExprStr))
 			return nil, nil, false
 		} else if argVal == ir.Nil {
 			argVal = irType.UninitializedValue()
@@ -270,7 +341,7 @@ func (b *builder) processCallArgVals(callExpr *ast.CallExpr, calleeSignature *ty
 					argExpr := callExpr.Args[i]
 					argExprStr := b.nodeToString(argExpr)
 					p := b.fset.Position(argExpr.Pos())
-					b.addWarning(fmt.Errorf("%v: could not resolve argument: %s", p, argExprStr))
+					b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, argExprStr, "could not resolve argument: %s", argExprStr))
 					return nil, nil, false
 				} else if val == ir.Nil {
 					val = irElementType.UninitializedValue()
@@ -362,13 +433,216 @@ func (b *builder) processRegularCallExprWithCallKind(callExpr *ast.CallExpr, cal
 	return resultVars
 }
 
-func (b *builder) liftedSpecialOpFunc(specialOp ir.SpecialOp) *ir.Func {
-	irFunc, ok := b.liftedSpecialOpFuncs[specialOp]
+// findInterfaceMethod returns the interface method called by the given func
+// expression or nil if the expression does not refer to an interface method
+// (without substitute).
+func (b *builder) findInterfaceMethod(funcExpr ast.Expr, ctx *context) *types.Func {
+	selExpr, ok := astutil.Unparen(funcExpr).(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	typesSelection, ok := ctx.typesInfo.Selections[selExpr]
+	if !ok || typesSelection.Kind() != types.MethodVal {
+		return nil
+	}
+	method, ok := typesSelection.Obj().(*types.Func)
+	if !ok {
+		return nil
+	}
+	recv := method.Type().(*types.Signature).Recv()
+	if recv == nil || !types.IsInterface(recv.Type()) {
+		return nil
+	} else if b.getSubstituteFunc(method) != nil {
+		return nil
+	}
+	return method
+}
+
+// findInterfaceMethodImpls returns the concrete methods implementing the
+// given interface method among the types declared in the loaded packages
+// (excluding methods without ir.Func).
+func (b *builder) findInterfaceMethodImpls(method *types.Func) []*types.Func {
+	recv := method.Type().(*types.Signature).Recv()
+	iface := recv.Type().Underlying().(*types.Interface)
+	var impls []*types.Func
+	seen := make(map[*types.Func]bool)
+	for _, pkg := range b.pkgs {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			typesType := typeName.Type()
+			if types.IsInterface(typesType) {
+				continue
+			} else if typesNamed, ok := typesType.(*types.Named); ok && typesNamed.TypeParams().Len() > 0 {
+				continue
+			}
+			ptrType := types.NewPointer(typesType)
+			if !types.Implements(ptrType, iface) {
+				continue
+			}
+			implObj, _, _ := types.LookupFieldOrMethod(ptrType, false, method.Pkg(), method.Name())
+			impl, ok := implObj.(*types.Func)
+			if !ok || seen[impl] {
+				continue
+			} else if _, ok := b.funcs[impl]; !ok {
+				continue
+			}
+			seen[impl] = true
+			impls = append(impls, impl)
+		}
+	}
+	return impls
+}
+
+// processInterfaceMethodCallExprWithCallKind models a call of an interface
+// method. For modeled interfaces, the method value held by the interface value
+// gets called, which calls the method of the dynamic value. Calls of methods
+// of other interfaces are modeled as a nondeterministic choice between calls
+// of all implementations of the method. Since the dynamic values of these
+// interface values are unknown, implementations with modeled receiver types
+// get called with a new receiver instance.
+func (b *builder) processInterfaceMethodCallExprWithCallKind(callExpr *ast.CallExpr, method *types.Func, callKind ir.CallKind, ctx *context) map[int]*ir.Variable {
+	selExpr := astutil.Unparen(callExpr.Fun).(*ast.SelectorExpr)
+	recvVal := b.processExpr(selExpr.X, ctx)
+	if ifaceType := b.modeledInterfaceType(ctx.typesInfo.TypeOf(selExpr.X)); ifaceType != nil {
+		recvLV, ok := recvVal.(ir.LValue)
+		if !ok || recvLV == nil {
+			p := b.fset.Position(selExpr.X.Pos())
+			recvExprStr := b.nodeToString(selExpr.X)
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, recvExprStr, "could not resolve interface value: %s", recvExprStr))
+			b.processExprs(callExpr.Args, ctx)
+			return map[int]*ir.Variable{}
+		}
+		callee := b.interfaceMethodFieldSelection(recvLV, ifaceType, method)
+		calleeSignature := methodValueSignature(method.Type().(*types.Signature))
+		argVals, argRequiresCopy, ok := b.processCallArgVals(callExpr, calleeSignature, ctx)
+		if !ok {
+			return map[int]*ir.Variable{}
+		}
+		callStmt := ir.NewCallStmt(callee, calleeSignature, callKind, callExpr.Pos(), callExpr.End())
+		ctx.body.AddStmt(callStmt)
+		for i, v := range argVals {
+			callStmt.AddArg(i, v, argRequiresCopy[i])
+		}
+		if callKind != ir.Call {
+			return map[int]*ir.Variable{}
+		}
+		resultVars, resultRequiresCopy := b.processCallResultVars(calleeSignature, ctx)
+		for i, v := range resultVars {
+			callStmt.AddResult(i, v, resultRequiresCopy[i])
+		}
+		return resultVars
+	}
+
+	impls := b.findInterfaceMethodImpls(method)
+	if len(impls) == 0 {
+		if _, ok := b.typesPkgs[method.Pkg()]; ok {
+			p := b.fset.Position(callExpr.Pos())
+			callExprStr := b.nodeToString(callExpr)
+			b.addWarning(diag.Warningf(diag.UnresolvedCallee, p, callExprStr, "could not find implementations of interface method: %s", callExprStr))
+		}
+		b.processExprs(callExpr.Args, ctx)
+		return map[int]*ir.Variable{}
+	}
+
+	methodSignature := method.Type().(*types.Signature)
+	argVals, argRequiresCopy, ok := b.processCallArgVals(callExpr, methodSignature, ctx)
+	if !ok {
+		return map[int]*ir.Variable{}
+	}
+	resultVars := map[int]*ir.Variable{}
+	var resultRequiresCopy map[int]bool
+	if callKind == ir.Call {
+		resultVars, resultRequiresCopy = b.processCallResultVars(methodSignature, ctx)
+	}
+
+	b.addInterfaceMethodImplCalls(impls, callKind, argVals, argRequiresCopy, resultVars, resultRequiresCopy, callExpr, ctx.body)
+	return resultVars
+}
+
+// addInterfaceMethodImplCalls adds a nondeterministic choice between calls of
+// the given interface method implementations to the body.
+func (b *builder) addInterfaceMethodImplCalls(impls []*types.Func, callKind ir.CallKind, argVals map[int]ir.RValue, argRequiresCopy map[int]bool, resultVars map[int]*ir.Variable, resultRequiresCopy map[int]bool, callExpr ast.Node, body *ir.Body) {
+	for i, impl := range impls {
+		implBody := body
+		if i < len(impls)-1 {
+			ifStmt := ir.NewIfStmt(body.Scope(), callExpr.Pos(), callExpr.End(), callExpr.Pos(), callExpr.Pos())
+			body.AddStmt(ifStmt)
+			implBody = ifStmt.IfBranch()
+			body = ifStmt.ElseBranch()
+		}
+
+		callee := b.funcs[impl]
+		calleeSignature := impl.Type().(*types.Signature)
+		callStmt := ir.NewCallStmt(callee, calleeSignature, callKind, callExpr.Pos(), callExpr.End())
+		if recvVal := b.makeInterfaceMethodReceiver(calleeSignature, implBody, callExpr); recvVal != nil {
+			callStmt.AddArg(-1, recvVal, false)
+		}
+		implBody.AddStmt(callStmt)
+		for i, v := range argVals {
+			callStmt.AddArg(i, v, argRequiresCopy[i])
+		}
+		for i, v := range resultVars {
+			callStmt.AddResult(i, v, resultRequiresCopy[i])
+		}
+	}
+}
+
+func (b *builder) makeInterfaceMethodReceiver(calleeSignature *types.Signature, body *ir.Body, callExpr ast.Node) ir.RValue {
+	return b.makeFreshValue(calleeSignature.Recv().Type(), body, callExpr.Pos(), callExpr.End())
+}
+
+// makeFreshValue returns a new value of the given type, adding statements to
+// the given body to allocate structs and arrays. Returns nil if the type is
+// not modeled.
+func (b *builder) makeFreshValue(typesType types.Type, body *ir.Body, pos, end token.Pos) ir.RValue {
+	irType := b.typesTypeToIrType(typesType)
+	switch irType := irType.(type) {
+	case nil:
+		return nil
+	case *ir.StructType:
+		irVar := b.program.NewVariable("", irType.UninitializedValue())
+		body.Scope().AddVariable(irVar)
+		makeStructStmt := ir.NewMakeStructStmt(irVar, true, pos, end)
+		body.AddStmt(makeStructStmt)
+		return irVar
+	case *ir.ContainerType:
+		if irType.Kind() != ir.Array {
+			return irType.UninitializedValue()
+		}
+		irVar := b.program.NewVariable("", irType.UninitializedValue())
+		body.Scope().AddVariable(irVar)
+		makeContainerStmt := ir.NewMakeContainerStmt(irVar, ir.MakeValue(int64(-1), ir.IntType), true, pos, end)
+		body.AddStmt(makeContainerStmt)
+		return irVar
+	default:
+		return irType.UninitializedValue()
+	}
+}
+
+// liftedSpecialOp identifies a lifted special op function. Operations on
+// sync.Mutex and sync.RWMutex share special ops but operate on different
+// types, therefore the type of the first argument is part of the key.
+type liftedSpecialOp struct {
+	specialOp ir.SpecialOp
+	argType   ir.Type
+}
+
+func (b *builder) liftedSpecialOpFunc(specialOp ir.SpecialOp, argType ir.Type) *ir.Func {
+	key := liftedSpecialOp{specialOp, argType}
+	irFunc, ok := b.liftedSpecialOpFuncs[key]
 	if ok {
 		return irFunc
 	}
 
-	irFunc = b.program.AddOuterFunc("lifted_"+specialOp.String(), nil, token.NoPos, token.NoPos)
+	name := "lifted_" + specialOp.String()
+	if argType == ir.RWMutexType {
+		name = "lifted_rw_" + specialOp.String()
+	}
+	irFunc = b.program.AddOuterFunc(name, nil, token.NoPos, token.NoPos)
 	subCtx := newContext(nil, nil, irFunc)
 	switch specialOp {
 	case ir.Close:
@@ -377,10 +651,10 @@ func (b *builder) liftedSpecialOpFunc(specialOp ir.SpecialOp) *ir.Func {
 		closeStmt := ir.NewCloseChanStmt(chanVar, token.NoPos, token.NoPos)
 		subCtx.body.AddStmt(closeStmt)
 
-	case ir.Lock, ir.Unlock, ir.RLock, ir.RUnlock:
-		mutexVar := b.program.NewVariable("mu", ir.MutexType.UninitializedValue())
+	case ir.Lock, ir.Unlock, ir.RLock, ir.RUnlock, ir.TryLock, ir.TryRLock:
+		mutexVar := b.program.NewVariable("mu", argType.UninitializedValue())
 		irFunc.AddArg(0, mutexVar)
-		mutexOpStmt := ir.NewMutexOpStmt(mutexVar, specialOp.(ir.MutexOp), token.NoPos, token.NoPos)
+		mutexOpStmt := ir.NewMutexOpStmt(mutexVar, specialOp.(ir.MutexOp), nil, token.NoPos, token.NoPos)
 		subCtx.body.AddStmt(mutexOpStmt)
 
 	case ir.Add, ir.Wait:
@@ -402,15 +676,27 @@ func (b *builder) liftedSpecialOpFunc(specialOp ir.SpecialOp) *ir.Func {
 		onceDoStmt := ir.NewOnceDoStmt(onceVar, fVar, token.NoPos, token.NoPos)
 		subCtx.body.AddStmt(onceDoStmt)
 
+	case ir.CondWait, ir.Signal, ir.Broadcast:
+		condVar := b.program.NewVariable("cond", ir.CondType.UninitializedValue())
+		irFunc.AddArg(0, condVar)
+		condOpStmt := ir.NewCondOpStmt(condVar, specialOp.(ir.CondOp), token.NoPos, token.NoPos)
+		subCtx.body.AddStmt(condOpStmt)
+
 	case ir.DeadEnd:
 		deadEndStmt := ir.NewDeadEndStmt(token.NoPos, token.NoPos)
 		subCtx.body.AddStmt(deadEndStmt)
 
+	case ir.Sleep:
+		durationVar := b.program.NewVariable("d", ir.IntType.UninitializedValue())
+		irFunc.AddArg(0, durationVar)
+		sleepStmt := ir.NewSleepStmt(durationVar, token.NoPos, token.NoPos)
+		subCtx.body.AddStmt(sleepStmt)
+
 	default:
 		panic("unexpected special op")
 	}
 
-	b.liftedSpecialOpFuncs[specialOp] = irFunc
+	b.liftedSpecialOpFuncs[key] = irFunc
 	return irFunc
 }
 
@@ -437,17 +723,30 @@ func (b *builder) processSpecialOpCallExprWithCallKind(callExpr *ast.CallExpr, c
 		}
 		liftedFuncArgs = []ir.RValue{chanVal.(ir.RValue)}
 
-	case ir.Lock, ir.Unlock, ir.RLock, ir.RUnlock:
+	case ir.Lock, ir.Unlock, ir.RLock, ir.RUnlock, ir.TryLock, ir.TryRLock:
 		selExpr := callExpr.Fun.(*ast.SelectorExpr)
-		mutexVal := b.findMutex(selExpr.X, ctx)
+		mutexExpr := selExpr.X
+		var mutexType ir.Type = ir.MutexType
+		if rwMutexExpr := b.rLockerMutexExpr(mutexExpr, ctx); rwMutexExpr != nil {
+			mutexExpr = rwMutexExpr
+			mutexType = ir.RWMutexType
+		} else if method, ok := ctx.typesInfo.Uses[selExpr.Sel].(*types.Func); ok {
+			mutexType = b.mutexTypeForMethod(method)
+		}
+		mutexVal := b.findMutex(mutexExpr, mutexType, ctx)
 		if mutexVal == nil {
 			return nil
 		}
 
 		if callKind == ir.Call {
-			mutexOpStmt := ir.NewMutexOpStmt(mutexVal, specialOp.(ir.MutexOp), callExpr.Pos(), callExpr.End())
+			var result *ir.Variable
+			if specialOp == ir.TryLock || specialOp == ir.TryRLock {
+				result = b.program.NewVariable("", ir.IntType.UninitializedValue())
+				ctx.body.Scope().AddVariable(result)
+			}
+			mutexOpStmt := ir.NewMutexOpStmt(mutexVal, specialOp.(ir.MutexOp), result, callExpr.Pos(), callExpr.End())
 			ctx.body.AddStmt(mutexOpStmt)
-			return nil
+			return result
 		}
 		liftedFuncArgs = []ir.RValue{mutexVal.(ir.RValue)}
 
@@ -468,7 +767,7 @@ func (b *builder) processSpecialOpCallExprWithCallKind(callExpr *ast.CallExpr, c
 			} else {
 				p := b.fset.Position(deltaExpr.Pos())
 				aStr := b.nodeToString(deltaExpr)
-				b.addWarning(fmt.Errorf("%v: can not process sync.WaitGroup.Add argument: %s", p, aStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, aStr, "can not process sync.WaitGroup.Add argument: %s", aStr))
 			}
 		}
 
@@ -493,7 +792,7 @@ func (b *builder) processSpecialOpCallExprWithCallKind(callExpr *ast.CallExpr, c
 		if f == nil {
 			p := b.fset.Position(callExpr.Args[0].Pos())
 			fStr := b.nodeToString(callExpr.Args[0])
-			b.addWarning(fmt.Errorf("%v: can not process sync.Once.Do argument: %s", p, fStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, fStr, "can not process sync.Once.Do argument: %s", fStr))
 			return nil
 		}
 
@@ -504,6 +803,26 @@ func (b *builder) processSpecialOpCallExprWithCallKind(callExpr *ast.CallExpr, c
 		}
 		liftedFuncArgs = []ir.RValue{onceVal.(ir.RValue), f}
 
+	case ir.MakeCond:
+		if callKind != ir.Call {
+			return nil
+		}
+		return b.processNewCondExpr(callExpr, ctx)
+
+	case ir.CondWait, ir.Signal, ir.Broadcast:
+		selExpr := callExpr.Fun.(*ast.SelectorExpr)
+		condVal := b.findCond(selExpr.X, ctx)
+		if condVal == nil {
+			return nil
+		}
+
+		if callKind == ir.Call {
+			condOpStmt := ir.NewCondOpStmt(condVal, specialOp.(ir.CondOp), callExpr.Pos(), callExpr.End())
+			ctx.body.AddStmt(condOpStmt)
+			return nil
+		}
+		liftedFuncArgs = []ir.RValue{condVal.(ir.RValue)}
+
 	case ir.DeadEnd:
 		if callKind == ir.Call {
 			deadEndStmt := ir.NewDeadEndStmt(callExpr.Pos(), callExpr.End())
@@ -511,11 +830,25 @@ func (b *builder) processSpecialOpCallExprWithCallKind(callExpr *ast.CallExpr, c
 			return nil
 		}
 
+	case ir.Sleep:
+		duration := b.processDurationExpr(callExpr.Args[0], ctx)
+
+		if callKind == ir.Call {
+			sleepStmt := ir.NewSleepStmt(duration, callExpr.Pos(), callExpr.End())
+			ctx.body.AddStmt(sleepStmt)
+			return nil
+		}
+		liftedFuncArgs = []ir.RValue{duration}
+
 	default:
 		panic("unexpected special op")
 	}
 
-	liftedFunc := b.liftedSpecialOpFunc(specialOp)
+	var argType ir.Type
+	if len(liftedFuncArgs) > 0 {
+		argType = liftedFuncArgs[0].Type()
+	}
+	liftedFunc := b.liftedSpecialOpFunc(specialOp, argType)
 	callStmt := ir.NewCallStmt(liftedFunc, nil, callKind, callExpr.Pos(), callExpr.End())
 	ctx.body.AddStmt(callStmt)
 
@@ -1,12 +1,13 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/types"
 	"strings"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 func (b *builder) processFuncReceiver(recv *ast.FieldList, ctx *context) {
@@ -35,6 +36,11 @@ func (b *builder) processFuncType(funcType *ast.FuncType, ctx *context) {
 
 	argIndex := 0
 	for _, field := range funcType.Params.List {
+		for _, fieldNameIdent := range field.Names {
+			if typesVar, ok := ctx.typesInfo.Defs[fieldNameIdent].(*types.Var); ok {
+				b.addSharedVarCounter(typesVar, irFunc.Scope())
+			}
+		}
 		typesType := ctx.typesInfo.TypeOf(field.Type)
 		irType := b.typesTypeToIrType(typesType)
 		if irType == nil {
@@ -55,6 +61,11 @@ func (b *builder) processFuncType(funcType *ast.FuncType, ctx *context) {
 	}
 	resultIndex := 0
 	for _, field := range funcType.Results.List {
+		for _, fieldNameIdent := range field.Names {
+			if typesVar, ok := ctx.typesInfo.Defs[fieldNameIdent].(*types.Var); ok {
+				b.addSharedVarCounter(typesVar, irFunc.Scope())
+			}
+		}
 		typesType := ctx.typesInfo.TypeOf(field.Type)
 		irType := b.typesTypeToIrType(typesType)
 		if irType == nil {
@@ -83,7 +94,7 @@ func (b *builder) processFuncType(funcType *ast.FuncType, ctx *context) {
 }
 
 func (b *builder) processFuncLit(funcLit *ast.FuncLit, ctx *context) *ir.Func {
-	sig := ctx.typesInfo.Types[funcLit].Type.(*types.Signature)
+	sig := b.typeArgs.substitute(ctx.typesInfo.Types[funcLit].Type).(*types.Signature)
 	f := b.program.AddInnerFunc(sig, ctx.currentFunc(), ctx.body.Scope(), funcLit.Pos(), funcLit.End())
 	subCtx := ctx.subContextForFunc(f)
 	b.processFuncType(funcLit.Type, subCtx)
@@ -91,11 +102,281 @@ func (b *builder) processFuncLit(funcLit *ast.FuncLit, ctx *context) *ir.Func {
 	return f
 }
 
+// processCalledLaterFuncArg processes the given func argument of the given
+// call, which gets called from an inner func created for the call. Func
+// literals get processed where they get called, such that they can be called
+// statically, and get returned as fLit. All other funcs get evaluated into a
+// captured variable, returned as fVar, and get called via that variable.
+func (b *builder) processCalledLaterFuncArg(fExpr ast.Expr, callExpr *ast.CallExpr, ctx *context) (fLit *ast.FuncLit, fVar *ir.Variable, ok bool) {
+	fLit, _ = astutil.Unparen(fExpr).(*ast.FuncLit)
+	if fLit != nil {
+		return fLit, nil, true
+	}
+	fVal := b.processExpr(fExpr, ctx)
+	if fVal == nil {
+		p := b.fset.Position(fExpr.Pos())
+		fStr := b.nodeToString(fExpr)
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, fStr, "can not process %s argument: %s", b.nodeToString(callExpr.Fun), fStr))
+		return nil, nil, false
+	}
+	fVar = b.program.NewVariable("f", ir.FuncType.UninitializedValue())
+	fVar.SetCaptured(true)
+	ctx.body.Scope().AddVariable(fVar)
+	ctx.body.AddStmt(ir.NewAssignStmt(fVal, fVar, false, fExpr.Pos(), fExpr.End()))
+	return nil, fVar, true
+}
+
+// calledLaterFuncCallee returns the callee for a call of a func argument
+// processed by processCalledLaterFuncArg from the given inner func context.
+func (b *builder) calledLaterFuncCallee(fLit *ast.FuncLit, fVar *ir.Variable, ctx *context) ir.Callable {
+	if fLit != nil {
+		return b.processFuncLit(fLit, ctx)
+	}
+	return fVar
+}
+
+// processMethodValue lowers a method value into a closure capturing the
+// receiver. Method values of modeled interfaces are the bound func values
+// held by the interface value.
+func (b *builder) processMethodValue(selExpr *ast.SelectorExpr, ctx *context) ir.RValue {
+	method := ctx.typesInfo.Selections[selExpr].Obj().(*types.Func)
+	sig := b.typeArgs.substitute(ctx.typesInfo.TypeOf(selExpr)).(*types.Signature)
+	if ifaceType := b.modeledInterfaceType(ctx.typesInfo.TypeOf(selExpr.X)); ifaceType != nil {
+		recvVal := b.processExpr(selExpr.X, ctx)
+		if recvLV, ok := recvVal.(ir.LValue); ok && recvLV != nil {
+			return b.interfaceMethodFieldSelection(recvLV, ifaceType, method)
+		}
+		p := b.fset.Position(selExpr.X.Pos())
+		recvExprStr := b.nodeToString(selExpr.X)
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, recvExprStr, "could not resolve interface value: %s", recvExprStr))
+		return nil
+	}
+	recvVal, recvRequiresCopy, ok := b.processMethodValueReceiver(selExpr.X, method, ctx)
+	if !ok {
+		return nil
+	}
+	return b.makeMethodValue(method, sig, recvVal, recvRequiresCopy, selExpr, ctx)
+}
+
+// makeMethodValue returns a new closure with the given signature, capturing
+// the given receiver (if any) and calling the given method with it.
+func (b *builder) makeMethodValue(method *types.Func, sig *types.Signature, recvVal ir.RValue, recvRequiresCopy bool, node ast.Node, ctx *context) ir.RValue {
+	var recvVar *ir.Variable
+	if recvVal != nil {
+		recvVar = b.program.NewVariable("", recvVal.Type().UninitializedValue())
+		recvVar.SetCaptured(true)
+		ctx.body.Scope().AddVariable(recvVar)
+		assignStmt := ir.NewAssignStmt(recvVal, recvVar, recvRequiresCopy, node.Pos(), node.End())
+		ctx.body.AddStmt(assignStmt)
+	}
+
+	f := b.program.AddInnerFunc(sig, ctx.currentFunc(), ctx.body.Scope(), node.Pos(), node.End())
+	subCtx := ctx.subContextForFunc(f)
+	argVals := make(map[int]ir.RValue)
+	for i, arg := range b.addSignatureToFunc(f, sig) {
+		argVals[i] = arg
+	}
+	if recvVar != nil {
+		b.addMethodCallAndReturn(method, recvVar, recvRequiresCopy, argVals, node, subCtx)
+	} else {
+		b.addMethodCallAndReturn(method, nil, false, argVals, node, subCtx)
+	}
+	return f.FuncValue()
+}
+
+// processMethodExpr lowers a method expression into a func taking the
+// receiver as its first argument.
+func (b *builder) processMethodExpr(selExpr *ast.SelectorExpr, ctx *context) ir.RValue {
+	method := ctx.typesInfo.Selections[selExpr].Obj().(*types.Func)
+	sig := b.typeArgs.substitute(ctx.typesInfo.TypeOf(selExpr)).(*types.Signature)
+	if _, ok := b.specialOpForFunc(method); ok {
+		p := b.fset.Position(selExpr.Pos())
+		selExprStr := b.nodeToString(selExpr)
+		b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, selExprStr, "method expressions of special operations are not supported: %s", selExprStr))
+		return nil
+	}
+
+	f := b.program.AddInnerFunc(sig, ctx.currentFunc(), ctx.body.Scope(), selExpr.Pos(), selExpr.End())
+	subCtx := ctx.subContextForFunc(f)
+	args := b.addSignatureToFunc(f, sig)
+	argVals := make(map[int]ir.RValue)
+	for i, arg := range args {
+		if i > 0 {
+			argVals[i-1] = arg
+		}
+	}
+	if recvVar, ok := args[0]; ok {
+		b.addMethodCallAndReturn(method, recvVar, false, argVals, selExpr, subCtx)
+	} else {
+		b.addMethodCallAndReturn(method, nil, false, argVals, selExpr, subCtx)
+	}
+	return f.FuncValue()
+}
+
+func (b *builder) processMethodValueReceiver(recvExpr ast.Expr, method *types.Func, ctx *context) (recvVal ir.RValue, requiresCopy, ok bool) {
+	if specialOp, ok := b.specialOpForFunc(method); ok {
+		var recvLV ir.LValue
+		switch specialOp {
+		case ir.Lock, ir.Unlock, ir.RLock, ir.RUnlock, ir.TryLock, ir.TryRLock:
+			recvLV = b.findMutex(recvExpr, b.mutexTypeForMethod(method), ctx)
+		case ir.Add, ir.Wait:
+			recvLV = b.findWaitGroup(recvExpr, ctx)
+		case ir.Do:
+			recvLV = b.findOnce(recvExpr, ctx)
+		case ir.CondWait, ir.Signal, ir.Broadcast:
+			recvLV = b.findCond(recvExpr, ctx)
+		default:
+			b.processExpr(recvExpr, ctx)
+			return nil, false, true
+		}
+		if recvLV == nil {
+			return nil, false, false
+		}
+		return recvLV.(ir.RValue), false, true
+	}
+	recv := method.Type().(*types.Signature).Recv()
+	if types.IsInterface(recv.Type()) && b.getSubstituteFunc(method) == nil {
+		b.processExpr(recvExpr, ctx)
+		return nil, false, true
+	}
+	return b.processReceiverVal(recvExpr, recv, ctx)
+}
+
+// addSignatureToFunc adds args and result types for the modeled params and
+// results of the signature to the func and returns the arg variables.
+func (b *builder) addSignatureToFunc(f *ir.Func, sig *types.Signature) map[int]*ir.Variable {
+	args := make(map[int]*ir.Variable)
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		irType := b.typesTypeToIrType(param.Type())
+		if irType == nil {
+			continue
+		}
+		irVar := b.program.NewVariable(param.Name(), irType.UninitializedValue())
+		f.AddArg(i, irVar)
+		args[i] = irVar
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		irType := b.typesTypeToIrType(sig.Results().At(i).Type())
+		if irType == nil {
+			continue
+		}
+		f.AddResultType(i, irType)
+	}
+	return args
+}
+
+// addMethodCallAndReturn adds a call of the method with the given receiver
+// and args to the body of the current func of the context, followed by a
+// return statement passing on the results of the call.
+func (b *builder) addMethodCallAndReturn(method *types.Func, recvVal ir.RValue, recvRequiresCopy bool, argVals map[int]ir.RValue, node ast.Node, ctx *context) {
+	sig := b.typeArgs.substitute(method.Type()).(*types.Signature)
+	if specialOp, ok := b.specialOpForFunc(method); ok {
+		b.addSpecialOpMethodCall(method, specialOp, recvVal, argVals, node, ctx)
+		ctx.body.AddStmt(ir.NewReturnStmt(false, node.Pos(), node.End()))
+		return
+	}
+
+	argRequiresCopy := make(map[int]bool)
+	for i := range argVals {
+		paramTypesType := sig.Params().At(i).Type()
+		switch irType := b.typesTypeToIrType(paramTypesType).(type) {
+		case *ir.StructType:
+			argRequiresCopy[i] = !b.isPointer(paramTypesType)
+		case *ir.ContainerType:
+			argRequiresCopy[i] = irType.Kind() == ir.Array && !b.isPointer(paramTypesType)
+		}
+	}
+	resultVars, resultRequiresCopy := b.processCallResultVars(sig, ctx)
+
+	callee := b.getSubstituteFunc(method)
+	if callee == nil {
+		callee = b.findFunc(method, nil, ctx)
+	}
+	if callee != nil {
+		callStmt := ir.NewCallStmt(callee, sig, ir.Call, node.Pos(), node.End())
+		ctx.body.AddStmt(callStmt)
+		if recvVal != nil {
+			callStmt.AddArg(-1, recvVal, recvRequiresCopy)
+		}
+		for i, v := range argVals {
+			callStmt.AddArg(i, v, argRequiresCopy[i])
+		}
+		for i, v := range resultVars {
+			callStmt.AddResult(i, v, resultRequiresCopy[i])
+		}
+	} else if types.IsInterface(sig.Recv().Type()) {
+		recvLV, _ := recvVal.(ir.LValue)
+		if ifaceType := b.modeledInterfaceType(sig.Recv().Type()); ifaceType != nil && recvLV != nil {
+			callee := b.interfaceMethodFieldSelection(recvLV, ifaceType, method)
+			callStmt := ir.NewCallStmt(callee, methodValueSignature(sig), ir.Call, node.Pos(), node.End())
+			ctx.body.AddStmt(callStmt)
+			for i, v := range argVals {
+				callStmt.AddArg(i, v, argRequiresCopy[i])
+			}
+			for i, v := range resultVars {
+				callStmt.AddResult(i, v, resultRequiresCopy[i])
+			}
+		} else {
+			impls := b.findInterfaceMethodImpls(method)
+			b.addInterfaceMethodImplCalls(impls, ir.Call, argVals, argRequiresCopy, resultVars, resultRequiresCopy, node, ctx.body)
+		}
+	}
+
+	returnStmt := ir.NewReturnStmt(false, node.Pos(), node.End())
+	ctx.body.AddStmt(returnStmt)
+	for i, t := range ctx.currentFunc().ResultTypes() {
+		if v, ok := resultVars[i]; ok {
+			returnStmt.AddResult(i, v)
+		} else {
+			returnStmt.AddResult(i, t.UninitializedValue())
+		}
+	}
+}
+
+func (b *builder) addSpecialOpMethodCall(method *types.Func, specialOp ir.SpecialOp, recvVal ir.RValue, argVals map[int]ir.RValue, node ast.Node, ctx *context) {
+	switch specialOp {
+	case ir.Lock, ir.Unlock, ir.RLock, ir.RUnlock, ir.TryLock, ir.TryRLock:
+		mutexOpStmt := ir.NewMutexOpStmt(recvVal.(ir.LValue), specialOp.(ir.MutexOp), nil, node.Pos(), node.End())
+		ctx.body.AddStmt(mutexOpStmt)
+	case ir.Add, ir.Wait:
+		var delta ir.RValue = ir.MakeValue(-1, ir.IntType)
+		if specialOp == ir.Add && method.Name() == "Add" {
+			if arg, ok := argVals[0]; ok && arg.Type() == ir.IntType {
+				delta = arg
+			} else {
+				p := b.fset.Position(node.Pos())
+				nodeStr := b.nodeToString(node)
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, nodeStr, "can not process sync.WaitGroup.Add argument: %s", nodeStr))
+				return
+			}
+		}
+		waitGroupOpStmt := ir.NewWaitGroupOpStmt(recvVal.(ir.LValue), specialOp.(ir.WaitGroupOp), delta, node.Pos(), node.End())
+		ctx.body.AddStmt(waitGroupOpStmt)
+	case ir.Do:
+		f, ok := argVals[0]
+		if !ok {
+			p := b.fset.Position(node.Pos())
+			nodeStr := b.nodeToString(node)
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, nodeStr, "can not process sync.Once.Do argument: %s", nodeStr))
+			return
+		}
+		onceDoStmt := ir.NewOnceDoStmt(recvVal.(ir.LValue), f, node.Pos(), node.End())
+		ctx.body.AddStmt(onceDoStmt)
+	case ir.CondWait, ir.Signal, ir.Broadcast:
+		condOpStmt := ir.NewCondOpStmt(recvVal.(ir.LValue), specialOp.(ir.CondOp), node.Pos(), node.End())
+		ctx.body.AddStmt(condOpStmt)
+	case ir.DeadEnd:
+		deadEndStmt := ir.NewDeadEndStmt(node.Pos(), node.End())
+		ctx.body.AddStmt(deadEndStmt)
+	}
+}
+
 func (b *builder) processFuncBody(body *ast.BlockStmt, ctx *context) {
 	if body == nil {
 		f := ctx.currentFunc()
 		p := b.fset.Position(f.Pos())
-		b.addWarning(fmt.Errorf("%v: function is not defined: %s", p, f.Name()))
+		b.addWarning(diag.Warningf(diag.UnresolvedCallee, p, f.Name(), "function is not defined: %s", f.Name()))
 		return
 	}
 	b.processBlockStmt(body, ctx)
@@ -131,6 +412,8 @@ func (b *builder) canIgnoreCall(callExpr *ast.CallExpr, ctx *context) bool {
 		case *types.Func:
 			if funcType.String() == "func (error).Error() string" {
 				return true
+			} else if b.getUserSubstituteFunc(funcType) != nil {
+				return false
 			}
 			switch funcType.Pkg().Name() {
 			case "binary",
@@ -195,7 +478,7 @@ func (b *builder) canIgnoreCall(callExpr *ast.CallExpr, ctx *context) bool {
 					strings.HasPrefix(funcType.FullName(), "(*testing.common)") {
 					switch funcType.Name() {
 					case "Error", "Errorf", "Fail", "Failed", "Helper",
-						"Log", "Logf", "Name", "Parallel", "Skipped",
+						"Log", "Logf", "Name", "Skipped",
 						"ReportAllocs", "ReportMetric", "ResetTimer",
 						"SetBytes", "SetParallelism",
 						"StartTimer", "StopTimer":
@@ -208,8 +491,10 @@ func (b *builder) canIgnoreCall(callExpr *ast.CallExpr, ctx *context) bool {
 					return true
 				}
 				switch funcType.Name() {
-				case "Now", "Sleep", "Since", "Until":
+				case "Now", "Since", "Until":
 					return true
+				case "Sleep":
+					return !b.config.Timed
 				}
 			}
 		case *types.TypeName:
@@ -246,31 +531,62 @@ func (b *builder) specialOpForCall(callExpr *ast.CallExpr, ctx *context) (ir.Spe
 			return ir.Close, true
 		}
 	case *types.Func:
-		switch usedTypesObj.FullName() {
-		case "(*sync.Mutex).Lock", "(*sync.RWMutex).Lock":
-			return ir.Lock, true
-		case "(*sync.Mutex).Unlock", "(*sync.RWMutex).Unlock":
-			return ir.Unlock, true
-		case "(*sync.RWMutex).RLock":
-			return ir.RLock, true
-		case "(*sync.RWMutex).RUnlock":
-			return ir.RUnlock, true
-		case "(*sync.WaitGroup).Add", "(*sync.WaitGroup).Done":
-			return ir.Add, true
-		case "(*sync.WaitGroup).Wait":
-			return ir.Wait, true
-		case "(*sync.Once).Do":
-			return ir.Do, true
-		case "os.Exit",
-			"log.Fatal", "log.Fatalf", "log.Fatalln",
-			"(*log.Logger).Fatal", "(*log.Logger).Fatalf", "(*log.Logger).Fatalln":
-			return ir.DeadEnd, true
+		// The sync.Locker returned by sync.RWMutex.RLocker operates on the
+		// read lock:
+		if selExpr, ok := callExpr.Fun.(*ast.SelectorExpr); ok && b.rLockerMutexExpr(selExpr.X, ctx) != nil {
+			switch usedTypesObj.FullName() {
+			case "(sync.Locker).Lock":
+				return ir.RLock, true
+			case "(sync.Locker).Unlock":
+				return ir.RUnlock, true
+			}
 		}
+		return b.specialOpForFunc(usedTypesObj)
 	}
 
 	return nil, false
 }
 
+func (b *builder) specialOpForFunc(typesFunc *types.Func) (ir.SpecialOp, bool) {
+	switch typesFunc.FullName() {
+	case "(*sync.Mutex).Lock", "(*sync.RWMutex).Lock":
+		return ir.Lock, true
+	case "(*sync.Mutex).Unlock", "(*sync.RWMutex).Unlock":
+		return ir.Unlock, true
+	case "(*sync.RWMutex).RLock":
+		return ir.RLock, true
+	case "(*sync.RWMutex).RUnlock":
+		return ir.RUnlock, true
+	case "(*sync.Mutex).TryLock", "(*sync.RWMutex).TryLock":
+		return ir.TryLock, true
+	case "(*sync.RWMutex).TryRLock":
+		return ir.TryRLock, true
+	case "(*sync.WaitGroup).Add", "(*sync.WaitGroup).Done":
+		return ir.Add, true
+	case "(*sync.WaitGroup).Wait":
+		return ir.Wait, true
+	case "(*sync.Once).Do":
+		return ir.Do, true
+	case "sync.NewCond":
+		return ir.MakeCond, true
+	case "(*sync.Cond).Wait":
+		return ir.CondWait, true
+	case "(*sync.Cond).Signal":
+		return ir.Signal, true
+	case "(*sync.Cond).Broadcast":
+		return ir.Broadcast, true
+	case "time.Sleep":
+		if b.config.Timed {
+			return ir.Sleep, true
+		}
+	case "os.Exit",
+		"log.Fatal", "log.Fatalf", "log.Fatalln",
+		"(*log.Logger).Fatal", "(*log.Logger).Fatalf", "(*log.Logger).Fatalln":
+		return ir.DeadEnd, true
+	}
+	return nil, false
+}
+
 func (b *builder) isKnownBuiltin(callExpr *ast.CallExpr, ctx *context) (string, bool) {
 	var usedTypesObj types.Object
 
@@ -299,19 +615,24 @@ func (b *builder) isKnownBuiltin(callExpr *ast.CallExpr, ctx *context) (string,
 		return "", false
 	case *types.Func:
 		switch usedTypesObj.FullName() {
-		case "(*testing.common).FailNow",
+		case "runtime.Goexit",
+			"(*testing.common).FailNow",
 			"(*testing.common).Fatal",
 			"(*testing.common).Fatalf",
 			"(*testing.common).Skip",
 			"(*testing.common).SkipNow",
-			"(*testing.common).Skipf",
-			"log.Panic",
+			"(*testing.common).Skipf":
+			return "runtime.Goexit", true
+		case "log.Panic",
 			"log.Panicf",
 			"log.Panicln",
 			"(*log.Logger).Panic",
 			"(*log.Logger).Panicf",
 			"(*log.Logger).Panicln":
 			return "panic", true
+		case "sync.OnceFunc", "sync.OnceValue", "sync.OnceValues",
+			"(*testing.T).Run":
+			return usedTypesObj.FullName(), true
 		}
 	}
 	return "", false
@@ -0,0 +1,235 @@
+package builder
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/arneph/toph/ir"
+)
+
+// typeArgs maps the type parameters of a generic function or method to the
+// type arguments of one of its instantiations.
+type typeArgs map[*types.TypeParam]types.Type
+
+// substitute returns the given type with all type parameters replaced by their
+// type arguments. Types without type parameters get returned unchanged.
+func (m typeArgs) substitute(typesType types.Type) types.Type {
+	if len(m) == 0 || typesType == nil {
+		return typesType
+	}
+	switch t := typesType.(type) {
+	case *types.TypeParam:
+		if typeArg, ok := m[t]; ok {
+			return typeArg
+		}
+	case *types.Pointer:
+		if elem := m.substitute(t.Elem()); elem != t.Elem() {
+			return types.NewPointer(elem)
+		}
+	case *types.Slice:
+		if elem := m.substitute(t.Elem()); elem != t.Elem() {
+			return types.NewSlice(elem)
+		}
+	case *types.Array:
+		if elem := m.substitute(t.Elem()); elem != t.Elem() {
+			return types.NewArray(elem, t.Len())
+		}
+	case *types.Map:
+		key, elem := m.substitute(t.Key()), m.substitute(t.Elem())
+		if key != t.Key() || elem != t.Elem() {
+			return types.NewMap(key, elem)
+		}
+	case *types.Chan:
+		if elem := m.substitute(t.Elem()); elem != t.Elem() {
+			return types.NewChan(t.Dir(), elem)
+		}
+	case *types.Struct:
+		changed := false
+		fields := make([]*types.Var, t.NumFields())
+		tags := make([]string, t.NumFields())
+		for i := 0; i < t.NumFields(); i++ {
+			fields[i] = m.substituteVar(t.Field(i))
+			tags[i] = t.Tag(i)
+			changed = changed || fields[i] != t.Field(i)
+		}
+		if changed {
+			return types.NewStruct(fields, tags)
+		}
+	case *types.Signature:
+		recv := t.Recv()
+		if recv != nil {
+			recv = m.substituteVar(recv)
+		}
+		params := m.substituteTuple(t.Params())
+		results := m.substituteTuple(t.Results())
+		if recv != t.Recv() || params != t.Params() || results != t.Results() ||
+			t.TypeParams().Len() > 0 || t.RecvTypeParams().Len() > 0 {
+			return types.NewSignatureType(recv, nil, nil, params, results, t.Variadic())
+		}
+	case *types.Named:
+		changed := false
+		args := make([]types.Type, t.TypeArgs().Len())
+		for i := range args {
+			args[i] = m.substitute(t.TypeArgs().At(i))
+			changed = changed || args[i] != t.TypeArgs().At(i)
+		}
+		if !changed {
+			break
+		}
+		instance, err := types.Instantiate(nil, t.Origin(), args, false)
+		if err == nil {
+			return instance
+		}
+	}
+	return typesType
+}
+
+func (m typeArgs) substituteVar(typesVar *types.Var) *types.Var {
+	typesType := m.substitute(typesVar.Type())
+	if typesType == typesVar.Type() {
+		return typesVar
+	}
+	if typesVar.IsField() {
+		return types.NewField(typesVar.Pos(), typesVar.Pkg(), typesVar.Name(), typesType, typesVar.Embedded())
+	}
+	return types.NewParam(typesVar.Pos(), typesVar.Pkg(), typesVar.Name(), typesType)
+}
+
+func (m typeArgs) substituteTuple(tuple *types.Tuple) *types.Tuple {
+	if tuple == nil {
+		return nil
+	}
+	changed := false
+	vars := make([]*types.Var, tuple.Len())
+	for i := range vars {
+		vars[i] = m.substituteVar(tuple.At(i))
+		changed = changed || vars[i] != tuple.At(i)
+	}
+	if !changed {
+		return tuple
+	}
+	return types.NewTuple(vars...)
+}
+
+// genericFunc holds the declaration of a generic function or a method of a
+// generic type. Its body gets processed once per instantiation.
+type genericFunc struct {
+	decl      *ast.FuncDecl
+	cmap      ast.CommentMap
+	typesInfo *types.Info
+}
+
+type funcInstanceKey struct {
+	origin   *types.Func
+	typeArgs string
+}
+
+// funcInstance is an instantiation of a generic function whose signature and
+// body still need to be processed.
+type funcInstance struct {
+	genericFunc *genericFunc
+	typeArgs    typeArgs
+	irFunc      *ir.Func
+}
+
+func (b *builder) isGenericFunc(typesFunc *types.Func) bool {
+	typesSig := typesFunc.Type().(*types.Signature)
+	return typesSig.TypeParams().Len() > 0 || typesSig.RecvTypeParams().Len() > 0
+}
+
+// findFunc returns the ir.Func for the given function or method, or nil if
+// there is none. Generic functions get instantiated with the type arguments
+// recorded for the given identifier, methods of generic types with the type
+// arguments of their receiver.
+func (b *builder) findFunc(typesFunc *types.Func, ident *ast.Ident, ctx *context) *ir.Func {
+	if irFunc, ok := b.funcs[typesFunc]; ok {
+		return irFunc
+	}
+	origin := typesFunc.Origin()
+	genericFunc, ok := b.genericFuncs[origin]
+	if !ok {
+		return nil
+	}
+
+	var typesTypeArgs *types.TypeList
+	if instance, ok := ctx.typesInfo.Instances[ident]; ok {
+		typesTypeArgs = instance.TypeArgs
+	} else if recv := typesFunc.Type().(*types.Signature).Recv(); recv != nil {
+		recvType := recv.Type()
+		if typesPointer, ok := recvType.(*types.Pointer); ok {
+			recvType = typesPointer.Elem()
+		}
+		if typesNamed, ok := b.typeArgs.substitute(recvType).(*types.Named); ok {
+			typesTypeArgs = typesNamed.TypeArgs()
+		}
+	}
+	if typesTypeArgs == nil {
+		return nil
+	}
+	args := make([]types.Type, typesTypeArgs.Len())
+	for i := range args {
+		args[i] = b.typeArgs.substitute(typesTypeArgs.At(i))
+	}
+	return b.instantiateFunc(origin, genericFunc, args)
+}
+
+// instantiateFunc returns the ir.Func for the instantiation of the generic
+// function with the given type arguments. New instantiations get processed
+// later by processFuncInstances.
+func (b *builder) instantiateFunc(origin *types.Func, genericFunc *genericFunc, args []types.Type) *ir.Func {
+	argStrs := make([]string, len(args))
+	for i, arg := range args {
+		argStrs[i] = types.TypeString(arg, nil)
+	}
+	key := funcInstanceKey{origin, strings.Join(argStrs, ", ")}
+	if irFunc, ok := b.funcInstances[key]; ok {
+		return irFunc
+	}
+
+	typesSig := origin.Type().(*types.Signature)
+	typeParams := typesSig.TypeParams()
+	if typesSig.RecvTypeParams().Len() > 0 {
+		typeParams = typesSig.RecvTypeParams()
+	}
+	if typeParams.Len() != len(args) {
+		return nil
+	}
+	instanceTypeArgs := make(typeArgs)
+	for i, arg := range args {
+		instanceTypeArgs[typeParams.At(i)] = arg
+	}
+	instanceSig := instanceTypeArgs.substitute(typesSig).(*types.Signature)
+
+	decl := genericFunc.decl
+	irFunc := b.program.AddOuterFunc(origin.Name(), instanceSig, decl.Pos(), decl.End())
+	b.funcInstances[key] = irFunc
+	b.pendingFuncInstances = append(b.pendingFuncInstances, &funcInstance{
+		genericFunc: genericFunc,
+		typeArgs:    instanceTypeArgs,
+		irFunc:      irFunc,
+	})
+	return irFunc
+}
+
+// processFuncInstances processes the signatures and bodies of all
+// instantiated generic functions, including instantiations found while doing
+// so.
+func (b *builder) processFuncInstances() {
+	for len(b.pendingFuncInstances) > 0 {
+		instance := b.pendingFuncInstances[0]
+		b.pendingFuncInstances = b.pendingFuncInstances[1:]
+
+		b.typeArgs = instance.typeArgs
+		decl := instance.genericFunc.decl
+		ctx := newContext(instance.genericFunc.cmap, instance.genericFunc.typesInfo, instance.irFunc)
+		b.processFuncReceiver(decl.Recv, ctx)
+		b.processFuncType(decl.Type, ctx)
+		b.processFuncDeclAnnotations(decl, instance.irFunc, instance.genericFunc.cmap)
+		typesFunc := instance.genericFunc.typesInfo.Defs[decl.Name].(*types.Func)
+		if !b.isExcludedFunc(typesFunc) && !b.isIgnoredFuncDecl(decl, instance.genericFunc.cmap) {
+			b.processFuncBody(decl.Body, ctx)
+		}
+		b.typeArgs = nil
+	}
+}
@@ -0,0 +1,206 @@
+package builder
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/arneph/toph/ir"
+)
+
+// shouldModelInterface returns whether values of the given interface type
+// get modeled. This is the case for named, non-generic interfaces with
+// methods, declared in the loaded packages and implemented by at least one
+// type declared in the loaded packages. Calls of methods of other interfaces
+// are modeled as a nondeterministic choice between all implementations.
+func (b *builder) shouldModelInterface(typesType types.Type, typesInterface *types.Interface) bool {
+	typesNamed, ok := typesType.(*types.Named)
+	if !ok || typesNamed.TypeParams().Len() > 0 || typesNamed.TypeArgs().Len() > 0 {
+		return false
+	} else if typesInterface.NumMethods() == 0 {
+		return false
+	}
+	for _, pkg := range b.pkgs {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			implType := typeName.Type()
+			if types.IsInterface(implType) {
+				continue
+			} else if implNamed, ok := implType.(*types.Named); ok && implNamed.TypeParams().Len() > 0 {
+				continue
+			}
+			if types.Implements(implType, typesInterface) ||
+				types.Implements(types.NewPointer(implType), typesInterface) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typesInterfaceToIrType returns a new ir.StructType modeling values of the
+// given interface type. The struct holds a func value for each method of the
+// interface, bound to the dynamic value of the interface value. Interface
+// values never change after their creation and get treated like pointers.
+func (b *builder) typesInterfaceToIrType(typesType types.Type, typesInterface *types.Interface) ir.Type {
+	irStructType := b.program.AddStructType(typesType.(*types.Named).Obj().Name())
+	info := new(typeInfo)
+	info.irType = irStructType
+	info.isInterface = true
+	b.types.Set(typesType, info)
+
+	for i := 0; i < typesInterface.NumMethods(); i++ {
+		irStructType.AddField(i, typesInterface.Method(i).Name(), ir.FuncType, false, false)
+	}
+	return irStructType
+}
+
+// modeledInterfaceType returns the ir.StructType modeling values of the given
+// interface type or nil if the type is not a modeled interface. Interfaces
+// with substitutes, for example context.Context, are not modeled interfaces.
+func (b *builder) modeledInterfaceType(typesType types.Type) *ir.StructType {
+	if typesType == nil {
+		return nil
+	}
+	typesType = b.typeArgs.substitute(typesType)
+	if _, ok := typesType.Underlying().(*types.Interface); !ok {
+		return nil
+	}
+	irStructType, ok := b.typesTypeToIrType(typesType).(*ir.StructType)
+	if !ok {
+		return nil
+	}
+	info, ok := b.types.At(typesType).(*typeInfo)
+	if !ok || !info.isInterface {
+		return nil
+	}
+	return irStructType
+}
+
+// interfaceMethodFieldSelection returns the func value bound to the dynamic
+// value of the given interface value for the given interface method.
+func (b *builder) interfaceMethodFieldSelection(ifaceVal ir.LValue, ifaceType *ir.StructType, method *types.Func) *ir.FieldSelection {
+	for _, field := range ifaceType.Fields() {
+		if field.Name() == method.Name() {
+			return ir.NewFieldSelection(ifaceVal, field)
+		}
+	}
+	panic("attempted to select method not in interface")
+}
+
+// methodValueSignature returns the signature of method values of a method
+// with the given signature, which is the signature without receiver.
+func methodValueSignature(sig *types.Signature) *types.Signature {
+	return types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+}
+
+// processImplicitConversion returns the value of the given expression,
+// converted to the given type. Only conversions to modeled interfaces change
+// the value: concrete values get converted to a new interface value holding
+// method values bound to the concrete value, interface values get converted
+// to a new interface value holding the method values of the original
+// interface value. Conversions from interfaces that are not modeled result in
+// an interface value without methods. Values of types that are not modeled
+// are nil and result in method values without receiver.
+func (b *builder) processImplicitConversion(val ir.RValue, expr ast.Expr, toTypesType types.Type, ctx *context) ir.RValue {
+	ifaceType := b.modeledInterfaceType(toTypesType)
+	if ifaceType == nil {
+		return val
+	}
+	fromTypesType := ctx.typesInfo.TypeOf(expr)
+	if fromTypesType == nil {
+		return val
+	}
+	fromTypesType = b.typeArgs.substitute(fromTypesType)
+	toTypesType = b.typeArgs.substitute(toTypesType)
+	if types.Identical(fromTypesType, types.Typ[types.UntypedNil]) ||
+		types.Identical(fromTypesType, toTypesType) {
+		return val
+	} else if val == nil && b.typesTypeToIrType(fromTypesType) != nil {
+		return nil
+	}
+
+	ifaceVar := b.program.NewVariable("", ifaceType.UninitializedValue())
+	ctx.body.Scope().AddVariable(ifaceVar)
+	makeStructStmt := ir.NewMakeStructStmt(ifaceVar, true, expr.Pos(), expr.End())
+	ctx.body.AddStmt(makeStructStmt)
+
+	typesInterface := toTypesType.Underlying().(*types.Interface)
+	fromIfaceType := b.modeledInterfaceType(fromTypesType)
+	fromIfaceVal, _ := val.(ir.LValue)
+	for i := 0; i < typesInterface.NumMethods(); i++ {
+		method := typesInterface.Method(i)
+		var methodVal ir.RValue
+		if fromIfaceType != nil && fromIfaceVal != nil {
+			methodVal = b.interfaceMethodFieldSelection(fromIfaceVal, fromIfaceType, method)
+		} else if !types.IsInterface(fromTypesType) {
+			methodVal = b.makeConcreteMethodValue(val, fromTypesType, method, expr, ctx)
+		}
+		if methodVal == nil {
+			continue
+		}
+		field := b.interfaceMethodFieldSelection(ifaceVar, ifaceType, method)
+		assignStmt := ir.NewAssignStmt(methodVal, field, false, expr.Pos(), expr.End())
+		ctx.body.AddStmt(assignStmt)
+	}
+	return ifaceVar
+}
+
+// makeConcreteMethodValue returns a new method value, calling the method of
+// the given concrete value (if modeled) implementing the given interface
+// method.
+func (b *builder) makeConcreteMethodValue(recvVal ir.RValue, recvTypesType types.Type, ifaceMethod *types.Func, node ast.Node, ctx *context) ir.RValue {
+	obj, index, _ := types.LookupFieldOrMethod(recvTypesType, false, ifaceMethod.Pkg(), ifaceMethod.Name())
+	method, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	// Promoted methods get called with the embedded field as receiver:
+	for _, i := range index[:len(index)-1] {
+		typesStruct, ok := derefType(recvTypesType).Underlying().(*types.Struct)
+		if !ok {
+			recvVal = nil
+			break
+		}
+		typesField := typesStruct.Field(i)
+		recvTypesType = typesField.Type()
+		recvLV, ok := recvVal.(ir.LValue)
+		if !ok || recvLV == nil {
+			recvVal = nil
+			continue
+		}
+		irStructType, ok := recvLV.Type().(*ir.StructType)
+		if !ok {
+			recvVal = nil
+			continue
+		}
+		irField, ok := b.findField(typesField, irStructType)
+		if !ok {
+			recvVal = nil
+			continue
+		}
+		recvVal = ir.NewFieldSelection(recvLV, irField)
+	}
+
+	methodSig := b.typeArgs.substitute(method.Type()).(*types.Signature)
+	recvRequiresCopy := false
+	if recvVal != nil {
+		switch irType := recvVal.Type().(type) {
+		case *ir.StructType:
+			recvRequiresCopy = !b.isPointer(methodSig.Recv().Type())
+		case *ir.ContainerType:
+			recvRequiresCopy = irType.Kind() == ir.Array && !b.isPointer(methodSig.Recv().Type())
+		}
+	}
+	return b.makeMethodValue(method, methodValueSignature(methodSig), recvVal, recvRequiresCopy, node, ctx)
+}
+
+func derefType(typesType types.Type) types.Type {
+	if typesPointer, ok := typesType.Underlying().(*types.Pointer); ok {
+		return typesPointer.Elem()
+	}
+	return typesType
+}
@@ -0,0 +1,191 @@
+package builder
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/ir"
+)
+
+// rangeFuncLoop holds the state of a range-over-func loop whose body gets
+// processed as the yield func passed to the iterator func.
+type rangeFuncLoop struct {
+	label string
+
+	// done gets set once the yield func returned false, i.e. the loop body
+	// executed a break or return statement.
+	done *ir.Variable
+
+	// returned and results are shared by nested range-over-func loops and
+	// hold whether the loop body executed a return statement and with which
+	// results.
+	returned *ir.Variable
+	results  map[int]*ir.Variable
+	// returnFunc is the func containing the outermost range-over-func loop.
+	returnFunc *ir.Func
+
+	parent *rangeFuncLoop
+}
+
+// processRangeFuncStmt lowers a range-over-func loop into a call of the
+// iterator func with the loop body as yield func. The yield func returns
+// false (unmodeled) and sets the done variable for break and return
+// statements in the loop body. Return statements additionally set the
+// returned variable, which gets checked after the iterator call. Returns
+// whether the iterator func could be resolved.
+func (b *builder) processRangeFuncStmt(stmt *ast.RangeStmt, seqSig *types.Signature, label string, ctx *context) bool {
+	if seqSig.Params().Len() != 1 {
+		return false
+	}
+	yieldSig, ok := b.typeArgs.substitute(seqSig.Params().At(0).Type()).Underlying().(*types.Signature)
+	if !ok {
+		return false
+	}
+	callee, _ := b.findCallee(stmt.X, ctx)
+	if callee == nil {
+		return false
+	}
+
+	loop := &rangeFuncLoop{label: label, parent: ctx.rangeFuncLoop}
+	loop.done = b.program.NewVariable("done", ir.IntType.InitializedValue())
+	loop.done.SetCaptured(true)
+	ctx.body.Scope().AddVariable(loop.done)
+	if loop.parent != nil {
+		loop.returned = loop.parent.returned
+		loop.results = loop.parent.results
+		loop.returnFunc = loop.parent.returnFunc
+	} else if b.bodyHasReturnStmt(stmt.Body) {
+		loop.returned = b.program.NewVariable("returned", ir.IntType.InitializedValue())
+		loop.returned.SetCaptured(true)
+		ctx.body.Scope().AddVariable(loop.returned)
+		loop.results = make(map[int]*ir.Variable)
+		loop.returnFunc = ctx.currentFunc()
+		for i, t := range loop.returnFunc.ResultTypes() {
+			resultVar := b.program.NewVariable("", t.UninitializedValue())
+			resultVar.SetCaptured(true)
+			ctx.body.Scope().AddVariable(resultVar)
+			loop.results[i] = resultVar
+		}
+	}
+
+	yieldFunc := b.program.AddInnerFunc(yieldSig, ctx.currentFunc(), ctx.body.Scope(), stmt.Pos(), stmt.End())
+	args := b.addSignatureToFunc(yieldFunc, yieldSig)
+	yieldCtx := ctx.subContextForFunc(yieldFunc)
+	yieldCtx.rangeFuncLoop = loop
+
+	// Calls of the yield func after the loop body returned false do not
+	// execute the loop body:
+	doneIfStmt := ir.NewIfStmt(yieldCtx.body.Scope(), stmt.Pos(), stmt.Pos(), stmt.Pos(), stmt.Pos())
+	doneIfStmt.SetCond(loop.done, false)
+	doneIfStmt.IfBranch().AddStmt(ir.NewReturnStmt(false, stmt.Pos(), stmt.Pos()))
+	yieldCtx.body.AddStmt(doneIfStmt)
+
+	for i, expr := range []ast.Expr{stmt.Key, stmt.Value} {
+		arg, ok := args[i]
+		if expr == nil || !ok {
+			continue
+		}
+		if ident, ok := expr.(*ast.Ident); ok && stmt.Tok == token.DEFINE {
+			b.processVarDefinitionInScope(ident, yieldCtx.body.Scope(), false, yieldCtx)
+		}
+		lv, ok := b.processExpr(expr, yieldCtx).(ir.LValue)
+		if !ok {
+			continue
+		}
+		paramTypesType := yieldSig.Params().At(i).Type()
+		requiresCopy := false
+		switch irType := arg.Type().(type) {
+		case *ir.StructType:
+			requiresCopy = !b.isPointer(paramTypesType)
+		case *ir.ContainerType:
+			requiresCopy = irType.Kind() == ir.Array && !b.isPointer(paramTypesType)
+		}
+		yieldCtx.body.AddStmt(ir.NewAssignStmt(arg, lv, requiresCopy, expr.Pos(), expr.End()))
+	}
+
+	b.processStmt(stmt.Body, yieldCtx)
+	yieldCtx.body.AddStmt(ir.NewReturnStmt(false, stmt.Body.End(), stmt.Body.End()))
+
+	callStmt := ir.NewCallStmt(callee, seqSig, ir.Call, stmt.Pos(), stmt.End())
+	callStmt.AddArg(0, yieldFunc.FuncValue(), false)
+	ctx.body.AddStmt(callStmt)
+
+	if loop.returned != nil {
+		returnedIfStmt := ir.NewIfStmt(ctx.body.Scope(), stmt.End(), stmt.End(), stmt.End(), stmt.End())
+		returnedIfStmt.SetCond(loop.returned, false)
+		ctx.body.AddStmt(returnedIfStmt)
+		returnedCtx := ctx.subContextForBody(returnedIfStmt, "", returnedIfStmt.IfBranch())
+		if loop.parent != nil {
+			b.addRangeFuncExit(loop.parent, stmt.End(), returnedCtx)
+		} else {
+			returnStmt := ir.NewReturnStmt(false, stmt.End(), stmt.End())
+			for i, v := range loop.results {
+				returnStmt.AddResult(i, v)
+			}
+			returnedCtx.body.AddStmt(returnStmt)
+		}
+	}
+	return true
+}
+
+// bodyHasReturnStmt returns whether the given body contains a return
+// statement, excluding return statements in func literals.
+func (b *builder) bodyHasReturnStmt(body *ast.BlockStmt) bool {
+	hasReturnStmt := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			hasReturnStmt = true
+		}
+		return !hasReturnStmt
+	})
+	return hasReturnStmt
+}
+
+// addRangeFuncExit adds statements to the yield func of the given loop
+// setting the done variable and returning false (unmodeled).
+func (b *builder) addRangeFuncExit(loop *rangeFuncLoop, pos token.Pos, ctx *context) {
+	ctx.body.AddStmt(ir.NewAssignStmt(ir.MakeValue(1, ir.IntType), loop.done, false, pos, pos))
+	ctx.body.AddStmt(ir.NewReturnStmt(false, pos, pos))
+}
+
+func (b *builder) processRangeFuncBranchStmt(stmt *ast.BranchStmt, label string, ctx *context) {
+	loop := ctx.rangeFuncLoop
+	if label != "" && label != loop.label {
+		p := b.fset.Position(stmt.Pos())
+		stmtStr := b.nodeToString(stmt)
+		b.addWarning(diag.Warningf(diag.UnsupportedStatement, p, stmtStr, "unsupported branch statement out of range-over-func loop: %s", stmtStr))
+		return
+	}
+	switch stmt.Tok {
+	case token.BREAK:
+		b.addRangeFuncExit(loop, stmt.Pos(), ctx)
+	case token.CONTINUE:
+		ctx.body.AddStmt(ir.NewReturnStmt(false, stmt.Pos(), stmt.End()))
+	}
+}
+
+func (b *builder) processRangeFuncReturnStmt(stmt *ast.ReturnStmt, ctx *context) {
+	loop := ctx.rangeFuncLoop
+	if len(stmt.Results) > 0 {
+		resultVals := b.processExprs(stmt.Results, ctx)
+		for i, resultVar := range loop.results {
+			v, ok := resultVals[i]
+			if !ok || v == ir.Nil {
+				v = resultVar.Type().UninitializedValue()
+			}
+			ctx.body.AddStmt(ir.NewAssignStmt(v, resultVar, false, stmt.Pos(), stmt.End()))
+		}
+	} else {
+		for i, v := range loop.returnFunc.Results() {
+			v.SetCaptured(true)
+			ctx.body.AddStmt(ir.NewAssignStmt(v, loop.results[i], false, stmt.Pos(), stmt.End()))
+		}
+	}
+	ctx.body.AddStmt(ir.NewAssignStmt(ir.MakeValue(1, ir.IntType), loop.returned, false, stmt.Pos(), stmt.End()))
+	b.addRangeFuncExit(loop, stmt.Pos(), ctx)
+}
@@ -0,0 +1,336 @@
+package builder
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/arneph/toph/ir"
+)
+
+// findSharedVars determines the variables and structure fields whose accesses
+// get recorded to check for data races. Variables and fields marked with the
+// "shared" annotation always get recorded. Additionally, package level
+// variables and local variables captured by function literals get recorded if
+// their types are not modeled otherwise. The translator later discards
+// inferred variables that are accessed by a single goroutine only.
+//
+// Accesses get counted per instance: package level variables have a single
+// counter, local variables have a counter per call of the defining function,
+// and structure fields have a counter field in each structure. Therefore,
+// structures with shared fields always get modeled.
+func (b *builder) findSharedVars() {
+	b.sharedVars = make(map[*types.Var]*ir.SharedVar)
+	b.sharedVarCounters = make(map[*types.Var]*ir.Variable)
+	if !b.config.GenerateDataRaceQueries {
+		return
+	}
+
+	var annotated, inferred []*types.Var
+	addInferred := func(typesVar *types.Var) {
+		switch typesVar.Type().Underlying().(type) {
+		case *types.Basic, *types.Struct, *types.Array, *types.Slice, *types.Map:
+			inferred = append(inferred, typesVar)
+		}
+	}
+
+	for _, pkg := range b.pkgs {
+		typesInfo := pkg.TypesInfo
+		for _, astFile := range pkg.Syntax {
+			ctx := newContext(b.cmaps[astFile], typesInfo, b.program.InitFunc())
+			ast.Inspect(astFile, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.GenDecl, *ast.DeclStmt, *ast.AssignStmt, *ast.Field:
+					if !b.hasSharedAnnotation(node, ctx) {
+						return true
+					}
+					ast.Inspect(node, func(n ast.Node) bool {
+						if _, ok := n.(*ast.FuncLit); ok {
+							return false
+						}
+						if ident, ok := n.(*ast.Ident); ok {
+							if typesVar, ok := typesInfo.Defs[ident].(*types.Var); ok {
+								annotated = append(annotated, typesVar)
+							}
+						}
+						return true
+					})
+				case *ast.FuncLit:
+					ast.Inspect(node.Body, func(n ast.Node) bool {
+						ident, ok := n.(*ast.Ident)
+						if !ok {
+							return true
+						}
+						typesVar, ok := typesInfo.Uses[ident].(*types.Var)
+						if !ok || typesVar.IsField() ||
+							typesVar.Parent() == nil || typesVar.Parent() == pkg.Types.Scope() ||
+							(node.Pos() <= typesVar.Pos() && typesVar.Pos() < node.End()) {
+							return true
+						}
+						addInferred(typesVar)
+						return true
+					})
+				}
+				return true
+			})
+		}
+
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			if typesVar, ok := scope.Lookup(name).(*types.Var); ok {
+				addInferred(typesVar)
+			}
+		}
+	}
+
+	// Annotated fields affect which types are modeled, therefore inferred
+	// variables get filtered after all annotated variables are known.
+	for _, typesVar := range annotated {
+		if _, ok := b.sharedVars[typesVar]; ok || typesVar.Name() == "_" {
+			continue
+		}
+		b.sharedVars[typesVar] = b.program.AddSharedVar(typesVar.Name(), true, typesVar.Pos())
+	}
+	for _, typesVar := range inferred {
+		if _, ok := b.sharedVars[typesVar]; ok || typesVar.Name() == "_" ||
+			b.shouldModelType(typesVar.Type(), nil) {
+			continue
+		}
+		b.sharedVars[typesVar] = b.program.AddSharedVar(typesVar.Name(), false, typesVar.Pos())
+	}
+}
+
+// hasSharedFields returns whether the given structure has shared fields.
+func (b *builder) hasSharedFields(typesStruct *types.Struct) bool {
+	for i := 0; i < typesStruct.NumFields(); i++ {
+		if _, ok := b.sharedVars[typesStruct.Field(i)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedFieldCounterName returns the name of the access counter field of the
+// given shared field.
+func sharedFieldCounterName(fieldTypesVar *types.Var) string {
+	return fieldTypesVar.Name() + "_accesses"
+}
+
+// addSharedVarCounter adds the access counter of the given variable to the
+// given scope if the variable is shared.
+func (b *builder) addSharedVarCounter(typesVar *types.Var, scope *ir.Scope) {
+	if _, ok := b.sharedVars[typesVar]; !ok || typesVar.IsField() {
+		return
+	}
+	counter := b.program.NewVariable(typesVar.Name()+"_accesses", ir.IntType.InitializedValue())
+	scope.AddVariable(counter)
+	b.sharedVarCounters[typesVar] = counter
+}
+
+func (b *builder) hasSharedAnnotation(node ast.Node, ctx *context) bool {
+	for _, info := range b.findAnnotations(node, ctx) {
+		if info == "shared" {
+			return true
+		}
+	}
+	return false
+}
+
+type sharedVarAccess struct {
+	sharedVar *ir.SharedVar
+	counter   ir.LValue
+	ident     *ast.Ident
+}
+
+// processSharedVarReads adds access statements for all shared variables read
+// by the given simple statement or expression to the current body and
+// returns the accesses for all shared variables written by it. The writes
+// should be added with processSharedVarWrites after the statement itself, to
+// preserve the order of synchronization operations within the statement.
+// Function literals in the node do not get inspected, since their bodies
+// become separate functions.
+func (b *builder) processSharedVarReads(node ast.Node, ctx *context) (writes []sharedVarAccess) {
+	if len(b.sharedVars) == 0 || node == nil {
+		return nil
+	}
+
+	var writeTargets []ast.Expr
+	switch node := node.(type) {
+	case *ast.AssignStmt:
+		writeTargets = node.Lhs
+	case *ast.IncDecStmt:
+		writeTargets = []ast.Expr{node.X}
+	case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt,
+		*ast.SelectStmt, *ast.BlockStmt, *ast.LabeledStmt, *ast.BranchStmt:
+		return nil
+	}
+	written := make(map[*ast.Ident]bool)
+	for _, target := range writeTargets {
+		access, ok := b.findWrittenSharedVar(target, ctx)
+		if !ok {
+			continue
+		}
+		written[access.ident] = true
+		writes = append(writes, access)
+	}
+
+	seen := make(map[string]bool)
+	for _, w := range writes {
+		seen[w.counter.Handle()] = true
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		var access sharedVarAccess
+		var ok bool
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.UnaryExpr:
+			// Taking the address of a variable does not access it.
+			return n.Op != token.AND
+		case *ast.SelectorExpr:
+			if written[n.Sel] {
+				return true
+			}
+			access, ok = b.lookupSharedField(n, ctx)
+		case *ast.Ident:
+			if written[n] {
+				return true
+			}
+			access, ok = b.lookupSharedVar(n, ctx)
+		}
+		if !ok || seen[access.counter.Handle()] {
+			return true
+		}
+		seen[access.counter.Handle()] = true
+		accessStmt := ir.NewAccessStmt(access.sharedVar, access.counter, ir.Read, access.ident.Pos(), access.ident.End())
+		ctx.body.AddStmt(accessStmt)
+		return true
+	})
+	return writes
+}
+
+// processSharedVarWrites adds access statements for the given writes of
+// shared variables to the current body.
+func (b *builder) processSharedVarWrites(writes []sharedVarAccess, ctx *context) {
+	seen := make(map[string]bool)
+	for _, w := range writes {
+		if seen[w.counter.Handle()] {
+			continue
+		}
+		seen[w.counter.Handle()] = true
+		accessStmt := ir.NewAccessStmt(w.sharedVar, w.counter, ir.Write, w.ident.Pos(), w.ident.End())
+		ctx.body.AddStmt(accessStmt)
+	}
+}
+
+// lookupSharedVar returns the access of the shared variable referred to by
+// the given identifier, if any. Shared fields get looked up with
+// lookupSharedField instead.
+func (b *builder) lookupSharedVar(ident *ast.Ident, ctx *context) (sharedVarAccess, bool) {
+	typesVar, ok := ctx.typesInfo.Uses[ident].(*types.Var)
+	if !ok || typesVar.IsField() {
+		return sharedVarAccess{}, false
+	}
+	sharedVar, ok := b.sharedVars[typesVar]
+	if !ok {
+		return sharedVarAccess{}, false
+	}
+	counter := b.sharedVarCounters[typesVar]
+	if counter == nil {
+		return sharedVarAccess{}, false
+	}
+	if s := counter.Scope(); s != b.program.Scope() && s.IsParentOf(ctx.currentFunc().Scope()) {
+		counter.SetCaptured(true)
+	}
+	return sharedVarAccess{sharedVar, counter, ident}, true
+}
+
+// lookupSharedField returns the access of the shared field selected by the
+// given selector expression, if any. The access counter is the counter field
+// of the selected structure. Structures that can not be determined without
+// side effects, for example results of function calls, are not supported.
+func (b *builder) lookupSharedField(selExpr *ast.SelectorExpr, ctx *context) (sharedVarAccess, bool) {
+	typesSelection, ok := ctx.typesInfo.Selections[selExpr]
+	if !ok || typesSelection.Kind() != types.FieldVal {
+		return sharedVarAccess{}, false
+	}
+	fieldTypesVar := typesSelection.Obj().(*types.Var)
+	sharedVar, ok := b.sharedVars[fieldTypesVar]
+	if !ok || !isSideEffectFreePath(selExpr.X) {
+		return sharedVarAccess{}, false
+	}
+	structVal, ok := b.processExpr(selExpr.X, ctx).(ir.LValue)
+	if !ok || structVal == nil {
+		return sharedVarAccess{}, false
+	}
+	// Promoted fields are counted in the embedded structure:
+	typesType := ctx.typesInfo.TypeOf(selExpr.X)
+	indices := typesSelection.Index()
+	for _, i := range indices[:len(indices)-1] {
+		typesStruct, ok := derefType(typesType).Underlying().(*types.Struct)
+		if !ok {
+			return sharedVarAccess{}, false
+		}
+		embeddedTypesVar := typesStruct.Field(i)
+		typesType = embeddedTypesVar.Type()
+		irStructType, ok := structVal.Type().(*ir.StructType)
+		if !ok {
+			return sharedVarAccess{}, false
+		}
+		irField, ok := b.findField(embeddedTypesVar, irStructType)
+		if !ok {
+			return sharedVarAccess{}, false
+		}
+		structVal = ir.NewFieldSelection(structVal, irField)
+	}
+	irStructType, ok := structVal.Type().(*ir.StructType)
+	if !ok {
+		return sharedVarAccess{}, false
+	}
+	for _, irField := range irStructType.Fields() {
+		if irField.Name() == sharedFieldCounterName(fieldTypesVar) {
+			counter := ir.NewFieldSelection(structVal, irField)
+			return sharedVarAccess{sharedVar, counter, selExpr.Sel}, true
+		}
+	}
+	return sharedVarAccess{}, false
+}
+
+// isSideEffectFreePath returns whether the given expression only consists of
+// identifiers, field selections, and dereferences.
+func isSideEffectFreePath(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.ParenExpr:
+		return isSideEffectFreePath(expr.X)
+	case *ast.StarExpr:
+		return isSideEffectFreePath(expr.X)
+	case *ast.SelectorExpr:
+		return isSideEffectFreePath(expr.X)
+	default:
+		return false
+	}
+}
+
+// findWrittenSharedVar returns the access of the shared variable written by
+// an assignment to the given expression, if any. Assignments to a field of a
+// structure or an element of an array, slice, or map count as writes of the
+// structure or container, unless the field itself is shared.
+func (b *builder) findWrittenSharedVar(expr ast.Expr, ctx *context) (sharedVarAccess, bool) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return b.lookupSharedVar(expr, ctx)
+	case *ast.ParenExpr:
+		return b.findWrittenSharedVar(expr.X, ctx)
+	case *ast.SelectorExpr:
+		if access, ok := b.lookupSharedField(expr, ctx); ok {
+			return access, true
+		}
+		return b.findWrittenSharedVar(expr.X, ctx)
+	case *ast.IndexExpr:
+		return b.findWrittenSharedVar(expr.X, ctx)
+	default:
+		return sharedVarAccess{}, false
+	}
+}
@@ -242,3 +242,22 @@ func (b *builder) staticForLoopBoundsEval(forStmt *ast.ForStmt, ctx *context) in
 	}
 	return iterCount
 }
+
+func (b *builder) staticRangeLoopBoundsEval(rangeStmt *ast.RangeStmt, ctx *context) int {
+	val, ok := b.staticExprEval(rangeStmt.X, nil, ctx)
+	if !ok {
+		return -1
+	}
+	val = constant.ToInt(val)
+	if val.Kind() != constant.Int {
+		return -1
+	}
+	const MaxIterCount int64 = 1000000
+	n, ok := constant.Int64Val(val)
+	if !ok || n >= MaxIterCount {
+		return -1
+	} else if n < 0 {
+		return 0
+	}
+	return int(n)
+}
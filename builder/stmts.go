@@ -1,11 +1,18 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
+	"go/token"
+
+	"github.com/arneph/toph/diag"
 )
 
 func (b *builder) processStmt(stmt ast.Stmt, ctx *context) {
+	if _, ok := b.findAnnotation(stmt, "ignore", ctx); ok {
+		return
+	}
+	sharedVarWrites := b.processSharedVarReads(stmt, ctx)
+
 	switch s := stmt.(type) {
 	case *ast.AssignStmt:
 		b.processAssignStmt(s, ctx)
@@ -18,7 +25,11 @@ func (b *builder) processStmt(stmt ast.Stmt, ctx *context) {
 	case *ast.DeclStmt:
 		b.processGenDecl(s.Decl.(*ast.GenDecl), true, ctx.body.Scope(), ctx)
 	case *ast.ExprStmt:
-		b.processExpr(s.X, ctx)
+		if receiveExpr, ok := s.X.(*ast.UnaryExpr); ok && receiveExpr.Op == token.ARROW {
+			b.processReceiveExpr(receiveExpr, true, false, ctx)
+		} else {
+			b.processExpr(s.X, ctx)
+		}
 	case *ast.ForStmt:
 		b.processForStmt(s, "", ctx)
 	case *ast.GoStmt:
@@ -26,7 +37,11 @@ func (b *builder) processStmt(stmt ast.Stmt, ctx *context) {
 	case *ast.IfStmt:
 		b.processIfStmt(s, ctx)
 	case *ast.IncDecStmt:
-		b.processExpr(s.X, ctx)
+		if v := b.findTrackedVar(s.X, ctx); v != nil {
+			b.processTrackedVarUpdate(s.X, v, s.Tok, nil, ctx)
+		} else {
+			b.processArithmeticUpdate(s.X, ctx)
+		}
 	case *ast.LabeledStmt:
 		b.processLabeledStmt(s, ctx)
 	case *ast.RangeStmt:
@@ -43,8 +58,11 @@ func (b *builder) processStmt(stmt ast.Stmt, ctx *context) {
 		b.processTypeSwitchStmt(s, "", ctx)
 	default:
 		p := b.fset.Position(stmt.Pos())
-		b.addWarning(fmt.Errorf("%v: ignoring %T statement", p, s))
+		stmtStr := b.nodeToString(stmt)
+		b.addWarning(diag.Warningf(diag.UnsupportedStatement, p, stmtStr, "ignoring %T statement", s))
 	}
+
+	b.processSharedVarWrites(sharedVarWrites, ctx)
 }
 
 func (b *builder) processBlockStmt(stmt *ast.BlockStmt, ctx *context) {
@@ -20,17 +20,54 @@ func (b *builder) getSubstitute(typesObj types.Object) ir.RValue {
 }
 
 func (b *builder) getSubstituteFunc(funcType *types.Func) *ir.Func {
+	if funcType.Pkg() == nil {
+		return nil
+	} else if subFunc := b.getUserSubstituteFunc(funcType); subFunc != nil {
+		return subFunc
+	}
 	var subFuncName string
 	switch funcType.Pkg().Name() {
+	case "context":
+		switch funcType.FullName() {
+		case "context.Background", "context.TODO":
+			subFuncName = "subContextBackground"
+		case "context.WithCancel":
+			subFuncName = "subContextWithCancel"
+		case "context.WithDeadline":
+			subFuncName = "subContextWithDeadline"
+		case "context.WithTimeout":
+			subFuncName = "subContextWithTimeout"
+		case "context.WithValue":
+			subFuncName = "subContextWithValue"
+		case "(context.Context).Deadline",
+			"(context.Context).Done",
+			"(context.Context).Err",
+			"(context.Context).Value":
+			return b.getSubstituteMethod("subContext", funcType.Name())
+		}
 	case "time":
-		if funcType.Name() == "After" {
+		switch funcType.FullName() {
+		case "time.After":
 			subFuncName = "subTimeAfter"
+		case "time.NewTimer":
+			subFuncName = "subTimeNewTimer"
+		case "(*time.Timer).Stop":
+			return b.getSubstituteMethod("subTimer", funcType.Name())
+		}
+	case "testing":
+		switch funcType.FullName() {
+		case "(*testing.T).Parallel":
+			return b.getSubstituteMethod("subT", funcType.Name())
 		}
 	case "filepath":
 		if funcType.Name() == "Walk" {
 			subFuncName = "subFilepathWalk"
 		}
 	}
+	return b.lookupSubstituteFunc(subFuncName)
+}
+
+func (b *builder) lookupSubstituteFunc(subFuncName string) *ir.Func {
 	for _, f := range b.program.Funcs() {
 		if f.Name() == subFuncName {
 			return f
@@ -39,23 +76,282 @@ func (b *builder) getSubstituteFunc(funcType *types.Func) *ir.Func {
 	return nil
 }
 
+// testEntryFunc returns a func calling the given test func with a
+// *testing.T value and finishing the test afterwards, such that it waits for
+// parallel subtests.
+func (b *builder) testEntryFunc(testFunc *ir.Func) *ir.Func {
+	mainFunc := b.lookupSubstituteFunc("subTMain")
+	finishFunc := b.lookupSubstituteFunc("subTFinish")
+	if mainFunc == nil || finishFunc == nil {
+		return testFunc
+	}
+	entrySig := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+	entryFunc := b.program.AddOuterFunc(testFunc.Name(), entrySig, testFunc.Pos(), testFunc.End())
+	tVar := b.program.NewVariable("t", mainFunc.ResultTypes()[0].UninitializedValue())
+	entryFunc.Body().Scope().AddVariable(tVar)
+
+	mainCallStmt := ir.NewCallStmt(mainFunc, mainFunc.Signature(), ir.Call, testFunc.Pos(), testFunc.Pos())
+	mainCallStmt.AddResult(0, tVar, false)
+	finishCallStmt := ir.NewCallStmt(finishFunc, finishFunc.Signature(), ir.Defer, testFunc.Pos(), testFunc.Pos())
+	finishCallStmt.AddArg(0, tVar, false)
+	testCallStmt := ir.NewCallStmt(testFunc, testFunc.Signature(), ir.Call, testFunc.Pos(), testFunc.End())
+	testCallStmt.AddArg(0, tVar, false)
+	entryFunc.Body().AddStmts(mainCallStmt, finishCallStmt, testCallStmt)
+	return entryFunc
+}
+
+func (b *builder) getSubstituteMethod(subTypeName, methodName string) *ir.Func {
+	subTypeObj := b.subsPkg.Scope().Lookup(subTypeName)
+	if subTypeObj == nil {
+		return nil
+	}
+	subType := types.NewPointer(subTypeObj.Type())
+	methodObj, _, _ := types.LookupFieldOrMethod(subType, false, b.subsPkg, methodName)
+	methodFunc, ok := methodObj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	return b.funcs[methodFunc]
+}
+
+// getSubstituteType returns the ir.Type of the substitute for the given
+// types.Type, for example context.Context, or nil if it has no substitute.
+func (b *builder) getSubstituteType(typesType types.Type) ir.Type {
+	if subType := b.getUserSubstituteType(typesType); subType != nil {
+		return subType
+	}
+	var subTypeName string
+	switch typesType.String() {
+	case "context.Context":
+		subTypeName = "subContext"
+	case "time.Timer":
+		subTypeName = "subTimer"
+	case "testing.T":
+		subTypeName = "subT"
+	default:
+		return nil
+	}
+	if b.subsPkg == nil {
+		return nil
+	}
+	subTypeObj := b.subsPkg.Scope().Lookup(subTypeName)
+	if subTypeObj == nil {
+		return nil
+	}
+	return b.typesTypeToIrType(subTypeObj.Type())
+}
+
 const substitutesCode = `
 package subs
 
 import (
 	"math/rand"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
-func subTimeAfter(time.Duration) <-chan time.Time {
+// subContext models all context.Context values. Cancelling a context closes
+// its done channel and the done channels of all its descendants. (Descendants
+// are tracked explicitly to avoid recursion.) All contexts derived from the
+// same background context share a mutex, such that concurrent cancellations
+// close each done channel only once.
+type subContext struct {
+	mu          *sync.Mutex
+	done        chan struct{}
+	ancestors   []*subContext
+	descendants []*subContext
+}
+
+func (c *subContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (c *subContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *subContext) Err() error {
+	return nil
+}
+
+func (c *subContext) Value(key interface{}) interface{} {
+	return nil
+}
+
+func (c *subContext) cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subCloseDone(c)
+	for _, descendant := range c.descendants {
+		subCloseDone(descendant)
+	}
+}
+
+// subCloseDone closes the done channel of the given context if it is not
+// closed yet. The caller must hold the mutex of the context.
+func subCloseDone(c *subContext) {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
+
+func subContextBackground() *subContext {
+	var mu sync.Mutex
+	c := new(subContext)
+	c.mu = &mu
+	c.done = make(chan struct{})
+	return c
+}
+
+func subContextWithCancel(parent *subContext) (*subContext, func()) {
+	c := new(subContext)
+	c.mu = parent.mu
+	c.done = make(chan struct{})
+	c.mu.Lock()
+	for _, ancestor := range parent.ancestors {
+		c.ancestors = append(c.ancestors, ancestor)
+		ancestor.descendants = append(ancestor.descendants, c)
+	}
+	c.ancestors = append(c.ancestors, parent)
+	parent.descendants = append(parent.descendants, c)
+	select {
+	case <-parent.done:
+		subCloseDone(c)
+	default:
+	}
+	c.mu.Unlock()
+	return c, func() {
+		c.cancel()
+	}
+}
+
+// subContextWithDeadline ignores the deadline, since points in time are not
+// modeled. Without timed mode, the context gets cancelled at an arbitrary
+// point, like contexts with timeouts. In timed mode, the context gets
+// cancelled without delay, like contexts with timeouts of durations that
+// could not be determined.
+func subContextWithDeadline(parent *subContext, deadline time.Time) (*subContext, func()) {
+	c, cancel := subContextWithCancel(parent)
+	go func() {
+		c.cancel()
+	}()
+	return c, cancel
+}
+
+func subContextWithTimeout(parent *subContext, timeout time.Duration) (*subContext, func()) {
+	c, cancel := subContextWithCancel(parent)
+	go func() {
+		time.Sleep(timeout)
+		c.cancel()
+	}()
+	return c, cancel
+}
+
+func subContextWithValue(parent *subContext, key, val interface{}) *subContext {
+	return parent
+}
+
+func subTimeAfter(d time.Duration) <-chan time.Time {
 	ch := make(chan time.Time, 1)
 	go func() {
+		time.Sleep(d)
 		ch <- time.Time{}
 	}()
 	return ch
 }
 
+// subTimer models *time.Timer values. Stopping a timer prevents it from
+// firing if it has not fired yet.
+type subTimer struct {
+	C    chan time.Time
+	stop chan struct{}
+}
+
+func (t *subTimer) Stop() bool {
+	select {
+	case <-t.stop:
+		return false
+	default:
+		close(t.stop)
+		return true
+	}
+}
+
+func subTimeNewTimer(d time.Duration) *subTimer {
+	t := new(subTimer)
+	t.C = make(chan time.Time, 1)
+	t.stop = make(chan struct{})
+	go func() {
+		time.Sleep(d)
+		select {
+		case <-t.stop:
+		default:
+			t.C <- time.Time{}
+		}
+	}()
+	return t
+}
+
+// subT models *testing.T values. t.Run runs the subtest in its own goroutine
+// and waits until the subtest finished or called t.Parallel. Parallel
+// subtests wait until their parent test function returned, the parent waits
+// for them before it finishes itself.
+type subT struct {
+	parent   *subT
+	ready    chan struct{}
+	release  chan struct{}
+	parallel sync.WaitGroup
+}
+
+func (t *subT) Run(name string, f func(t *subT)) bool {
+	sub := subTNew(t)
+	go subTRunner(sub, f)
+	<-sub.ready
+	return true
+}
+
+func (t *subT) Parallel() {
+	t.parent.parallel.Add(1)
+	close(t.ready)
+	<-t.parent.release
+}
+
+func subTNew(parent *subT) *subT {
+	t := new(subT)
+	t.parent = parent
+	t.ready = make(chan struct{})
+	t.release = make(chan struct{})
+	return t
+}
+
+// subTMain returns the *testing.T value passed to a test entry func. Its
+// parent releases parallel top-level tests immediately.
+func subTMain() *subT {
+	parent := new(subT)
+	parent.release = make(chan struct{})
+	close(parent.release)
+	return subTNew(parent)
+}
+
+func subTRunner(t *subT, f func(t *subT)) {
+	defer subTFinish(t)
+	f(t)
+}
+
+func subTFinish(t *subT) {
+	close(t.release)
+	t.parallel.Wait()
+	select {
+	case <-t.ready:
+		t.parent.parallel.Done()
+	default:
+		close(t.ready)
+	}
+}
+
 func subFilepathWalk(root string, walkFn filepath.WalkFunc) error {
 	for rand.Int() == 0 {
 		walkFn("", nil, nil)
@@ -1,28 +1,61 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
+	"go/types"
+	"strings"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
-func (b *builder) findMutex(mutexExpr ast.Expr, ctx *context) ir.LValue {
+// mutexTypeForMethod returns the type of the receiver of the given
+// sync.Mutex or sync.RWMutex method.
+func (b *builder) mutexTypeForMethod(method *types.Func) ir.Type {
+	if strings.HasPrefix(method.FullName(), "(*sync.RWMutex).") {
+		return ir.RWMutexType
+	}
+	return ir.MutexType
+}
+
+// rLockerMutexExpr returns the expression x if the given expression is a call
+// x.RLocker() of a sync.RWMutex and nil otherwise.
+func (b *builder) rLockerMutexExpr(expr ast.Expr, ctx *context) ast.Expr {
+	callExpr, ok := astutil.Unparen(expr).(*ast.CallExpr)
+	if !ok || len(callExpr.Args) != 0 {
+		return nil
+	}
+	selExpr, ok := astutil.Unparen(callExpr.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	method, ok := ctx.typesInfo.Uses[selExpr.Sel].(*types.Func)
+	if !ok || method.FullName() != "(*sync.RWMutex).RLocker" {
+		return nil
+	}
+	return selExpr.X
+}
+
+func (b *builder) findMutex(mutexExpr ast.Expr, mutexType ir.Type, ctx *context) ir.LValue {
 	rv := b.processExpr(mutexExpr, ctx)
 	lv, ok := rv.(ir.LValue)
 	if !ok || lv == nil {
 		p := b.fset.Position(mutexExpr.Pos())
 		mutexExprStr := b.nodeToString(mutexExpr)
-		b.addWarning(fmt.Errorf("%v: could not resolve mutex expr: %v", p, mutexExprStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, mutexExprStr, "could not resolve mutex expr: %v", mutexExprStr))
 		return nil
 	}
-	if lv.Type() != ir.MutexType {
-		structType := lv.Type().(*ir.StructType)
-		embeddedFields, ok := structType.FindEmbeddedFieldOfType(ir.MutexType)
+	if lv.Type() != mutexType {
+		structType, ok := lv.Type().(*ir.StructType)
+		var embeddedFields []*ir.Field
+		if ok {
+			embeddedFields, ok = structType.FindEmbeddedFieldOfType(mutexType)
+		}
 		if !ok {
 			p := b.fset.Position(mutexExpr.Pos())
 			mutexExprStr := b.nodeToString(mutexExpr)
-			b.addWarning(fmt.Errorf("%v: could not resolve mutex expr: %v", p, mutexExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, mutexExprStr, "could not resolve mutex expr: %v", mutexExprStr))
 			return nil
 		}
 		for _, field := range embeddedFields {
@@ -38,7 +71,7 @@ func (b *builder) findWaitGroup(waitGroupExpr ast.Expr, ctx *context) ir.LValue
 	if !ok || lv == nil {
 		p := b.fset.Position(waitGroupExpr.Pos())
 		waitGroupExprStr := b.nodeToString(waitGroupExpr)
-		b.addWarning(fmt.Errorf("%v: could not resolve wait group expr: %v", p, waitGroupExprStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, waitGroupExprStr, "could not resolve wait group expr: %v", waitGroupExprStr))
 		return nil
 	}
 	if lv.Type() != ir.WaitGroupType {
@@ -47,7 +80,7 @@ func (b *builder) findWaitGroup(waitGroupExpr ast.Expr, ctx *context) ir.LValue
 		if !ok {
 			p := b.fset.Position(waitGroupExpr.Pos())
 			waitGroupExprStr := b.nodeToString(waitGroupExpr)
-			b.addWarning(fmt.Errorf("%v: could not resolve wait group expr: %v", p, waitGroupExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, waitGroupExprStr, "could not resolve wait group expr: %v", waitGroupExprStr))
 			return nil
 		}
 		for _, field := range embeddedFields {
@@ -63,7 +96,7 @@ func (b *builder) findOnce(onceExpr ast.Expr, ctx *context) ir.LValue {
 	if !ok || lv == nil {
 		p := b.fset.Position(onceExpr.Pos())
 		onceExprStr := b.nodeToString(onceExpr)
-		b.addWarning(fmt.Errorf("%v: could not resolve once expr: %v", p, onceExprStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, onceExprStr, "could not resolve once expr: %v", onceExprStr))
 		return nil
 	}
 	if lv.Type() != ir.OnceType {
@@ -72,7 +105,38 @@ func (b *builder) findOnce(onceExpr ast.Expr, ctx *context) ir.LValue {
 		if !ok {
 			p := b.fset.Position(onceExpr.Pos())
 			onceExprStr := b.nodeToString(onceExpr)
-			b.addWarning(fmt.Errorf("%v: could not resolve once expr: %v", p, onceExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, onceExprStr, "could not resolve once expr: %v", onceExprStr))
+			return nil
+		}
+		for _, field := range embeddedFields {
+			lv = ir.NewFieldSelection(lv, field)
+		}
+	}
+	return lv
+}
+
+func (b *builder) findCond(condExpr ast.Expr, ctx *context) ir.LValue {
+	rv := b.processExpr(condExpr, ctx)
+	lv, ok := rv.(ir.LValue)
+	if !ok || lv == nil {
+		p := b.fset.Position(condExpr.Pos())
+		condExprStr := b.nodeToString(condExpr)
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, condExprStr, "could not resolve cond expr: %v", condExprStr))
+		return nil
+	}
+	if lv.Type() != ir.CondType {
+		structType, ok := lv.Type().(*ir.StructType)
+		if !ok {
+			p := b.fset.Position(condExpr.Pos())
+			condExprStr := b.nodeToString(condExpr)
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, condExprStr, "could not resolve cond expr: %v", condExprStr))
+			return nil
+		}
+		embeddedFields, ok := structType.FindEmbeddedFieldOfType(ir.CondType)
+		if !ok {
+			p := b.fset.Position(condExpr.Pos())
+			condExprStr := b.nodeToString(condExpr)
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, condExprStr, "could not resolve cond expr: %v", condExprStr))
 			return nil
 		}
 		for _, field := range embeddedFields {
@@ -81,3 +145,142 @@ func (b *builder) findOnce(onceExpr ast.Expr, ctx *context) ir.LValue {
 	}
 	return lv
 }
+
+func (b *builder) processNewCondExpr(callExpr *ast.CallExpr, ctx *context) *ir.Variable {
+	return b.processMakeCond(callExpr.Args[0], callExpr, ctx)
+}
+
+func (b *builder) processCondCompositeLit(compositeLit *ast.CompositeLit, ctx *context) *ir.Variable {
+	var lockerExpr ast.Expr
+	for i, valExpr := range compositeLit.Elts {
+		if keyValueExpr, ok := valExpr.(*ast.KeyValueExpr); ok {
+			if keyExpr, ok := keyValueExpr.Key.(*ast.Ident); ok && keyExpr.Name == "L" {
+				lockerExpr = keyValueExpr.Value
+			}
+		} else if i == 0 {
+			lockerExpr = valExpr
+		}
+	}
+	return b.processMakeCond(lockerExpr, compositeLit, ctx)
+}
+
+func (b *builder) processMakeCond(lockerExpr ast.Expr, condExpr ast.Expr, ctx *context) *ir.Variable {
+	var mutex ir.RValue = ir.MutexType.UninitializedValue()
+	usesRLocker := false
+	if lockerExpr != nil {
+		var mutexType ir.Type = ir.MutexType
+		if rwMutexExpr := b.rLockerMutexExpr(lockerExpr, ctx); rwMutexExpr != nil {
+			lockerExpr = rwMutexExpr
+			mutexType = ir.RWMutexType
+			usesRLocker = true
+		} else if b.typesTypeToIrType(ctx.typesInfo.TypeOf(lockerExpr)) == ir.RWMutexType {
+			mutexType = ir.RWMutexType
+		}
+		if mutexVal := b.findMutex(lockerExpr, mutexType, ctx); mutexVal != nil {
+			mutex = mutexVal.(ir.RValue)
+		}
+	}
+
+	result := b.program.NewVariable("", ir.CondType.InitializedValue())
+	ctx.body.Scope().AddVariable(result)
+	makeStmt := ir.NewMakeCondStmt(result, mutex, condExpr.Pos(), condExpr.End())
+	makeStmt.SetUsesRLocker(usesRLocker)
+	ctx.body.AddStmt(makeStmt)
+
+	return result
+}
+
+// processCondLockerAssignment handles an assignment to the L field of a
+// sync.Cond by making a new cond with the assigned locker. It returns false if
+// the given assignment is not of that form.
+func (b *builder) processCondLockerAssignment(lhsExprs, rhsExprs []ast.Expr, ctx *context) bool {
+	if len(lhsExprs) != 1 || len(rhsExprs) != 1 {
+		return false
+	}
+	selectorExpr, ok := lhsExprs[0].(*ast.SelectorExpr)
+	if !ok || selectorExpr.Sel.Name != "L" ||
+		b.typesTypeToIrType(ctx.typesInfo.TypeOf(selectorExpr.X)) != ir.CondType {
+		return false
+	}
+	cond := b.findCond(selectorExpr.X, ctx)
+	if cond == nil {
+		return true
+	}
+	made := b.processMakeCond(rhsExprs[0], selectorExpr, ctx)
+	assignStmt := ir.NewAssignStmt(made, cond, false, selectorExpr.Pos(), selectorExpr.End())
+	ctx.body.AddStmt(assignStmt)
+	return true
+}
+
+// processOnceFuncExpr lowers a sync.OnceFunc, sync.OnceValue or
+// sync.OnceValues call into a closure that calls the given function through a
+// captured sync.Once and returns the results of that first call.
+func (b *builder) processOnceFuncExpr(callExpr *ast.CallExpr, ctx *context) *ir.Variable {
+	fExpr := callExpr.Args[0]
+	fSig := b.typeArgs.substitute(ctx.typesInfo.TypeOf(fExpr)).Underlying().(*types.Signature)
+	onceFuncSig := b.typeArgs.substitute(ctx.typesInfo.TypeOf(callExpr)).Underlying().(*types.Signature)
+
+	fLit, fVar, ok := b.processCalledLaterFuncArg(fExpr, callExpr, ctx)
+	if !ok {
+		return nil
+	}
+
+	onceVar := b.program.NewVariable("once", ir.OnceType.InitializedValue())
+	onceVar.SetCaptured(true)
+	ctx.body.Scope().AddVariable(onceVar)
+	resultVars := make(map[int]*ir.Variable)
+	for i := 0; i < fSig.Results().Len(); i++ {
+		irType := b.typesTypeToIrType(fSig.Results().At(i).Type())
+		if irType == nil {
+			continue
+		}
+		resultVar := b.program.NewVariable("", irType.UninitializedValue())
+		resultVar.SetCaptured(true)
+		ctx.body.Scope().AddVariable(resultVar)
+		resultVars[i] = resultVar
+	}
+
+	// The func returned by sync.OnceFunc, sync.OnceValue or sync.OnceValues:
+	onceFunc := b.program.AddInnerFunc(onceFuncSig, ctx.currentFunc(), ctx.body.Scope(), callExpr.Pos(), callExpr.End())
+	b.addSignatureToFunc(onceFunc, onceFuncSig)
+	onceCtx := ctx.subContextForFunc(onceFunc)
+
+	// The func passed to sync.Once.Do, storing the results of f if needed:
+	var doFuncVal ir.RValue
+	if len(resultVars) > 0 {
+		doSig := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+		doFunc := b.program.AddInnerFunc(doSig, onceFunc, onceFunc.Body().Scope(), callExpr.Pos(), callExpr.End())
+		doCtx := onceCtx.subContextForFunc(doFunc)
+		callee := b.calledLaterFuncCallee(fLit, fVar, doCtx)
+		callStmt := ir.NewCallStmt(callee, fSig, ir.Call, callExpr.Pos(), callExpr.End())
+		doCtx.body.AddStmt(callStmt)
+		callResultVars, callResultRequiresCopy := b.processCallResultVars(fSig, doCtx)
+		for i, v := range callResultVars {
+			callStmt.AddResult(i, v, callResultRequiresCopy[i])
+			if resultVars[i] == nil {
+				continue
+			}
+			assignStmt := ir.NewAssignStmt(v, resultVars[i], callResultRequiresCopy[i], callExpr.Pos(), callExpr.End())
+			doCtx.body.AddStmt(assignStmt)
+		}
+		doCtx.body.AddStmt(ir.NewReturnStmt(false, callExpr.Pos(), callExpr.End()))
+		doFuncVal = doFunc.FuncValue()
+	} else if fLit != nil {
+		doFuncVal = b.processFuncLit(fLit, onceCtx).FuncValue()
+	} else {
+		doFuncVal = fVar
+	}
+
+	onceDoStmt := ir.NewOnceDoStmt(onceVar, doFuncVal, callExpr.Pos(), callExpr.End())
+	onceCtx.body.AddStmt(onceDoStmt)
+	returnStmt := ir.NewReturnStmt(false, callExpr.Pos(), callExpr.End())
+	onceCtx.body.AddStmt(returnStmt)
+	for i, v := range resultVars {
+		returnStmt.AddResult(i, v)
+	}
+
+	result := b.program.NewVariable("", ir.FuncType.UninitializedValue())
+	ctx.body.Scope().AddVariable(result)
+	ctx.body.AddStmt(ir.NewAssignStmt(onceFunc.FuncValue(), result, false, callExpr.Pos(), callExpr.End()))
+	return result
+}
@@ -0,0 +1,46 @@
+package builder
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/arneph/toph/ir"
+)
+
+// processTestRunExpr lowers a testing.T.Run call into a call of the
+// substitute for testing.T.Run. The subtest func gets called from a closure
+// taking the substitute *testing.T value, such that the substitute can call
+// it dynamically.
+func (b *builder) processTestRunExpr(callExpr *ast.CallExpr, ctx *context) {
+	runFunc := b.getSubstituteMethod("subT", "Run")
+	if runFunc == nil {
+		return
+	}
+	typesFunc := ctx.typesInfo.Uses[callExpr.Fun.(*ast.SelectorExpr).Sel].(*types.Func)
+	tVal, _, ok := b.processCallReceiverVal(callExpr, typesFunc.Type().(*types.Signature), ctx)
+	if !ok || tVal == nil {
+		return
+	}
+	fExpr := callExpr.Args[1]
+	fSig := b.typeArgs.substitute(ctx.typesInfo.TypeOf(fExpr)).Underlying().(*types.Signature)
+	runnerSig := runFunc.Signature().Params().At(1).Type().(*types.Signature)
+
+	fLit, fVar, ok := b.processCalledLaterFuncArg(fExpr, callExpr, ctx)
+	if !ok {
+		return
+	}
+
+	runner := b.program.AddInnerFunc(runnerSig, ctx.currentFunc(), ctx.body.Scope(), fExpr.Pos(), fExpr.End())
+	runnerArgs := b.addSignatureToFunc(runner, runnerSig)
+	runnerCtx := ctx.subContextForFunc(runner)
+	callee := b.calledLaterFuncCallee(fLit, fVar, runnerCtx)
+	callStmt := ir.NewCallStmt(callee, fSig, ir.Call, fExpr.Pos(), fExpr.End())
+	callStmt.AddArg(0, runnerArgs[0], false)
+	runnerCtx.body.AddStmt(callStmt)
+	runnerCtx.body.AddStmt(ir.NewReturnStmt(false, fExpr.Pos(), fExpr.End()))
+
+	runCallStmt := ir.NewCallStmt(runFunc, runFunc.Signature(), ir.Call, callExpr.Pos(), callExpr.End())
+	runCallStmt.AddArg(-1, tVal, false)
+	runCallStmt.AddArg(1, runner.FuncValue(), false)
+	ctx.body.AddStmt(runCallStmt)
+}
@@ -0,0 +1,347 @@
+package builder
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/ir"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Tracked ints are local int and bool variables defined by statements
+// annotated with "toph: track", for example loop counters and flags
+// controlling concurrency operations. Unlike all other ints and bools, they
+// get modeled as Uppaal ints (bools are one for true and zero for false) and
+// conditions over them become guards instead of nondeterministic choices.
+// Values that can not be determined, for example results of function calls,
+// get chosen nondeterministically between zero and config.TrackedIntMax.
+// The ok variables of receive operations, for example in v, ok := <-ch, are
+// always tracked and hold whether a sent value was received. Likewise, local
+// variables assigned the result of sync.Mutex.TryLock or
+// sync.RWMutex.TryRLock, for example in ok := mu.TryLock(), are always
+// tracked and hold whether the mutex was acquired.
+
+// minTrackedInt and maxTrackedInt are the bounds of Uppaal ints.
+const (
+	minTrackedInt = -32768
+	maxTrackedInt = 32767
+)
+
+var trackedIntBinaryOps = map[token.Token]ir.BinaryOp{
+	token.ADD:  ir.Plus,
+	token.SUB:  ir.Minus,
+	token.MUL:  ir.Times,
+	token.QUO:  ir.Div,
+	token.REM:  ir.Rem,
+	token.EQL:  ir.Equal,
+	token.NEQ:  ir.NotEqual,
+	token.LSS:  ir.Less,
+	token.LEQ:  ir.LessEqual,
+	token.GTR:  ir.Greater,
+	token.GEQ:  ir.GreaterEqual,
+	token.LAND: ir.And,
+	token.LOR:  ir.Or,
+}
+
+// findTrackedVars finds all variables defined by statements annotated with
+// "toph: track", all local ok variables of receive operations, and all local
+// variables assigned results of try lock operations. For loops with the
+// annotation track the variables defined by their init statement.
+func (b *builder) findTrackedVars() {
+	b.trackedVars = make(map[*types.Var]bool)
+	for _, pkg := range b.pkgs {
+		typesInfo := pkg.TypesInfo
+		for _, astFile := range pkg.Syntax {
+			ctx := newContext(b.cmaps[astFile], typesInfo, b.program.InitFunc())
+			ast.Inspect(astFile, func(node ast.Node) bool {
+				var defs ast.Node
+				switch node := node.(type) {
+				case *ast.DeclStmt:
+					defs = node
+				case *ast.AssignStmt:
+					if typesVar := b.findReceiveOkVar(node, typesInfo); typesVar != nil {
+						b.trackedVars[typesVar] = true
+					}
+					for _, typesVar := range b.findTryLockResultVars(node, typesInfo) {
+						b.trackedVars[typesVar] = true
+					}
+					defs = node
+				case *ast.ForStmt:
+					if node.Init == nil {
+						return true
+					}
+					defs = node.Init
+				default:
+					return true
+				}
+				if _, ok := b.findAnnotation(node, "track", ctx); !ok {
+					return true
+				}
+				ast.Inspect(defs, func(n ast.Node) bool {
+					if _, ok := n.(*ast.FuncLit); ok {
+						return false
+					}
+					ident, ok := n.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					typesVar, ok := typesInfo.Defs[ident].(*types.Var)
+					if !ok || typesVar.Name() == "_" {
+						return true
+					}
+					if !b.isTrackableType(typesVar.Type()) {
+						p := b.fset.Position(ident.Pos())
+						b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, ident.Name, "can only track int and bool variables: %s", ident.Name))
+						return true
+					}
+					b.trackedVars[typesVar] = true
+					return true
+				})
+				return true
+			})
+		}
+	}
+}
+
+// findReceiveOkVar returns the local variable receiving the ok result of the
+// receive operation assigned by the given statement, or nil if the statement
+// does not assign the ok result of a receive operation to a local variable.
+func (b *builder) findReceiveOkVar(stmt *ast.AssignStmt, typesInfo *types.Info) *types.Var {
+	if receiveOkExpr(stmt.Lhs, stmt.Rhs) == nil {
+		return nil
+	}
+	return b.findLocalTrackableVar(stmt.Lhs[1], typesInfo)
+}
+
+// findTryLockResultVars returns the local variables the given statement
+// assigns results of sync.Mutex.TryLock, sync.RWMutex.TryLock, or
+// sync.RWMutex.TryRLock calls to.
+func (b *builder) findTryLockResultVars(stmt *ast.AssignStmt, typesInfo *types.Info) (typesVars []*types.Var) {
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		return nil
+	}
+	for i, rhsExpr := range stmt.Rhs {
+		if !isTryLockCall(rhsExpr, typesInfo) {
+			continue
+		}
+		if typesVar := b.findLocalTrackableVar(stmt.Lhs[i], typesInfo); typesVar != nil {
+			typesVars = append(typesVars, typesVar)
+		}
+	}
+	return typesVars
+}
+
+// isTryLockCall returns whether the given expression is a call of
+// sync.Mutex.TryLock, sync.RWMutex.TryLock, or sync.RWMutex.TryRLock.
+func isTryLockCall(expr ast.Expr, typesInfo *types.Info) bool {
+	callExpr, ok := astutil.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	selExpr, ok := astutil.Unparen(callExpr.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	method, ok := typesInfo.Uses[selExpr.Sel].(*types.Func)
+	if !ok {
+		return false
+	}
+	switch method.FullName() {
+	case "(*sync.Mutex).TryLock", "(*sync.RWMutex).TryLock", "(*sync.RWMutex).TryRLock":
+		return true
+	default:
+		return false
+	}
+}
+
+// findLocalTrackableVar returns the local int or bool variable the given
+// expression refers to or nil if the expression refers to a different kind of
+// variable.
+func (b *builder) findLocalTrackableVar(expr ast.Expr, typesInfo *types.Info) *types.Var {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	typesVar, ok := typesInfo.ObjectOf(ident).(*types.Var)
+	if !ok || typesVar.Name() == "_" || typesVar.Pkg() == nil ||
+		typesVar.Parent() == typesVar.Pkg().Scope() || !b.isTrackableType(typesVar.Type()) {
+		return nil
+	}
+	return typesVar
+}
+
+// receiveOkExpr returns the receive expression of an assignment of the form
+// v, ok = <-ch or nil if the assignment has a different form.
+func receiveOkExpr(lhsExprs, rhsExprs []ast.Expr) *ast.UnaryExpr {
+	if len(lhsExprs) != 2 || len(rhsExprs) != 1 {
+		return nil
+	}
+	receiveExpr, ok := astutil.Unparen(rhsExprs[0]).(*ast.UnaryExpr)
+	if !ok || receiveExpr.Op != token.ARROW {
+		return nil
+	}
+	return receiveExpr
+}
+
+// bindReceiveOk stores the ok result of the given receive operation in a new
+// variable and returns the variable.
+func (b *builder) bindReceiveOk(receiveStmt *ir.ChanCommOpStmt, ctx *context) *ir.Variable {
+	okVar := b.program.NewVariable("", ir.IntType.UninitializedValue())
+	ctx.body.Scope().AddVariable(okVar)
+	receiveStmt.SetOk(okVar)
+	return okVar
+}
+
+// isTrackableType returns whether the given type is an int or bool type that
+// is not modeled otherwise.
+func (b *builder) isTrackableType(typesType types.Type) bool {
+	if typesType == nil || b.isDuration(typesType) {
+		return false
+	}
+	basic, ok := b.typeArgs.substitute(typesType).Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsInteger|types.IsBoolean) != 0
+}
+
+// findTrackedVar returns the variable of the given tracked variable expression
+// or nil if the expression is not a tracked variable.
+func (b *builder) findTrackedVar(expr ast.Expr, ctx *context) *ir.Variable {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	typesVar, ok := ctx.typesInfo.ObjectOf(ident).(*types.Var)
+	if !ok || !b.trackedVars[typesVar] {
+		return nil
+	}
+	v := b.vars[typesVar]
+	if v == nil {
+		return nil
+	}
+	if s := v.Scope(); s != b.program.Scope() && s.IsParentOf(ctx.currentFunc().Scope()) {
+		v.SetCaptured(true)
+	}
+	return v
+}
+
+// usesTrackedVars returns whether the given expression reads any tracked
+// variables, excluding reads in func literals.
+func (b *builder) usesTrackedVars(expr ast.Expr, ctx *context) bool {
+	if len(b.trackedVars) == 0 {
+		return false
+	}
+	usesTrackedVars := false
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.Ident:
+			if typesVar, ok := ctx.typesInfo.Uses[node].(*types.Var); ok && b.trackedVars[typesVar] {
+				usesTrackedVars = true
+			}
+		}
+		return !usesTrackedVars
+	})
+	return usesTrackedVars
+}
+
+// unknownTrackedValue returns the value of tracked variables of the given
+// type that get assigned values that can not be determined.
+func (b *builder) unknownTrackedValue(typesType types.Type) ir.RValue {
+	if basic, ok := typesType.Underlying().(*types.Basic); ok && basic.Info()&types.IsBoolean != 0 {
+		return ir.NewRandomInt(0, 1)
+	}
+	return ir.NewRandomInt(0, b.config.TrackedIntMax)
+}
+
+// processIntExpr processes the given int or bool expression and returns its
+// modeled int value or nil if the value depends on values that are not
+// modeled. Constants, tracked variables, operations on modeled values, and
+// modeled results, for example of sync.Mutex.TryLock, are modeled. All parts
+// of the expression get processed exactly once.
+func (b *builder) processIntExpr(expr ast.Expr, ctx *context) ir.RValue {
+	if typeAndValue := ctx.typesInfo.Types[expr]; typeAndValue.Value != nil {
+		return intConstValue(typeAndValue.Value)
+	}
+
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return b.processIntExpr(e.X, ctx)
+	case *ast.Ident:
+		if v := b.findTrackedVar(e, ctx); v != nil {
+			return v
+		}
+	case *ast.BinaryExpr:
+		op, ok := trackedIntBinaryOps[e.Op]
+		if !ok {
+			break
+		}
+		x := b.processIntExpr(e.X, ctx)
+		y := b.processIntExpr(e.Y, ctx)
+		if x == nil || y == nil {
+			return nil
+		}
+		return ir.NewBinaryExpr(x, op, y)
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.ADD:
+			return b.processIntExpr(e.X, ctx)
+		case token.SUB, token.NOT:
+			x := b.processIntExpr(e.X, ctx)
+			if x == nil {
+				return nil
+			} else if e.Op == token.SUB {
+				return ir.NewBinaryExpr(ir.MakeValue(0, ir.IntType), ir.Minus, x)
+			}
+			return ir.NewBinaryExpr(x, ir.Equal, ir.MakeValue(0, ir.IntType))
+		}
+	}
+	v := b.processExpr(expr, ctx)
+	if v == nil || v.Type() != ir.IntType || !b.isTrackableType(ctx.typesInfo.TypeOf(expr)) {
+		return nil
+	}
+	return v
+}
+
+// intConstValue returns the value of the given int or bool constant or nil if
+// the constant is of a different kind or exceeds the bounds of Uppaal ints.
+func intConstValue(val constant.Value) ir.RValue {
+	switch val.Kind() {
+	case constant.Bool:
+		if constant.BoolVal(val) {
+			return ir.MakeValue(1, ir.IntType)
+		}
+		return ir.MakeValue(0, ir.IntType)
+	case constant.Int:
+		v, exact := constant.Int64Val(val)
+		if !exact || v < minTrackedInt || v > maxTrackedInt {
+			return nil
+		}
+		return ir.MakeValue(v, ir.IntType)
+	default:
+		return nil
+	}
+}
+
+// processTrackedVarUpdate processes a compound assignment or an increment or
+// decrement statement of the given tracked variable.
+func (b *builder) processTrackedVarUpdate(lhsExpr ast.Expr, v *ir.Variable, tok token.Token, rhsExpr ast.Expr, ctx *context) {
+	var source ir.RValue
+	switch tok {
+	case token.INC:
+		source = ir.NewBinaryExpr(v, ir.Plus, ir.MakeValue(1, ir.IntType))
+	case token.DEC:
+		source = ir.NewBinaryExpr(v, ir.Minus, ir.MakeValue(1, ir.IntType))
+	default:
+		op, ok := trackedIntBinaryOps[tok-(token.ADD_ASSIGN-token.ADD)]
+		y := b.processIntExpr(rhsExpr, ctx)
+		if ok && y != nil {
+			source = ir.NewBinaryExpr(v, op, y)
+		} else {
+			source = b.unknownTrackedValue(ctx.typesInfo.TypeOf(lhsExpr))
+		}
+	}
+	ctx.body.AddStmt(ir.NewAssignStmt(source, v, false, lhsExpr.Pos(), lhsExpr.End()))
+}
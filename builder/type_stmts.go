@@ -1,10 +1,10 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/types"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
@@ -14,7 +14,7 @@ func (b *builder) findContainer(containerExpr ast.Expr, ctx *context) ir.LValue
 	if !ok || lv == nil {
 		p := b.fset.Position(containerExpr.Pos())
 		containerExprStr := b.nodeToString(containerExpr)
-		b.addWarning(fmt.Errorf("%v: could not resolve container expr: %s", p, containerExprStr))
+		b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, containerExprStr, "could not resolve container expr: %s", containerExprStr))
 		return nil
 	}
 	return lv
@@ -51,7 +51,7 @@ func (b *builder) processNewExpr(callExpr *ast.CallExpr, ctx *context) *ir.Varia
 	}
 	p := b.fset.Position(callExpr.Pos())
 	callExprStr := b.nodeToString(callExpr)
-	b.addWarning(fmt.Errorf("%v: new not supported for type: %s", p, callExprStr))
+	b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, callExprStr, "new not supported for type: %s", callExprStr))
 	return nil
 }
 
@@ -66,7 +66,7 @@ func (b *builder) processMakeContainerExpr(callExpr *ast.CallExpr, ctx *context)
 	if !ok {
 		p := b.fset.Position(callExpr.Pos())
 		callExprStr := b.nodeToString(callExpr)
-		b.addWarning(fmt.Errorf("%v: unexpected make with non-container type: %s", p, callExprStr))
+		b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, callExprStr, "unexpected make with non-container type: %s", callExprStr))
 		return nil
 	}
 	var length ir.RValue = ir.MakeValue(0, ir.IntType)
@@ -82,7 +82,7 @@ func (b *builder) processMakeContainerExpr(callExpr *ast.CallExpr, ctx *context)
 			} else {
 				p := b.fset.Position(lengthExpr.Pos())
 				aStr := b.nodeToString(lengthExpr)
-				b.addWarning(fmt.Errorf("%v: can not process slice legnth: %s", p, aStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, aStr, "can not process slice legnth: %s", aStr))
 			}
 		}
 	case ir.Map:
@@ -114,7 +114,7 @@ func (b *builder) processSliceAppendExpr(callExpr *ast.CallExpr, ctx *context) *
 		if _, ok := valueTypesType.(*types.Slice); ok {
 			p := b.fset.Position(callExpr.Pos())
 			callStr := b.nodeToString(callExpr)
-			b.addWarning(fmt.Errorf("%v: appending slice to slice is unsupported: %s", p, callStr))
+			b.addWarning(diag.Warningf(diag.UnsupportedOperation, p, callStr, "appending slice to slice is unsupported: %s", callStr))
 			return nil
 		}
 	}
@@ -130,7 +130,7 @@ func (b *builder) processSliceAppendExpr(callExpr *ast.CallExpr, ctx *context) *
 		if argVal == nil {
 			p := b.fset.Position(argExpr.Pos())
 			argStr := b.nodeToString(argExpr)
-			b.addWarning(fmt.Errorf("%v: can not process slice element: %s", p, argStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, argStr, "can not process slice element: %s", argStr))
 			continue
 		}
 		requiresCopy := irSliceType.RequiresDeepCopies()
@@ -158,6 +158,11 @@ func (b *builder) processCompositeLit(compositeLit *ast.CompositeLit, ctx *conte
 		return b.processStructCompositeLit(compositeLit, typesStruct, irType, ctx)
 	case *ir.ContainerType:
 		return b.processContainerCompositeLit(compositeLit, typesType, irType, ctx)
+	case ir.BasicType:
+		if irType == ir.CondType {
+			return b.processCondCompositeLit(compositeLit, ctx)
+		}
+		return nil
 	default:
 		return nil
 	}
@@ -182,15 +187,16 @@ func (b *builder) processStructCompositeLit(compositeLit *ast.CompositeLit,
 		} else {
 			typesVar = typesStruct.Field(i)
 		}
-		irField := b.fields[typesVar]
+		irField, ok := b.findField(typesVar, irStructType)
 		irFieldVal := b.processExpr(valExpr, ctx)
-		if irField == nil {
+		if !ok {
 			continue
 		}
+		irFieldVal = b.processImplicitConversion(irFieldVal, valExpr, typesVar.Type(), ctx)
 		if irFieldVal == nil {
 			p := b.fset.Position(valExpr.Pos())
 			valExprStr := b.nodeToString(valExpr)
-			b.addWarning(fmt.Errorf("%v: could not evaluate field value: %s", p, valExprStr))
+			b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, valExprStr, "could not evaluate field value: %s", valExprStr))
 			continue
 		}
 		requiresCopy := irField.RequiresDeepCopy()
@@ -201,7 +207,7 @@ func (b *builder) processStructCompositeLit(compositeLit *ast.CompositeLit,
 			if !ok {
 				p := b.fset.Position(valExpr.Pos())
 				valExprStr := b.nodeToString(valExpr)
-				b.addWarning(fmt.Errorf("%v: could not find field for value: %s", p, valExprStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, valExprStr, "could not find field for value: %s", valExprStr))
 				return nil
 			}
 			for _, irField := range embeddedFields {
@@ -238,6 +244,16 @@ func (b *builder) processContainerCompositeLit(compositeLit *ast.CompositeLit,
 	irVar := b.program.NewVariable("", irContainerType.UninitializedValue())
 	ctx.body.Scope().AddVariable(irVar)
 
+	var elemTypesType types.Type
+	switch typesType := typesType.Underlying().(type) {
+	case *types.Array:
+		elemTypesType = typesType.Elem()
+	case *types.Slice:
+		elemTypesType = typesType.Elem()
+	case *types.Map:
+		elemTypesType = typesType.Elem()
+	}
+
 	var arrayOrSliceEntries []arrayOrSliceCompositeLitEntry
 	var length int
 	switch irContainerType.Kind() {
@@ -261,10 +277,11 @@ func (b *builder) processContainerCompositeLit(compositeLit *ast.CompositeLit,
 			index := entry.index
 			valExpr := entry.valueExpr
 			irElemVal := b.processExpr(valExpr, ctx)
+			irElemVal = b.processImplicitConversion(irElemVal, valExpr, elemTypesType, ctx)
 			if irElemVal == nil {
 				p := b.fset.Position(valExpr.Pos())
 				valExprStr := b.nodeToString(valExpr)
-				b.addWarning(fmt.Errorf("%v: could not evaluate element value: %s", p, valExprStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, valExprStr, "could not evaluate element value: %s", valExprStr))
 				index++
 				continue
 			}
@@ -301,10 +318,11 @@ func (b *builder) processContainerCompositeLit(compositeLit *ast.CompositeLit,
 
 			b.processExpr(keyExpr, ctx)
 			irElemVal := b.processExpr(valExpr, ctx)
+			irElemVal = b.processImplicitConversion(irElemVal, valExpr, elemTypesType, ctx)
 			if irElemVal == nil {
 				p := b.fset.Position(valExpr.Pos())
 				valExprStr := b.nodeToString(valExpr)
-				b.addWarning(fmt.Errorf("%v: could not evaluate element value: %s", p, valExprStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, valExprStr, "could not evaluate element value: %s", valExprStr))
 				continue
 			}
 			requiresCopy := irContainerType.RequiresDeepCopies()
@@ -337,7 +355,7 @@ func (b *builder) indicesAndValueExprsForArrayOrSliceCompositeLit(compositeLit *
 			if res != nil || !ok {
 				p := b.fset.Position(keyExpr.Pos())
 				keyExprStr := b.nodeToString(keyExpr)
-				b.addWarning(fmt.Errorf("%v: could not evaluate index value: %s", p, keyExprStr))
+				b.addWarning(diag.Warningf(diag.UnresolvedExpression, p, keyExprStr, "could not evaluate index value: %s", keyExprStr))
 			} else {
 				index = int(resInt.Value())
 			}
@@ -7,10 +7,15 @@ import (
 )
 
 type typeInfo struct {
-	irType ir.Type
+	irType      ir.Type
+	isInterface bool
 }
 
 func (b *builder) typesTypeToIrType(typesType types.Type) ir.Type {
+	typesType = b.typeArgs.substitute(typesType)
+	if b.isDuration(typesType) {
+		return ir.IntType
+	}
 	switch underlyingTypesType := typesType.Underlying().(type) {
 	case *types.Chan:
 		return ir.ChanType
@@ -22,13 +27,16 @@ func (b *builder) typesTypeToIrType(typesType types.Type) ir.Type {
 		if typesType.String() == "sync.Mutex" {
 			return ir.MutexType
 		} else if typesType.String() == "sync.RWMutex" {
-			return ir.MutexType
+			return ir.RWMutexType
 		} else if typesType.String() == "sync.WaitGroup" {
 			return ir.WaitGroupType
 		} else if typesType.String() == "sync.Once" {
 			return ir.OnceType
-		} else if typesType.String() == "testing.T" {
-			return nil
+		} else if typesType.String() == "sync.Cond" {
+			return ir.CondType
+		} else if typesType.String() == "testing.T" || typesType.String() == "time.Timer" ||
+			b.hasUserSubstituteType(typesType) {
+			return b.getSubstituteType(typesType)
 		}
 		info, ok := b.types.At(typesType).(*typeInfo)
 		if ok {
@@ -58,10 +66,32 @@ func (b *builder) typesTypeToIrType(typesType types.Type) ir.Type {
 					return elementIrType
 				}
 				return nil
+			case ir.BasicType:
+				switch elementIrType {
+				case ir.MutexType, ir.RWMutexType, ir.WaitGroupType, ir.CondType:
+					return elementIrType
+				default:
+					return nil
+				}
 			default:
 				return nil
 			}
 		}
+	case *types.Interface:
+		if subType := b.getSubstituteType(typesType); subType != nil {
+			return subType
+		}
+		info, ok := b.types.At(typesType).(*typeInfo)
+		if ok {
+			return info.irType
+		}
+		if !b.shouldModelType(typesType, nil) {
+			info = new(typeInfo)
+			info.irType = nil
+			b.types.Set(typesType, info)
+			return nil
+		}
+		return b.typesInterfaceToIrType(typesType, underlyingTypesType)
 	case *types.Array, *types.Slice, *types.Map:
 		info, ok := b.types.At(typesType).(*typeInfo)
 		if ok {
@@ -81,8 +111,10 @@ func (b *builder) typesTypeToIrType(typesType types.Type) ir.Type {
 
 func (b *builder) typesStructToIrType(typesType types.Type, typesStruct *types.Struct) ir.Type {
 	name := ""
+	isInstance := false
 	if typesNamed, ok := typesType.(*types.Named); ok {
 		name = typesNamed.Obj().Name()
+		isInstance = typesNamed.TypeArgs().Len() > 0
 	}
 	irStructType := b.program.AddStructType(name)
 	info := new(typeInfo)
@@ -99,11 +131,36 @@ func (b *builder) typesStructToIrType(typesType types.Type, typesStruct *types.S
 		isPointer := b.isPointer(fieldTypesType)
 		isEmbedded := fieldTypesVar.Embedded()
 		irField := irStructType.AddField(i, fieldTypesVar.Name(), fieldIrType, isPointer, isEmbedded)
-		b.fields[fieldTypesVar] = irField
+		if !isInstance {
+			// Instantiations of generic types can share field objects,
+			// their fields get looked up by name instead.
+			b.fields[fieldTypesVar] = irField
+		}
+	}
+	for i := 0; i < typesStruct.NumFields(); i++ {
+		fieldTypesVar := typesStruct.Field(i)
+		if _, ok := b.sharedVars[fieldTypesVar]; ok {
+			irStructType.AddCounterField(typesStruct.NumFields()+i, sharedFieldCounterName(fieldTypesVar))
+		}
 	}
 	return irStructType
 }
 
+// findField returns the ir.Field for the given struct field. Fields of
+// substituted types, for example time.Timer.C, and of instantiated generic
+// types get looked up by name in the given struct type.
+func (b *builder) findField(fieldTypesVar *types.Var, irStructType *ir.StructType) (*ir.Field, bool) {
+	if irField, ok := b.fields[fieldTypesVar]; ok {
+		return irField, true
+	}
+	for _, irField := range irStructType.Fields() {
+		if irField.Name() == fieldTypesVar.Name() {
+			return irField, true
+		}
+	}
+	return nil, false
+}
+
 func (b *builder) typesContainerToIrType(typesType types.Type) ir.Type {
 	var kind ir.ContainerKind
 	var len int
@@ -134,19 +191,31 @@ func (b *builder) typesContainerToIrType(typesType types.Type) ir.Type {
 }
 
 func (b *builder) shouldModelType(typesType types.Type, seen []types.Type) bool {
-	if typesType.String() == "sync.Mutex" {
+	if typesType.String() == "sync.Mutex" || typesType.String() == "*sync.Mutex" {
 		return true
-	} else if typesType.String() == "sync.RWMutex" {
+	} else if typesType.String() == "sync.RWMutex" || typesType.String() == "*sync.RWMutex" {
 		return true
-	} else if typesType.String() == "sync.WaitGroup" {
+	} else if typesType.String() == "sync.WaitGroup" || typesType.String() == "*sync.WaitGroup" {
 		return true
 	} else if typesType.String() == "sync.Once" {
 		return true
+	} else if typesType.String() == "sync.Cond" || typesType.String() == "*sync.Cond" {
+		return true
+	} else if typesType.String() == "context.Context" {
+		return true
+	} else if typesType.String() == "time.Timer" || typesType.String() == "*time.Timer" {
+		return true
+	} else if typesType.String() == "testing.T" || typesType.String() == "*testing.T" {
+		return true
+	} else if b.hasUserSubstituteType(typesType) {
+		return true
 	}
 	if typesNamed, ok := typesType.(*types.Named); ok {
 		_, ok := b.typesPkgs[typesNamed.Obj().Pkg()]
 		if !ok {
 			return false
+		} else if b.isExcludedType(typesNamed) {
+			return false
 		}
 	}
 	for _, seen := range seen {
@@ -154,6 +223,9 @@ func (b *builder) shouldModelType(typesType types.Type, seen []types.Type) bool
 			return false
 		}
 	}
+	if typesInterface, ok := typesType.Underlying().(*types.Interface); ok {
+		return b.shouldModelInterface(typesType, typesInterface)
+	}
 
 	switch typesType := typesType.Underlying().(type) {
 	case *types.Chan:
@@ -170,6 +242,9 @@ func (b *builder) shouldModelType(typesType types.Type, seen []types.Type) bool
 			return false
 		}
 	case *types.Struct:
+		if b.hasSharedFields(typesType) {
+			return true
+		}
 		for i := 0; i < typesType.NumFields(); i++ {
 			typesVar := typesType.Field(i)
 			if b.shouldModelType(typesVar.Type(), append(seen, typesType)) {
@@ -189,6 +264,7 @@ func (b *builder) shouldModelType(typesType types.Type, seen []types.Type) bool
 }
 
 func (b *builder) isPointer(typesType types.Type) bool {
+	typesType = b.typeArgs.substitute(typesType)
 	switch typesType.Underlying().(type) {
 	case *types.Pointer:
 		irType := b.typesTypeToIrType(typesType)
@@ -197,9 +273,14 @@ func (b *builder) isPointer(typesType types.Type) bool {
 			return true
 		case *ir.ContainerType:
 			return irType.Kind() == ir.Array
+		case ir.BasicType:
+			return irType == ir.MutexType || irType == ir.RWMutexType || irType == ir.WaitGroupType || irType == ir.CondType
 		default:
 			return false
 		}
+	case *types.Interface:
+		_, ok := b.typesTypeToIrType(typesType).(*ir.StructType)
+		return ok
 	case *types.Array:
 		return false
 	case *types.Slice:
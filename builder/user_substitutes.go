@@ -0,0 +1,313 @@
+package builder
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/ir"
+)
+
+// userSubstitutes holds the substitutes defined in the files (or directories)
+// listed in config.Config.SubstitutesFiles, keyed by the fully qualified name of the
+// substituted function, method, or type, for example "os/signal.Notify",
+// "(*golang.org/x/sync/errgroup.Group).Wait", or
+// "golang.org/x/sync/errgroup.Group".
+//
+// A file annotated with "toph: substitute=<package path>" in its package
+// comment substitutes the exported functions and types of the package with
+// the same names. Individual declarations annotated with
+// "toph: substitute=<fully qualified name>" substitute the named function,
+// method, or type. Methods of substituted types get substituted by the
+// methods of the substitute type with the same names.
+//
+// Substitute functions and methods must have the same parameter and result
+// types as the substituted ones, except that substitute types can be used in
+// place of the types they substitute. Substitutes with different signatures
+// get ignored.
+type userSubstitutes struct {
+	funcs      map[string]*types.Func
+	types      map[string]*types.TypeName
+	mismatches map[*types.Func]bool
+}
+
+// loadUserSubstitutes parses and type checks the user-defined substitutes
+// files and returns the parsed files and their type information. Returns nil
+// if no substitutes files are given or loading them failed.
+func (b *builder) loadUserSubstitutes() ([]*ast.File, *types.Info) {
+	b.userSubs.funcs = make(map[string]*types.Func)
+	b.userSubs.types = make(map[string]*types.TypeName)
+	b.userSubs.mismatches = make(map[*types.Func]bool)
+	fileNames, ok := b.findUserSubstitutesFileNames()
+	if !ok || len(fileNames) == 0 {
+		return nil, nil
+	}
+
+	var files []*ast.File
+	for _, fileName := range fileNames {
+		file, err := parser.ParseFile(b.fset, fileName, nil, parserMode)
+		if err != nil {
+			b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, fileName, "parsing substitutes file failed: %v", err))
+			return nil, nil
+		}
+		if len(files) > 0 && file.Name.Name != files[0].Name.Name {
+			p := b.fset.Position(file.Name.Pos())
+			b.addWarning(diag.Warningf(diag.LoadFailure, p, fileName, "substitutes files declare different packages: %s and %s", files[0].Name.Name, file.Name.Name))
+			return nil, nil
+		}
+		files = append(files, file)
+	}
+	typesInfo := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	typesConfig := &types.Config{
+		Importer: importer.ForCompiler(b.fset, "source", nil),
+	}
+	typesPkg, err := typesConfig.Check(files[0].Name.Name, b.fset, files, typesInfo)
+	if err != nil {
+		b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, "", "type checking substitutes files failed: %v", err))
+		return nil, nil
+	}
+	b.typesPkgs[typesPkg] = struct{}{}
+
+	for _, file := range files {
+		b.findUserSubstitutesInFile(file, typesInfo)
+	}
+	return files, typesInfo
+}
+
+// findUserSubstitutesFileNames returns the names of the substitutes files
+// listed in config.Config.SubstitutesFiles. Directories get replaced by the
+// Go files they contain, excluding test files.
+func (b *builder) findUserSubstitutesFileNames() ([]string, bool) {
+	var fileNames []string
+	for _, name := range b.config.SubstitutesFiles {
+		info, err := os.Stat(name)
+		if err != nil {
+			b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, name, "reading substitutes failed: %v", err))
+			return nil, false
+		} else if !info.IsDir() {
+			fileNames = append(fileNames, name)
+			continue
+		}
+		entries, err := os.ReadDir(name)
+		if err != nil {
+			b.addWarning(diag.Warningf(diag.LoadFailure, token.Position{}, name, "reading substitutes directory failed: %v", err))
+			return nil, false
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+				continue
+			}
+			fileNames = append(fileNames, filepath.Join(name, entry.Name()))
+		}
+	}
+	return fileNames, true
+}
+
+func (b *builder) findUserSubstitutesInFile(file *ast.File, typesInfo *types.Info) {
+	pkgPath := b.findSubstituteAnnotation(file.Doc)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			typesFunc := typesInfo.Defs[decl.Name].(*types.Func)
+			name := b.findSubstituteAnnotation(decl.Doc)
+			if name == "" && pkgPath != "" && decl.Recv == nil && decl.Name.IsExported() {
+				name = pkgPath + "." + decl.Name.Name
+			}
+			if other, ok := b.userSubs.funcs[name]; ok {
+				b.addDuplicateUserSubstituteWarning(name, other, decl.Name)
+			} else if name != "" {
+				b.userSubs.funcs[name] = typesFunc
+			}
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				typeName := typesInfo.Defs[typeSpec.Name].(*types.TypeName)
+				name := b.findSubstituteAnnotation(typeSpec.Doc)
+				if name == "" && len(decl.Specs) == 1 {
+					name = b.findSubstituteAnnotation(decl.Doc)
+				}
+				if name == "" && pkgPath != "" && typeSpec.Name.IsExported() {
+					name = pkgPath + "." + typeSpec.Name.Name
+				}
+				if other, ok := b.userSubs.types[name]; ok {
+					b.addDuplicateUserSubstituteWarning(name, other, typeSpec.Name)
+				} else if name != "" {
+					b.userSubs.types[name] = typeName
+				}
+			}
+		}
+	}
+}
+
+func (b *builder) findSubstituteAnnotation(commentGroup *ast.CommentGroup) string {
+	for _, info := range b.findAnnotationsInCommentGroup(commentGroup) {
+		if strings.HasPrefix(info, "substitute=") {
+			return info[11:]
+		}
+	}
+	return ""
+}
+
+func (b *builder) addDuplicateUserSubstituteWarning(name string, other types.Object, ident *ast.Ident) {
+	p := b.fset.Position(ident.Pos())
+	b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, ident.Name, "ignoring substitute %s for %s, already substituted by %s", ident.Name, name, other.Name()))
+}
+
+// getUserSubstituteFunc returns the user-defined substitute for the given
+// function or method, or nil if it has none or the substitute has a
+// different signature.
+func (b *builder) getUserSubstituteFunc(funcType *types.Func) *ir.Func {
+	subFunc := b.findUserSubstituteFunc(funcType)
+	if subFunc == nil {
+		return nil
+	}
+	funcSig := funcType.Type().(*types.Signature)
+	subSig := subFunc.Type().(*types.Signature)
+	if !b.isUserSubstituteTuple(funcSig.Params(), subSig.Params()) ||
+		!b.isUserSubstituteTuple(funcSig.Results(), subSig.Results()) ||
+		funcSig.Variadic() != subSig.Variadic() {
+		if !b.userSubs.mismatches[subFunc] {
+			b.userSubs.mismatches[subFunc] = true
+			p := b.fset.Position(subFunc.Pos())
+			b.addWarning(diag.Warningf(diag.InvalidAnnotation, p, subFunc.Name(), "ignoring substitute %s for %s, signatures differ: %s and %s", subFunc.Name(), funcType.FullName(), methodValueSignature(subSig), methodValueSignature(funcSig)))
+		}
+		return nil
+	}
+	return b.funcs[subFunc]
+}
+
+// findUserSubstituteFunc returns the types.Func of the user-defined
+// substitute for the given function or method, or nil if it has none.
+func (b *builder) findUserSubstituteFunc(funcType *types.Func) *types.Func {
+	if subFunc, ok := b.userSubs.funcs[funcType.FullName()]; ok {
+		return subFunc
+	}
+	recv := funcType.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	recvType := recv.Type()
+	if pointer, ok := recvType.(*types.Pointer); ok {
+		recvType = pointer.Elem()
+	}
+	subTypeObj, ok := b.userSubs.types[recvType.String()]
+	if !ok {
+		return nil
+	}
+	subType := types.NewPointer(subTypeObj.Type())
+	methodObj, _, _ := types.LookupFieldOrMethod(subType, false, subTypeObj.Pkg(), funcType.Name())
+	methodFunc, ok := methodObj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	return methodFunc
+}
+
+// isUserSubstituteTuple returns whether the given tuples of parameters or
+// results have identical types, allowing types in subTuple to be user-defined
+// substitutes of the corresponding types in tuple.
+func (b *builder) isUserSubstituteTuple(tuple, subTuple *types.Tuple) bool {
+	if tuple.Len() != subTuple.Len() {
+		return false
+	}
+	for i := 0; i < tuple.Len(); i++ {
+		if !b.isUserSubstituteTypeOf(tuple.At(i).Type(), subTuple.At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUserSubstituteTypeOf returns whether the given types are identical or
+// subType is the user-defined substitute of typesType. Pointers, slices,
+// arrays, maps, channels, and funcs of substitute types substitute pointers,
+// slices, arrays, maps, channels, and funcs of the substituted types. The
+// substitutes files get type checked separately from the loaded packages,
+// therefore named types are identical if their names and packages are.
+func (b *builder) isUserSubstituteTypeOf(typesType, subType types.Type) bool {
+	if types.Identical(typesType, subType) {
+		return true
+	}
+	switch typesType := typesType.(type) {
+	case *types.Named:
+		if subTypeObj, ok := b.userSubs.types[typesType.String()]; ok {
+			return types.Identical(subTypeObj.Type(), subType)
+		}
+		subNamed, ok := subType.(*types.Named)
+		if !ok || typesType.Obj().Name() != subNamed.Obj().Name() ||
+			typesType.TypeArgs().Len() != subNamed.TypeArgs().Len() {
+			return false
+		} else if pkg, subPkg := typesType.Obj().Pkg(), subNamed.Obj().Pkg(); (pkg == nil) != (subPkg == nil) ||
+			pkg != nil && pkg.Path() != subPkg.Path() {
+			return false
+		}
+		for i := 0; i < typesType.TypeArgs().Len(); i++ {
+			if !b.isUserSubstituteTypeOf(typesType.TypeArgs().At(i), subNamed.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	case *types.Signature:
+		subSig, ok := subType.(*types.Signature)
+		return ok && typesType.Variadic() == subSig.Variadic() &&
+			b.isUserSubstituteTuple(typesType.Params(), subSig.Params()) &&
+			b.isUserSubstituteTuple(typesType.Results(), subSig.Results())
+	case *types.Pointer:
+		subPointer, ok := subType.(*types.Pointer)
+		return ok && b.isUserSubstituteTypeOf(typesType.Elem(), subPointer.Elem())
+	case *types.Slice:
+		subSlice, ok := subType.(*types.Slice)
+		return ok && b.isUserSubstituteTypeOf(typesType.Elem(), subSlice.Elem())
+	case *types.Array:
+		subArray, ok := subType.(*types.Array)
+		return ok && typesType.Len() == subArray.Len() &&
+			b.isUserSubstituteTypeOf(typesType.Elem(), subArray.Elem())
+	case *types.Map:
+		subMap, ok := subType.(*types.Map)
+		return ok && b.isUserSubstituteTypeOf(typesType.Key(), subMap.Key()) &&
+			b.isUserSubstituteTypeOf(typesType.Elem(), subMap.Elem())
+	case *types.Chan:
+		subChan, ok := subType.(*types.Chan)
+		return ok && typesType.Dir() == subChan.Dir() &&
+			b.isUserSubstituteTypeOf(typesType.Elem(), subChan.Elem())
+	default:
+		return false
+	}
+}
+
+// getUserSubstituteType returns the ir.Type of the user-defined substitute
+// for the given types.Type, or nil if it has none.
+func (b *builder) getUserSubstituteType(typesType types.Type) ir.Type {
+	subTypeObj, ok := b.userSubs.types[typesType.String()]
+	if !ok {
+		return nil
+	}
+	return b.typesTypeToIrType(subTypeObj.Type())
+}
+
+// hasUserSubstituteType returns whether the given type, or the type it points
+// to, has a user-defined substitute.
+func (b *builder) hasUserSubstituteType(typesType types.Type) bool {
+	if pointer, ok := typesType.(*types.Pointer); ok {
+		typesType = pointer.Elem()
+	}
+	_, ok := b.userSubs.types[typesType.String()]
+	return ok
+}
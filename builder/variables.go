@@ -1,10 +1,10 @@
 package builder
 
 import (
-	"fmt"
 	"go/ast"
 	"go/types"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 )
 
@@ -39,12 +39,15 @@ func (b *builder) processVarDefinitionInScope(ident *ast.Ident, scope *ir.Scope,
 	typesType := typesVar.Type()
 	if typesType == nil {
 		p := b.fset.Position(ident.Pos())
-		b.addWarning(fmt.Errorf("%v: types.Type for identifier is nil: %s", p, ident.Name))
+		b.addWarning(diag.Warningf(diag.Internal, p, ident.Name, "types.Type for identifier is nil: %s", ident.Name))
 		return nil
 	}
+	b.addSharedVarCounter(typesVar, scope)
 
 	irType := b.typesTypeToIrType(typesType)
-	if irType == nil {
+	if irType == nil && b.trackedVars[typesVar] {
+		irType = ir.IntType
+	} else if irType == nil {
 		return nil
 	}
 	initialValue := irType.UninitializedValue()
@@ -0,0 +1,167 @@
+package checker
+
+// The types below form the abstract syntax tree of the subset of the Uppaal
+// modelling language that the translator generates.
+
+type expr interface {
+	exprNode()
+}
+
+type identExpr struct {
+	name string
+}
+
+type intLitExpr struct {
+	val int
+}
+
+type boolLitExpr struct {
+	val bool
+}
+
+type deadlockExpr struct{}
+
+type unaryExpr struct {
+	op string
+	x  expr
+}
+
+type binaryExpr struct {
+	op   string
+	x, y expr
+}
+
+type condExpr struct {
+	cond, x, y expr
+}
+
+type assignExpr struct {
+	op       string
+	lhs, rhs expr
+}
+
+type incDecExpr struct {
+	op     string
+	prefix bool
+	x      expr
+}
+
+type indexExpr struct {
+	x, index expr
+}
+
+type selectorExpr struct {
+	x   expr
+	sel string
+}
+
+type callExpr struct {
+	fun  string
+	args []expr
+}
+
+type initListExpr struct {
+	elems []expr
+}
+
+func (*identExpr) exprNode()    {}
+func (*intLitExpr) exprNode()   {}
+func (*boolLitExpr) exprNode()  {}
+func (*deadlockExpr) exprNode() {}
+func (*unaryExpr) exprNode()    {}
+func (*binaryExpr) exprNode()   {}
+func (*condExpr) exprNode()     {}
+func (*assignExpr) exprNode()   {}
+func (*incDecExpr) exprNode()   {}
+func (*indexExpr) exprNode()    {}
+func (*selectorExpr) exprNode() {}
+func (*callExpr) exprNode()     {}
+func (*initListExpr) exprNode() {}
+
+type typeExpr struct {
+	prefixes map[string]bool
+	base     string // int, bool, chan, clock, void, struct, or a type name
+	lo, hi   expr
+	fields   []*varDecl
+}
+
+type varDecl struct {
+	typ  *typeExpr
+	name string
+	ref  bool
+	dims []expr
+	init expr
+}
+
+type typeDecl struct {
+	typ  *typeExpr
+	name string
+	dims []expr
+}
+
+type funcDecl struct {
+	result *typeExpr
+	name   string
+	params []*varDecl
+	body   *blockStmt
+}
+
+type declarations struct {
+	types []*typeDecl
+	vars  []*varDecl
+	funcs []*funcDecl
+}
+
+type stmt interface {
+	stmtNode()
+}
+
+type exprStmt struct {
+	x expr
+}
+
+type declStmt struct {
+	decls []*varDecl
+}
+
+type blockStmt struct {
+	stmts []stmt
+}
+
+type ifStmt struct {
+	cond            expr
+	then, otherwise stmt
+}
+
+type forStmt struct {
+	init, cond, post expr
+	body             stmt
+}
+
+type forRangeStmt struct {
+	name string
+	typ  *typeExpr
+	body stmt
+}
+
+type whileStmt struct {
+	cond   expr
+	body   stmt
+	doLoop bool
+}
+
+type returnStmt struct {
+	x expr
+}
+
+type emptyStmt struct{}
+
+func (*exprStmt) stmtNode()     {}
+func (*declStmt) stmtNode()     {}
+func (*blockStmt) stmtNode()    {}
+func (*ifStmt) stmtNode()       {}
+func (*forStmt) stmtNode()      {}
+func (*forRangeStmt) stmtNode() {}
+func (*whileStmt) stmtNode()    {}
+func (*returnStmt) stmtNode()   {}
+func (*emptyStmt) stmtNode()    {}
@@ -0,0 +1,211 @@
+// Package checker implements an explicit-state model checker for the Uppaal
+// systems generated by the translator. It explores all interleavings of the
+// process instances of a system and decides the system's queries without
+// requiring Uppaal's verifyta.
+//
+// The checker supports the untimed subset of Uppaal used by the translator:
+// bounded integers, booleans, structs, arrays, binary channels, committed
+// locations, select statements, functions, and queries of the forms A[] p and
+// E<> p.
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	c "github.com/arneph/toph/config"
+	"github.com/arneph/toph/trace"
+	"github.com/arneph/toph/uppaal"
+)
+
+// Verdict is the outcome of checking a query.
+type Verdict int
+
+const (
+	// Unknown indicates that the query could not be decided before reaching
+	// the state limit.
+	Unknown Verdict = iota
+	// Satisfied indicates that the query holds.
+	Satisfied
+	// NotSatisfied indicates that the query does not hold.
+	NotSatisfied
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Unknown:
+		return "unknown"
+	case Satisfied:
+		return "satisfied"
+	case NotSatisfied:
+		return "not satisfied"
+	default:
+		panic(fmt.Errorf("unexpected verdict: %d", v))
+	}
+}
+
+// QueryResult holds the verdict for a query.
+type QueryResult struct {
+	Query   *uppaal.Query
+	Verdict Verdict
+	// Trace leads from the initial state to a state violating an A[] query
+	// or to a state satisfying an E<> query.
+	Trace *trace.Trace
+}
+
+// Result holds the results for all queries of a system.
+type Result struct {
+	QueryResults []*QueryResult
+	// ExploredStates is the number of states the checker explored.
+	ExploredStates int
+	// Complete indicates if all reachable states got explored.
+	Complete bool
+}
+
+// NotSatisfied returns all query results with the NotSatisfied verdict.
+func (r *Result) NotSatisfied() []*QueryResult {
+	var results []*QueryResult
+	for _, qr := range r.QueryResults {
+		if qr.Verdict == NotSatisfied {
+			results = append(results, qr)
+		}
+	}
+	return results
+}
+
+type query struct {
+	result *QueryResult
+	always bool
+	pred   func(*env) int32
+}
+
+// CheckSystem explores the state space of the system and checks all queries
+// of the system, including the queries of all process instances. Exploration
+// stops after config.MaxCheckedStates states if the limit is positive.
+func CheckSystem(sys *uppaal.System, config *c.Config) (result *Result, err error) {
+	m, err := newModel(sys)
+	if err != nil {
+		return nil, fmt.Errorf("checker: %v", err)
+	}
+	queries, err := m.compileQueries()
+	if err != nil {
+		return nil, fmt.Errorf("checker: %v", err)
+	}
+
+	x := newExplorer(m)
+	defer func() {
+		if r := recover(); r != nil {
+			me, ok := r.(modelError)
+			if !ok {
+				panic(r)
+			}
+			result, err = nil, fmt.Errorf("checker: %v in transition %s", me, trace.Transition{
+				Instance: m.insts[x.current.inst].inst,
+				Trans:    x.current.trans,
+			})
+		}
+	}()
+
+	x.add(m.initEnv.vars, -1, step{})
+	vars := make([]int32, len(m.initEnv.vars))
+	next := make([]int32, len(m.initEnv.vars))
+	undecided := len(queries)
+	result = new(Result)
+	result.Complete = true
+	for i := 0; i < x.states.len() && undecided > 0; i++ {
+		if config.MaxCheckedStates > 0 && i >= config.MaxCheckedStates {
+			result.Complete = false
+			break
+		}
+		x.get(int32(i), vars)
+		steps := x.successors(vars)
+		e := &env{vars: vars, deadlock: len(steps) == 0}
+		for _, q := range queries {
+			if q.result.Verdict != Unknown {
+				continue
+			}
+			holds := q.pred(e) != 0
+			if q.always && !holds {
+				q.result.Verdict = NotSatisfied
+			} else if !q.always && holds {
+				q.result.Verdict = Satisfied
+			} else {
+				continue
+			}
+			q.result.Trace = m.convertTrace(x.trace(int32(i)))
+			q.result.Trace.Query = q.result.Query
+			undecided--
+		}
+		for _, s := range steps {
+			x.apply(vars, next, s)
+			x.add(next, int32(i), s)
+		}
+		result.ExploredStates = i + 1
+	}
+	for _, q := range queries {
+		if q.result.Verdict == Unknown && result.Complete {
+			if q.always {
+				q.result.Verdict = Satisfied
+			} else {
+				q.result.Verdict = NotSatisfied
+			}
+		}
+		result.QueryResults = append(result.QueryResults, q.result)
+	}
+	return result, nil
+}
+
+// compileQueries compiles all system queries and process queries for all
+// instances, in the same order as in the generated query files.
+func (m *model) compileQueries() (queries []*query, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			me, ok := r.(modelError)
+			if !ok {
+				panic(r)
+			}
+			queries, err = nil, me
+		}
+	}()
+
+	for _, q := range m.sys.AllQueries() {
+		str := strings.TrimSpace(q.Query())
+		cq := &query{result: &QueryResult{Query: q}}
+		switch {
+		case strings.HasPrefix(str, "A[]"):
+			cq.always = true
+		case strings.HasPrefix(str, "E<>"):
+			cq.always = false
+		default:
+			return nil, fmt.Errorf("unsupported query: %s", str)
+		}
+		p, err := newParser(str[3:], m.typeNames)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %v", str, err)
+		}
+		x, err := p.parseSingleExpr()
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %v", str, err)
+		}
+		c := &compiler{m: m, sc: m.globals}
+		cq.pred, _ = c.scalar(x)
+		queries = append(queries, cq)
+	}
+	return queries, nil
+}
+
+func (m *model) convertTrace(steps []step) *trace.Trace {
+	traceSteps := make([]trace.Step, len(steps))
+	for i, s := range steps {
+		for _, cand := range []candidate{s.a, s.b} {
+			if cand.edge == nil {
+				continue
+			}
+			traceSteps[i] = append(traceSteps[i], trace.Transition{
+				Instance: m.insts[cand.edge.inst].inst,
+				Trans:    cand.edge.trans,
+			})
+		}
+	}
+	return trace.New(m.sys, traceSteps)
+}
@@ -0,0 +1,171 @@
+package checker
+
+import (
+	"fmt"
+	"testing"
+
+	c "github.com/arneph/toph/config"
+	"github.com/arneph/toph/uppaal"
+)
+
+// addQuery adds a system query with the given formula and returns it.
+func addQuery(sys *uppaal.System, formula string) *uppaal.Query {
+	q := uppaal.NewQuery(formula, "", "", uppaal.NoChannelRelatedDeadlocks)
+	sys.AddQuery(q)
+	return q
+}
+
+// addChain adds a process with an instance of the same name to the system,
+// consisting of a chain of normal states s0, s1, ... connected by transitions
+// with the given syncs (empty for internal transitions).
+func addChain(sys *uppaal.System, name string, syncs ...string) *uppaal.Process {
+	proc := sys.AddProcess(name)
+	prev := proc.AddState("s0", uppaal.NoRenaming)
+	proc.SetInitialState(prev)
+	for i, sync := range syncs {
+		next := proc.AddState(fmt.Sprintf("s%d", i+1), uppaal.NoRenaming)
+		trans := proc.AddTransition(prev, next)
+		if sync != "" {
+			trans.SetSync(sync)
+		}
+		prev = next
+	}
+	sys.AddProcessInstance(proc, name+"_inst")
+	return proc
+}
+
+// addCounter adds a process incrementing the global variable x while it is
+// less than max.
+func addCounter(sys *uppaal.System, max string) {
+	sys.Declarations().AddVariable("x", "int", "0")
+	proc := sys.AddProcess("Counter")
+	s0 := proc.AddState("s0", uppaal.NoRenaming)
+	proc.SetInitialState(s0)
+	inc := proc.AddTransition(s0, s0)
+	inc.SetGuard("x < "+max, true)
+	inc.AddUpdate("x++", true)
+	sys.AddProcessInstance(proc, "counter")
+}
+
+func check(t *testing.T, sys *uppaal.System, maxStates int) *Result {
+	t.Helper()
+	result, err := CheckSystem(sys, &c.Config{MaxCheckedStates: maxStates})
+	if err != nil {
+		t.Fatalf("CheckSystem failed: %v", err)
+	}
+	return result
+}
+
+func expectVerdict(t *testing.T, result *Result, q *uppaal.Query, want Verdict) *QueryResult {
+	t.Helper()
+	for _, qr := range result.QueryResults {
+		if qr.Query.Query() != q.Query() {
+			continue
+		}
+		if qr.Verdict != want {
+			t.Errorf("%s: got %v, want %v", q.Query(), qr.Verdict, want)
+		}
+		return qr
+	}
+	t.Fatalf("no result for %s", q.Query())
+	return nil
+}
+
+func TestDeadlock(t *testing.T) {
+	sys := uppaal.NewSystem()
+	sys.Declarations().AddVariable("ch", "chan", "")
+	addChain(sys, "Sender", "ch!", "ch!")
+	addChain(sys, "Receiver", "ch?")
+
+	blockedSender := addQuery(sys, "A[] not (deadlock and Sender_inst.s1)")
+	blockedReceiver := addQuery(sys, "A[] not (deadlock and Receiver_inst.s0)")
+	anyDeadlock := addQuery(sys, "E<> deadlock")
+
+	result := check(t, sys, 0)
+	if !result.Complete {
+		t.Errorf("expected complete exploration")
+	}
+	qr := expectVerdict(t, result, blockedSender, NotSatisfied)
+	if qr.Trace == nil || len(qr.Trace.Steps) != 1 || len(qr.Trace.Steps[0]) != 2 {
+		t.Errorf("expected trace with one synchronization, got %v", qr.Trace)
+	}
+	expectVerdict(t, result, blockedReceiver, Satisfied)
+	expectVerdict(t, result, anyDeadlock, Satisfied)
+}
+
+func TestCommittedLocationPriority(t *testing.T) {
+	sys := uppaal.NewSystem()
+	sys.Declarations().AddVariable("x", "int", "0")
+	sys.Declarations().AddVariable("y", "int", "0")
+
+	// Leaves its committed initial location before any other process moves.
+	first := sys.AddProcess("First")
+	f0 := first.AddState("f0", uppaal.NoRenaming)
+	f0.SetType(uppaal.Committed)
+	f1 := first.AddState("f1", uppaal.NoRenaming)
+	first.SetInitialState(f0)
+	setX := first.AddTransition(f0, f1)
+	setX.AddUpdate("x = 1", true)
+	sys.AddProcessInstance(first, "first")
+
+	// Could only set y if it moved before first.
+	second := sys.AddProcess("Second")
+	g0 := second.AddState("g0", uppaal.NoRenaming)
+	g1 := second.AddState("g1", uppaal.NoRenaming)
+	second.SetInitialState(g0)
+	setY := second.AddTransition(g0, g1)
+	setY.SetGuard("x == 0", true)
+	setY.AddUpdate("y = 1", true)
+	sys.AddProcessInstance(second, "second")
+
+	yUnchanged := addQuery(sys, "A[] y == 0")
+	secondMoves := addQuery(sys, "E<> second.g1")
+
+	result := check(t, sys, 0)
+	expectVerdict(t, result, yUnchanged, Satisfied)
+	expectVerdict(t, result, secondMoves, NotSatisfied)
+}
+
+func TestVerdicts(t *testing.T) {
+	sys := uppaal.NewSystem()
+	addCounter(sys, "3")
+
+	reachable := addQuery(sys, "E<> x == 3")
+	unreachable := addQuery(sys, "E<> x == 4")
+	invariant := addQuery(sys, "A[] x <= 3")
+	violated := addQuery(sys, "A[] x < 3")
+
+	result := check(t, sys, 0)
+	if !result.Complete || result.ExploredStates != 4 {
+		t.Errorf("expected complete exploration of 4 states, got %d (complete: %t)",
+			result.ExploredStates, result.Complete)
+	}
+	qr := expectVerdict(t, result, reachable, Satisfied)
+	if qr.Trace == nil || len(qr.Trace.Steps) != 3 {
+		t.Errorf("expected witness with 3 steps, got %v", qr.Trace)
+	}
+	expectVerdict(t, result, unreachable, NotSatisfied)
+	expectVerdict(t, result, invariant, Satisfied)
+	qr = expectVerdict(t, result, violated, NotSatisfied)
+	if qr.Trace == nil || len(qr.Trace.Steps) != 3 {
+		t.Errorf("expected counterexample with 3 steps, got %v", qr.Trace)
+	}
+}
+
+func TestStateLimit(t *testing.T) {
+	sys := uppaal.NewSystem()
+	addCounter(sys, "100")
+
+	early := addQuery(sys, "E<> x == 5")
+	invariant := addQuery(sys, "A[] x <= 100")
+	late := addQuery(sys, "E<> x == 100")
+
+	result := check(t, sys, 10)
+	if result.Complete || result.ExploredStates != 10 {
+		t.Errorf("expected incomplete exploration of 10 states, got %d (complete: %t)",
+			result.ExploredStates, result.Complete)
+	}
+	expectVerdict(t, result, early, Satisfied)
+	expectVerdict(t, result, invariant, Unknown)
+	expectVerdict(t, result, late, Unknown)
+}
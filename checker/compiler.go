@@ -0,0 +1,707 @@
+package checker
+
+import (
+	"fmt"
+)
+
+// modelError indicates an unsupported or erroneous model or a runtime error
+// while evaluating the model, for example an out of bounds array access.
+type modelError struct {
+	msg string
+}
+
+func (e modelError) Error() string {
+	return e.msg
+}
+
+func fail(format string, args ...interface{}) {
+	panic(modelError{fmt.Sprintf(format, args...)})
+}
+
+const maxCallDepth = 1000
+
+// env holds everything needed to evaluate compiled expressions and
+// statements.
+type env struct {
+	vars     []int32
+	base     int
+	chanBase int
+	frame    []int32
+	ret      []int32
+	retInt   int32
+	depth    int
+	deadlock bool
+}
+
+// compiler turns expressions and statements into closures operating on an
+// env.
+type compiler struct {
+	m  *model
+	sc *scope
+	fn *function
+}
+
+func (c *compiler) withScope(sc *scope) *compiler {
+	return &compiler{m: c.m, sc: sc, fn: c.fn}
+}
+
+// Lvalues
+
+func (c *compiler) lvalue(x expr) (func(*env) ([]int32, int), *typ) {
+	switch x := x.(type) {
+	case *identExpr:
+		sym := c.sc.lookup(x.name)
+		if sym == nil {
+			fail("undeclared identifier: %s", x.name)
+		} else if sym.kind != varSymbol {
+			fail("%s is not a variable", x.name)
+		}
+		off := sym.offset
+		switch sym.class {
+		case globalStorage:
+			return func(e *env) ([]int32, int) { return e.vars, off }, sym.typ
+		case localStorage:
+			return func(e *env) ([]int32, int) { return e.vars, e.base + off }, sym.typ
+		case frameStorage:
+			return func(e *env) ([]int32, int) { return e.frame, off }, sym.typ
+		default:
+			fail("channel %s used as value", x.name)
+		}
+	case *indexExpr:
+		base, t := c.lvalue(x.x)
+		if t.kind != arrayKind {
+			fail("indexing non-array value of type %v", t)
+		}
+		index, _ := c.scalar(x.index)
+		length := int32(t.length)
+		elemSize := t.elem.size()
+		return func(e *env) ([]int32, int) {
+			mem, off := base(e)
+			i := index(e)
+			if i < 0 || i >= length {
+				fail("array index %d out of range [0, %d]", i, length-1)
+			}
+			return mem, off + int(i)*elemSize
+		}, t.elem
+	case *selectorExpr:
+		if inst := c.instanceOf(x.x); inst != nil {
+			sym, ok := inst.proc.scope.symbols[x.sel]
+			if !ok || sym.kind != varSymbol || sym.class != localStorage {
+				fail("%s has no variable %s", inst.name, x.sel)
+			}
+			off := inst.base + sym.offset
+			return func(e *env) ([]int32, int) { return e.vars, off }, sym.typ
+		}
+		base, t := c.lvalue(x.x)
+		if t.kind != structKind {
+			fail("selecting field %s of non-struct value", x.sel)
+		}
+		f, ok := t.findField(x.sel)
+		if !ok {
+			fail("struct has no field %s", x.sel)
+		}
+		off := f.offset
+		return func(e *env) ([]int32, int) {
+			mem, i := base(e)
+			return mem, i + off
+		}, f.typ
+	}
+	fail("expression is not assignable")
+	return nil, nil
+}
+
+// instanceOf returns the process instance the expression refers to, if any.
+// This is only possible in queries, for example: P.x or P.state.
+func (c *compiler) instanceOf(x expr) *instance {
+	ident, ok := x.(*identExpr)
+	if !ok || c.sc.lookup(ident.name) != nil {
+		return nil
+	}
+	return c.m.instNames[ident.name]
+}
+
+func (c *compiler) channel(x expr) func(*env) int {
+	switch x := x.(type) {
+	case *identExpr:
+		sym := c.sc.lookup(x.name)
+		if sym == nil {
+			fail("undeclared identifier: %s", x.name)
+		}
+		off := sym.offset
+		switch sym.class {
+		case globalChanStorage:
+			return func(e *env) int { return off }
+		case localChanStorage:
+			return func(e *env) int { return e.chanBase + off }
+		}
+		fail("%s is not a channel", x.name)
+	case *indexExpr:
+		base := c.channel(x.x)
+		t := c.channelType(x.x)
+		index, _ := c.scalar(x.index)
+		length := int32(t.length)
+		elemSize := t.elem.size()
+		return func(e *env) int {
+			i := index(e)
+			if i < 0 || i >= length {
+				fail("channel index %d out of range [0, %d]", i, length-1)
+			}
+			return base(e) + int(i)*elemSize
+		}
+	}
+	fail("expression is not a channel")
+	return nil
+}
+
+func (c *compiler) channelType(x expr) *typ {
+	switch x := x.(type) {
+	case *identExpr:
+		return c.sc.lookup(x.name).typ
+	case *indexExpr:
+		t := c.channelType(x.x)
+		if t.kind != arrayKind {
+			fail("indexing non-array channel")
+		}
+		return t.elem
+	}
+	fail("expression is not a channel")
+	return nil
+}
+
+// Scalar expressions
+
+func (c *compiler) scalar(x expr) (func(*env) int32, *typ) {
+	switch x := x.(type) {
+	case *intLitExpr:
+		v := int32(x.val)
+		return func(*env) int32 { return v }, wideIntType
+	case *boolLitExpr:
+		var v int32
+		if x.val {
+			v = 1
+		}
+		return func(*env) int32 { return v }, boolType
+	case *deadlockExpr:
+		return func(e *env) int32 {
+			if e.deadlock {
+				return 1
+			}
+			return 0
+		}, boolType
+	case *identExpr, *indexExpr:
+		return c.load(x)
+	case *selectorExpr:
+		if inst := c.instanceOf(x.x); inst != nil {
+			if loc, ok := inst.proc.locLookup[x.sel]; ok {
+				slot := inst.locSlot
+				l := int32(loc)
+				return func(e *env) int32 {
+					if e.vars[slot] == l {
+						return 1
+					}
+					return 0
+				}, boolType
+			}
+		}
+		return c.load(x)
+	case *unaryExpr:
+		f, _ := c.scalar(x.x)
+		switch x.op {
+		case "!":
+			return func(e *env) int32 {
+				if f(e) == 0 {
+					return 1
+				}
+				return 0
+			}, boolType
+		case "-":
+			return func(e *env) int32 { return -f(e) }, wideIntType
+		case "+":
+			return f, wideIntType
+		case "~":
+			return func(e *env) int32 { return ^f(e) }, wideIntType
+		}
+	case *binaryExpr:
+		return c.binary(x)
+	case *condExpr:
+		cond, _ := c.scalar(x.cond)
+		a, t := c.scalar(x.x)
+		b, _ := c.scalar(x.y)
+		return func(e *env) int32 {
+			if cond(e) != 0 {
+				return a(e)
+			}
+			return b(e)
+		}, t
+	case *assignExpr:
+		return c.assign(x)
+	case *incDecExpr:
+		ref, t := c.lvalue(x.x)
+		if !t.isScalar() {
+			fail("%s applied to non-scalar value", x.op)
+		}
+		delta := int32(1)
+		if x.op == "--" {
+			delta = -1
+		}
+		prefix := x.prefix
+		return func(e *env) int32 {
+			mem, i := ref(e)
+			old := mem[i]
+			mem[i] = checkRange(old+delta, t)
+			if prefix {
+				return mem[i]
+			}
+			return old
+		}, wideIntType
+	case *callExpr:
+		call, fn := c.call(x)
+		if !fn.result.isScalar() {
+			fail("call of %s does not return a scalar value", x.fun)
+		}
+		return func(e *env) int32 {
+			call(e)
+			return e.retInt
+		}, fn.result
+	}
+	fail("unsupported expression")
+	return nil, nil
+}
+
+func (c *compiler) load(x expr) (func(*env) int32, *typ) {
+	if ident, ok := x.(*identExpr); ok {
+		// Fast paths for the most common case.
+		sym := c.sc.lookup(ident.name)
+		if sym != nil && sym.kind == varSymbol && sym.typ.isScalar() {
+			off := sym.offset
+			switch sym.class {
+			case globalStorage:
+				return func(e *env) int32 { return e.vars[off] }, sym.typ
+			case localStorage:
+				return func(e *env) int32 { return e.vars[e.base+off] }, sym.typ
+			case frameStorage:
+				return func(e *env) int32 { return e.frame[off] }, sym.typ
+			}
+		}
+	}
+	ref, t := c.lvalue(x)
+	if !t.isScalar() {
+		fail("expected scalar value, found %v", t)
+	}
+	return func(e *env) int32 {
+		mem, i := ref(e)
+		return mem[i]
+	}, t
+}
+
+func (c *compiler) binary(x *binaryExpr) (func(*env) int32, *typ) {
+	a, _ := c.scalar(x.x)
+	b, _ := c.scalar(x.y)
+	b2i := func(v bool) int32 {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	switch x.op {
+	case "&&":
+		return func(e *env) int32 { return b2i(a(e) != 0 && b(e) != 0) }, boolType
+	case "||":
+		return func(e *env) int32 { return b2i(a(e) != 0 || b(e) != 0) }, boolType
+	case "imply":
+		return func(e *env) int32 { return b2i(a(e) == 0 || b(e) != 0) }, boolType
+	case "==":
+		return func(e *env) int32 { return b2i(a(e) == b(e)) }, boolType
+	case "!=":
+		return func(e *env) int32 { return b2i(a(e) != b(e)) }, boolType
+	case "<":
+		return func(e *env) int32 { return b2i(a(e) < b(e)) }, boolType
+	case "<=":
+		return func(e *env) int32 { return b2i(a(e) <= b(e)) }, boolType
+	case ">":
+		return func(e *env) int32 { return b2i(a(e) > b(e)) }, boolType
+	case ">=":
+		return func(e *env) int32 { return b2i(a(e) >= b(e)) }, boolType
+	}
+	op, ok := arithmeticOps[x.op]
+	if !ok {
+		fail("unsupported operator: %s", x.op)
+	}
+	return func(e *env) int32 { return op(a(e), b(e)) }, wideIntType
+}
+
+var arithmeticOps = map[string]func(a, b int32) int32{
+	"+": func(a, b int32) int32 { return a + b },
+	"-": func(a, b int32) int32 { return a - b },
+	"*": func(a, b int32) int32 { return a * b },
+	"/": func(a, b int32) int32 {
+		if b == 0 {
+			fail("division by zero")
+		}
+		return a / b
+	},
+	"%": func(a, b int32) int32 {
+		if b == 0 {
+			fail("division by zero")
+		}
+		return a % b
+	},
+	"&":  func(a, b int32) int32 { return a & b },
+	"|":  func(a, b int32) int32 { return a | b },
+	"^":  func(a, b int32) int32 { return a ^ b },
+	"<<": func(a, b int32) int32 { return a << uint32(b) },
+	">>": func(a, b int32) int32 { return a >> uint32(b) },
+}
+
+func (c *compiler) assign(x *assignExpr) (func(*env) int32, *typ) {
+	ref, t := c.lvalue(x.lhs)
+	if !t.isScalar() {
+		fail("assignment of non-scalar value used as expression")
+	}
+	rhs, _ := c.scalar(x.rhs)
+	if x.op == "=" || x.op == ":=" {
+		return func(e *env) int32 {
+			v := rhs(e)
+			mem, i := ref(e)
+			mem[i] = checkRange(v, t)
+			return mem[i]
+		}, t
+	}
+	op, ok := arithmeticOps[x.op[:len(x.op)-1]]
+	if !ok {
+		fail("unsupported assignment operator: %s", x.op)
+	}
+	return func(e *env) int32 {
+		v := rhs(e)
+		mem, i := ref(e)
+		mem[i] = checkRange(op(mem[i], v), t)
+		return mem[i]
+	}, t
+}
+
+// call returns a closure performing the given call. Scalar results get
+// stored in env.retInt, other results in env.ret.
+func (c *compiler) call(x *callExpr) (func(*env), *function) {
+	sym := c.sc.lookup(x.fun)
+	if sym == nil || sym.kind != funcSymbol {
+		fail("undeclared function: %s", x.fun)
+	}
+	fn := sym.fn
+	if len(x.args) != len(fn.params) {
+		fail("%s expects %d arguments, got %d", x.fun, len(fn.params), len(x.args))
+	}
+	args := make([]func(e *env, frame []int32), len(x.args))
+	for i, arg := range x.args {
+		param := fn.params[i]
+		off := param.offset
+		if param.typ.isScalar() {
+			f, _ := c.scalar(arg)
+			t := param.typ
+			args[i] = func(e *env, frame []int32) {
+				frame[off] = checkRange(f(e), t)
+			}
+		} else {
+			f := c.aggregate(arg, param.typ)
+			args[i] = func(e *env, frame []int32) {
+				copy(frame[off:], f(e))
+			}
+		}
+	}
+	return func(e *env) {
+		frame := make([]int32, fn.frameSize)
+		for _, arg := range args {
+			arg(e, frame)
+		}
+		if e.depth >= maxCallDepth {
+			fail("maximum call depth exceeded in %s", fn.name)
+		}
+		outer := e.frame
+		e.frame = frame
+		e.depth++
+		fn.body(e)
+		e.depth--
+		e.frame = outer
+	}, fn
+}
+
+// Aggregate expressions
+
+func (c *compiler) aggregate(x expr, t *typ) func(*env) []int32 {
+	size := t.size()
+	switch x := x.(type) {
+	case *initListExpr:
+		var elems []func(e *env, dst []int32)
+		addElem := func(elem expr, et *typ, off int) {
+			if et.isScalar() {
+				f, _ := c.scalar(elem)
+				elems = append(elems, func(e *env, dst []int32) {
+					dst[off] = checkRange(f(e), et)
+				})
+			} else {
+				f := c.aggregate(elem, et)
+				elems = append(elems, func(e *env, dst []int32) {
+					copy(dst[off:], f(e))
+				})
+			}
+		}
+		switch t.kind {
+		case structKind:
+			if len(x.elems) != len(t.fields) {
+				fail("expected %d struct fields in initializer, got %d", len(t.fields), len(x.elems))
+			}
+			for i, elem := range x.elems {
+				addElem(elem, t.fields[i].typ, t.fields[i].offset)
+			}
+		case arrayKind:
+			if len(x.elems) != t.length {
+				fail("expected %d array elements in initializer, got %d", t.length, len(x.elems))
+			}
+			for i, elem := range x.elems {
+				addElem(elem, t.elem, i*t.elem.size())
+			}
+		default:
+			fail("initializer list for scalar type %v", t)
+		}
+		return func(e *env) []int32 {
+			v := make([]int32, size)
+			for _, elem := range elems {
+				elem(e, v)
+			}
+			return v
+		}
+	case *callExpr:
+		call, fn := c.call(x)
+		if fn.result.size() != size || fn.result.isScalar() {
+			fail("call of %s returns %v, expected %v", x.fun, fn.result, t)
+		}
+		return func(e *env) []int32 {
+			call(e)
+			return e.ret
+		}
+	case *condExpr:
+		cond, _ := c.scalar(x.cond)
+		a := c.aggregate(x.x, t)
+		b := c.aggregate(x.y, t)
+		return func(e *env) []int32 {
+			if cond(e) != 0 {
+				return a(e)
+			}
+			return b(e)
+		}
+	}
+	ref, rt := c.lvalue(x)
+	if rt.size() != size || rt.isScalar() {
+		fail("expected value of type %v, found %v", t, rt)
+	}
+	return func(e *env) []int32 {
+		mem, i := ref(e)
+		v := make([]int32, size)
+		copy(v, mem[i:i+size])
+		return v
+	}
+}
+
+// initializer returns a closure that stores the value of x in the storage of
+// the given variable symbol.
+func (c *compiler) initializer(sym *symbol, x expr) func(*env) {
+	ref := c.symbolRef(sym)
+	t := sym.typ
+	if t.isScalar() {
+		f, _ := c.scalar(x)
+		return func(e *env) {
+			v := f(e)
+			mem, i := ref(e)
+			mem[i] = checkRange(v, t)
+		}
+	}
+	f := c.aggregate(x, t)
+	return func(e *env) {
+		v := f(e)
+		mem, i := ref(e)
+		copy(mem[i:], v)
+	}
+}
+
+func (c *compiler) symbolRef(sym *symbol) func(*env) ([]int32, int) {
+	off := sym.offset
+	switch sym.class {
+	case globalStorage:
+		return func(e *env) ([]int32, int) { return e.vars, off }
+	case localStorage:
+		return func(e *env) ([]int32, int) { return e.vars, e.base + off }
+	case frameStorage:
+		return func(e *env) ([]int32, int) { return e.frame, off }
+	}
+	fail("channels can not be initialized")
+	return nil
+}
+
+// effect compiles an expression that gets evaluated only for its side
+// effects, for example an update or an expression statement.
+func (c *compiler) effect(x expr) func(*env) {
+	switch x := x.(type) {
+	case *callExpr:
+		call, _ := c.call(x)
+		return call
+	case *assignExpr:
+		_, t := c.lvalue(x.lhs)
+		if t.isScalar() {
+			break
+		}
+		if x.op != "=" && x.op != ":=" {
+			fail("unsupported assignment operator %s for type %v", x.op, t)
+		}
+		ref, _ := c.lvalue(x.lhs)
+		rhs := c.aggregate(x.rhs, t)
+		size := t.size()
+		return func(e *env) {
+			v := rhs(e)
+			mem, i := ref(e)
+			copy(mem[i:i+size], v)
+		}
+	}
+	f, _ := c.scalar(x)
+	return func(e *env) { f(e) }
+}
+
+// Statements
+
+// stmt compiles a statement into a closure that returns whether the
+// statement returned from the enclosing function.
+func (c *compiler) stmt(s stmt) func(*env) bool {
+	switch s := s.(type) {
+	case *emptyStmt:
+		return func(*env) bool { return false }
+	case *exprStmt:
+		f := c.effect(s.x)
+		return func(e *env) bool {
+			f(e)
+			return false
+		}
+	case *blockStmt:
+		inner := c.withScope(newScope(c.sc))
+		stmts := make([]func(*env) bool, len(s.stmts))
+		for i, st := range s.stmts {
+			stmts[i] = inner.stmt(st)
+		}
+		return func(e *env) bool {
+			for _, st := range stmts {
+				if st(e) {
+					return true
+				}
+			}
+			return false
+		}
+	case *declStmt:
+		var inits []func(*env)
+		for _, decl := range s.decls {
+			sym := c.m.declareVar(decl, c.sc, frameStorage, frameStorage)
+			if decl.init != nil {
+				inits = append(inits, c.initializer(sym, decl.init))
+				continue
+			}
+			off, size := sym.offset, sym.typ.size()
+			inits = append(inits, func(e *env) {
+				for i := off; i < off+size; i++ {
+					e.frame[i] = 0
+				}
+			})
+		}
+		return func(e *env) bool {
+			for _, init := range inits {
+				init(e)
+			}
+			return false
+		}
+	case *ifStmt:
+		cond, _ := c.scalar(s.cond)
+		then := c.stmt(s.then)
+		otherwise := func(*env) bool { return false }
+		if s.otherwise != nil {
+			otherwise = c.stmt(s.otherwise)
+		}
+		return func(e *env) bool {
+			if cond(e) != 0 {
+				return then(e)
+			}
+			return otherwise(e)
+		}
+	case *forStmt:
+		init := func(*env) {}
+		if s.init != nil {
+			init = c.effect(s.init)
+		}
+		cond := func(*env) int32 { return 1 }
+		if s.cond != nil {
+			cond, _ = c.scalar(s.cond)
+		}
+		post := func(*env) {}
+		if s.post != nil {
+			post = c.effect(s.post)
+		}
+		body := c.stmt(s.body)
+		return func(e *env) bool {
+			for init(e); cond(e) != 0; post(e) {
+				if body(e) {
+					return true
+				}
+			}
+			return false
+		}
+	case *forRangeStmt:
+		inner := c.withScope(newScope(c.sc))
+		sym := c.m.declareVar(&varDecl{typ: s.typ, name: s.name}, inner.sc, frameStorage, frameStorage)
+		if sym.typ.kind != intKind {
+			fail("unsupported range type: %v", sym.typ)
+		}
+		off, lo, hi := sym.offset, sym.typ.lo, sym.typ.hi
+		body := inner.stmt(s.body)
+		return func(e *env) bool {
+			for i := lo; i <= hi; i++ {
+				e.frame[off] = i
+				if body(e) {
+					return true
+				}
+			}
+			return false
+		}
+	case *whileStmt:
+		cond, _ := c.scalar(s.cond)
+		body := c.stmt(s.body)
+		doLoop := s.doLoop
+		return func(e *env) bool {
+			if doLoop && body(e) {
+				return true
+			}
+			for cond(e) != 0 {
+				if body(e) {
+					return true
+				}
+			}
+			return false
+		}
+	case *returnStmt:
+		if c.fn == nil {
+			fail("return outside of function")
+		}
+		t := c.fn.result
+		switch {
+		case s.x == nil:
+			return func(*env) bool { return true }
+		case t.isScalar():
+			f, _ := c.scalar(s.x)
+			return func(e *env) bool {
+				e.retInt = checkRange(f(e), t)
+				return true
+			}
+		default:
+			f := c.aggregate(s.x, t)
+			return func(e *env) bool {
+				e.ret = f(e)
+				return true
+			}
+		}
+	}
+	fail("unsupported statement")
+	return nil
+}
@@ -0,0 +1,213 @@
+package checker
+
+// candidate is an enabled edge of a process instance, together with the
+// values of its select variables.
+type candidate struct {
+	edge  *edge
+	frame []int32
+}
+
+// step describes how a state was reached from its parent: either through an
+// internal transition (b.edge == nil) or through a synchronization where a is
+// the sender and b the receiver.
+type step struct {
+	a, b candidate
+}
+
+// storedStep is the compact representation of a step, referring to edges by
+// id (-1 for none). The values of select variables are only stored for
+// steps with select variables.
+type storedStep struct {
+	a, b int32
+}
+
+type explorer struct {
+	m *model
+
+	states  *stateStore
+	parents []int32
+	steps   []storedStep
+	frames  map[int32][2][]int32
+
+	buf []byte
+
+	// current is the edge currently being evaluated, for error messages.
+	current *edge
+}
+
+func newExplorer(m *model) *explorer {
+	x := new(explorer)
+	x.m = m
+	x.states = newStateStore()
+	x.frames = make(map[int32][2][]int32)
+	return x
+}
+
+// add adds the state to the explored states, if it is new, and returns its id
+// and whether it was new.
+func (x *explorer) add(vars []int32, parent int32, s step) (int32, bool) {
+	x.buf = encodeState(x.buf[:0], vars)
+	id, ok := x.states.add(x.buf)
+	if !ok {
+		return id, false
+	}
+	stored := storedStep{a: -1, b: -1}
+	if s.a.edge != nil {
+		stored.a = s.a.edge.id
+	}
+	if s.b.edge != nil {
+		stored.b = s.b.edge.id
+	}
+	if s.a.frame != nil || s.b.frame != nil {
+		x.frames[id] = [2][]int32{s.a.frame, s.b.frame}
+	}
+	x.parents = append(x.parents, parent)
+	x.steps = append(x.steps, stored)
+	return id, true
+}
+
+// get stores the state with the given id in vars.
+func (x *explorer) get(id int32, vars []int32) {
+	decodeState(vars, x.states.get(id))
+}
+
+// successors returns all steps possible in the given state, following
+// Uppaal's semantics for binary channels and committed locations.
+func (x *explorer) successors(vars []int32) []step {
+	e := &env{vars: vars}
+	committed := false
+	for _, inst := range x.m.insts {
+		if inst.proc.locations[vars[inst.locSlot]].committed {
+			committed = true
+			break
+		}
+	}
+
+	type syncCandidate struct {
+		candidate
+		ch int
+	}
+	var internal []candidate
+	var senders, receivers []syncCandidate
+	for _, inst := range x.m.insts {
+		loc := inst.proc.locations[vars[inst.locSlot]]
+		e.base = inst.base
+		e.chanBase = inst.chanBase
+		for _, ed := range loc.edges {
+			x.current = ed
+			forEachSelection(ed, func(frame []int32) {
+				e.frame = frame
+				if ed.guard != nil && ed.guard(e) == 0 {
+					return
+				}
+				c := candidate{edge: ed}
+				if len(ed.selects) > 0 {
+					c.frame = append([]int32(nil), frame...)
+				}
+				if ed.sync == nil {
+					if !committed || loc.committed {
+						internal = append(internal, c)
+					}
+					return
+				}
+				sc := syncCandidate{candidate: c, ch: ed.sync(e)}
+				if ed.send {
+					senders = append(senders, sc)
+				} else {
+					receivers = append(receivers, sc)
+				}
+			})
+		}
+	}
+
+	var steps []step
+	for _, c := range internal {
+		steps = append(steps, step{a: c})
+	}
+	for _, s := range senders {
+		for _, r := range receivers {
+			if s.ch != r.ch || s.edge.inst == r.edge.inst {
+				continue
+			}
+			if committed && !x.isCommitted(vars, s.edge.inst) && !x.isCommitted(vars, r.edge.inst) {
+				continue
+			}
+			steps = append(steps, step{a: s.candidate, b: r.candidate})
+		}
+	}
+	return steps
+}
+
+func (x *explorer) isCommitted(vars []int32, i int) bool {
+	inst := x.m.insts[i]
+	return inst.proc.locations[vars[inst.locSlot]].committed
+}
+
+// forEachSelection calls f with a frame for every combination of values of
+// the select variables of the edge.
+func forEachSelection(ed *edge, f func(frame []int32)) {
+	frame := make([]int32, ed.frameSize)
+	if len(ed.selects) == 0 {
+		f(frame)
+		return
+	}
+	var rec func(i int)
+	rec = func(i int) {
+		if i == len(ed.selects) {
+			f(frame)
+			return
+		}
+		sel := ed.selects[i]
+		for v := sel.lo; v <= sel.hi; v++ {
+			frame[sel.offset] = v
+			rec(i + 1)
+		}
+	}
+	rec(0)
+}
+
+// apply executes the updates of the sender (or internal transition) and the
+// receiver (if any) of the step in that order and stores the resulting state
+// in next.
+func (x *explorer) apply(vars, next []int32, s step) {
+	copy(next, vars)
+	e := &env{vars: next}
+	for _, c := range [2]candidate{s.a, s.b} {
+		if c.edge == nil {
+			continue
+		}
+		inst := x.m.insts[c.edge.inst]
+		e.base = inst.base
+		e.chanBase = inst.chanBase
+		e.frame = c.frame
+		if e.frame == nil {
+			e.frame = make([]int32, c.edge.frameSize)
+		}
+		x.current = c.edge
+		if c.edge.update != nil {
+			c.edge.update(e)
+		}
+		next[inst.locSlot] = int32(c.edge.dst)
+	}
+}
+
+// trace returns the steps leading from the initial state to the given state.
+func (x *explorer) trace(id int32) []step {
+	var steps []step
+	for ; id > 0; id = x.parents[id] {
+		stored := x.steps[id]
+		frames := x.frames[id]
+		var s step
+		if stored.a >= 0 {
+			s.a = candidate{edge: x.m.edges[stored.a], frame: frames[0]}
+		}
+		if stored.b >= 0 {
+			s.b = candidate{edge: x.m.edges[stored.b], frame: frames[1]}
+		}
+		steps = append(steps, s)
+	}
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return steps
+}
@@ -0,0 +1,537 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arneph/toph/uppaal"
+)
+
+type storageClass int
+
+const (
+	globalStorage storageClass = iota
+	localStorage
+	frameStorage
+	globalChanStorage
+	localChanStorage
+)
+
+type symbolKind int
+
+const (
+	varSymbol symbolKind = iota
+	funcSymbol
+	typeSymbol
+)
+
+type symbol struct {
+	kind symbolKind
+	typ  *typ
+
+	// varSymbol
+	class  storageClass
+	offset int
+
+	// funcSymbol
+	fn *function
+}
+
+type scope struct {
+	parent  *scope
+	symbols map[string]*symbol
+
+	// frameSize points to the frame size counter of the enclosing function
+	// or transition, if any.
+	frameSize *int
+}
+
+func newScope(parent *scope) *scope {
+	s := new(scope)
+	s.parent = parent
+	s.symbols = make(map[string]*symbol)
+	if parent != nil {
+		s.frameSize = parent.frameSize
+	}
+	return s
+}
+
+func (s *scope) lookup(name string) *symbol {
+	for ; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+type function struct {
+	name      string
+	result    *typ
+	params    []*symbol
+	frameSize int
+	body      func(*env) bool
+}
+
+type location struct {
+	name      string
+	committed bool
+	edges     []*edge
+}
+
+type selectVar struct {
+	offset int
+	lo, hi int32
+}
+
+type edge struct {
+	id        int32
+	inst      int
+	trans     *uppaal.Trans
+	dst       int
+	selects   []selectVar
+	frameSize int
+	guard     func(*env) int32
+	sync      func(*env) int
+	send      bool
+	update    func(*env)
+}
+
+type process struct {
+	proc      *uppaal.Process
+	scope     *scope
+	decls     *declarations
+	params    []*varDecl
+	locations []*location
+	locLookup map[string]int
+	initial   int
+}
+
+type instance struct {
+	name     string
+	inst     *uppaal.ProcessInstance
+	proc     *process
+	locSlot  int
+	base     int
+	chanBase int
+}
+
+// model is the executable form of a uppaal.System.
+type model struct {
+	sys       *uppaal.System
+	globals   *scope
+	typeNames map[string]bool
+	procs     map[string]*process
+	insts     []*instance
+	instNames map[string]*instance
+	edges     []*edge
+
+	// initEnv holds the initial variable values while the model is being
+	// built.
+	initEnv   *env
+	chanCount int
+}
+
+func newModel(sys *uppaal.System) (m *model, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(modelError)
+			if !ok {
+				panic(r)
+			}
+			m, err = nil, re
+		}
+	}()
+
+	m = new(model)
+	m.sys = sys
+	m.globals = newScope(nil)
+	m.typeNames = make(map[string]bool)
+	m.procs = make(map[string]*process)
+	m.instNames = make(map[string]*instance)
+
+	p, err := newParser(sys.Declarations().AsXTA(), m.typeNames)
+	if err != nil {
+		return nil, fmt.Errorf("global declarations: %v", err)
+	}
+	globalDecls, err := p.parseDeclarations()
+	if err != nil {
+		return nil, fmt.Errorf("global declarations: %v", err)
+	}
+	m.initEnv = &env{}
+	m.declare(globalDecls, m.globals, globalStorage, globalChanStorage)
+
+	procs := sys.Processes()
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].Name() < procs[j].Name()
+	})
+	for _, proc := range procs {
+		if err := m.addProcess(proc); err != nil {
+			return nil, fmt.Errorf("process %s: %v", proc.Name(), err)
+		}
+	}
+
+	insts := sys.ProcessInstances()
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].Name() < insts[j].Name()
+	})
+	for _, inst := range insts {
+		if err := m.addInstance(inst); err != nil {
+			return nil, fmt.Errorf("instance %s: %v", inst.Name(), err)
+		}
+	}
+	return m, nil
+}
+
+// declare adds the given declarations to the scope, allocates storage for
+// all variables and initializes them in e.vars.
+func (m *model) declare(decls *declarations, sc *scope, class, chanClass storageClass) {
+	for _, decl := range decls.types {
+		t := m.resolveType(decl.typ, decl.dims, sc)
+		sc.symbols[decl.name] = &symbol{kind: typeSymbol, typ: t}
+	}
+	for _, fn := range decls.funcs {
+		m.declareFunc(fn, sc)
+	}
+	for _, decl := range decls.vars {
+		m.declareVar(decl, sc, class, chanClass)
+	}
+	for _, fn := range decls.funcs {
+		m.compileFunc(fn, sc)
+	}
+}
+
+func (m *model) declareVar(decl *varDecl, sc *scope, class, chanClass storageClass) *symbol {
+	t := m.resolveType(decl.typ, decl.dims, sc)
+	sym := &symbol{kind: varSymbol, typ: t}
+	switch {
+	case t.containsChans():
+		sym.class = chanClass
+		switch chanClass {
+		case globalChanStorage:
+			sym.offset = m.chanCount
+		case localChanStorage:
+			sym.offset = m.initEnv.chanBase
+		default:
+			fail("channel %s can not be declared here", decl.name)
+		}
+		m.chanCount += t.size()
+		m.initEnv.chanBase += t.size()
+		if t.kind == chanKind && t.broadcast ||
+			t.kind == arrayKind && elemType(t).broadcast {
+			fail("broadcast channel %s is not supported", decl.name)
+		}
+	case t.kind == clockKind || t.kind == arrayKind && elemType(t).kind == clockKind:
+		fail("clock %s is not supported", decl.name)
+	default:
+		sym.class = class
+		switch class {
+		case globalStorage:
+			sym.offset = len(m.initEnv.vars)
+		case localStorage:
+			sym.offset = len(m.initEnv.vars) - m.initEnv.base
+		case frameStorage:
+			sym.offset = *sc.frameSize
+			*sc.frameSize += t.size()
+		}
+		if class != frameStorage {
+			m.initEnv.vars = append(m.initEnv.vars, make([]int32, t.size())...)
+		}
+	}
+	sc.symbols[decl.name] = sym
+	if class != frameStorage && decl.init != nil {
+		c := &compiler{m: m, sc: sc}
+		c.initializer(sym, decl.init)(m.initEnv)
+	}
+	return sym
+}
+
+func elemType(t *typ) *typ {
+	for t.kind == arrayKind {
+		t = t.elem
+	}
+	return t
+}
+
+func (m *model) resolveType(texpr *typeExpr, dims []expr, sc *scope) *typ {
+	var t *typ
+	switch texpr.base {
+	case "int":
+		if texpr.lo == nil {
+			t = defaultIntType
+		} else {
+			lo := m.constValue(texpr.lo, sc)
+			hi := m.constValue(texpr.hi, sc)
+			t = &typ{kind: intKind, lo: lo, hi: hi}
+		}
+	case "bool":
+		t = boolType
+	case "chan":
+		t = &typ{kind: chanKind, broadcast: texpr.prefixes["broadcast"]}
+	case "clock":
+		t = &typ{kind: clockKind}
+	case "void":
+		t = voidType
+	case "struct":
+		t = &typ{kind: structKind}
+		offset := 0
+		for _, f := range texpr.fields {
+			ft := m.resolveType(f.typ, f.dims, sc)
+			t.fields = append(t.fields, field{name: f.name, typ: ft, offset: offset})
+			offset += ft.size()
+		}
+	default:
+		sym := sc.lookup(texpr.base)
+		if sym == nil || sym.kind != typeSymbol {
+			fail("unknown type: %s", texpr.base)
+		}
+		t = sym.typ
+	}
+	if len(dims) == 0 {
+		return t
+	}
+	lengths := make([]int, len(dims))
+	for i, dim := range dims {
+		lengths[i] = int(m.constValue(dim, sc))
+		if lengths[i] < 0 {
+			fail("negative array size: %d", lengths[i])
+		}
+	}
+	return arrayOf(t, lengths)
+}
+
+func (m *model) constValue(x expr, sc *scope) int32 {
+	c := &compiler{m: m, sc: sc}
+	f, _ := c.scalar(x)
+	return f(m.initEnv)
+}
+
+func (m *model) declareFunc(decl *funcDecl, sc *scope) {
+	fn := &function{name: decl.name}
+	fn.result = m.resolveType(decl.result, nil, sc)
+	sc.symbols[decl.name] = &symbol{kind: funcSymbol, typ: fn.result, fn: fn}
+}
+
+func (m *model) compileFunc(decl *funcDecl, sc *scope) {
+	fn := sc.symbols[decl.name].fn
+	fnScope := newScope(sc)
+	fnScope.frameSize = &fn.frameSize
+	for _, param := range decl.params {
+		if param.ref {
+			fail("reference parameter %s of %s is not supported", param.name, decl.name)
+		}
+		sym := m.declareVar(param, fnScope, frameStorage, frameStorage)
+		fn.params = append(fn.params, sym)
+	}
+	c := &compiler{m: m, sc: fnScope, fn: fn}
+	fn.body = c.stmt(decl.body)
+}
+
+func (m *model) addProcess(proc *uppaal.Process) error {
+	p := &process{proc: proc}
+	p.locLookup = make(map[string]int)
+
+	parser, err := newParser(strings.Join(proc.Parameters(), ", "), m.typeNames)
+	if err != nil {
+		return err
+	}
+	p.params, err = parser.parseParams()
+	if err != nil {
+		return fmt.Errorf("parameters: %v", err)
+	}
+	// Local type names must not leak into other processes.
+	typeNames := make(map[string]bool)
+	for name := range m.typeNames {
+		typeNames[name] = true
+	}
+	parser, err = newParser(proc.Declarations().AsXTA(), typeNames)
+	if err != nil {
+		return err
+	}
+	p.decls, err = parser.parseDeclarations()
+	if err != nil {
+		return fmt.Errorf("declarations: %v", err)
+	}
+
+	states := proc.States()
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name() < states[j].Name()
+	})
+	for i, state := range states {
+		if state.Invariant() != "" {
+			return fmt.Errorf("invariant of state %s is not supported", state.Name())
+		}
+		p.locations = append(p.locations, &location{
+			name:      state.Name(),
+			committed: state.Type() == uppaal.Committed,
+		})
+		p.locLookup[state.Name()] = i
+	}
+	if proc.InitialState() == nil {
+		return fmt.Errorf("no initial state")
+	}
+	p.initial = p.locLookup[proc.InitialState().Name()]
+	m.procs[proc.Name()] = p
+	return nil
+}
+
+// addInstance allocates the location and local variables of the instance and
+// compiles the process for it. Processes get compiled per instance, since
+// local declarations (for example array sizes) can depend on parameters.
+func (m *model) addInstance(inst *uppaal.ProcessInstance) error {
+	e := m.initEnv
+	p := m.procs[inst.Process().Name()]
+	if len(inst.Parameters()) != len(p.params) {
+		return fmt.Errorf("expected %d parameters, got %d", len(p.params), len(inst.Parameters()))
+	}
+	args := make([]int32, len(p.params))
+	for i, param := range inst.Parameters() {
+		parser, err := newParser(param, m.typeNames)
+		if err != nil {
+			return err
+		}
+		x, err := parser.parseSingleExpr()
+		if err != nil {
+			return err
+		}
+		args[i] = m.constValue(x, m.globals)
+	}
+
+	in := &instance{name: inst.Name(), inst: inst}
+	in.locSlot = len(e.vars)
+	e.vars = append(e.vars, 0)
+	in.base = len(e.vars)
+	in.chanBase = m.chanCount
+	e.base = in.base
+	e.chanBase = 0
+
+	sc := newScope(m.globals)
+	for i, param := range p.params {
+		sym := m.declareVar(param, sc, localStorage, localChanStorage)
+		if !sym.typ.isScalar() {
+			return fmt.Errorf("parameter %s has unsupported type %v", param.name, sym.typ)
+		}
+		e.vars[in.base+sym.offset] = checkRange(args[i], sym.typ)
+	}
+	m.declare(p.decls, sc, localStorage, localChanStorage)
+
+	instProc := &process{
+		proc:      p.proc,
+		scope:     sc,
+		locLookup: p.locLookup,
+		initial:   p.initial,
+	}
+	for _, loc := range p.locations {
+		instProc.locations = append(instProc.locations, &location{
+			name:      loc.name,
+			committed: loc.committed,
+		})
+	}
+	for _, state := range p.proc.States() {
+		src := instProc.locations[p.locLookup[state.Name()]]
+		for _, trans := range state.OutgoingTransitions() {
+			ed, err := m.compileEdge(trans, instProc)
+			if err != nil {
+				return fmt.Errorf("transition %s -> %s: %v", trans.Start().Name(), trans.End().Name(), err)
+			}
+			src.edges = append(src.edges, ed)
+		}
+	}
+	for _, loc := range instProc.locations {
+		sort.SliceStable(loc.edges, func(i, j int) bool {
+			return loc.edges[i].trans.End().Name() < loc.edges[j].trans.End().Name()
+		})
+		for _, ed := range loc.edges {
+			ed.id = int32(len(m.edges))
+			ed.inst = len(m.insts)
+			m.edges = append(m.edges, ed)
+		}
+	}
+	e.vars[in.locSlot] = int32(p.initial)
+	in.proc = instProc
+	m.insts = append(m.insts, in)
+	m.instNames[in.name] = in
+	return nil
+}
+
+func (m *model) compileEdge(trans *uppaal.Trans, p *process) (*edge, error) {
+	ed := &edge{trans: trans, dst: p.locLookup[trans.End().Name()]}
+	sc := newScope(p.scope)
+	sc.frameSize = &ed.frameSize
+
+	if s := trans.Select(); s != "" {
+		parser, err := newParser(s, m.typeNames)
+		if err != nil {
+			return nil, err
+		}
+		selects, err := parser.parseSelects()
+		if err != nil {
+			return nil, fmt.Errorf("select: %v", err)
+		}
+		for _, sel := range selects {
+			sym := m.declareVar(sel, sc, frameStorage, frameStorage)
+			if sym.typ.kind != intKind {
+				return nil, fmt.Errorf("select %s has unsupported type %v", sel.name, sym.typ)
+			}
+			ed.selects = append(ed.selects, selectVar{offset: sym.offset, lo: sym.typ.lo, hi: sym.typ.hi})
+		}
+	}
+	c := &compiler{m: m, sc: sc}
+	if g := trans.Guard(); g != "" {
+		parser, err := newParser(g, m.typeNames)
+		if err != nil {
+			return nil, err
+		}
+		x, err := parser.parseSingleExpr()
+		if err != nil {
+			return nil, fmt.Errorf("guard: %v", err)
+		}
+		ed.guard, _ = c.scalar(x)
+	}
+	if s := trans.Sync(); s != "" {
+		parser, err := newParser(s, m.typeNames)
+		if err != nil {
+			return nil, err
+		}
+		ch, dir, err := parser.parseSync()
+		if err != nil {
+			return nil, fmt.Errorf("sync: %v", err)
+		}
+		ed.sync = c.channel(ch)
+		ed.send = dir == "!"
+	}
+	if u := trans.Update(); u != "" {
+		parser, err := newParser(u, m.typeNames)
+		if err != nil {
+			return nil, err
+		}
+		xs, err := parser.parseExprList()
+		if err != nil {
+			return nil, fmt.Errorf("update: %v", err)
+		}
+		var fs []func(*env)
+		for _, x := range xs {
+			fs = append(fs, c.effect(x))
+		}
+		ed.update = func(e *env) {
+			for _, f := range fs {
+				f(e)
+			}
+		}
+	}
+	return ed, nil
+}
+
+func checkRange(v int32, t *typ) int32 {
+	if t.kind == boolKind {
+		if v != 0 {
+			return 1
+		}
+		return 0
+	}
+	if v < t.lo || v > t.hi {
+		fail("value %d out of range %v", v, t)
+	}
+	return v
+}
@@ -0,0 +1,578 @@
+package checker
+
+import (
+	"fmt"
+)
+
+type parser struct {
+	tokens    []token
+	pos       int
+	typeNames map[string]bool
+}
+
+func newParser(src string, typeNames map[string]bool) (*parser, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := new(parser)
+	p.tokens = tokens
+	p.typeNames = typeNames
+	return p, nil
+}
+
+type parseError struct {
+	msg string
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	panic(parseError{fmt.Sprintf("line %d: %s", p.peek().line, msg)})
+}
+
+func (p *parser) recover(err *error) {
+	if r := recover(); r != nil {
+		pe, ok := r.(parseError)
+		if !ok {
+			panic(r)
+		}
+		*err = fmt.Errorf("%s", pe.msg)
+	}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return t.kind != tokenEOF && t.kind != tokenInt && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) {
+	if !p.accept(text) {
+		p.fail("expected %q, found %s", text, p.peek())
+	}
+}
+
+func (p *parser) expectIdent() string {
+	t := p.next()
+	if t.kind != tokenIdent {
+		p.fail("expected identifier, found %s", t)
+	}
+	return t.text
+}
+
+func (p *parser) atEOF() bool {
+	return p.peek().kind == tokenEOF
+}
+
+// Declarations
+
+var typePrefixes = map[string]bool{
+	"const":     true,
+	"urgent":    true,
+	"broadcast": true,
+	"meta":      true,
+}
+
+var baseTypes = map[string]bool{
+	"int":    true,
+	"bool":   true,
+	"chan":   true,
+	"clock":  true,
+	"void":   true,
+	"struct": true,
+}
+
+func (p *parser) isTypeStart() bool {
+	t := p.peek()
+	if t.kind != tokenIdent {
+		return false
+	}
+	return typePrefixes[t.text] || baseTypes[t.text] || p.typeNames[t.text]
+}
+
+func (p *parser) parseType() *typeExpr {
+	typ := &typeExpr{prefixes: make(map[string]bool)}
+	for typePrefixes[p.peek().text] && p.peek().kind == tokenIdent {
+		typ.prefixes[p.next().text] = true
+	}
+	name := p.expectIdent()
+	if !baseTypes[name] && !p.typeNames[name] {
+		p.fail("unknown type: %s", name)
+	}
+	typ.base = name
+	switch name {
+	case "int":
+		if p.accept("[") {
+			typ.lo = p.parseExpr()
+			p.expect(",")
+			typ.hi = p.parseExpr()
+			p.expect("]")
+		}
+	case "struct":
+		p.expect("{")
+		for !p.accept("}") {
+			fieldType := p.parseType()
+			for {
+				field := &varDecl{typ: fieldType}
+				field.name = p.expectIdent()
+				field.dims = p.parseDims()
+				typ.fields = append(typ.fields, field)
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(";")
+		}
+	}
+	return typ
+}
+
+func (p *parser) parseDims() []expr {
+	var dims []expr
+	for p.accept("[") {
+		dims = append(dims, p.parseExpr())
+		p.expect("]")
+	}
+	return dims
+}
+
+func (p *parser) parseDeclarations() (decls *declarations, err error) {
+	defer p.recover(&err)
+	decls = new(declarations)
+	for !p.atEOF() {
+		if p.accept(";") {
+			continue
+		}
+		if p.accept("typedef") {
+			typ := p.parseType()
+			for {
+				decl := &typeDecl{typ: typ}
+				decl.name = p.expectIdent()
+				decl.dims = p.parseDims()
+				p.typeNames[decl.name] = true
+				decls.types = append(decls.types, decl)
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(";")
+			continue
+		}
+		typ := p.parseType()
+		if p.peek().kind == tokenIdent && p.peekAt(1).text == "(" {
+			decls.funcs = append(decls.funcs, p.parseFunc(typ))
+			continue
+		}
+		decls.vars = append(decls.vars, p.parseVarDecls(typ)...)
+	}
+	return decls, nil
+}
+
+func (p *parser) parseVarDecls(typ *typeExpr) []*varDecl {
+	var vars []*varDecl
+	for {
+		decl := &varDecl{typ: typ}
+		decl.name = p.expectIdent()
+		decl.dims = p.parseDims()
+		if p.accept("=") || p.accept(":=") {
+			decl.init = p.parseInitializer()
+		}
+		vars = append(vars, decl)
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+	return vars
+}
+
+func (p *parser) parseInitializer() expr {
+	if !p.accept("{") {
+		return p.parseAssignExpr()
+	}
+	list := new(initListExpr)
+	for !p.accept("}") {
+		list.elems = append(list.elems, p.parseInitializer())
+		if !p.accept(",") {
+			p.expect("}")
+			break
+		}
+	}
+	return list
+}
+
+func (p *parser) parseFunc(result *typeExpr) *funcDecl {
+	f := &funcDecl{result: result}
+	f.name = p.expectIdent()
+	p.expect("(")
+	for !p.accept(")") {
+		f.params = append(f.params, p.parseParam())
+		if !p.accept(",") {
+			p.expect(")")
+			break
+		}
+	}
+	f.body = p.parseBlock()
+	return f
+}
+
+func (p *parser) parseParam() *varDecl {
+	param := &varDecl{typ: p.parseType()}
+	param.ref = p.accept("&")
+	param.name = p.expectIdent()
+	param.dims = p.parseDims()
+	return param
+}
+
+// parseParams parses process parameters, for example: "int[0, 9] pid".
+func (p *parser) parseParams() (params []*varDecl, err error) {
+	defer p.recover(&err)
+	for !p.atEOF() {
+		params = append(params, p.parseParam())
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.atEOF() {
+		p.fail("unexpected %s after parameters", p.peek())
+	}
+	return params, nil
+}
+
+// Statements
+
+func (p *parser) parseBlock() *blockStmt {
+	p.expect("{")
+	block := new(blockStmt)
+	for !p.accept("}") {
+		if p.atEOF() {
+			p.fail("unexpected end of input in block")
+		}
+		block.stmts = append(block.stmts, p.parseStmt())
+	}
+	return block
+}
+
+func (p *parser) parseStmt() stmt {
+	switch {
+	case p.is("{"):
+		return p.parseBlock()
+	case p.accept(";"):
+		return &emptyStmt{}
+	case p.accept("if"):
+		s := new(ifStmt)
+		p.expect("(")
+		s.cond = p.parseExpr()
+		p.expect(")")
+		s.then = p.parseStmt()
+		if p.accept("else") {
+			s.otherwise = p.parseStmt()
+		}
+		return s
+	case p.accept("for"):
+		p.expect("(")
+		if p.peek().kind == tokenIdent && p.peekAt(1).text == ":" {
+			s := new(forRangeStmt)
+			s.name = p.expectIdent()
+			p.expect(":")
+			s.typ = p.parseType()
+			p.expect(")")
+			s.body = p.parseStmt()
+			return s
+		}
+		s := new(forStmt)
+		if !p.is(";") {
+			s.init = p.parseExpr()
+		}
+		p.expect(";")
+		if !p.is(";") {
+			s.cond = p.parseExpr()
+		}
+		p.expect(";")
+		if !p.is(")") {
+			s.post = p.parseExpr()
+		}
+		p.expect(")")
+		s.body = p.parseStmt()
+		return s
+	case p.accept("while"):
+		s := new(whileStmt)
+		p.expect("(")
+		s.cond = p.parseExpr()
+		p.expect(")")
+		s.body = p.parseStmt()
+		return s
+	case p.accept("do"):
+		s := &whileStmt{doLoop: true}
+		s.body = p.parseStmt()
+		p.expect("while")
+		p.expect("(")
+		s.cond = p.parseExpr()
+		p.expect(")")
+		p.expect(";")
+		return s
+	case p.accept("return"):
+		s := new(returnStmt)
+		if !p.is(";") {
+			s.x = p.parseExpr()
+		}
+		p.expect(";")
+		return s
+	case p.isTypeStart():
+		return &declStmt{decls: p.parseVarDecls(p.parseType())}
+	default:
+		s := &exprStmt{x: p.parseExpr()}
+		p.expect(";")
+		return s
+	}
+}
+
+// Expressions
+
+// parseExprList parses a comma separated list of expressions, as found in
+// updates of transitions.
+func (p *parser) parseExprList() (exprs []expr, err error) {
+	defer p.recover(&err)
+	for !p.atEOF() {
+		exprs = append(exprs, p.parseAssignExpr())
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.atEOF() {
+		p.fail("unexpected %s after expression", p.peek())
+	}
+	return exprs, nil
+}
+
+// parseSingleExpr parses a complete input as one expression.
+func (p *parser) parseSingleExpr() (x expr, err error) {
+	defer p.recover(&err)
+	x = p.parseExpr()
+	if !p.atEOF() {
+		p.fail("unexpected %s after expression", p.peek())
+	}
+	return x, nil
+}
+
+// parseSelects parses the select clause of a transition, for example:
+// "r0 : int[0, 5], r1 : int[0, 2]".
+func (p *parser) parseSelects() (selects []*varDecl, err error) {
+	defer p.recover(&err)
+	for !p.atEOF() {
+		decl := new(varDecl)
+		decl.name = p.expectIdent()
+		p.expect(":")
+		decl.typ = p.parseType()
+		selects = append(selects, decl)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.atEOF() {
+		p.fail("unexpected %s after select", p.peek())
+	}
+	return selects, nil
+}
+
+// parseSync parses the synchronization label of a transition and returns the
+// channel expression and the direction ("!" or "?").
+func (p *parser) parseSync() (ch expr, dir string, err error) {
+	defer p.recover(&err)
+	ch = p.parsePostfixExpr()
+	if p.accept("!") {
+		dir = "!"
+	} else {
+		p.expect("?")
+		dir = "?"
+	}
+	if !p.atEOF() {
+		p.fail("unexpected %s after sync", p.peek())
+	}
+	return ch, dir, nil
+}
+
+func (p *parser) parseExpr() expr {
+	return p.parseAssignExpr()
+}
+
+var assignOps = map[string]bool{
+	"=": true, ":=": true, "+=": true, "-=": true, "*=": true, "/=": true,
+	"%=": true, "&=": true, "|=": true, "^=": true, "<<=": true, ">>=": true,
+}
+
+func (p *parser) parseAssignExpr() expr {
+	lhs := p.parseCondExpr()
+	if t := p.peek(); t.kind == tokenOp && assignOps[t.text] {
+		p.next()
+		rhs := p.parseAssignExpr()
+		return &assignExpr{op: t.text, lhs: lhs, rhs: rhs}
+	}
+	return lhs
+}
+
+func (p *parser) parseCondExpr() expr {
+	cond := p.parseBinaryExpr(0)
+	if p.accept("?") {
+		x := p.parseAssignExpr()
+		p.expect(":")
+		y := p.parseCondExpr()
+		return &condExpr{cond: cond, x: x, y: y}
+	}
+	return cond
+}
+
+// binaryOps lists binary operators by increasing precedence.
+var binaryOps = [][]string{
+	{"imply"},
+	{"or", "||"},
+	{"and", "&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinaryExpr(level int) expr {
+	if level == len(binaryOps) {
+		return p.parseUnaryExpr()
+	}
+	if level == 3 && p.accept("not") {
+		// The keyword not binds weaker than comparisons but stronger than
+		// and, or, and imply.
+		return &unaryExpr{op: "!", x: p.parseBinaryExpr(level)}
+	}
+	x := p.parseBinaryExpr(level + 1)
+	for {
+		t := p.peek()
+		if t.kind == tokenInt || t.kind == tokenEOF {
+			return x
+		}
+		found := false
+		for _, op := range binaryOps[level] {
+			if t.text == op {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return x
+		}
+		p.next()
+		op := t.text
+		switch op {
+		case "or":
+			op = "||"
+		case "and":
+			op = "&&"
+		}
+		y := p.parseBinaryExpr(level + 1)
+		x = &binaryExpr{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnaryExpr() expr {
+	t := p.peek()
+	if t.kind == tokenOp {
+		switch t.text {
+		case "!", "-", "+", "~":
+			p.next()
+			return &unaryExpr{op: t.text, x: p.parseUnaryExpr()}
+		case "++", "--":
+			p.next()
+			return &incDecExpr{op: t.text, prefix: true, x: p.parseUnaryExpr()}
+		}
+	} else if t.kind == tokenIdent && t.text == "not" {
+		p.next()
+		return &unaryExpr{op: "!", x: p.parseUnaryExpr()}
+	}
+	return p.parsePostfixExpr()
+}
+
+func (p *parser) parsePostfixExpr() expr {
+	x := p.parsePrimaryExpr()
+	for {
+		switch {
+		case p.accept("["):
+			index := p.parseExpr()
+			p.expect("]")
+			x = &indexExpr{x: x, index: index}
+		case p.accept("."):
+			x = &selectorExpr{x: x, sel: p.expectIdent()}
+		case p.is("++") || p.is("--"):
+			x = &incDecExpr{op: p.next().text, x: x}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) parsePrimaryExpr() expr {
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		return &intLitExpr{val: t.val}
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &boolLitExpr{val: true}
+		case "false":
+			return &boolLitExpr{val: false}
+		case "deadlock":
+			return &deadlockExpr{}
+		}
+		if p.accept("(") {
+			call := &callExpr{fun: t.text}
+			for !p.accept(")") {
+				call.args = append(call.args, p.parseAssignExpr())
+				if !p.accept(",") {
+					p.expect(")")
+					break
+				}
+			}
+			return call
+		}
+		return &identExpr{name: t.text}
+	case tokenOp:
+		if t.text == "(" {
+			x := p.parseExpr()
+			p.expect(")")
+			return x
+		}
+	}
+	if t.kind != tokenEOF {
+		p.pos--
+	}
+	p.fail("unexpected %s in expression", t)
+	return nil
+}
@@ -0,0 +1,125 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// encodeState appends a compact encoding of the state to b. Most values in
+// states are small, so varints take considerably less memory than the plain
+// values.
+func encodeState(b []byte, vars []int32) []byte {
+	var tmp [binary.MaxVarintLen32]byte
+	for _, v := range vars {
+		n := binary.PutVarint(tmp[:], int64(v))
+		b = append(b, tmp[:n]...)
+	}
+	return b
+}
+
+func decodeState(vars []int32, b []byte) {
+	for i := range vars {
+		v, n := binary.Varint(b)
+		vars[i] = int32(v)
+		b = b[n:]
+	}
+}
+
+const chunkSize = 1 << 22
+
+// stateStore is a hash set of encoded states. States are identified by
+// consecutive ids in insertion order and are stored back to back in large
+// chunks to keep the memory overhead per state low.
+type stateStore struct {
+	chunks    [][]byte
+	locations []uint64
+	table     []int32
+	count     int
+}
+
+func newStateStore() *stateStore {
+	s := new(stateStore)
+	s.table = make([]int32, 1<<10)
+	return s
+}
+
+func hashState(b []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= binary.LittleEndian.Uint64(b)
+		h *= 1099511628211
+		h ^= h >> 29
+	}
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h ^ h>>32
+}
+
+// get returns the encoded state with the given id.
+func (s *stateStore) get(id int32) []byte {
+	loc := s.locations[id]
+	chunk := s.chunks[loc>>32]
+	off := int(loc & 0xffffffff)
+	n, k := binary.Uvarint(chunk[off:])
+	return chunk[off+k : off+k+int(n)]
+}
+
+// add adds the encoded state, if it is new, and returns its id and whether
+// it was new.
+func (s *stateStore) add(b []byte) (int32, bool) {
+	mask := uint64(len(s.table) - 1)
+	i := hashState(b) & mask
+	for {
+		entry := s.table[i]
+		if entry == 0 {
+			break
+		}
+		if bytes.Equal(s.get(entry-1), b) {
+			return entry - 1, false
+		}
+		i = (i + 1) & mask
+	}
+
+	var header [binary.MaxVarintLen64]byte
+	k := binary.PutUvarint(header[:], uint64(len(b)))
+	if len(s.chunks) == 0 || len(s.chunks[len(s.chunks)-1])+k+len(b) > chunkSize {
+		size := chunkSize
+		if k+len(b) > size {
+			size = k + len(b)
+		}
+		s.chunks = append(s.chunks, make([]byte, 0, size))
+	}
+	c := len(s.chunks) - 1
+	off := len(s.chunks[c])
+	s.chunks[c] = append(s.chunks[c], header[:k]...)
+	s.chunks[c] = append(s.chunks[c], b...)
+
+	id := int32(s.count)
+	s.count++
+	s.locations = append(s.locations, uint64(c)<<32|uint64(off))
+	s.table[i] = id + 1
+	if 2*s.count > len(s.table) {
+		s.grow()
+	}
+	return id, true
+}
+
+func (s *stateStore) grow() {
+	table := make([]int32, 2*len(s.table))
+	mask := uint64(len(table) - 1)
+	for id := 0; id < s.count; id++ {
+		i := hashState(s.get(int32(id))) & mask
+		for table[i] != 0 {
+			i = (i + 1) & mask
+		}
+		table[i] = int32(id) + 1
+	}
+	s.table = table
+}
+
+// len returns the number of stored states.
+func (s *stateStore) len() int {
+	return s.count
+}
@@ -0,0 +1,136 @@
+package checker
+
+import (
+	"fmt"
+	"math"
+)
+
+type typeKind int
+
+const (
+	intKind typeKind = iota
+	boolKind
+	chanKind
+	clockKind
+	voidKind
+	structKind
+	arrayKind
+)
+
+// Uppaal's default range for int variables.
+const (
+	defaultIntMin = -32768
+	defaultIntMax = 32767
+)
+
+type field struct {
+	name   string
+	typ    *typ
+	offset int
+}
+
+type typ struct {
+	kind typeKind
+
+	// int
+	lo, hi int32
+
+	// chan
+	broadcast bool
+
+	// struct
+	fields []field
+
+	// array
+	elem   *typ
+	length int
+}
+
+var (
+	defaultIntType = &typ{kind: intKind, lo: defaultIntMin, hi: defaultIntMax}
+	wideIntType    = &typ{kind: intKind, lo: math.MinInt32, hi: math.MaxInt32}
+	boolType       = &typ{kind: boolKind, lo: 0, hi: 1}
+	chanType       = &typ{kind: chanKind}
+	voidType       = &typ{kind: voidKind}
+)
+
+func (t *typ) isScalar() bool {
+	return t.kind == intKind || t.kind == boolKind
+}
+
+func (t *typ) isAggregate() bool {
+	return t.kind == structKind || t.kind == arrayKind
+}
+
+// containsChans returns whether values of the type are channels (or arrays
+// of channels).
+func (t *typ) containsChans() bool {
+	switch t.kind {
+	case chanKind:
+		return true
+	case arrayKind:
+		return t.elem.containsChans()
+	default:
+		return false
+	}
+}
+
+// size returns the number of slots a value of the type occupies. Channels
+// are allocated separately and occupy one channel id each.
+func (t *typ) size() int {
+	switch t.kind {
+	case intKind, boolKind, chanKind:
+		return 1
+	case structKind:
+		n := 0
+		for _, f := range t.fields {
+			n += f.typ.size()
+		}
+		return n
+	case arrayKind:
+		return t.length * t.elem.size()
+	default:
+		return 0
+	}
+}
+
+func (t *typ) findField(name string) (field, bool) {
+	for _, f := range t.fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func arrayOf(elem *typ, dims []int) *typ {
+	t := elem
+	for i := len(dims) - 1; i >= 0; i-- {
+		t = &typ{kind: arrayKind, elem: t, length: dims[i]}
+	}
+	return t
+}
+
+func (t *typ) String() string {
+	switch t.kind {
+	case intKind:
+		if t.lo == defaultIntMin && t.hi == defaultIntMax {
+			return "int"
+		}
+		return fmt.Sprintf("int[%d, %d]", t.lo, t.hi)
+	case boolKind:
+		return "bool"
+	case chanKind:
+		return "chan"
+	case clockKind:
+		return "clock"
+	case voidKind:
+		return "void"
+	case structKind:
+		return "struct"
+	case arrayKind:
+		return fmt.Sprintf("%v[%d]", t.elem, t.length)
+	default:
+		return "?"
+	}
+}
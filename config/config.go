@@ -2,6 +2,9 @@ package config
 
 import (
 	"go/build"
+	"regexp"
+	"strings"
+	"time"
 )
 
 // Config holds paramters for the Run function.
@@ -9,32 +12,61 @@ type Config struct {
 	BuildContext *build.Context
 	PackageExcludeInfo
 
+	// SubstitutesFiles lists Go files (or directories of Go files) of a single
+	// package containing user-defined substitutes for functions, methods, and
+	// types that should be modeled instead of the real ones (see
+	// 'toph: substitute=' annotations).
+	SubstitutesFiles []string
+
 	MaxProcessCount   int
 	MaxDeferCount     int
 	MaxChannelCount   int
 	MaxMutexCount     int
 	MaxWaitGroupCount int
 	MaxOnceCount      int
+	MaxCondCount      int
 	MaxStructCount    int
 	MaxContainerCount int
 	ContainerCapacity int
+	// TrackedIntMax is the largest value of tracked ints (see 'toph: track'
+	// annotations) that get assigned unknown values.
+	TrackedIntMax int
 
 	GenerateResourceBoundQueries            bool
 	GenerateIndividualResourceBoundQueries  bool
 	GenerateChannelSafetyQueries            bool
 	GenerateMutexSafetyQueries              bool
 	GenerateWaitGroupSafetyQueries          bool
+	GenerateCondSafetyQueries               bool
 	GenerateChannelRelatedDeadlockQueries   bool
 	GenerateMutexRelatedDeadlockQueries     bool
 	GenerateWaitGroupRelatedDeadlockQueries bool
 	GenerateOnceRelatedDeadlockQueries      bool
+	GenerateCondRelatedDeadlockQueries      bool
 	GenerateFunctionCallsWithNilQueries     bool
+	GenerateCloseOfNilChannelQueries        bool
 	GenerateGoroutineExitWithPanicQueries   bool
+	GenerateGoroutineLeakQueries            bool
+	GenerateDataRaceQueries                 bool
 	GenerateReachabilityQueries             bool
 
 	OptimizeIR           bool
 	OptimizeUppaalSystem bool
 
+	// Timed indicates if time.Sleep, time.After, time.NewTimer, and
+	// context.WithTimeout should be modeled with Uppaal clocks.
+	Timed bool
+	// TimeUnit is the duration of one clock unit in timed mode. Durations
+	// get rounded down to multiples of the unit.
+	TimeUnit time.Duration
+
+	// Check indicates if the generated systems should be verified with the
+	// built-in model checker.
+	Check bool
+	// MaxCheckedStates limits the number of states the built-in model
+	// checker explores per system (no limit if not positive).
+	MaxCheckedStates int
+
 	// Debug indicates if debug output files should be generated.
 	Debug bool
 
@@ -45,19 +77,66 @@ type Config struct {
 }
 
 // PackageExcludeInfo stores which packages and members of packages should be excluded from translation.
-type PackageExcludeInfo map[string]struct{}
+type PackageExcludeInfo struct {
+	packages map[string]struct{}
+	members  []*regexp.Regexp
+}
 
 func (pei *PackageExcludeInfo) ShouldExcludeEntirePackage(packagePath string) bool {
-	if (*pei) == nil {
-		return false
-	}
-	_, ok := (*pei)[packagePath]
+	_, ok := pei.packages[packagePath]
 	return ok
 }
 
 func (pei *PackageExcludeInfo) SetExcludeEntirePackage(packagePath string) {
-	if (*pei) == nil {
-		*pei = make(PackageExcludeInfo)
+	if pei.packages == nil {
+		pei.packages = make(map[string]struct{})
+	}
+	pei.packages[packagePath] = struct{}{}
+}
+
+// ShouldExcludeMember returns if the function, method, or type with the given
+// fully qualified name, for example "pkg.Func", "(*pkg.T).Method", or "pkg.T",
+// should be excluded. The name gets matched as given, so callers need to check
+// the names qualified with the package path and the package name separately.
+func (pei *PackageExcludeInfo) ShouldExcludeMember(name string) bool {
+	for _, member := range pei.members {
+		if member.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetExcludeMembers excludes all functions, methods, and types whose fully
+// qualified names match the given pattern. A '*' in the pattern matches any
+// sequence of characters, except directly after '(' where it denotes a
+// pointer receiver, for example "(*pkg.T).Method" or "pkg.*Debug*".
+func (pei *PackageExcludeInfo) SetExcludeMembers(pattern string) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i, r := range pattern {
+		if r == '*' && (i == 0 || pattern[i-1] != '(') {
+			b.WriteString(".*")
+		} else {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	pei.members = append(pei.members, regexp.MustCompile(b.String()))
+}
+
+// SetExclude excludes the package with the given path or the members matching
+// the given pattern (see SetExcludeMembers). Strings containing '*' or '(' are
+// patterns. Other strings with a '.' in their last path element, for example
+// "pkg.Func", are patterns or package paths like "gopkg.in/yaml.v2" and get
+// used as both. All remaining strings are package paths.
+func (pei *PackageExcludeInfo) SetExclude(pathOrPattern string) {
+	if strings.ContainsAny(pathOrPattern, "*(") {
+		pei.SetExcludeMembers(pathOrPattern)
+		return
+	}
+	pei.SetExcludeEntirePackage(pathOrPattern)
+	if strings.Contains(pathOrPattern[strings.LastIndex(pathOrPattern, "/")+1:], ".") {
+		pei.SetExcludeMembers(pathOrPattern)
 	}
-	(*pei)[packagePath] = struct{}{}
 }
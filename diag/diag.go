@@ -0,0 +1,153 @@
+// Package diag defines the warnings reported while building and translating
+// programs. Warnings carry a kind, the position, and the source snippet they
+// relate to, such that tools can process them without parsing messages.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// Kind classifies warnings.
+type Kind int
+
+const (
+	// General is the kind of warnings that do not belong to a more specific
+	// kind.
+	General Kind = iota
+	// LoadFailure indicates that a package or file could not be loaded or
+	// type checked.
+	LoadFailure
+	// UnsupportedStatement indicates that a statement is not supported and
+	// got ignored.
+	UnsupportedStatement
+	// UnsupportedOperation indicates that an operation, e.g. a kind of call or
+	// assignment, is not supported.
+	UnsupportedOperation
+	// UnresolvedCallee indicates that the callee of a call could not be
+	// resolved.
+	UnresolvedCallee
+	// UnresolvedExpression indicates that an expression could not be resolved
+	// or evaluated.
+	UnresolvedExpression
+	// IgnoredExpression indicates that an expression or part of it got
+	// ignored.
+	IgnoredExpression
+	// InvalidAnnotation indicates a problem with a toph annotation.
+	InvalidAnnotation
+	// Internal indicates unexpected type information or program state.
+	Internal
+)
+
+func (k Kind) String() string {
+	switch k {
+	case General:
+		return "general"
+	case LoadFailure:
+		return "load-failure"
+	case UnsupportedStatement:
+		return "unsupported-statement"
+	case UnsupportedOperation:
+		return "unsupported-operation"
+	case UnresolvedCallee:
+		return "unresolved-callee"
+	case UnresolvedExpression:
+		return "unresolved-expression"
+	case IgnoredExpression:
+		return "ignored-expression"
+	case InvalidAnnotation:
+		return "invalid-annotation"
+	case Internal:
+		return "internal"
+	default:
+		panic(fmt.Errorf("unexpected kind: %d", k))
+	}
+}
+
+// Kinds returns all kinds in order.
+func Kinds() []Kind {
+	return []Kind{
+		General,
+		LoadFailure,
+		UnsupportedStatement,
+		UnsupportedOperation,
+		UnresolvedCallee,
+		UnresolvedExpression,
+		IgnoredExpression,
+		InvalidAnnotation,
+		Internal,
+	}
+}
+
+// Warning describes a problem that did not prevent building or translating a
+// program but might affect the results.
+type Warning struct {
+	Kind Kind
+	// Pos is the position of the offending source code, if known.
+	Pos token.Position
+	// Snippet is the offending source code, if known.
+	Snippet string
+	// Msg describes the problem, without the position.
+	Msg string
+}
+
+// Warningf returns a new warning with a message formatted according to the
+// format specifier.
+func Warningf(kind Kind, pos token.Position, snippet string, format string, args ...interface{}) *Warning {
+	return &Warning{
+		Kind:    kind,
+		Pos:     pos,
+		Snippet: snippet,
+		Msg:     fmt.Sprintf(format, args...),
+	}
+}
+
+// FromError returns the warning contained in err or, if err is not a warning,
+// a new warning of the given kind with the message of err.
+func FromError(err error, kind Kind) *Warning {
+	var w *Warning
+	if errors.As(err, &w) {
+		return w
+	}
+	return &Warning{Kind: kind, Msg: err.Error()}
+}
+
+func (w *Warning) Error() string {
+	if !w.Pos.IsValid() {
+		return w.Msg
+	}
+	return fmt.Sprintf("%v: %s", w.Pos, w.Msg)
+}
+
+// ParsePosition parses a position in the format returned by
+// token.Position.String, as used in package errors and in state comments of
+// Uppaal systems. It returns the zero position if the string is not a valid
+// position.
+func ParsePosition(s string) token.Position {
+	var pos token.Position
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 {
+		return pos
+	}
+	var nums []int
+	for len(parts) > 0 && len(nums) < 2 {
+		n, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil || n <= 0 {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		parts = parts[:len(parts)-1]
+	}
+	if len(nums) == 0 {
+		return pos
+	}
+	pos.Filename = strings.Join(parts, ":")
+	pos.Line = nums[0]
+	if len(nums) > 1 {
+		pos.Column = nums[1]
+	}
+	return pos
+}
@@ -48,6 +48,9 @@ type FuncCallGraph struct {
 	canPanicExternally map[*ir.Func]bool
 	canRecover         map[*ir.Func]bool
 
+	canGoexitInternally map[*ir.Func]bool
+	canGoexitExternally map[*ir.Func]bool
+
 	// Strongly connected components:
 	sccsOk     bool
 	sccCount   int
@@ -70,6 +73,8 @@ func newFuncCallGraph(entry *ir.Func) *FuncCallGraph {
 	fcg.canPanicInternally = make(map[*ir.Func]bool)
 	fcg.canPanicExternally = make(map[*ir.Func]bool)
 	fcg.canRecover = make(map[*ir.Func]bool)
+	fcg.canGoexitInternally = make(map[*ir.Func]bool)
+	fcg.canGoexitExternally = make(map[*ir.Func]bool)
 	fcg.sccsOk = false
 
 	if entry != nil {
@@ -174,6 +179,12 @@ func (fcg *FuncCallGraph) CanRecover(f *ir.Func) bool {
 	return fcg.canRecover[f]
 }
 
+// CanGoexit returns if the given function can terminate its goroutine via
+// runtime.Goexit.
+func (fcg *FuncCallGraph) CanGoexit(f *ir.Func) bool {
+	return fcg.canGoexitInternally[f] || fcg.canGoexitExternally[f]
+}
+
 // SCCCount returns the number of strongly connected components in the
 // function graph. Disconnected functions are counted in SCC 0.
 func (fcg *FuncCallGraph) SCCCount() int {
@@ -508,6 +519,8 @@ func (fcg *FuncCallGraph) String() string {
 		fmt.Fprintf(&b, "\tcan panic internally: %t\n", fcg.canPanicInternally[f])
 		fmt.Fprintf(&b, "\tcan panic externally: %t\n", fcg.canPanicExternally[f])
 		fmt.Fprintf(&b, "\tcan recover: %t\n", fcg.canRecover[f])
+		fmt.Fprintf(&b, "\tcan goexit internally: %t\n", fcg.canGoexitInternally[f])
+		fmt.Fprintf(&b, "\tcan goexit externally: %t\n", fcg.canGoexitExternally[f])
 	}
 	b.WriteString("\n")
 
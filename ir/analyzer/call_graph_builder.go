@@ -26,6 +26,7 @@ func BuildFuncCallGraph(program *ir.Program, callKinds ir.CallKind, config *c.Co
 		b.removeCallsToClosuresInsideUncalledFunctionsFromFuncCallGraph()
 	}
 	b.analyzePanics()
+	b.analyzeGoexits()
 
 	return b.fcg
 }
@@ -103,6 +104,16 @@ func (b *callGraphBuilder) addDynamicCalleesToFuncCallGraph() {
 				for _, result := range stmt.Results() {
 					b.addDynamicCalleeToFuncCallGraph(result)
 				}
+			case *ir.ChanCommOpStmt:
+				if stmt.Op() == ir.Send && stmt.Payload() != nil {
+					b.addDynamicCalleeToFuncCallGraph(stmt.Payload())
+				}
+			case *ir.SelectStmt:
+				for _, c := range stmt.Cases() {
+					if c.OpStmt().Op() == ir.Send && c.OpStmt().Payload() != nil {
+						b.addDynamicCalleeToFuncCallGraph(c.OpStmt().Payload())
+					}
+				}
 			}
 		})
 	}
@@ -188,27 +199,32 @@ func (b *callGraphBuilder) analyzePanics() {
 		b.fcg.canPanicInternally[f] = b.canPanicInternally(f)
 		b.fcg.canRecover[f] = b.canRecover(f)
 	}
+	b.propagateExternally(b.fcg.canPanicInternally, b.fcg.canPanicExternally)
+}
+
+// propagateExternally sets the external property of each function if any of
+// its callees has the internal or external property. All functions in a
+// cycle of calls get the external property if any of them has either
+// property.
+func (b *callGraphBuilder) propagateExternally(internally, externally map[*ir.Func]bool) {
 	for i := 1; i < b.fcg.SCCCount(); i++ {
 		sccFuncs := b.fcg.FuncsInSCC(SCC(i))
-		canPanicInSCC := false
+		inSCC := false
 		for _, caller := range sccFuncs {
-			canPanicExternally := false
+			externally[caller] = false
 			for _, callee := range b.fcg.AllCallees(caller) {
-				if b.fcg.canPanicInternally[callee] ||
-					b.fcg.canPanicExternally[callee] {
-					canPanicExternally = true
+				if internally[callee] || externally[callee] {
+					externally[caller] = true
 					break
 				}
 			}
-			b.fcg.canPanicExternally[caller] = canPanicExternally
-			if b.fcg.canPanicInternally[caller] ||
-				b.fcg.canPanicExternally[caller] {
-				canPanicInSCC = true
+			if internally[caller] || externally[caller] {
+				inSCC = true
 			}
 		}
-		if len(sccFuncs) > 1 && canPanicInSCC {
+		if len(sccFuncs) > 1 && inSCC {
 			for _, f := range sccFuncs {
-				b.fcg.canPanicExternally[f] = true
+				externally[f] = true
 			}
 		}
 	}
@@ -216,9 +232,46 @@ func (b *callGraphBuilder) analyzePanics() {
 
 func (b *callGraphBuilder) canPanicInternally(f *ir.Func) (canPanic bool) {
 	f.Body().WalkStmts(func(stmt ir.Stmt, scope *ir.Scope) {
-		returnStmt, ok := stmt.(*ir.ReturnStmt)
-		if ok && returnStmt.IsPanic() {
+		switch stmt := stmt.(type) {
+		case *ir.ReturnStmt:
+			if stmt.IsPanic() {
+				canPanic = true
+			}
+		case *ir.CloseChanStmt:
+			// Closing a nil or closed channel panics.
 			canPanic = true
+		case *ir.ChanCommOpStmt:
+			// Sending on a closed channel panics.
+			if stmt.Op() == ir.Send {
+				canPanic = true
+			}
+		case *ir.SelectStmt:
+			for _, c := range stmt.Cases() {
+				if c.OpStmt().Op() == ir.Send {
+					canPanic = true
+				}
+			}
+		case *ir.CondOpStmt:
+			// Waiting on a cond with a nil locker panics.
+			if stmt.Op() == ir.CondWait {
+				canPanic = true
+			}
+		}
+	})
+	return
+}
+
+func (b *callGraphBuilder) analyzeGoexits() {
+	for _, f := range b.program.Funcs() {
+		b.fcg.canGoexitInternally[f] = b.canGoexitInternally(f)
+	}
+	b.propagateExternally(b.fcg.canGoexitInternally, b.fcg.canGoexitExternally)
+}
+
+func (b *callGraphBuilder) canGoexitInternally(f *ir.Func) (canGoexit bool) {
+	f.Body().WalkStmts(func(stmt ir.Stmt, scope *ir.Scope) {
+		if _, ok := stmt.(*ir.GoexitStmt); ok {
+			canGoexit = true
 		}
 	})
 	return
@@ -412,7 +465,7 @@ func (b *callGraphBuilder) findCalleesInfoForBody(body *ir.Body) (res callsInfo)
 			res.add(b.findCalleesInfoForChanRangeStmt(stmt))
 		case *ir.ContainerRangeStmt:
 			res.add(b.findCalleesInfoForContainerRangeStmt(stmt))
-		case *ir.BranchStmt, *ir.ChanCommOpStmt, *ir.DeleteMapEntryStmt, *ir.ReturnStmt, *ir.RecoverStmt:
+		case *ir.AccessStmt, *ir.BranchStmt, *ir.ChanCommOpStmt, *ir.DeleteMapEntryStmt, *ir.ReturnStmt, *ir.RecoverStmt, *ir.GoexitStmt:
 			continue
 		default:
 			panic(fmt.Errorf("unexpected ir.Stmt type: %T", stmt))
@@ -440,6 +493,8 @@ func (b *callGraphBuilder) findCalleesInfoForAssignStmt(assignStmt *ir.AssignStm
 		res.addTypeAllocations(ir.MutexType, 1)
 	} else if ok && v == ir.InitializedWaitGroup {
 		res.addTypeAllocations(ir.WaitGroupType, 1)
+	} else if ok && v == ir.InitializedRWMutex {
+		res.addTypeAllocations(ir.RWMutexType, 1)
 	}
 	if assignStmt.RequiresCopy() {
 		res.add(b.findCalleesForTypeCopy(assignStmt.Destination().Type()))
@@ -487,7 +542,7 @@ func (b *callGraphBuilder) findCalleesForInitializedType(irType ir.Type) (res ca
 	res.init()
 	switch irType := irType.(type) {
 	case ir.BasicType:
-		if irType == ir.MutexType || irType == ir.WaitGroupType {
+		if irType == ir.MutexType || irType == ir.RWMutexType || irType == ir.WaitGroupType || irType == ir.OnceType {
 			res.addTypeAllocations(irType, 1)
 		}
 	case *ir.StructType:
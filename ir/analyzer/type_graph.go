@@ -135,7 +135,7 @@ func (tg *TypeGraph) updateTopologicalOrder() {
 		return
 	}
 	tg.topologicalOrderOk = true
-	tg.topologicalOrder = []ir.Type{ir.IntType, ir.FuncType, ir.ChanType, ir.MutexType, ir.WaitGroupType, ir.OnceType}
+	tg.topologicalOrder = []ir.Type{ir.IntType, ir.FuncType, ir.ChanType, ir.MutexType, ir.WaitGroupType, ir.OnceType, ir.CondType, ir.RWMutexType}
 
 	added := map[ir.Type]bool{
 		ir.IntType:       true,
@@ -144,6 +144,8 @@ func (tg *TypeGraph) updateTopologicalOrder() {
 		ir.MutexType:     true,
 		ir.WaitGroupType: true,
 		ir.OnceType:      true,
+		ir.CondType:      true,
+		ir.RWMutexType:   true,
 	}
 
 	for len(tg.topologicalOrder) < len(tg.dependantsToDependees) {
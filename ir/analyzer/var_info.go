@@ -14,6 +14,7 @@ type VarInfo struct {
 	totalTypeUses   map[ir.Type]int
 	varUses         map[*ir.Variable]map[*ir.Func]int
 	totalVarUses    map[*ir.Variable]int
+	sharedVarUses   map[*ir.SharedVar]map[*ir.Func]int
 }
 
 // FindVarInfo computes and returns variable usage information for the given
@@ -25,6 +26,7 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 	vi.totalTypeUses = make(map[ir.Type]int)
 	vi.varUses = make(map[*ir.Variable]map[*ir.Func]int)
 	vi.totalVarUses = make(map[*ir.Variable]int)
+	vi.sharedVarUses = make(map[*ir.SharedVar]map[*ir.Func]int)
 
 	for _, t := range program.Types() {
 		vi.typeUsesInVars[t] = make(map[*ir.Variable]struct{})
@@ -43,6 +45,10 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 		}
 	}
 
+	for _, v := range program.SharedVars() {
+		vi.sharedVarUses[v] = make(map[*ir.Func]int)
+	}
+
 	for _, f := range program.Funcs() {
 		for _, arg := range f.Args() {
 			vi.addVariableUse(arg, f)
@@ -52,6 +58,9 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 		}
 		f.Body().WalkStmts(func(stmt ir.Stmt, scope *ir.Scope) {
 			switch stmt := stmt.(type) {
+			case *ir.AccessStmt:
+				vi.sharedVarUses[stmt.SharedVar()][f]++
+				vi.addLValueUse(stmt.Counter(), f)
 			case *ir.AssignStmt:
 				vi.addRValueUse(stmt.Source(), f)
 				vi.addLValueUse(stmt.Destination(), f)
@@ -59,10 +68,17 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 				vi.addVariableUse(stmt.Channel(), f)
 			case *ir.ChanCommOpStmt:
 				vi.addLValueUse(stmt.Channel(), f)
+				if stmt.Payload() != nil {
+					vi.addRValueUse(stmt.Payload(), f)
+				}
+				if stmt.Ok() != nil {
+					vi.addVariableUse(stmt.Ok(), f)
+				}
 			case *ir.CloseChanStmt:
 				vi.addLValueUse(stmt.Channel(), f)
 			case *ir.MutexOpStmt:
 				vi.addLValueUse(stmt.Mutex(), f)
+				vi.addVariableUse(stmt.Result(), f)
 			case *ir.WaitGroupOpStmt:
 				vi.addLValueUse(stmt.WaitGroup(), f)
 				vi.addRValueUse(stmt.Delta(), f)
@@ -71,6 +87,11 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 				if v, ok := stmt.F().(ir.LValue); ok {
 					vi.addLValueUse(v, f)
 				}
+			case *ir.MakeCondStmt:
+				vi.addVariableUse(stmt.Cond(), f)
+				vi.addRValueUse(stmt.Mutex(), f)
+			case *ir.CondOpStmt:
+				vi.addLValueUse(stmt.Cond(), f)
 			case *ir.MakeStructStmt:
 				vi.addVariableUse(stmt.StructVar(), f)
 			case *ir.MakeContainerStmt:
@@ -90,9 +111,18 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 			case *ir.SelectStmt:
 				for _, c := range stmt.Cases() {
 					vi.addLValueUse(c.OpStmt().Channel(), f)
+					if c.OpStmt().Payload() != nil {
+						vi.addRValueUse(c.OpStmt().Payload(), f)
+					}
+					if c.OpStmt().Ok() != nil {
+						vi.addVariableUse(c.OpStmt().Ok(), f)
+					}
 				}
 			case *ir.ChanRangeStmt:
 				vi.addLValueUse(stmt.Channel(), f)
+				if stmt.ValueVal() != nil {
+					vi.addLValueUse(stmt.ValueVal(), f)
+				}
 			case *ir.ContainerRangeStmt:
 				vi.addLValueUse(stmt.Container(), f)
 				if stmt.CounterVar() != nil {
@@ -101,7 +131,17 @@ func FindVarInfo(program *ir.Program) *VarInfo {
 				if stmt.ValueVal() != nil {
 					vi.addLValueUse(stmt.ValueVal(), f)
 				}
-			case *ir.BranchStmt, *ir.DeadEndStmt, *ir.CopySliceStmt, *ir.DeleteMapEntryStmt, *ir.IfStmt, *ir.SwitchStmt, *ir.ForStmt, *ir.RecoverStmt:
+			case *ir.SleepStmt:
+				vi.addRValueUse(stmt.Duration(), f)
+			case *ir.IfStmt:
+				if stmt.Cond() != nil {
+					vi.addRValueUse(stmt.Cond(), f)
+				}
+			case *ir.ForStmt:
+				if stmt.CondValue() != nil {
+					vi.addRValueUse(stmt.CondValue(), f)
+				}
+			case *ir.BranchStmt, *ir.DeadEndStmt, *ir.CopySliceStmt, *ir.DeleteMapEntryStmt, *ir.SwitchStmt, *ir.RecoverStmt, *ir.GoexitStmt:
 			default:
 				panic(fmt.Errorf("unexpected ir.Stmt type: %T", stmt))
 			}
@@ -135,7 +175,10 @@ func (vi *VarInfo) addRValueUse(rvalue ir.RValue, f *ir.Func) {
 		vi.typeUsesInFuncs[v.Type()][f]++
 		vi.totalTypeUses[v.Type()]++
 		vi.addLValueUse(v.ContainerVal(), f)
-	case ir.Value:
+	case *ir.BinaryExpr:
+		vi.addRValueUse(v.X(), f)
+		vi.addRValueUse(v.Y(), f)
+	case ir.Value, *ir.RandomInt:
 	default:
 		panic(fmt.Errorf("unexpected ir.RValue type: %T", v))
 	}
@@ -225,6 +268,22 @@ func (vi *VarInfo) TotalVarUses(v *ir.Variable) int {
 	return vi.totalVarUses[v]
 }
 
+// FuncsUsingSharedVar returns all functions accessing the given shared
+// variable.
+func (vi *VarInfo) FuncsUsingSharedVar(v *ir.SharedVar) []*ir.Func {
+	var users []*ir.Func
+	for f := range vi.sharedVarUses[v] {
+		users = append(users, f)
+	}
+	return users
+}
+
+// SharedVarUsesInFunc returns how many times the given shared variable is
+// accessed in the given function.
+func (vi *VarInfo) SharedVarUsesInFunc(v *ir.SharedVar, f *ir.Func) int {
+	return vi.sharedVarUses[v][f]
+}
+
 func (vi *VarInfo) String() string {
 	var b strings.Builder
 
@@ -28,14 +28,15 @@ func (b *Body) WalkStmts(visitFunc func(stmt Stmt, scope *Scope)) {
 		visitFunc(b.stmts[i], b.Scope())
 
 		switch stmt := stmt.(type) {
-		case *AssignStmt,
+		case *AccessStmt, *AssignStmt,
 			*BranchStmt,
 			*MakeChanStmt, *ChanCommOpStmt, *CloseChanStmt,
-			*DeadEndStmt,
+			*DeadEndStmt, *SleepStmt,
 			*CopySliceStmt, *DeleteMapEntryStmt,
 			*MutexOpStmt, *WaitGroupOpStmt, *OnceDoStmt,
+			*MakeCondStmt, *CondOpStmt,
 			*MakeStructStmt, *MakeContainerStmt,
-			*CallStmt, *ReturnStmt, *RecoverStmt:
+			*CallStmt, *ReturnStmt, *RecoverStmt, *GoexitStmt:
 			continue
 		case *SelectStmt:
 			for _, c := range stmt.Cases() {
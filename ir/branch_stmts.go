@@ -22,8 +22,10 @@ const (
 
 // IfStmt represents an if or else branch.
 type IfStmt struct {
-	ifBranch   Body
-	elseBranch Body
+	cond        RValue
+	condNegated bool
+	ifBranch    Body
+	elseBranch  Body
 
 	Node
 	ifPos   token.Pos
@@ -45,6 +47,25 @@ func NewIfStmt(superScope *Scope, pos, end, ifPos, elsePos token.Pos) *IfStmt {
 	return s
 }
 
+// Cond returns the int value deciding which branch gets taken (the if branch
+// for non-zero values, unless negated) or nil if the branch gets chosen
+// nondeterministically.
+func (s *IfStmt) Cond() RValue {
+	return s.cond
+}
+
+// IsCondNegated returns whether the if branch gets taken if the condition is
+// zero.
+func (s *IfStmt) IsCondNegated() bool {
+	return s.condNegated
+}
+
+// SetCond sets the int value deciding which branch gets taken.
+func (s *IfStmt) SetCond(cond RValue, negated bool) {
+	s.cond = cond
+	s.condNegated = negated
+}
+
 // IfBranch returns the body of the if branch.
 func (s *IfStmt) IfBranch() *Body {
 	return &s.ifBranch
@@ -67,7 +88,13 @@ func (s *IfStmt) ElsePos() token.Pos {
 
 func (s *IfStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
-	b.WriteString("if{\n")
+	if s.cond != nil && s.condNegated {
+		fmt.Fprintf(b, "if !%s{\n", s.cond)
+	} else if s.cond != nil {
+		fmt.Fprintf(b, "if %s{\n", s.cond)
+	} else {
+		b.WriteString("if{\n")
+	}
 	s.ifBranch.tree(b, indent+1)
 	b.WriteString("\n")
 	writeIndent(b, indent)
@@ -236,9 +263,11 @@ type ForStmt struct {
 	cond Body
 	body Body
 
-	isInfinite    bool
-	minIterations int
-	maxIterations int
+	condVal        RValue
+	condValNegated bool
+	isInfinite     bool
+	minIterations  int
+	maxIterations  int
 
 	Node
 }
@@ -268,6 +297,25 @@ func (s *ForStmt) Body() *Body {
 	return &s.body
 }
 
+// CondValue returns the int value deciding whether the loop continues (for
+// non-zero values, unless negated), evaluated after the condition body, or nil
+// if the loop continues nondeterministically.
+func (s *ForStmt) CondValue() RValue {
+	return s.condVal
+}
+
+// IsCondValueNegated returns whether the loop continues if the condition value
+// is zero.
+func (s *ForStmt) IsCondValueNegated() bool {
+	return s.condValNegated
+}
+
+// SetCondValue sets the int value deciding whether the loop continues.
+func (s *ForStmt) SetCondValue(cond RValue, negated bool) {
+	s.condVal = cond
+	s.condValNegated = negated
+}
+
 // IsInfinite returns whether the loop has no condition and runs forever unless
 // exited otherwise.
 func (s *ForStmt) IsInfinite() bool {
@@ -316,7 +364,13 @@ func (s *ForStmt) SetMaxIterations(maxIterations int) {
 
 func (s *ForStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
-	b.WriteString("for{\n")
+	if s.condVal != nil && s.condValNegated {
+		fmt.Fprintf(b, "for !%s{\n", s.condVal)
+	} else if s.condVal != nil {
+		fmt.Fprintf(b, "for %s{\n", s.condVal)
+	} else {
+		b.WriteString("for{\n")
+	}
 	writeIndent(b, indent+1)
 	b.WriteString("cond{\n")
 	s.cond.tree(b, indent+2)
@@ -331,8 +385,10 @@ func (s *ForStmt) tree(b *strings.Builder, indent int) {
 
 // ChanRangeStmt represents a loop ranging over a channel.
 type ChanRangeStmt struct {
-	channel LValue
-	body    Body
+	channel     LValue
+	payloadType Type
+	valueVal    LValue
+	body        Body
 
 	Node
 }
@@ -355,6 +411,25 @@ func (s *ChanRangeStmt) Channel() LValue {
 	return s.channel
 }
 
+// PayloadType returns the type of the values received from the channel, or
+// nil if the element type of the channel is not modeled.
+func (s *ChanRangeStmt) PayloadType() Type {
+	return s.payloadType
+}
+
+// ValueVal returns the lvalue receiving the values from the channel, or nil if
+// the values get discarded.
+func (s *ChanRangeStmt) ValueVal() LValue {
+	return s.valueVal
+}
+
+// SetPayload sets the type of the values received from the channel and the
+// lvalue receiving them.
+func (s *ChanRangeStmt) SetPayload(payloadType Type, valueVal LValue) {
+	s.payloadType = payloadType
+	s.valueVal = valueVal
+}
+
 // Body returns the main body of the range loop.
 func (s *ChanRangeStmt) Body() *Body {
 	return &s.body
@@ -362,7 +437,11 @@ func (s *ChanRangeStmt) Body() *Body {
 
 func (s *ChanRangeStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
-	fmt.Fprintf(b, "channel range %s {\n", s.channel.Handle())
+	if s.valueVal != nil {
+		fmt.Fprintf(b, "channel range %s -> %s {\n", s.channel.Handle(), s.valueVal.Handle())
+	} else {
+		fmt.Fprintf(b, "channel range %s {\n", s.channel.Handle())
+	}
 	s.body.tree(b, indent+1)
 	b.WriteString("\n")
 	writeIndent(b, indent)
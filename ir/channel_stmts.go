@@ -92,8 +92,11 @@ func (o ChanCommOp) String() string {
 
 // ChanCommOpStmt represents a channel operation statement.
 type ChanCommOpStmt struct {
-	channel LValue
-	op      ChanCommOp
+	channel     LValue
+	op          ChanCommOp
+	payloadType Type
+	payload     RValue
+	ok          *Variable // only applicable for Receive ops
 
 	Node
 }
@@ -120,9 +123,66 @@ func (s *ChanCommOpStmt) Op() ChanCommOp {
 	return s.op
 }
 
+// PayloadType returns the type of the values transferred through the
+// channel, or nil if the element type of the channel is not modeled.
+func (s *ChanCommOpStmt) PayloadType() Type {
+	return s.payloadType
+}
+
+// Payload returns the sent value of a send operation or the lvalue receiving
+// the value of a receive operation. The payload of a receive operation is nil
+// if the received value gets discarded.
+func (s *ChanCommOpStmt) Payload() RValue {
+	return s.payload
+}
+
+// SetPayload sets the type of the values transferred through the channel and
+// the sent value or the lvalue receiving the value.
+func (s *ChanCommOpStmt) SetPayload(payloadType Type, payload RValue) {
+	if _, ok := payload.(LValue); s.op == Receive && payload != nil && !ok {
+		panic("attempted to receive value in rvalue")
+	}
+	s.payloadType = payloadType
+	s.payload = payload
+}
+
+// Ok returns the int variable that holds 1 if a receive operation received a
+// sent value and 0 if it completed because the channel was closed and
+// drained, or nil if the result is not used.
+func (s *ChanCommOpStmt) Ok() *Variable {
+	return s.ok
+}
+
+// SetOk sets the int variable that holds whether a receive operation received
+// a sent value.
+func (s *ChanCommOpStmt) SetOk(ok *Variable) {
+	if s.op != Receive {
+		panic("attempted to set ok result of send operation")
+	}
+	s.ok = ok
+}
+
 func (s *ChanCommOpStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
 	fmt.Fprintf(b, "%v %s", s.op, s.channel.Handle())
+	if s.ok != nil {
+		defer fmt.Fprintf(b, " (ok -> %s)", s.ok.Handle())
+	}
+	if s.payload == nil {
+		return
+	}
+	switch p := s.payload.(type) {
+	case Value:
+		fmt.Fprintf(b, " <- %s", p.String())
+	case LValue:
+		if s.op == Send {
+			fmt.Fprintf(b, " <- %s", p.Handle())
+		} else {
+			fmt.Fprintf(b, " -> %s", p.Handle())
+		}
+	default:
+		panic(fmt.Errorf("unexpected %T payload type", p))
+	}
 }
 
 // CloseChanStmt represents a channel close statement.
@@ -24,6 +24,8 @@ type Func struct {
 	enclosingFunc *Func
 	body          Body
 
+	maxInstances int
+
 	Node
 }
 
@@ -147,6 +149,18 @@ func (f *Func) EnclosingFunc() *Func {
 	return f.enclosingFunc
 }
 
+// MaxInstances returns the annotated maximum number of concurrent instances
+// of the function or zero if it was not annotated.
+func (f *Func) MaxInstances() int {
+	return f.maxInstances
+}
+
+// SetMaxInstances sets the maximum number of concurrent instances of the
+// function, overriding the number derived from the call graph.
+func (f *Func) SetMaxInstances(maxInstances int) {
+	f.maxInstances = maxInstances
+}
+
 // Scope returns the function scope.
 func (f *Func) Scope() *Scope {
 	return f.Body().Scope()
@@ -197,6 +211,10 @@ func (f *Func) tree(b *strings.Builder, indent int) {
 		writeIndent(b, indent+1)
 		fmt.Fprintf(b, "enclosing func index: %d (%s)\n", f.enclosingFunc.index, f.enclosingFunc.name)
 	}
+	if f.maxInstances > 0 {
+		writeIndent(b, indent+1)
+		fmt.Fprintf(b, "max instances: %d\n", f.maxInstances)
+	}
 	f.body.tree(b, indent+1)
 	b.WriteString("\n")
 	writeIndent(b, indent)
@@ -264,3 +264,24 @@ func (s *RecoverStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
 	b.WriteString("recover")
 }
+
+// GoexitStmt represents a call to runtime.Goexit or a function calling it,
+// such as testing.T.FailNow. The statement terminates the goroutine after
+// running all deferred calls. Unlike a panic, the exit can not be recovered.
+type GoexitStmt struct {
+	Node
+}
+
+// NewGoexitStmt creates a new GoexitStmt.
+func NewGoexitStmt(pos, end token.Pos) *GoexitStmt {
+	s := new(GoexitStmt)
+	s.pos = pos
+	s.end = end
+
+	return s
+}
+
+func (s *GoexitStmt) tree(b *strings.Builder, indent int) {
+	writeIndent(b, indent)
+	b.WriteString("goexit")
+}
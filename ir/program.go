@@ -16,6 +16,8 @@ type Program struct {
 	scope         Scope
 	variableCount int
 
+	sharedVars []*SharedVar
+
 	types      []Type
 	typeLookup map[TypeIndex]Type
 	typeCount  int
@@ -30,9 +32,10 @@ func NewProgram(fset *token.FileSet) *Program {
 	p.funcLookup = make(map[FuncIndex]*Func)
 	p.funcCount = 0
 	p.variableCount = 0
-	p.types = []Type{IntType, FuncType, ChanType, MutexType, WaitGroupType, OnceType}
+	p.types = []Type{IntType, FuncType, ChanType, MutexType, WaitGroupType, OnceType, CondType, RWMutexType}
 	p.typeLookup = map[TypeIndex]Type{
-		0: IntType, 1: FuncType, 2: ChanType, 3: MutexType, 4: WaitGroupType, 5: OnceType,
+		0: IntType, 1: FuncType, 2: ChanType, 3: MutexType, 4: WaitGroupType, 5: OnceType, 6: CondType,
+		7: RWMutexType,
 	}
 	p.typeCount = len(p.types)
 	p.fset = fset
@@ -121,6 +124,20 @@ func (p *Program) NewVariable(name string, initialValue Value) *Variable {
 	return v
 }
 
+// SharedVars returns all shared variables in the program.
+func (p *Program) SharedVars() []*SharedVar {
+	return p.sharedVars
+}
+
+// AddSharedVar adds a new shared variable with the given arguments to the
+// program and returns the new shared variable.
+func (p *Program) AddSharedVar(name string, annotated bool, pos token.Pos) *SharedVar {
+	v := newSharedVar(SharedVarIndex(len(p.sharedVars)), name, annotated, pos)
+	p.sharedVars = append(p.sharedVars, v)
+
+	return v
+}
+
 // Types returns all types defined in the program.
 func (p *Program) Types() []Type {
 	return p.types
@@ -163,6 +180,14 @@ func (p *Program) Tree() string {
 	b.WriteString("prog{\n")
 	p.scope.tree(&b, 1)
 	b.WriteString("\n")
+	if len(p.sharedVars) > 0 {
+		b.WriteString("\tshared{\n")
+		for _, v := range p.sharedVars {
+			v.tree(&b, 2)
+			b.WriteString("\n")
+		}
+		b.WriteString("\t}\n")
+	}
 	b.WriteString("\tfuncs{\n")
 	for _, f := range p.funcs {
 		f.tree(&b, 2)
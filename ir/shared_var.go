@@ -0,0 +1,124 @@
+package ir
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// SharedVarIndex represents the index of a shared variable.
+type SharedVarIndex int
+
+// SharedVar represents a variable or structure field in Go source code whose
+// accesses get recorded to check for data races. Unlike Variable, SharedVar
+// does not hold a value. All instances of a local variable or structure field
+// are represented by the same SharedVar, access statements refer to the
+// access counter of the accessed instance.
+type SharedVar struct {
+	index     SharedVarIndex
+	name      string
+	annotated bool
+	pos       token.Pos
+}
+
+func newSharedVar(index SharedVarIndex, name string, annotated bool, pos token.Pos) *SharedVar {
+	v := new(SharedVar)
+	v.index = index
+	v.name = name
+	v.annotated = annotated
+	v.pos = pos
+
+	return v
+}
+
+// Name returns the name of the shared variable.
+func (v *SharedVar) Name() string {
+	return v.name
+}
+
+// IsAnnotated returns whether the shared variable was explicitly marked as
+// shared with an annotation.
+func (v *SharedVar) IsAnnotated() bool {
+	return v.annotated
+}
+
+// Pos returns the position of the declaration of the shared variable.
+func (v *SharedVar) Pos() token.Pos {
+	return v.pos
+}
+
+// Handle returns a shorthand to uniquely reference the shared variable.
+func (v *SharedVar) Handle() string {
+	return fmt.Sprintf("shared%d_%s", v.index, v.name)
+}
+
+func (v *SharedVar) tree(b *strings.Builder, indent int) {
+	writeIndent(b, indent)
+	b.WriteString(fmt.Sprintf("shared %s", v.Handle()))
+	if v.annotated {
+		b.WriteString(" (annotated)")
+	}
+}
+
+func (v *SharedVar) String() string {
+	return v.Handle()
+}
+
+// AccessStmt represents a read or write of a shared variable.
+type AccessStmt struct {
+	sharedVar *SharedVar
+	counter   LValue
+	kind      AccessKind
+
+	Node
+}
+
+// NewAccessStmt creates a new access statement for the given shared variable.
+// The counter holds the number of ongoing accesses of the accessed instance of
+// the shared variable.
+func NewAccessStmt(sharedVar *SharedVar, counter LValue, kind AccessKind, pos, end token.Pos) *AccessStmt {
+	if sharedVar == nil {
+		panic("tried to create AccessStmt with nil shared variable")
+	} else if counter == nil {
+		panic("tried to create AccessStmt with nil counter")
+	} else if counter.Type() != IntType {
+		panic("tried to create AccessStmt with non-int counter")
+	}
+
+	s := new(AccessStmt)
+	s.sharedVar = sharedVar
+	s.counter = counter
+	s.kind = kind
+	s.pos = pos
+	s.end = end
+
+	return s
+}
+
+// SharedVar returns the accessed shared variable.
+func (s *AccessStmt) SharedVar() *SharedVar {
+	return s.sharedVar
+}
+
+// Counter returns the access counter of the accessed instance of the shared
+// variable.
+func (s *AccessStmt) Counter() LValue {
+	return s.counter
+}
+
+// Kind returns whether the shared variable gets read or written.
+func (s *AccessStmt) Kind() AccessKind {
+	return s.kind
+}
+
+func (s *AccessStmt) tree(b *strings.Builder, indent int) {
+	writeIndent(b, indent)
+	switch s.kind {
+	case Read:
+		fmt.Fprintf(b, "read %s (%s)", s.sharedVar.Handle(), s.counter.Handle())
+	case Write:
+		fmt.Fprintf(b, "write %s (%s)", s.sharedVar.Handle(), s.counter.Handle())
+	default:
+		panic(fmt.Errorf("unexpected AccessKind: %d", s.kind))
+	}
+}
@@ -15,6 +15,7 @@ type Stmt interface {
 	stmt()
 }
 
+func (s *AccessStmt) stmt()         {}
 func (s *AssignStmt) stmt()         {}
 func (s *BranchStmt) stmt()         {}
 func (s *CallStmt) stmt()           {}
@@ -22,12 +23,15 @@ func (s *ChanCommOpStmt) stmt()     {}
 func (s *ChanRangeStmt) stmt()      {}
 func (s *CloseChanStmt) stmt()      {}
 func (s *ContainerRangeStmt) stmt() {}
+func (s *CondOpStmt) stmt()         {}
 func (s *CopySliceStmt) stmt()      {}
 func (s *DeadEndStmt) stmt()        {}
 func (s *DeleteMapEntryStmt) stmt() {}
 func (s *ForStmt) stmt()            {}
+func (s *GoexitStmt) stmt()         {}
 func (s *IfStmt) stmt()             {}
 func (s *MakeChanStmt) stmt()       {}
+func (s *MakeCondStmt) stmt()       {}
 func (s *MakeStructStmt) stmt()     {}
 func (s *MakeContainerStmt) stmt()  {}
 func (s *OnceDoStmt) stmt()         {}
@@ -35,6 +39,7 @@ func (s *MutexOpStmt) stmt()        {}
 func (s *RecoverStmt) stmt()        {}
 func (s *ReturnStmt) stmt()         {}
 func (s *SelectStmt) stmt()         {}
+func (s *SleepStmt) stmt()          {}
 func (s *SwitchStmt) stmt()         {}
 func (s *WaitGroupOpStmt) stmt()    {}
 
@@ -51,6 +56,8 @@ func (o DeadEndOp) specialOp()   {}
 func (o MutexOp) specialOp()     {}
 func (o WaitGroupOp) specialOp() {}
 func (o OnceOp) specialOp()      {}
+func (o CondOp) specialOp()      {}
+func (o SleepOp) specialOp()     {}
 
 // SpecialOps returns a list of all defined special operations.
 func SpecialOps() []SpecialOp {
@@ -60,6 +67,8 @@ func SpecialOps() []SpecialOp {
 		Lock, Unlock, RLock, RUnlock,
 		Add, Wait,
 		Do,
+		MakeCond, CondWait, Signal, Broadcast,
+		Sleep,
 	}
 }
 
@@ -18,6 +18,10 @@ const (
 	RLock
 	// RUnlock represents a sync.RWMutex.RUnlock() operation.
 	RUnlock
+	// TryLock represents a sync.(RW)Mutex.TryLock() operation.
+	TryLock
+	// TryRLock represents a sync.RWMutex.TryRLock() operation.
+	TryRLock
 )
 
 func (o MutexOp) String() string {
@@ -30,25 +34,33 @@ func (o MutexOp) String() string {
 		return "rlock"
 	case RUnlock:
 		return "runlock"
+	case TryLock:
+		return "trylock"
+	case TryRLock:
+		return "tryrlock"
 	default:
 		panic(fmt.Sprintf("unknown MutexOp: %d", o))
 	}
 }
 
-// MutexOpStmt represents a sync.(RW)Mutex operation statement.
+// MutexOpStmt represents a sync.(RW)Mutex operation statement. The mutex is of
+// type MutexType or RWMutexType.
 type MutexOpStmt struct {
-	mutex LValue
-	op    MutexOp
+	mutex  LValue
+	op     MutexOp
+	result *Variable // only applicable for TryLock and TryRLock ops
 
 	Node
 }
 
 // NewMutexOpStmt creates a new mutex operation statement for the given muxtex
-// and with the given mutex operation.
-func NewMutexOpStmt(mutex LValue, op MutexOp, pos, end token.Pos) *MutexOpStmt {
+// and with the given mutex operation. The optional result variable receives
+// the outcome of TryLock and TryRLock operations.
+func NewMutexOpStmt(mutex LValue, op MutexOp, result *Variable, pos, end token.Pos) *MutexOpStmt {
 	s := new(MutexOpStmt)
 	s.mutex = mutex
 	s.op = op
+	s.result = result
 	s.pos = pos
 	s.end = end
 
@@ -65,6 +77,19 @@ func (s *MutexOpStmt) Op() MutexOp {
 	return s.op
 }
 
+// IsTry returns whether the operation is a TryLock or TryRLock operation,
+// which does not block and reports whether it acquired the mutex.
+func (s *MutexOpStmt) IsTry() bool {
+	return s.op == TryLock || s.op == TryRLock
+}
+
+// Result returns the int variable that holds 1 if a TryLock or TryRLock
+// operation acquired the mutex and 0 otherwise, or nil if the result is not
+// used.
+func (s *MutexOpStmt) Result() *Variable {
+	return s.result
+}
+
 // SpecialOp returns the operation performed on the mutex.
 func (s *MutexOpStmt) SpecialOp() SpecialOp {
 	return s.op
@@ -72,6 +97,10 @@ func (s *MutexOpStmt) SpecialOp() SpecialOp {
 
 func (s *MutexOpStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
+	if s.result != nil {
+		fmt.Fprintf(b, "%s <- %s %s", s.result.Handle(), s.op, s.mutex.Handle())
+		return
+	}
 	fmt.Fprintf(b, "%s %s", s.op, s.mutex.Handle())
 }
 
@@ -198,3 +227,132 @@ func (s *OnceDoStmt) tree(b *strings.Builder, indent int) {
 	writeIndent(b, indent)
 	fmt.Fprintf(b, "once_do %s %s", s.once.Handle(), s.f.String())
 }
+
+// CondOp represents an operation performed on a sync.Cond.
+type CondOp int
+
+const (
+	// MakeCond represents a sync.NewCond call or sync.Cond composite literal.
+	MakeCond CondOp = iota
+	// CondWait represents a sync.Cond.Wait operation.
+	CondWait
+	// Signal represents a sync.Cond.Signal operation.
+	Signal
+	// Broadcast represents a sync.Cond.Broadcast operation.
+	Broadcast
+)
+
+func (o CondOp) String() string {
+	switch o {
+	case MakeCond:
+		return "make_cond"
+	case CondWait:
+		return "cond_wait"
+	case Signal:
+		return "signal"
+	case Broadcast:
+		return "broadcast"
+	default:
+		panic(fmt.Sprintf("unknown CondOp: %d", o))
+	}
+}
+
+// MakeCondStmt represents a sync.NewCond call or sync.Cond composite literal.
+type MakeCondStmt struct {
+	cond        *Variable
+	mutex       RValue
+	usesRLocker bool
+
+	Node
+}
+
+// NewMakeCondStmt creates a new MakeCondStmt for the given cond and
+// associated mutex.
+func NewMakeCondStmt(cond *Variable, mutex RValue, pos, end token.Pos) *MakeCondStmt {
+	s := new(MakeCondStmt)
+	s.cond = cond
+	s.mutex = mutex
+	s.pos = pos
+	s.end = end
+
+	return s
+}
+
+// Cond returns the variable holding the newly made cond.
+func (s *MakeCondStmt) Cond() *Variable {
+	return s.cond
+}
+
+// Mutex returns the mutex associated with the newly made cond (the L field of
+// sync.Cond).
+func (s *MakeCondStmt) Mutex() RValue {
+	return s.mutex
+}
+
+// UsesRLocker returns whether the cond uses the read lock of its mutex, which
+// is the case if the mutex was passed via sync.RWMutex.RLocker.
+func (s *MakeCondStmt) UsesRLocker() bool {
+	return s.usesRLocker
+}
+
+// SetUsesRLocker sets whether the cond uses the read lock of its mutex.
+func (s *MakeCondStmt) SetUsesRLocker(usesRLocker bool) {
+	s.usesRLocker = usesRLocker
+}
+
+// SpecialOp returns the performed operation (always MakeCond).
+func (s *MakeCondStmt) SpecialOp() SpecialOp {
+	return MakeCond
+}
+
+func (s *MakeCondStmt) tree(b *strings.Builder, indent int) {
+	writeIndent(b, indent)
+	if s.usesRLocker {
+		fmt.Fprintf(b, "%s <- make_cond(rlocker %s)", s.cond.Handle(), s.mutex)
+		return
+	}
+	fmt.Fprintf(b, "%s <- make_cond(%s)", s.cond.Handle(), s.mutex)
+}
+
+// CondOpStmt represents a sync.Cond operation statement.
+type CondOpStmt struct {
+	cond LValue
+	op   CondOp
+
+	Node
+}
+
+// NewCondOpStmt creates a new cond operation statement for the given cond and
+// with the given cond operation.
+func NewCondOpStmt(cond LValue, op CondOp, pos, end token.Pos) *CondOpStmt {
+	if op == MakeCond {
+		panic("attempted to create CondOpStmt with MakeCond op")
+	}
+	s := new(CondOpStmt)
+	s.cond = cond
+	s.op = op
+	s.pos = pos
+	s.end = end
+
+	return s
+}
+
+// Cond returns the cond that is operated on.
+func (s *CondOpStmt) Cond() LValue {
+	return s.cond
+}
+
+// Op returns the operation performed on the cond.
+func (s *CondOpStmt) Op() CondOp {
+	return s.op
+}
+
+// SpecialOp returns the operation performed on the cond.
+func (s *CondOpStmt) SpecialOp() SpecialOp {
+	return s.op
+}
+
+func (s *CondOpStmt) tree(b *strings.Builder, indent int) {
+	writeIndent(b, indent)
+	fmt.Fprintf(b, "%s %s", s.op, s.cond.Handle())
+}
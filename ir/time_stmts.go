@@ -0,0 +1,51 @@
+package ir
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// SleepOp represents the operation performed by SleepStmt.
+type SleepOp struct{}
+
+// Sleep is the sole instance of SleepOp.
+var Sleep SleepOp
+
+func (o SleepOp) String() string {
+	return "sleep"
+}
+
+// SleepStmt represents a time.Sleep call. It only gets created in timed mode.
+type SleepStmt struct {
+	duration RValue
+
+	Node
+}
+
+// NewSleepStmt creates a new sleep statement for the given duration. The
+// duration is measured in the time unit of the configuration and negative if
+// unknown.
+func NewSleepStmt(duration RValue, pos, end token.Pos) *SleepStmt {
+	s := new(SleepStmt)
+	s.duration = duration
+	s.pos = pos
+	s.end = end
+
+	return s
+}
+
+// Duration returns the duration of the sleep statement.
+func (s *SleepStmt) Duration() RValue {
+	return s.duration
+}
+
+// SpecialOp returns the performed operation (always Sleep).
+func (s *SleepStmt) SpecialOp() SpecialOp {
+	return Sleep
+}
+
+func (s *SleepStmt) tree(b *strings.Builder, indent int) {
+	writeIndent(b, indent)
+	fmt.Fprintf(b, "%s %s", Sleep, s.duration)
+}
@@ -32,12 +32,16 @@ const (
 	FuncType
 	// ChanType is the type of a channel variable.
 	ChanType
-	// MutexType is the type of a (rw)mutex variable.
+	// MutexType is the type of a mutex variable.
 	MutexType
 	// WaitGroupType is the type of a wait group variable.
 	WaitGroupType
 	// OnceType is the type of a once variable.
 	OnceType
+	// CondType is the type of a cond variable.
+	CondType
+	// RWMutexType is the type of a rw mutex variable.
+	RWMutexType
 )
 
 // UninitializedValue returns the Uppaal zero value for the given type.
@@ -45,7 +49,7 @@ func (t BasicType) UninitializedValue() Value {
 	switch t {
 	case IntType:
 		return Value{0, IntType}
-	case FuncType, ChanType, MutexType, WaitGroupType:
+	case FuncType, ChanType, MutexType, WaitGroupType, CondType, RWMutexType:
 		return Value{-1, t}
 	case OnceType:
 		return Value{0, OnceType}
@@ -59,7 +63,7 @@ func (t BasicType) InitializedValue() Value {
 	switch t {
 	case IntType:
 		return Value{0, IntType}
-	case FuncType, ChanType:
+	case FuncType, ChanType, CondType:
 		return Value{-1, t}
 	case MutexType:
 		return InitializedMutex
@@ -67,6 +71,8 @@ func (t BasicType) InitializedValue() Value {
 		return InitializedWaitGroup
 	case OnceType:
 		return InitializedOnce
+	case RWMutexType:
+		return InitializedRWMutex
 	default:
 		panic(fmt.Errorf("unknown Type: %d", t))
 	}
@@ -87,6 +93,10 @@ func (t BasicType) VariablePrefix() string {
 		return "wid"
 	case OnceType:
 		return "oid"
+	case CondType:
+		return "cvid"
+	case RWMutexType:
+		return "rwmid"
 	default:
 		panic(fmt.Errorf("unknown Type: %d", t))
 	}
@@ -106,6 +116,10 @@ func (t BasicType) String() string {
 		return "WaitGroup"
 	case OnceType:
 		return "Once"
+	case CondType:
+		return "Cond"
+	case RWMutexType:
+		return "RWMutex"
 	default:
 		panic(fmt.Errorf("unknown Type: %d", t))
 	}
@@ -118,6 +132,7 @@ type Field struct {
 	t          Type
 	isPointer  bool
 	isEmbedded bool
+	isCounter  bool
 
 	structType *StructType
 }
@@ -148,6 +163,12 @@ func (f *Field) IsEmbedded() bool {
 	return f.isEmbedded
 }
 
+// IsCounter returns if the field is an int counting ongoing operations on the
+// enclosing structure, which does not get copied with the structure.
+func (f *Field) IsCounter() bool {
+	return f.isCounter
+}
+
 // StructType returns the enclosing structure type.
 func (f *Field) StructType() *StructType {
 	return f.structType
@@ -216,6 +237,15 @@ func (t *StructType) AddField(index int, name string, fieldType Type, isPointer,
 	return f
 }
 
+// AddCounterField adds a new int field with the given index and name to the
+// struct, counting ongoing operations on each structure. Copies of structures
+// start with a zero count.
+func (t *StructType) AddCounterField(index int, name string) *Field {
+	f := t.AddField(index, name, IntType, false, false)
+	f.isCounter = true
+	return f
+}
+
 // FindEmbeddedFieldOfType is used to resolve receivers of methods of embedded fields.
 func (t *StructType) FindEmbeddedFieldOfType(embeddedFieldType Type) (embeddedFieldsPath []*Field, ok bool) {
 	for _, field := range t.fields {
@@ -29,6 +29,9 @@ var (
 
 	// Nil represents an untyped nil
 	Nil = Value{math.MinInt64 + 7, nil}
+
+	// InitializedRWMutex is a placeholder initial value for rw mutexes.
+	InitializedRWMutex = Value{math.MinInt64 + 8, RWMutexType}
 )
 
 // InitializedStruct returns a placeholder initial value for structure types.
@@ -74,6 +77,8 @@ func (v Value) String() string {
 		return "initialized wait group"
 	case InitializedOnce.v:
 		return "initialized once"
+	case InitializedRWMutex.v:
+		return "initialized rw mutex"
 	case initializedStruct:
 		return "initialized struct"
 	case initializedArray:
@@ -278,6 +283,159 @@ func (ca *ContainerAccess) String() string {
 	return ca.Handle()
 }
 
+// BinaryOp defines an arithmetic, comparison, or logical operation on int
+// values.
+type BinaryOp int
+
+const (
+	// Plus represents an addition.
+	Plus BinaryOp = iota
+	// Minus represents a subtraction.
+	Minus
+	// Times represents a multiplication.
+	Times
+	// Div represents a division.
+	Div
+	// Rem represents a remainder operation.
+	Rem
+	// Equal represents an equality comparison.
+	Equal
+	// NotEqual represents an inequality comparison.
+	NotEqual
+	// Less represents a less than comparison.
+	Less
+	// LessEqual represents a less than or equal comparison.
+	LessEqual
+	// Greater represents a greater than comparison.
+	Greater
+	// GreaterEqual represents a greater than or equal comparison.
+	GreaterEqual
+	// And represents a logical and.
+	And
+	// Or represents a logical or.
+	Or
+)
+
+func (o BinaryOp) String() string {
+	switch o {
+	case Plus:
+		return "+"
+	case Minus:
+		return "-"
+	case Times:
+		return "*"
+	case Div:
+		return "/"
+	case Rem:
+		return "%"
+	case Equal:
+		return "=="
+	case NotEqual:
+		return "!="
+	case Less:
+		return "<"
+	case LessEqual:
+		return "<="
+	case Greater:
+		return ">"
+	case GreaterEqual:
+		return ">="
+	case And:
+		return "&&"
+	case Or:
+		return "||"
+	default:
+		panic(fmt.Errorf("unknown BinaryOp: %d", o))
+	}
+}
+
+// BinaryExpr represents an operation on two int values. Comparisons and
+// logical operations result in one for true and zero for false.
+type BinaryExpr struct {
+	x  RValue
+	op BinaryOp
+	y  RValue
+}
+
+// NewBinaryExpr creates a new binary expression for the given operands and
+// operation.
+func NewBinaryExpr(x RValue, op BinaryOp, y RValue) *BinaryExpr {
+	if x == nil || y == nil {
+		panic("attempted to create BinaryExpr with nil operand")
+	} else if x.Type() != IntType || y.Type() != IntType {
+		panic("attempted to create BinaryExpr with non-int operand")
+	}
+
+	e := new(BinaryExpr)
+	e.x = x
+	e.op = op
+	e.y = y
+
+	return e
+}
+
+// X returns the left operand of the binary expression.
+func (e *BinaryExpr) X() RValue {
+	return e.x
+}
+
+// Op returns the operation of the binary expression.
+func (e *BinaryExpr) Op() BinaryOp {
+	return e.op
+}
+
+// Y returns the right operand of the binary expression.
+func (e *BinaryExpr) Y() RValue {
+	return e.y
+}
+
+// Type returns the type of the binary expression (always IntType).
+func (e *BinaryExpr) Type() Type {
+	return IntType
+}
+
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.x, e.op, e.y)
+}
+
+// RandomInt represents an unknown int value, chosen nondeterministically from
+// a range.
+type RandomInt struct {
+	min, max int
+}
+
+// NewRandomInt creates a new random int value in the given range (inclusive).
+func NewRandomInt(min, max int) *RandomInt {
+	if min > max {
+		panic("attempted to create RandomInt with empty range")
+	}
+
+	r := new(RandomInt)
+	r.min = min
+	r.max = max
+
+	return r
+}
+
+// Min returns the smallest possible value of the random int.
+func (r *RandomInt) Min() int {
+	return r.min
+}
+
+// Max returns the largest possible value of the random int.
+func (r *RandomInt) Max() int {
+	return r.max
+}
+
+// Type returns the type of the random int (always IntType).
+func (r *RandomInt) Type() Type {
+	return IntType
+}
+
+func (r *RandomInt) String() string {
+	return fmt.Sprintf("random[%d, %d]", r.min, r.max)
+}
+
 // RValue represents a value that can be assigned from.
 type RValue interface {
 	fmt.Stringer
@@ -292,6 +450,8 @@ func (v *Variable) rvalue()         {}
 func (fs *FieldSelection) rvalue()  {}
 func (cl *ContainerLength) rvalue() {}
 func (ca *ContainerAccess) rvalue() {}
+func (e *BinaryExpr) rvalue()       {}
+func (r *RandomInt) rvalue()        {}
 
 // LValue represents a storage location that can be assigned to.
 type LValue interface {
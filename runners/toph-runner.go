@@ -5,7 +5,9 @@ import (
 	"go/build"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/arneph/toph/api"
 	c "github.com/arneph/toph/config"
@@ -17,6 +19,29 @@ func ignore(info os.FileInfo) bool {
 		strings.HasPrefix(info.Name(), "_")
 }
 
+// substitutesFiles returns the Go files in the substitutes directory of the
+// test, if any.
+func substitutesFiles(testPath string) []string {
+	files, _ := filepath.Glob(testPath + "substitutes/*.go")
+	return files
+}
+
+// setExcludes excludes the packages and patterns listed in the exclude file
+// of the test, if any.
+func setExcludes(testPath string, config *c.Config) {
+	content, err := ioutil.ReadFile(testPath + "exclude")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		config.SetExclude(line)
+	}
+}
+
 func main() {
 	var requiredSubString string
 	if len(os.Args) > 1 {
@@ -54,26 +79,35 @@ func main() {
 			fmt.Printf("running test: %s\n", testPath)
 			config := c.Config{
 				BuildContext:                            &build.Default,
+				SubstitutesFiles:                        substitutesFiles(testPath),
 				MaxProcessCount:                         5,
 				MaxDeferCount:                           10,
 				MaxChannelCount:                         100,
 				MaxMutexCount:                           100,
 				MaxWaitGroupCount:                       100,
 				MaxOnceCount:                            100,
+				MaxCondCount:                            100,
 				MaxStructCount:                          100,
 				MaxContainerCount:                       100,
 				ContainerCapacity:                       5,
+				TrackedIntMax:                           4,
+				Timed:                                   dir.Name() == "timed",
+				TimeUnit:                                time.Millisecond,
 				GenerateResourceBoundQueries:            true,
 				GenerateIndividualResourceBoundQueries:  true,
 				GenerateChannelSafetyQueries:            true,
 				GenerateMutexSafetyQueries:              true,
 				GenerateWaitGroupSafetyQueries:          true,
+				GenerateCondSafetyQueries:               true,
 				GenerateChannelRelatedDeadlockQueries:   true,
 				GenerateMutexRelatedDeadlockQueries:     true,
 				GenerateWaitGroupRelatedDeadlockQueries: true,
 				GenerateOnceRelatedDeadlockQueries:      true,
+				GenerateCondRelatedDeadlockQueries:      true,
 				GenerateFunctionCallsWithNilQueries:     true,
+				GenerateCloseOfNilChannelQueries:        true,
 				GenerateGoroutineExitWithPanicQueries:   true,
+				GenerateGoroutineLeakQueries:            true,
 				GenerateReachabilityQueries:             true,
 				OptimizeIR:                              true,
 				OptimizeUppaalSystem:                    true,
@@ -81,6 +115,7 @@ func main() {
 				OutName:                                 testPath + test.Name(),
 				OutFormats:                              map[string]bool{"xml": true},
 			}
+			setExcludes(testPath, &config)
 			result := api.Run([]string{testPath}, &config)
 			perfect := result == api.RunSuccessful
 			attemptedTests++
@@ -19,6 +19,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/arneph/toph/trace"
+	"github.com/arneph/toph/uppaal"
 )
 
 func uppaalDir() string {
@@ -36,7 +39,7 @@ func uppaalDir() string {
 
 var (
 	uppaalPath     = flag.String("uppaal", uppaalDir()+"/", "path to bin-Windows, bin-Darwin, or bin-Linux Uppaal directory")
-	uppaalFlags    = flag.String("uppaal-flags", "-o0 -s -q", "flags for the Uppaal verifier")
+	uppaalFlags    = flag.String("uppaal-flags", "-o0 -s -q", "flags for the Uppaal verifier (with -t0, -t1, or -t2, traces get included in detailed results)")
 	uppaalProcesss = flag.Int("uppaal-processes", runtime.GOMAXPROCS(0), "number of parallel Uppaal verifier processes")
 
 	shuffleSystems = flag.Bool("shuffle", false, "verify Uppaal systems in random order")
@@ -168,6 +171,7 @@ func generateDetailedResults(systemPath string, outString string) string {
 		return ""
 	}
 	systemString := string(systemBytes)
+	traces := parseTraces(systemString, outString)
 	queryStrings := regexp.MustCompile("(?s:<query>.*?</query>)").FindAllString(systemString, -1)
 	type query struct {
 		index          int
@@ -269,12 +273,42 @@ func generateDetailedResults(systemPath string, outString string) string {
 			if query.sourceLocation != "" {
 				fmt.Fprintf(&b, "\n\t                   %s", query.sourceLocation)
 			}
+			if t, ok := traces[query.index]; ok && !query.satisfied {
+				for _, line := range strings.Split(strings.TrimSuffix(t.String(), "\n"), "\n") {
+					fmt.Fprintf(&b, "\n\t\t%s", line)
+				}
+			}
 		}
 	}
 
 	return b.String()
 }
 
+// parseTraces returns the traces in the verifier output, keyed by the index of
+// the formula they belong to, if the verifier generated traces.
+func parseTraces(systemString string, outString string) map[int]*trace.Trace {
+	if !regexp.MustCompile(`(^|\s)-t[012]`).MatchString(*uppaalFlags) {
+		return nil
+	}
+	sys, err := uppaal.ParseXML(systemString)
+	if err != nil {
+		print(fmt.Sprint(err), true)
+		return nil
+	}
+	parsedTraces, err := trace.Parse(strings.NewReader(outString), sys)
+	if err != nil {
+		print(fmt.Sprint(err), true)
+		return nil
+	}
+	traces := make(map[int]*trace.Trace)
+	for _, t := range parsedTraces {
+		if t.Formula > 0 {
+			traces[t.Formula] = t
+		}
+	}
+	return traces
+}
+
 func printRunningSystems() {
 	status := "\rrunning: "
 	first := true
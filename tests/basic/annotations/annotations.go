@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+var verbose = len(os.Args) > 1
+
+// logLoop logs forever and leaks when main returns.
+func logLoop(logs chan string) {
+	for {
+		<-logs
+	}
+}
+
+// toph: ignore
+func blockForever() {
+	select {}
+}
+
+func worker(jobs <-chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for range jobs {
+	}
+}
+
+// handle gets analyzed on its own, without a caller providing the channel.
+// toph: entry
+func handle(requests chan int) {
+	go func() {
+		requests <- 1
+	}()
+	<-requests
+}
+
+func main() {
+	// toph: assume=false
+	if verbose {
+		go logLoop(make(chan string))
+	}
+
+	go blockForever() // toph: ignore
+	blockForever()
+
+	n := len(os.Args) * 4
+	results := make(chan int, n) // toph: capacity=4
+	for i := 0; i < 4; i++ {
+		results <- i
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	// toph: min_iter=1, max_iter=4
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go worker(jobs, &wg) // toph: max_instances=4
+	}
+	for i := 0; i < 4; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
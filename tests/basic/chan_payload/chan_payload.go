@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+func main() {
+	testRequestResponse()
+	testFuncChannel()
+	testWaitGroupChannel()
+	testSelectReply()
+}
+
+type request struct {
+	n     int
+	reply chan int
+}
+
+func server(requests <-chan request) {
+	for req := range requests {
+		req.reply <- req.n * 2
+	}
+}
+
+func client(requests chan<- request, n int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	reply := make(chan int)
+	requests <- request{n: n, reply: reply}
+	fmt.Println(<-reply)
+}
+
+func testRequestResponse() {
+	requests := make(chan request)
+	go server(requests)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go client(requests, 1, &wg)
+	go client(requests, 2, &wg)
+	wg.Wait()
+	close(requests)
+}
+
+func testFuncChannel() {
+	tasks := make(chan func(), 1)
+	done := make(chan struct{})
+	go func() {
+		task := <-tasks
+		task()
+	}()
+	tasks <- func() {
+		close(done)
+	}
+	<-done
+}
+
+func testWaitGroupChannel() {
+	wgs := make(chan *sync.WaitGroup)
+	go func() {
+		wg := <-wgs
+		wg.Done()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	wgs <- &wg
+	wg.Wait()
+}
+
+func testSelectReply() {
+	replies := make(chan chan string)
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case reply := <-replies:
+				reply <- "pong"
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	reply := make(chan string)
+	replies <- reply
+	fmt.Println(<-reply)
+	close(quit)
+}
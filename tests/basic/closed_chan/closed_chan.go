@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+func main() {
+	testSendOnClosed()
+	testBlockedSender()
+	testCloseClosed()
+	testSelectSendOnClosed()
+	testUnlockUnlocked()
+}
+
+// testSendOnClosed recovers from sending on a closed channel.
+func testSendOnClosed() {
+	defer func() {
+		recover()
+	}()
+	ch := make(chan int, 1)
+	close(ch)
+	ch <- 42
+}
+
+// testBlockedSender closes a channel while a sender is blocked on it. The
+// sender panics and recovers.
+func testBlockedSender() {
+	var wg sync.WaitGroup
+	ch := make(chan int)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			recover()
+		}()
+		ch <- 42
+	}()
+	close(ch)
+	wg.Wait()
+}
+
+// testCloseClosed recovers from closing a closed channel.
+func testCloseClosed() {
+	defer func() {
+		recover()
+	}()
+	ch := make(chan int)
+	close(ch)
+	close(ch)
+}
+
+// testSelectSendOnClosed recovers from selecting a send case on a closed
+// channel.
+func testSelectSendOnClosed() {
+	defer func() {
+		recover()
+	}()
+	ch := make(chan int)
+	close(ch)
+	select {
+	case ch <- 42:
+	case x := <-ch:
+		fmt.Println(x)
+	}
+}
+
+// testUnlockUnlocked unlocks an unlocked mutex, which terminates the program.
+func testUnlockUnlocked() {
+	var mu sync.Mutex
+	mu.Lock()
+	mu.Unlock()
+	mu.Unlock()
+}
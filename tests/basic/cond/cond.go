@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type queue struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	items int
+}
+
+func newQueue() *queue {
+	q := new(queue)
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *queue) put() {
+	q.mu.Lock()
+	q.items++
+	q.cond.Signal()
+	q.mu.Unlock()
+}
+
+func (q *queue) take() {
+	q.mu.Lock()
+	for q.items == 0 {
+		q.cond.Wait()
+	}
+	q.items--
+	q.mu.Unlock()
+}
+
+func main() {
+	q := newQueue()
+	var mu sync.Mutex
+	done := sync.Cond{L: &mu}
+	finished := false
+
+	go func() {
+		q.take()
+		q.take()
+		mu.Lock()
+		finished = true
+		done.Broadcast()
+		mu.Unlock()
+	}()
+	q.put()
+	q.put()
+
+	mu.Lock()
+	for !finished {
+		done.Wait()
+	}
+	mu.Unlock()
+	fmt.Println("done")
+}
@@ -0,0 +1,30 @@
+package main
+
+import "sync"
+
+type queue struct {
+	mu   sync.Mutex
+	cond sync.Cond
+}
+
+func main() {
+	q := &queue{}
+	q.cond.L = &q.mu
+	ready := false // toph: track
+	go func() {
+		q.mu.Lock()
+		ready = true
+		q.cond.Signal()
+		q.mu.Unlock()
+	}()
+	q.mu.Lock()
+	for !ready {
+		q.cond.Wait()
+	}
+	q.mu.Unlock()
+
+	// Signal and Broadcast on a zero value cond without locker are no-ops:
+	var c sync.Cond
+	c.Signal()
+	c.Broadcast()
+}
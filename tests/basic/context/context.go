@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func worker(ctx context.Context, results chan<- int) {
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case results <- i:
+		}
+	}
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subCtx, subCancel := context.WithTimeout(ctx, time.Second)
+	defer subCancel()
+
+	results := make(chan int)
+	go worker(subCtx, results)
+	go worker(context.WithValue(ctx, "key", "value"), results)
+
+	fmt.Println(<-results)
+	cancel()
+	<-subCtx.Done()
+}
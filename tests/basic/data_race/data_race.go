@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type stats struct {
+	hits   int // toph: shared
+	misses int
+}
+
+var total int
+
+// worker only accesses its own instances of stats and n.
+func worker(wg *sync.WaitGroup) {
+	defer wg.Done()
+	s := &stats{}
+	s.hits++
+
+	n := 0
+	done := make(chan bool)
+	go func() {
+		n++
+		done <- true
+	}()
+	<-done
+	n++
+	fmt.Println(s.hits, n)
+}
+
+func main() {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counter := 0
+	s := &stats{}
+
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			counter++ // racy
+
+			mu.Lock()
+			total++ // protected by mu
+			s.hits++
+			mu.Unlock()
+
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	wg.Add(2)
+	go worker(&wg)
+	go worker(&wg)
+	wg.Wait()
+
+	fmt.Println(counter, total, s.hits)
+}
@@ -0,0 +1,42 @@
+package main
+
+import "sync"
+
+type debugServer struct {
+	mu       sync.Mutex
+	requests chan int
+}
+
+// serve handles requests forever and leaks when main returns.
+func (s *debugServer) serve() {
+	for {
+		s.mu.Lock()
+		<-s.requests
+		s.mu.Unlock()
+	}
+}
+
+func startDebugServer() {
+	s := &debugServer{requests: make(chan int)}
+	go s.serve()
+}
+
+// metricsLoop reports metrics forever and leaks when main returns.
+func metricsLoop(metrics chan int) {
+	for {
+		metrics <- 1
+	}
+}
+
+func work(results chan int) {
+	results <- 42
+}
+
+func main() {
+	startDebugServer()
+	go metricsLoop(make(chan int))
+
+	results := make(chan int)
+	go work(results)
+	<-results
+}
@@ -0,0 +1,84 @@
+package main
+
+import "sync"
+
+// Pool hands out a fixed set of resources via a buffered channel.
+type Pool[T any] struct {
+	items chan T
+}
+
+func NewPool[T any](a, b T) *Pool[T] {
+	p := &Pool[T]{items: make(chan T, 2)}
+	p.Put(a)
+	p.Put(b)
+	return p
+}
+
+func (p *Pool[T]) Get() T {
+	return <-p.items
+}
+
+func (p *Pool[T]) Put(item T) {
+	p.items <- item
+}
+
+// SafeMap guards a map with a mutex.
+type SafeMap[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]V
+}
+
+func (s *SafeMap[K, V]) Store(k K, v V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[k] = v
+}
+
+func (s *SafeMap[K, V]) Load(k K) V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[k]
+}
+
+// Send sends the value on the channel in a new goroutine.
+func Send[T any](ch chan T, v T) {
+	go func() {
+		ch <- v
+	}()
+}
+
+func pool() {
+	p := NewPool(make(chan int, 1), make(chan int, 1))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ch := p.Get()
+			ch <- 42
+			<-ch
+			p.Put(ch)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func safeMap() {
+	s := &SafeMap[string, int]{m: make(map[string]int)}
+	done := make(chan struct{})
+	go func() {
+		s.Store("a", 1)
+		close(done)
+	}()
+	s.Store("b", 2)
+	<-done
+	ch := make(chan int)
+	Send(ch, s.Load("a"))
+	<-ch
+	Send(make(chan string), "leak")
+}
+
+func main() {
+	pool()
+	safeMap()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+func stop() {
+	runtime.Goexit()
+}
+
+// worker exits its goroutine via a helper, the deferred call still runs.
+func worker(wg *sync.WaitGroup) {
+	defer wg.Done()
+	stop()
+	wg.Add(1)
+}
+
+// recoverer can not recover from runtime.Goexit and never sends.
+func recoverer(ch chan int, done chan struct{}) {
+	defer close(done)
+	defer func() {
+		recover()
+	}()
+	runtime.Goexit()
+	ch <- 1
+}
+
+func main() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go worker(&wg)
+	wg.Wait()
+
+	ch := make(chan int)
+	done := make(chan struct{})
+	go recoverer(ch, done)
+	<-done
+}
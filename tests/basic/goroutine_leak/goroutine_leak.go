@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+func leakingSender(results chan<- int) {
+	results <- 42
+}
+
+func main() {
+	results := make(chan int)
+	go leakingSender(results)
+
+	var mu sync.Mutex
+	mu.Lock()
+	go func() {
+		mu.Lock()
+		fmt.Println("never")
+		mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		wg.Wait()
+	}()
+
+	fmt.Println("done")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Store interface {
+	Put(key string, value int)
+	Get(key string) int
+}
+
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]int
+}
+
+func (s *memStore) Put(key string, value int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+func (s *memStore) Get(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+type chanStore struct {
+	ch chan int
+}
+
+func (s chanStore) Put(key string, value int) {
+	s.ch <- value
+}
+
+func (s chanStore) Get(key string) int {
+	return <-s.ch
+}
+
+func use(s Store, done chan<- bool) {
+	s.Put("a", 1)
+	fmt.Println(s.Get("a"))
+	done <- true
+}
+
+func main() {
+	done := make(chan bool)
+	go use(&memStore{values: make(map[string]int)}, done)
+	go use(chanStore{ch: make(chan int, 1)}, done)
+	<-done
+	<-done
+}
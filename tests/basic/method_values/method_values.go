@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type handlerFunc func(x int)
+
+type server struct {
+	mu    sync.Mutex
+	count int
+	done  chan bool
+}
+
+func (s *server) worker() {
+	s.mu.Lock()
+	s.count++
+	s.mu.Unlock()
+	s.done <- true
+}
+
+func (s *server) handle(x int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Println(x)
+}
+
+func main() {
+	s := &server{done: make(chan bool)}
+
+	f := s.worker
+	go f()
+	<-s.done
+
+	h := handlerFunc(s.handle)
+	h(42)
+
+	unlock := s.mu.Unlock
+	s.mu.Lock()
+	s.count++
+	unlock()
+
+	g := (*server).worker
+	go g(s)
+	<-s.done
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	done := wg.Done
+	go func() {
+		defer done()
+		s.handle(1)
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+func main() {
+	testDisabledCase()
+	testNilReceive()
+	testCloseNil()
+}
+
+// testDisabledCase waits on a select with a case disabled by a nil channel.
+func testDisabledCase() {
+	ch := make(chan int)
+	var disabled chan int
+	go func() {
+		ch <- 42
+	}()
+
+	select {
+	case x := <-ch:
+		fmt.Println(x)
+	case disabled <- 0:
+		panic("unreachable")
+	}
+}
+
+// testNilReceive leaks a goroutine blocked on a nil channel.
+func testNilReceive() {
+	var ch chan int
+	go func() {
+		<-ch
+	}()
+}
+
+// testCloseNil recovers from closing a nil channel.
+func testCloseNil() {
+	defer func() {
+		recover()
+	}()
+	var ch chan int
+	close(ch)
+}
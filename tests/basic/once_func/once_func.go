@@ -0,0 +1,38 @@
+package main
+
+import (
+	"sync"
+)
+
+func onceFunc() {
+	ch := make(chan int)
+	closeCh := sync.OnceFunc(func() {
+		close(ch)
+	})
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			closeCh()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	<-ch
+}
+
+func onceValues() {
+	results := make(chan int, 1)
+	getResults := sync.OnceValues(func() (chan int, error) {
+		results <- 42
+		return results, nil
+	})
+	getResults()
+	res, _ := getResults()
+	<-res
+}
+
+func main() {
+	onceFunc()
+	onceValues()
+}
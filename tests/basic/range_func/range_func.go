@@ -0,0 +1,99 @@
+package main
+
+import "iter"
+
+// values yields the values received from the channel until it gets closed.
+func values(ch chan int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// count yields the first two natural numbers.
+func count() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := range 2 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// both yields the two given channels.
+func both(a, b chan int) iter.Seq[chan int] {
+	return func(yield func(chan int) bool) {
+		if !yield(a) {
+			return
+		}
+		yield(b)
+	}
+}
+
+// rangeFuncBreak stops after the first value, the producer does not block
+// because the channel is buffered.
+func rangeFuncBreak() {
+	ch := make(chan int, 2)
+	done := make(chan struct{})
+	go func() {
+		ch <- 1
+		ch <- 2
+		close(ch)
+		close(done)
+	}()
+	for range values(ch) {
+		break
+	}
+	<-done
+}
+
+// rangeFuncContinue skips some iterations but still sends on every other one.
+func rangeFuncContinue() {
+	ch := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	for i := range count() {
+		if i%2 == 0 {
+			continue
+		}
+		ch <- i
+	}
+	close(ch)
+	<-done
+}
+
+// firstChanNested returns the first channel yielded by the outer iterator
+// from within the loop body of the inner iterator.
+func firstChanNested(a, b chan int) chan int {
+	for x := range both(a, b) {
+		for range both(b, a) {
+			return x
+		}
+	}
+	return nil
+}
+
+// rangeFuncNestedReturn receives from the channel returned from within the
+// nested loop body.
+func rangeFuncNestedReturn() {
+	a := make(chan int)
+	b := make(chan int)
+	go func() {
+		a <- 1
+	}()
+	<-firstChanNested(a, b)
+}
+
+func main() {
+	rangeFuncBreak()
+	rangeFuncContinue()
+	rangeFuncNestedReturn()
+}
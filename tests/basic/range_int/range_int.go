@@ -0,0 +1,32 @@
+package main
+
+// rangeInt receives exactly as many values as the goroutines send.
+func rangeInt() {
+	ch := make(chan int)
+	for i := range 3 {
+		go func() {
+			ch <- i
+		}()
+	}
+	for range 3 {
+		<-ch
+	}
+}
+
+// rangeIntConst fills the buffer of the channel exactly, one more send would
+// block forever.
+func rangeIntConst() {
+	const n = 2
+	ch := make(chan int, n*2)
+	for range n * 2 {
+		ch <- 0
+	}
+	for range n * 2 {
+		<-ch
+	}
+}
+
+func main() {
+	rangeInt()
+	rangeIntConst()
+}
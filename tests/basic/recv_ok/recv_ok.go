@@ -0,0 +1,72 @@
+package main
+
+import "sync"
+
+func producer(ch chan<- int, n int) {
+	for i := 0; i < n; i++ {
+		ch <- i
+	}
+	close(ch)
+}
+
+// consumer only returns after the channel got closed and drained.
+func consumer(ch <-chan int, done chan<- struct{}) {
+	for {
+		v, ok := <-ch
+		if !ok {
+			break
+		}
+		_ = v
+	}
+	done <- struct{}{}
+}
+
+// selectConsumer only returns after the channel got closed and drained.
+func selectConsumer(ch <-chan int, quit <-chan struct{}) {
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return
+			}
+			_ = v
+		case <-quit:
+			return
+		}
+	}
+}
+
+func main() {
+	ch := make(chan int)
+	done := make(chan struct{})
+	go producer(ch, 3)
+	go consumer(ch, done)
+	<-done
+
+	buffered := make(chan int, 2)
+	buffered <- 1
+	buffered <- 2
+	close(buffered)
+	if _, ok := <-buffered; !ok {
+		<-make(chan int)
+	}
+	if _, ok := <-buffered; !ok {
+		<-make(chan int)
+	}
+	if _, ok := <-buffered; ok {
+		<-make(chan int)
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan int)
+	quit := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		selectConsumer(results, quit)
+	}()
+	results <- 1
+	close(results)
+	wg.Wait()
+	close(quit)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// main has a mutex related deadlock: the pending writer blocks the second
+// RLock, which in turn prevents the first read lock from getting released.
+func main() {
+	var mu sync.RWMutex
+	mu.RLock()
+	done := make(chan struct{})
+	go func() {
+		mu.Lock()
+		mu.Unlock()
+		close(done)
+	}()
+	time.Sleep(1 * time.Millisecond)
+	mu.RLock()
+	mu.RUnlock()
+	mu.RUnlock()
+	<-done
+}
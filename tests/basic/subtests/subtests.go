@@ -0,0 +1,21 @@
+package subtests
+
+// Fixture is shared by the subtests of a test.
+type Fixture struct {
+	ready chan struct{}
+}
+
+// NewFixture returns a fixture that is not ready yet.
+func NewFixture() *Fixture {
+	return &Fixture{ready: make(chan struct{})}
+}
+
+// Ready marks the fixture as ready.
+func (f *Fixture) Ready() {
+	close(f.ready)
+}
+
+// Await blocks until the fixture is ready.
+func (f *Fixture) Await() {
+	<-f.ready
+}
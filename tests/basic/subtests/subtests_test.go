@@ -0,0 +1,29 @@
+package subtests
+
+import "testing"
+
+// TestRun relies on t.Run waiting for the subtest to finish.
+func TestRun(t *testing.T) {
+	f := NewFixture()
+	t.Run("ready", func(t *testing.T) {
+		f.Ready()
+	})
+	select {
+	case <-f.ready:
+	default:
+		t.Fatal("subtest did not finish")
+	}
+}
+
+// TestParallel relies on parallel subtests being paused until the parent
+// test returned.
+func TestParallel(t *testing.T) {
+	f := NewFixture()
+	for range 2 {
+		t.Run("await", func(t *testing.T) {
+			t.Parallel()
+			f.Await()
+		})
+	}
+	f.Ready()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+func worker(id int, results chan<- int) {
+	results <- id
+}
+
+func main() {
+	n := len(os.Args) // toph: track
+	results := make(chan int)
+	// toph: track
+	for i := 0; i < n; i++ {
+		go worker(i, results)
+	}
+	// toph: track
+	for i := 0; i < n; i++ {
+		<-results
+	}
+
+	verbose := n > 2 // toph: track
+	var mu sync.Mutex
+	if verbose {
+		mu.Lock()
+	}
+	if !verbose {
+		mu.Lock()
+	}
+	mu.Unlock()
+
+	done := false // toph: track
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.Lock()
+		done = true
+		mu.Unlock()
+	}()
+	wg.Wait()
+	if !done {
+		<-make(chan int)
+	}
+
+	count := 0 // toph: track
+	for count < 3 {
+		count += 1
+		results := make(chan int, 1)
+		results <- count
+		<-results
+	}
+	if count != 3 {
+		<-make(chan int)
+	}
+}
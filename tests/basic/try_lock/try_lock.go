@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+)
+
+// tryLock only unlocks the mutex if it acquired it. Any path where the failed
+// branch unlocks or the successful branch leaks the lock gets reported.
+func tryLock() {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			defer wg.Done()
+			if mu.TryLock() {
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	mu.Unlock()
+}
+
+// tryRLock fails while the writer holds the lock and otherwise shares the
+// lock with other readers.
+func tryRLock() {
+	var mu sync.RWMutex
+	done := make(chan struct{})
+	go func() {
+		mu.Lock()
+		mu.Unlock()
+		close(done)
+	}()
+	if !mu.TryRLock() {
+		<-done
+		mu.RLock()
+	}
+	mu.RUnlock()
+	<-done
+}
+
+// tryLockResult stores the result of TryLock, which fails while the mutex is
+// locked, such that the mutex only gets unlocked once.
+func tryLockResult() {
+	var mu sync.Mutex
+	mu.Lock()
+	ok := mu.TryLock()
+	if ok {
+		mu.Unlock()
+	}
+	mu.Unlock()
+}
+
+// rLocker uses the read lock through the sync.Locker returned by RLocker.
+// The writer can not proceed until the reader released the read lock.
+func rLocker() {
+	var mu sync.RWMutex
+	mu.RLocker().Lock()
+	done := make(chan struct{})
+	go func() {
+		mu.Lock()
+		close(done)
+		mu.Unlock()
+	}()
+	mu.RLocker().Unlock()
+	<-done
+}
+
+func main() {
+	tryLock()
+	tryRLock()
+	tryLockResult()
+	rLocker()
+}
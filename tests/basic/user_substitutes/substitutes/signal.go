@@ -0,0 +1,47 @@
+// Package substitutes models os/signal and time.Ticker.
+//
+// toph: substitute=os/signal
+package substitutes
+
+import (
+	"os"
+	"time"
+)
+
+// Notify delivers a single signal to c.
+func Notify(c chan<- os.Signal, sig ...os.Signal) {
+	go func() {
+		c <- nil
+	}()
+}
+
+func Stop(c chan<- os.Signal) {}
+
+// ticker models *time.Ticker values.
+//
+// toph: substitute=time.Ticker
+type ticker struct {
+	C    chan time.Time
+	stop chan struct{}
+}
+
+func (t *ticker) Stop() {
+	close(t.stop)
+}
+
+// toph: substitute=time.NewTicker
+func newTicker(d time.Duration) *ticker {
+	t := new(ticker)
+	t.C = make(chan time.Time, 1)
+	t.stop = make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-t.stop:
+				return
+			case t.C <- time.Time{}:
+			}
+		}
+	}()
+	return t
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"time"
+)
+
+// awaitSignal only terminates if signal.Notify delivers a signal.
+func awaitSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	<-ch
+	signal.Stop(ch)
+}
+
+// tick receives two ticks, the ticker sends until it gets stopped.
+func tick() {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for i := 0; i < 2; i++ {
+		<-t.C
+	}
+}
+
+func main() {
+	awaitSignal()
+	tick()
+}
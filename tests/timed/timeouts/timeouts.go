@@ -0,0 +1,67 @@
+// Programs in tests/timed rely on timed mode: the checked select case in
+// fastWorkerBeforeTimeout is only unreachable with clocks. Translate them with
+// toph -timed and verify them with Uppaal's verifyta, since the built-in
+// model checker does not support clocks:
+//
+//	toph -timed -out-formats xml,q ./tests/timed/timeouts
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func worker(results chan<- int, d time.Duration) {
+	time.Sleep(d)
+	results <- 42
+}
+
+func fastWorkerBeforeTimeout() {
+	results := make(chan int)
+	go worker(results, 10*time.Millisecond)
+
+	select {
+	case r := <-results:
+		fmt.Println(r)
+	// Only unreachable in timed mode:
+	// toph: check=unreachable
+	case <-time.After(100 * time.Millisecond):
+		fmt.Println("timeout")
+	}
+}
+
+func slowWorkerAfterTimer() {
+	results := make(chan int, 1)
+	go worker(results, 200*time.Millisecond)
+
+	timer := time.NewTimer(50 * time.Millisecond)
+	select {
+	case r := <-results:
+		timer.Stop()
+		fmt.Println(r)
+	case <-timer.C:
+		fmt.Println("timeout")
+	}
+}
+
+func contextTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	results := make(chan int, 1)
+	go worker(results, 5*time.Millisecond)
+
+	select {
+	case r := <-results:
+		fmt.Println(r)
+	case <-ctx.Done():
+		fmt.Println("cancelled")
+	}
+}
+
+func main() {
+	fastWorkerBeforeTimeout()
+	slowWorkerAfterTimer()
+	contextTimeout()
+}
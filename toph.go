@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/arneph/toph/api"
 	c "github.com/arneph/toph/config"
@@ -16,7 +17,8 @@ var (
 	goos   = flag.String("goos", build.Default.GOOS, "target operating system, e.g. windows, linux")
 	goarch = flag.String("goarch", build.Default.GOARCH, "target architecture, e.g. 386, amd64")
 
-	excludeFile = flag.String("exclude", "", "set file containing a list of packages to exclude from translation")
+	excludeFile      = flag.String("exclude", "", "set file containing a list of packages and patterns of functions, methods, and types (e.g. pkg.Func, (*pkg.T).Method, pkg.*Debug*) to exclude from translation")
+	substitutesFiles = flag.String("substitutes", "", "set comma separated list of Go files or directories containing substitutes for functions, methods, and types (annotated with 'toph: substitute=...')")
 
 	debug = flag.Bool("debug", false, "generate debug output files")
 
@@ -26,6 +28,7 @@ var (
 	maxMutexCount     = flag.Int("max-mutexes", 20, "set maximum number of sync.Mutexes and sync.RWMutexes in Uppaal")
 	maxWaitGroupCount = flag.Int("max-wait-groups", 20, "set maximum number of sync.WaitGroups in Uppaal")
 	maxOnceCount      = flag.Int("max-once", 20, "set maximum number of sync.Once in Uppaal")
+	maxCondCount      = flag.Int("max-conds", 20, "set maximum number of sync.Conds in Uppaal")
 	maxStructCount    = flag.Int("max-structs", 20, "set maximum number of struct instances (per defined struct) in Uppaal")
 	maxContainerCount = flag.Int("max-containers", 20, "set maximum number of array, slice, or map instances (per element type) in Uppaal")
 
@@ -34,19 +37,33 @@ var (
 	queryChannelSafety              = flag.Bool("query-channel-safety", false, "generate queries checking for channel safety")
 	queryMutexSafety                = flag.Bool("query-mutex-safety", false, "generate queries checking for sync.Mutex and sync.RWMutex safety")
 	queryWaitGroupSafety            = flag.Bool("query-wait-group-safety", false, "generate queries checking for sync.WaitGroup safety")
+	queryCondSafety                 = flag.Bool("query-cond-safety", false, "generate queries checking for sync.Cond safety")
 	queryChannelRelatedDeadlock     = flag.Bool("query-channel-deadlock", false, "generate queries checking for channel related deadlocks")
 	queryMutexRelatedDeadlock       = flag.Bool("query-mutex-deadlock", false, "generate queries checking for sync.Mutex and sync.RWMutex related deadlocks")
 	queryWaitGroupRelatedDeadlock   = flag.Bool("query-wait-group-deadlock", false, "generate queries checking for sync.WaitGroup related deadlocks")
 	queryOnceRelatedDeadlock        = flag.Bool("query-once-deadlock", false, "generate queries checking for sync.Once related deadlocks")
+	queryCondRelatedDeadlock        = flag.Bool("query-cond-deadlock", false, "generate queries checking for sync.Cond related deadlocks")
 	queryFunctionCallsWithNil       = flag.Bool("query-function-call-with-nil", false, "generate queries checking for function calls with nil variables")
+	queryCloseOfNilChannel          = flag.Bool("query-close-nil-channel", false, "generate queries checking for closing nil channels")
 	queryGoroutineExitWithPanic     = flag.Bool("query-goroutine-exit-with-panic", false, "generate queries checking for goroutines exiting with a panic")
+	queryGoroutineLeak              = flag.Bool("query-goroutine-leak", false, "generate queries checking for goroutines blocked when the entry function returns")
+	queryDataRace                   = flag.Bool("query-data-race", false, "generate queries checking for data races on shared variables (annotated with 'toph: shared' or inferred)")
 	queryReachability               = flag.Bool("query-reachability", false, "generate queries checking for the (un)reachability of code (requires annotations)")
 
 	containerCapacity = flag.Int("container-capacity", 5, "set the constant capacity of arrays, slices, and maps in Uppaal")
+	trackedIntMax     = flag.Int("tracked-int-max", 4, "set the largest value of tracked ints (annotated with 'toph: track') that get assigned unknown values")
 
 	optimizeIR     = flag.Bool("optimize-ir", true, "optimize intermediate representation of program")
 	optimizeSystem = flag.Bool("optimize-sys", true, "optimize uppaal system")
 
+	timed    = flag.Bool("timed", false, "model time.Sleep, time.After, time.NewTimer, and context.WithTimeout with Uppaal clocks (not supported by the built-in model checker)")
+	timeUnit = flag.Duration("time-unit", time.Millisecond, "set the duration of one clock unit in timed mode")
+
+	check            = flag.Bool("check", false, "verify generated systems with the built-in model checker instead of Uppaal")
+	maxCheckedStates = flag.Int("check-max-states", 1000000, "set maximum number of states explored by the built-in model checker per system")
+
+	report = flag.String("report", "", "print a machine-readable report to stdout instead of warnings and checker results, supports: json, sarif")
+
 	outName    = flag.String("out", "a", "set name out output files")
 	outFormats = flag.String("out-formats", "xml", "set comma separated, generated output file formats, supports: xml, xta, ugi, q")
 )
@@ -56,34 +73,51 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Usage: toph [flags] [package directories]\n\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\n")
-		fmt.Fprintf(os.Stderr, "Note: If none of the query flags are set, all kinds of queries, excpet individual resource bound queries, are generated.\n")
+		fmt.Fprintf(os.Stderr, "Note: If none of the query flags are set, all kinds of queries, except individual resource bound and data race queries, are generated.\n")
 	}
 	flag.Parse()
 	if flag.NArg() < 1 {
 		flag.Usage()
 		return
 	}
+	if *report != "" && *report != "json" && *report != "sarif" {
+		fmt.Fprintf(os.Stderr, "unsupported report format: %s\n", *report)
+		os.Exit(-1)
+	}
+	if *timed && *timeUnit <= 0 {
+		fmt.Fprintf(os.Stderr, "time unit must be positive: %v\n", *timeUnit)
+		os.Exit(-1)
+	}
 	if !*queryResourceBounds &&
 		!*queryChannelSafety &&
 		!*queryMutexSafety &&
 		!*queryWaitGroupSafety &&
+		!*queryCondSafety &&
 		!*queryChannelRelatedDeadlock &&
 		!*queryMutexRelatedDeadlock &&
 		!*queryWaitGroupRelatedDeadlock &&
 		!*queryOnceRelatedDeadlock &&
+		!*queryCondRelatedDeadlock &&
 		!*queryFunctionCallsWithNil &&
+		!*queryCloseOfNilChannel &&
 		!*queryGoroutineExitWithPanic &&
+		!*queryGoroutineLeak &&
+		!*queryDataRace &&
 		!*queryReachability {
 		*queryResourceBounds = true
 		*queryChannelSafety = true
 		*queryMutexSafety = true
 		*queryWaitGroupSafety = true
+		*queryCondSafety = true
 		*queryChannelRelatedDeadlock = true
 		*queryMutexRelatedDeadlock = true
 		*queryWaitGroupRelatedDeadlock = true
 		*queryOnceRelatedDeadlock = true
+		*queryCondRelatedDeadlock = true
 		*queryFunctionCallsWithNil = true
+		*queryCloseOfNilChannel = true
 		*queryGoroutineExitWithPanic = true
+		*queryGoroutineLeak = true
 		*queryReachability = true
 	}
 	buildContext := build.Default
@@ -102,27 +136,41 @@ func main() {
 		MaxMutexCount:                           *maxMutexCount,
 		MaxWaitGroupCount:                       *maxWaitGroupCount,
 		MaxOnceCount:                            *maxOnceCount,
+		MaxCondCount:                            *maxCondCount,
 		MaxStructCount:                          *maxStructCount,
 		MaxContainerCount:                       *maxContainerCount,
 		ContainerCapacity:                       *containerCapacity,
+		TrackedIntMax:                           *trackedIntMax,
 		GenerateResourceBoundQueries:            *queryResourceBounds,
 		GenerateIndividualResourceBoundQueries:  *queryResourceBoundsIndividually,
 		GenerateChannelSafetyQueries:            *queryChannelSafety,
 		GenerateMutexSafetyQueries:              *queryMutexSafety,
 		GenerateWaitGroupSafetyQueries:          *queryWaitGroupSafety,
+		GenerateCondSafetyQueries:               *queryCondSafety,
 		GenerateChannelRelatedDeadlockQueries:   *queryChannelRelatedDeadlock,
 		GenerateMutexRelatedDeadlockQueries:     *queryMutexRelatedDeadlock,
 		GenerateWaitGroupRelatedDeadlockQueries: *queryWaitGroupRelatedDeadlock,
 		GenerateOnceRelatedDeadlockQueries:      *queryOnceRelatedDeadlock,
+		GenerateCondRelatedDeadlockQueries:      *queryCondRelatedDeadlock,
 		GenerateFunctionCallsWithNilQueries:     *queryFunctionCallsWithNil,
+		GenerateCloseOfNilChannelQueries:        *queryCloseOfNilChannel,
 		GenerateGoroutineExitWithPanicQueries:   *queryGoroutineExitWithPanic,
+		GenerateGoroutineLeakQueries:            *queryGoroutineLeak,
+		GenerateDataRaceQueries:                 *queryDataRace,
 		GenerateReachabilityQueries:             *queryReachability,
 		OptimizeIR:                              *optimizeIR,
 		OptimizeUppaalSystem:                    *optimizeSystem,
+		Timed:                                   *timed,
+		TimeUnit:                                *timeUnit,
+		Check:                                   *check,
+		MaxCheckedStates:                        *maxCheckedStates,
 		Debug:                                   *debug,
 		OutName:                                 *outName,
 		OutFormats:                              ffmts,
 	}
+	if *substitutesFiles != "" {
+		config.SubstitutesFiles = strings.Split(*substitutesFiles, ",")
+	}
 	if *excludeFile != "" {
 		content, err := ioutil.ReadFile(*excludeFile)
 		if err != nil {
@@ -130,15 +178,35 @@ func main() {
 			os.Exit(-1)
 		}
 		lines := strings.Split(string(content), "\n")
-		for _, packagePath := range lines {
-			if packagePath == "" {
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
 				continue
 			}
-			config.SetExcludeEntirePackage(packagePath)
+			config.SetExclude(line)
 		}
 	}
 
-	result := api.Run(flag.Args(), &config)
+	if *report == "" {
+		result := api.Run(flag.Args(), &config)
+
+		os.Exit(int(result))
+	}
+
+	r := api.Analyze(flag.Args(), &config)
+	var out []byte
+	var err error
+	switch *report {
+	case "json":
+		out, err = r.JSON()
+	case "sarif":
+		out, err = r.SARIF()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate report: %v\n", err)
+		os.Exit(-1)
+	}
+	fmt.Println(string(out))
 
-	os.Exit(int(result))
+	os.Exit(int(r.Result))
 }
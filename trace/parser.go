@@ -0,0 +1,233 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/toph/uppaal"
+)
+
+var (
+	formulaRegex    = regexp.MustCompile(`^Verifying formula (\d+)`)
+	transitionRegex = regexp.MustCompile(`^(\w+)\.(\w+)\s*->\s*(\w+)\.(\w+)\s*(?:\{(.*)\})?$`)
+	locationRegex   = regexp.MustCompile(`(\w+)\.(\w+)`)
+)
+
+type parser struct {
+	sys       *uppaal.System
+	queries   []*uppaal.Query
+	instances map[string]*uppaal.ProcessInstance
+	states    map[*uppaal.Process]map[string]*uppaal.State
+
+	traces  []*Trace
+	current *Trace
+	formula int
+	line    int
+}
+
+// Parse parses the output of Uppaal's verifyta for the given system, including
+// the symbolic or concrete traces generated with the -t0, -t1, or -t2 option.
+// It returns all traces in the order they appear. Each trace gets associated
+// with the preceding formula, if any. Lines unrelated to traces are ignored.
+func Parse(r io.Reader, sys *uppaal.System) ([]*Trace, error) {
+	p := new(parser)
+	p.sys = sys
+	p.queries = sys.AllQueries()
+	p.instances = make(map[string]*uppaal.ProcessInstance)
+	p.states = make(map[*uppaal.Process]map[string]*uppaal.State)
+	for _, inst := range sys.ProcessInstances() {
+		p.instances[inst.Name()] = inst
+	}
+	for _, proc := range sys.Processes() {
+		p.states[proc] = make(map[string]*uppaal.State)
+		for _, state := range proc.States() {
+			p.states[proc][state.Name()] = state
+		}
+	}
+
+	const (
+		other = iota
+		stateLocations
+		stateValues
+		transitions
+	)
+	section := other
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		p.line++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			section = other
+		case formulaRegex.MatchString(line):
+			n, _ := strconv.Atoi(formulaRegex.FindStringSubmatch(line)[1])
+			p.formula = n
+			p.current = nil
+			section = other
+		case strings.HasPrefix(line, "Showing"):
+			p.current = nil
+			section = other
+		case line == "State:" || line == "State":
+			if p.current == nil {
+				p.current = New(sys, nil)
+				p.current.Formula = p.formula
+				if 0 < p.formula && p.formula <= len(p.queries) {
+					p.current.Query = p.queries[p.formula-1]
+				}
+				p.traces = append(p.traces, p.current)
+			}
+			section = stateLocations
+		case line == "Transitions:" || line == "Transition:" ||
+			line == "Transitions" || line == "Transition":
+			if p.current == nil {
+				return nil, p.errorf("transitions outside of trace")
+			}
+			p.current.Steps = append(p.current.Steps, nil)
+			section = transitions
+		case strings.HasPrefix(line, "Delay:"):
+			section = other
+		case section == stateLocations:
+			if err := p.checkLocations(line); err != nil {
+				return nil, err
+			}
+			section = stateValues
+		case section == transitions:
+			tr, err := p.parseTransition(line)
+			if err != nil {
+				return nil, err
+			}
+			steps := p.current.Steps
+			steps[len(steps)-1] = append(steps[len(steps)-1], tr)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, t := range p.traces {
+		for _, step := range t.Steps {
+			if len(step) == 0 || len(step) > 2 {
+				return nil, fmt.Errorf("trace for formula %d: step with %d transitions", t.Formula, len(step))
+			}
+		}
+	}
+	return p.traces, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) lookup(instName, stateName string) (*uppaal.ProcessInstance, *uppaal.State, error) {
+	inst, ok := p.instances[instName]
+	if !ok {
+		return nil, nil, p.errorf("unknown process instance: %s", instName)
+	}
+	state, ok := p.states[inst.Process()][stateName]
+	if !ok {
+		return nil, nil, p.errorf("unknown state of %s: %s", instName, stateName)
+	}
+	return inst, state, nil
+}
+
+// checkLocations checks that the locations of a state in the trace match the
+// locations reached with the transitions parsed so far.
+func (p *parser) checkLocations(line string) error {
+	if !strings.HasPrefix(line, "(") || !strings.HasSuffix(line, ")") {
+		return p.errorf("expected state locations: %s", line)
+	}
+	locations := make(map[*uppaal.ProcessInstance]*uppaal.State)
+	for _, inst := range p.sys.ProcessInstances() {
+		locations[inst] = inst.Process().InitialState()
+	}
+	for _, step := range p.current.Steps {
+		for _, tr := range step {
+			locations[tr.Instance] = tr.Trans.End()
+		}
+	}
+	for _, m := range locationRegex.FindAllStringSubmatch(line, -1) {
+		inst, state, err := p.lookup(m[1], m[2])
+		if err != nil {
+			return err
+		}
+		if locations[inst] != state {
+			return p.errorf("trace does not match system: expected %s.%s, got %s.%s",
+				inst.Name(), locations[inst].Name(), inst.Name(), state.Name())
+		}
+	}
+	return nil
+}
+
+// parseTransition parses a transition of the form
+// "Inst.start -> Inst.end { guard, sync, update }" and finds the matching
+// transition in the system.
+func (p *parser) parseTransition(line string) (Transition, error) {
+	m := transitionRegex.FindStringSubmatch(line)
+	if m == nil {
+		return Transition{}, p.errorf("expected transition: %s", line)
+	}
+	if m[1] != m[3] {
+		return Transition{}, p.errorf("transition between process instances: %s", line)
+	}
+	inst, start, err := p.lookup(m[1], m[2])
+	if err != nil {
+		return Transition{}, err
+	}
+	_, end, err := p.lookup(m[3], m[4])
+	if err != nil {
+		return Transition{}, err
+	}
+	candidates := inst.Process().TransitionLookup()[start][end]
+	if len(candidates) == 0 {
+		return Transition{}, p.errorf("unknown transition: %s", line)
+	}
+	// Uppaal might print guards differently from the system, therefore
+	// matching syncs suffices if guards do not match.
+	guard, sync := parseLabel(m[5])
+	for _, matchGuard := range []bool{true, false} {
+		for _, trans := range candidates {
+			if sync == removeSpaces(trans.Sync()) &&
+				(!matchGuard || guard == removeSpaces(trans.Guard())) {
+				return Transition{Instance: inst, Trans: trans}, nil
+			}
+		}
+	}
+	return Transition{Instance: inst, Trans: candidates[0]}, nil
+}
+
+// parseLabel returns the guard and sync of a transition label of the form
+// "guard, sync, update" without spaces. Uppaal prints "1" for missing guards
+// and "tau" for missing syncs, these get returned as empty strings.
+func parseLabel(label string) (guard, sync string) {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range label {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, removeSpaces(label[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, removeSpaces(label[start:]))
+	if len(parts) > 0 && parts[0] != "1" {
+		guard = parts[0]
+	}
+	if len(parts) > 1 && parts[1] != "tau" {
+		sync = parts[1]
+	}
+	return guard, sync
+}
+
+func removeSpaces(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
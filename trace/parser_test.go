@@ -0,0 +1,293 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arneph/toph/uppaal"
+)
+
+// testXTA models a Main goroutine starting a worker goroutine, which calls a
+// helper function that sends to Main, in the way the translator models
+// goroutines and function calls.
+const testXTA = `int x = 0;
+chan async_worker;
+chan sync_helper;
+chan ch;
+
+process Main() {
+state m0, m1, m2, m3;
+init m0;
+trans m0 -> m1 { sync async_worker!; },
+m1 -> m2 { sync ch?; },
+m2 -> m3 { guard x == 0; assign x = 1; };
+}
+
+process worker(int id) {
+state w0, w1, w2, w3;
+init w0;
+trans w0 -> w1 { sync async_worker?; },
+w1 -> w2 { sync sync_helper!; },
+w2 -> w3 { sync sync_helper?; };
+}
+
+process helper(int id) {
+state h0, h1, h2;
+init h0;
+trans h0 -> h1 { sync sync_helper?; },
+h1 -> h2 { sync ch!; },
+h2 -> h0 { sync sync_helper!; };
+}
+
+worker0 = worker(0);
+helper0 = helper(0);
+system Main, worker0, helper0;
+`
+
+// testPositions are the Go source positions stored in the state comments.
+var testPositions = map[string]string{
+	"m1": "main.go:10:2",
+	"m2": "main.go:11:2",
+	"m3": "main.go:12:1",
+	"w1": "main.go:4:2",
+	"w2": "main.go:5:2",
+	"w3": "main.go:6:1",
+	"h1": "helper.go:3:2",
+	"h2": "helper.go:4:2",
+}
+
+// testOutput is verifyta output for testXTA with -t0 (formula 1, old format)
+// and -t1 (formula 3, new format). Formula 2 is satisfied.
+const testOutput = `Options for the verification:
+  Generating some trace
+  Search order is breadth first
+
+Verifying formula 1 at /tmp/a.q:2
+ -- Formula is NOT satisfied.
+Showing counter example.
+State:
+( Main.m0 worker0.w0 helper0.h0 )
+x=0
+
+Transitions:
+  Main.m0 -> Main.m1 { 1, async_worker!, 1 }
+  worker0.w0 -> worker0.w1 { 1, async_worker?, 1 }
+
+State:
+( Main.m1 worker0.w1 helper0.h0 )
+x=0
+
+Transitions:
+  worker0.w1 -> worker0.w2 { 1, sync_helper!, 1 }
+  helper0.h0 -> helper0.h1 { 1, sync_helper?, 1 }
+
+State:
+( Main.m1 worker0.w2 helper0.h1 )
+x=0
+
+Transitions:
+  helper0.h1 -> helper0.h2 { 1, ch!, 1 }
+  Main.m1 -> Main.m2 { 1, ch?, 1 }
+
+State:
+( Main.m2 worker0.w2 helper0.h2 )
+x=0
+
+Transitions:
+  helper0.h2 -> helper0.h0 { 1, sync_helper!, 1 }
+  worker0.w2 -> worker0.w3 { 1, sync_helper?, 1 }
+
+State:
+( Main.m2 worker0.w3 helper0.h0 )
+x=0
+
+Transitions:
+  Main.m2 -> Main.m3 { x == 0, tau, x := 1 }
+
+State:
+( Main.m3 worker0.w3 helper0.h0 )
+x=1
+
+Verifying formula 2 at /tmp/a.q:4
+ -- Formula is satisfied.
+
+Verifying formula 3 at /tmp/a.q:6
+ -- Formula is NOT satisfied.
+Showing example trace.
+State
+( Main.m0 worker0.w0 helper0.h0 )
+x=0
+
+Transition
+  Main.m0 -> Main.m1 { 1, async_worker!, 1 }
+  worker0.w0 -> worker0.w1 { 1, async_worker?, 1 }
+
+State
+( Main.m1 worker0.w1 helper0.h0 )
+x=0
+
+Transition
+  worker0.w1 -> worker0.w2 { 1, sync_helper!, 1 }
+  helper0.h0 -> helper0.h1 { 1, sync_helper?, 1 }
+
+State
+( Main.m1 worker0.w2 helper0.h1 )
+x=0
+`
+
+func newTestSystem(t *testing.T) *uppaal.System {
+	t.Helper()
+	sys, err := uppaal.ParseXTA(testXTA)
+	if err != nil {
+		t.Fatalf("ParseXTA failed: %v", err)
+	}
+	for _, proc := range sys.Processes() {
+		for _, state := range proc.States() {
+			state.SetComment(testPositions[state.Name()])
+		}
+	}
+	for _, q := range []string{"A[] not deadlock", "A[] x <= 1", "E<> Main.m3"} {
+		sys.AddQuery(uppaal.NewQuery(q, "", "", uppaal.ReachabilityRequirements))
+	}
+	return sys
+}
+
+func parseTestOutput(t *testing.T) []*Trace {
+	t.Helper()
+	traces, err := Parse(strings.NewReader(testOutput), newTestSystem(t))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("got %d traces, want 2", len(traces))
+	}
+	return traces
+}
+
+func TestParse(t *testing.T) {
+	traces := parseTestOutput(t)
+	for i, want := range []struct {
+		formula int
+		query   string
+		steps   []string
+	}{
+		{1, "A[] not deadlock", []string{
+			"Main: m0 -> m1, worker0: w0 -> w1",
+			"worker0: w1 -> w2, helper0: h0 -> h1",
+			"helper0: h1 -> h2, Main: m1 -> m2",
+			"helper0: h2 -> h0, worker0: w2 -> w3",
+			"Main: m2 -> m3",
+		}},
+		{3, "E<> Main.m3", []string{
+			"Main: m0 -> m1, worker0: w0 -> w1",
+			"worker0: w1 -> w2, helper0: h0 -> h1",
+		}},
+	} {
+		tr := traces[i]
+		if tr.Formula != want.formula {
+			t.Errorf("trace %d: got formula %d, want %d", i, tr.Formula, want.formula)
+		}
+		if tr.Query == nil || tr.Query.Query() != want.query {
+			t.Errorf("trace %d: got query %v, want %s", i, tr.Query, want.query)
+		}
+		var steps []string
+		for _, step := range tr.Steps {
+			var s []string
+			for _, transition := range step {
+				s = append(s, transition.String())
+			}
+			steps = append(steps, strings.Join(s, ", "))
+		}
+		if got := strings.Join(steps, "\n"); got != strings.Join(want.steps, "\n") {
+			t.Errorf("trace %d: got steps:\n%s\nwant:\n%s", i, got, strings.Join(want.steps, "\n"))
+		}
+	}
+	if guard := traces[0].Steps[4][0].Trans.Guard(); guard != "x == 0" {
+		t.Errorf("got internal transition with guard %q, want \"x == 0\"", guard)
+	}
+}
+
+func TestInterleaving(t *testing.T) {
+	traces := parseTestOutput(t)
+
+	events, goroutines := traces[0].Interleaving()
+	var got []string
+	for _, e := range events {
+		g := "-"
+		if e.Goroutine != nil {
+			g = e.Goroutine.Name()
+		}
+		got = append(got, g+" "+e.Instance.Name()+" "+e.Position.String())
+	}
+	want := []string{
+		"Main Main main.go:10:2",
+		"worker0 worker0 main.go:4:2",
+		"worker0 worker0 main.go:5:2",
+		"worker0 helper0 helper.go:3:2",
+		"worker0 helper0 helper.go:4:2",
+		"Main Main main.go:11:2",
+		"worker0 helper0 helper.go:4:2",
+		"worker0 worker0 main.go:6:1",
+		"Main Main main.go:12:1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if events[2].Partner == nil || events[2].Partner.Instance.Name() != "helper0" {
+		t.Errorf("expected call of helper0 as partner of worker0")
+	}
+	if len(goroutines) != 2 {
+		t.Fatalf("got %d goroutines, want 2", len(goroutines))
+	}
+	for _, g := range goroutines {
+		if !g.Done || len(g.Stack) != 1 {
+			t.Errorf("goroutine %d: expected to be done with one stack frame, got %d frames in %s",
+				g.ID, len(g.Stack), g.State.Name())
+		}
+	}
+
+	_, goroutines = traces[1].Interleaving()
+	if len(goroutines) != 2 {
+		t.Fatalf("got %d goroutines, want 2", len(goroutines))
+	}
+	main, worker := goroutines[0], goroutines[1]
+	if main.ID != 1 || main.Name() != "Main" || main.Done || main.Position.String() != "main.go:10:2" {
+		t.Errorf("got goroutine %d %s (done: %t) at %s, want goroutine 1 Main at main.go:10:2",
+			main.ID, main.Name(), main.Done, main.Position)
+	}
+	if worker.ID != 2 || len(worker.Stack) != 2 || worker.Stack[1].Name() != "helper0" ||
+		worker.State.Name() != "h1" || worker.Position.String() != "helper.go:3:2" {
+		t.Errorf("got goroutine %d with %d frames in %s at %s, want goroutine 2 in helper0.h1 at helper.go:3:2",
+			worker.ID, len(worker.Stack), worker.State.Name(), worker.Position)
+	}
+	if s := traces[1].String(); !strings.Contains(s, "goroutine 2 [worker0 > helper0] in h1\n\thelper.go:3:2") {
+		t.Errorf("expected blocked worker in trace:\n%s", s)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	traces, err := Parse(strings.NewReader(""), newTestSystem(t))
+	if err != nil || len(traces) != 0 {
+		t.Errorf("got %d traces, %v, want no traces and no error", len(traces), err)
+	}
+	traces, err = Parse(strings.NewReader("Verifying formula 1 at /tmp/a.q:2\n -- Formula is satisfied.\n"), newTestSystem(t))
+	if err != nil || len(traces) != 0 {
+		t.Errorf("got %d traces, %v, want no traces and no error", len(traces), err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, output := range []string{
+		"Transitions:\n  Main.m0 -> Main.m1 { 1, async_worker!, 1 }\n",
+		"State:\n( Main.m0 )\n\nTransitions:\n  Main.m0 -> Main.m1 {\n",
+		"State:\n( Main.m0 )\n\nTransitions:\n  Main.m0 -> worker0.w1 { 1, tau, 1 }\n",
+		"State:\n( Main.m0 )\n\nTransitions:\n  nobody.m0 -> nobody.m1 { 1, tau, 1 }\n",
+		"State:\n( Main.m0 )\n\nTransitions:\n  Main.m0 -> Main.m3 { 1, tau, 1 }\n",
+		"State:\n( Main.m1 )\n",
+		"State:\n( Main.m0 )\n\nTransitions:\n\nState:\n( Main.m0 )\n",
+	} {
+		if _, err := Parse(strings.NewReader(output), newTestSystem(t)); err == nil {
+			t.Errorf("expected error for:\n%s", output)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package trace maps traces of Uppaal systems generated by the translator
+// back to the goroutines and Go source positions they originate from. Traces
+// can be parsed from the output of Uppaal's verifyta or be constructed from
+// other sources, such as the built-in model checker.
+package trace
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/uppaal"
+)
+
+// Transition is a transition taken by a process instance.
+type Transition struct {
+	Instance *uppaal.ProcessInstance
+	Trans    *uppaal.Trans
+}
+
+func (t Transition) String() string {
+	return fmt.Sprintf("%s: %s -> %s", t.Instance.Name(), t.Trans.Start().Name(), t.Trans.End().Name())
+}
+
+func (t Transition) isSend() bool {
+	return strings.HasSuffix(strings.TrimSpace(t.Trans.Sync()), "!")
+}
+
+func (t Transition) channel() string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(t.Trans.Sync()), "!?"))
+}
+
+// Step is a step in a trace. It consists of either a single internal
+// transition or the sending and receiving transitions of a channel
+// synchronization.
+type Step []Transition
+
+// Trace is a sequence of steps of a system, starting in its initial state.
+type Trace struct {
+	System *uppaal.System
+	// Formula is the number of the formula in the q file the trace belongs to,
+	// or 0 if unknown.
+	Formula int
+	// Query is the query the trace belongs to, or nil if unknown.
+	Query *uppaal.Query
+	Steps []Step
+}
+
+// New returns a trace for the system consisting of the given steps.
+func New(sys *uppaal.System, steps []Step) *Trace {
+	t := new(Trace)
+	t.System = sys
+	t.Steps = steps
+	return t
+}
+
+// Goroutine represents a goroutine of the Go program, executed by a stack of
+// function process instances.
+type Goroutine struct {
+	// ID is the number of the goroutine in order of creation, starting at 1.
+	ID int
+	// Stack holds the function process instances executing the goroutine at
+	// the end of the trace, from the outermost to the innermost call.
+	Stack []*uppaal.ProcessInstance
+	// State is the location of the innermost instance at the end of the trace.
+	State *uppaal.State
+	// Position is the Go source position of State, if known.
+	Position token.Position
+	// Done indicates if the goroutine terminated.
+	Done bool
+}
+
+// Name returns the name of the outermost function process instance of the
+// goroutine.
+func (g *Goroutine) Name() string {
+	return g.Stack[0].Name()
+}
+
+// Event is a transition of a trace, attributed to a goroutine.
+type Event struct {
+	// Step is the index of the step the transition is part of.
+	Step int
+	Transition
+	// Partner is the other transition of a channel synchronization, if any.
+	Partner *Transition
+	// Goroutine is the goroutine executing the transition, or nil for
+	// transitions of other process instances, e.g. mutexes or channels.
+	Goroutine *Goroutine
+	// Position is the Go source position reached with the transition, if
+	// known.
+	Position token.Position
+}
+
+// Interleaving returns the events of the trace in order and all goroutines
+// in order of creation, with their call stacks and locations at the end of
+// the trace.
+//
+// Goroutines get tracked through the synchronizations generated by the
+// translator: the instances without parameters execute the initial goroutine
+// (the init function), receiving on an async_ channel starts a new goroutine,
+// and receiving on a sync_ channel calls a function within the goroutine of
+// the sender.
+func (t *Trace) Interleaving() ([]*Event, []*Goroutine) {
+	locations := make(map[*uppaal.ProcessInstance]*uppaal.State)
+	stacks := make(map[*uppaal.ProcessInstance]*Goroutine)
+	var goroutines []*Goroutine
+	instances := t.System.ProcessInstances()
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name() < instances[j].Name()
+	})
+	for _, inst := range instances {
+		locations[inst] = inst.Process().InitialState()
+		if len(inst.Parameters()) == 0 && len(inst.Process().Parameters()) == 0 {
+			g := &Goroutine{ID: len(goroutines) + 1, Stack: []*uppaal.ProcessInstance{inst}}
+			goroutines = append(goroutines, g)
+			stacks[inst] = g
+		}
+	}
+
+	var events []*Event
+	for i, step := range t.Steps {
+		var sender, receiver *Transition
+		if len(step) == 2 {
+			sender, receiver = &step[0], &step[1]
+			if !sender.isSend() {
+				sender, receiver = receiver, sender
+			}
+		}
+		if sender != nil {
+			ch := receiver.channel()
+			g := stacks[sender.Instance]
+			switch {
+			case strings.HasPrefix(ch, "async_"):
+				g := &Goroutine{ID: len(goroutines) + 1, Stack: []*uppaal.ProcessInstance{receiver.Instance}}
+				goroutines = append(goroutines, g)
+				stacks[receiver.Instance] = g
+			case strings.HasPrefix(ch, "sync_") && g != nil && stacks[receiver.Instance] == nil:
+				g.Stack = append(g.Stack, receiver.Instance)
+				stacks[receiver.Instance] = g
+			case strings.HasPrefix(ch, "sync_") && g != nil && stacks[receiver.Instance] == g &&
+				len(g.Stack) > 1 && g.Stack[len(g.Stack)-1] == sender.Instance:
+				g.Stack = g.Stack[:len(g.Stack)-1]
+			}
+		}
+
+		for j := range step {
+			tr := step[j]
+			locations[tr.Instance] = tr.Trans.End()
+			e := &Event{
+				Step:       i,
+				Transition: tr,
+				Goroutine:  stacks[tr.Instance],
+				Position:   position(tr.Trans),
+			}
+			if len(step) == 2 {
+				e.Partner = &step[1-j]
+			}
+			events = append(events, e)
+		}
+	}
+
+	for _, g := range goroutines {
+		inst := g.Stack[len(g.Stack)-1]
+		g.State = locations[inst]
+		g.Position = diag.ParsePosition(g.State.Comment())
+		g.Done = len(g.Stack) == 1 && len(g.State.OutgoingTransitions()) == 0
+	}
+	return events, goroutines
+}
+
+// String returns a readable representation of the trace. It lists the
+// events of all goroutines in order, followed by the locations of all
+// goroutines that did not terminate.
+func (t *Trace) String() string {
+	var b strings.Builder
+	events, goroutines := t.Interleaving()
+	width := 0
+	for _, e := range events {
+		if n := len(e.Instance.Name()); n > width {
+			width = n
+		}
+	}
+	for _, e := range events {
+		if e.Goroutine == nil && e.Partner != nil {
+			// Described by the event of the partner.
+			continue
+		}
+		goroutine := "-"
+		if e.Goroutine != nil {
+			goroutine = fmt.Sprintf("goroutine %d", e.Goroutine.ID)
+		}
+		fmt.Fprintf(&b, "%4d %-12s %-*s %s", e.Step+1, goroutine, width, e.Instance.Name(), e.Trans.End().Name())
+		if e.Partner != nil {
+			op := "with"
+			if e.isSend() {
+				op = "to"
+			} else if e.Partner.isSend() {
+				op = "from"
+			}
+			fmt.Fprintf(&b, " (%s %s %s)", strings.TrimSpace(e.Trans.Sync()), op, e.Partner.Instance.Name())
+		}
+		if e.Position.IsValid() {
+			fmt.Fprintf(&b, "\n%*s%s", 18+width, "", e.Position)
+		}
+		b.WriteString("\n")
+	}
+	for _, g := range goroutines {
+		if g.Done {
+			continue
+		}
+		names := make([]string, len(g.Stack))
+		for i, inst := range g.Stack {
+			names[i] = inst.Name()
+		}
+		fmt.Fprintf(&b, "goroutine %d [%s] in %s", g.ID, strings.Join(names, " > "), g.State.Name())
+		if g.Position.IsValid() {
+			fmt.Fprintf(&b, "\n\t%s", g.Position)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// position returns the Go source position stored in the comment of the end
+// state of the transition or, if not available, its start state.
+func position(trans *uppaal.Trans) token.Position {
+	pos := diag.ParsePosition(trans.End().Comment())
+	if !pos.IsValid() {
+		pos = diag.ParsePosition(trans.Start().Comment())
+	}
+	return pos
+}
@@ -40,8 +40,21 @@ func (t *translator) translateIfStmt(stmt *ir.IfStmt, ctx *context) {
 	ifExit.SetLocationAndResetNameAndCommentLocation(
 		uppaal.Location{ctx.currentState.Location()[0], maxY + 136})
 
-	ctx.proc.AddTransition(ctx.currentState, ifEnter)
-	ctx.proc.AddTransition(ctx.currentState, elseEnter)
+	enterIf := ctx.proc.AddTransition(ctx.currentState, ifEnter)
+	enterElse := ctx.proc.AddTransition(ctx.currentState, elseEnter)
+	if stmt.Cond() != nil {
+		var rvs randomVariableSupplier
+		ifGuard, elseGuard, usesGlobals := t.translateCondGuards(stmt.Cond(), &rvs, ctx)
+		if stmt.IsCondNegated() {
+			ifGuard, elseGuard = elseGuard, ifGuard
+		}
+		enterIf.SetGuard(ifGuard, usesGlobals)
+		rvs.addToTrans(enterIf)
+		enterIf.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
+		enterElse.SetGuard(elseGuard, usesGlobals)
+		rvs.addToTrans(enterElse)
+		enterElse.SetGuardLocation(elseEnter.Location().Add(uppaal.Location{4, -72}))
+	}
 
 	ctx.currentState = ifExit
 	ctx.addLocation(ifEnter.Location())
@@ -198,6 +211,11 @@ func (t *translator) translateForStmt(stmt *ir.ForStmt, ctx *context) {
 	trans2 := ctx.proc.AddTransition(condExit, bodyEnter)
 	if stmt.HasMaxIterations() {
 		trans2.SetGuard(fmt.Sprintf("%s < %d", counterVar, stmt.MaxIterations()), false)
+	}
+	if stmt.CondValue() != nil {
+		t.addLoopCondGuard(trans2, stmt, true, ctx)
+	}
+	if trans2.Guard() != "" {
 		trans2.SetGuardLocation(condExit.Location().Add(uppaal.Location{4, 60}))
 	}
 
@@ -205,6 +223,11 @@ func (t *translator) translateForStmt(stmt *ir.ForStmt, ctx *context) {
 		trans3 := ctx.proc.AddTransition(condExit, loopExit)
 		if stmt.HasMinIterations() {
 			trans3.SetGuard(fmt.Sprintf("%s >= %d", counterVar, stmt.MinIterations()), false)
+		}
+		if stmt.CondValue() != nil {
+			t.addLoopCondGuard(trans3, stmt, false, ctx)
+		}
+		if trans3.Guard() != "" {
 			trans3.SetGuardLocation(condExit.Location().Add(uppaal.Location{-132, 60}))
 		}
 
@@ -225,6 +248,33 @@ func (t *translator) translateForStmt(stmt *ir.ForStmt, ctx *context) {
 	ctx.addLocationsFromSubContext(condSubCtx)
 }
 
+// addLoopCondGuard adds a guard to the given transition out of the loop
+// condition, requiring the condition value of the loop to continue the loop
+// or to exit it.
+func (t *translator) addLoopCondGuard(trans *uppaal.Trans, stmt *ir.ForStmt, continues bool, ctx *context) {
+	var rvs randomVariableSupplier
+	continueGuard, exitGuard, usesGlobals := t.translateCondGuards(stmt.CondValue(), &rvs, ctx)
+	if continues != stmt.IsCondValueNegated() {
+		rvs.addGuard(continueGuard, usesGlobals)
+	} else {
+		rvs.addGuard(exitGuard, usesGlobals)
+	}
+	rvs.addToTrans(trans)
+}
+
+// translateCondGuards returns the guards for the given int condition being
+// true (non-zero) and false (zero).
+func (t *translator) translateCondGuards(cond ir.RValue, rvs *randomVariableSupplier, ctx *context) (trueGuard, falseGuard string, usesGlobals bool) {
+	handle, usesGlobals := t.translateRValue(cond, rvs, ctx)
+	if e, ok := cond.(*ir.BinaryExpr); ok {
+		switch e.Op() {
+		case ir.Equal, ir.NotEqual, ir.Less, ir.LessEqual, ir.Greater, ir.GreaterEqual, ir.And, ir.Or:
+			return handle, "!" + handle, usesGlobals
+		}
+	}
+	return handle + " != 0", handle + " == 0", usesGlobals
+}
+
 func (t *translator) translateChanRangeStmt(stmt *ir.ChanRangeStmt, ctx *context) {
 	var rvs randomVariableSupplier
 	handle, usesGlobals := t.translateLValue(stmt.Channel(), &rvs, ctx)
@@ -243,11 +293,14 @@ func (t *translator) translateChanRangeStmt(stmt *ir.ChanRangeStmt, ctx *context
 	receiving.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
 	receiving.SetLocationAndResetNameAndCommentLocation(
 		rangeEnter.Location().Add(uppaal.Location{0, 136}))
+	t.addNilChannelBlock(rangeEnter, "range_receiving_nil_"+name+"_", channelVar, nil, stmt.Pos(), ctx)
 	trigger := ctx.proc.AddTransition(rangeEnter, receiving)
+	trigger.SetGuard(channelVar+" >= 0", false)
 	trigger.SetSync("receiver_trigger[" + channelVar + "]!")
 	trigger.AddUpdate("chan_counter["+channelVar+"]--", usesGlobals)
 	trigger.AddUpdate("ok = chan_counter["+channelVar+"] >= 0", usesGlobals)
 	trigger.SetSyncLocation(rangeEnter.Location().Add(uppaal.Location{4, 48}))
+	trigger.SetGuardLocation(rangeEnter.Location().Add(uppaal.Location{4, 32}))
 	trigger.SetUpdateLocation(rangeEnter.Location().Add(uppaal.Location{4, 64}))
 	received := ctx.proc.AddState("range_received_"+name+"_", uppaal.Renaming)
 	received.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
@@ -256,8 +309,13 @@ func (t *translator) translateChanRangeStmt(stmt *ir.ChanRangeStmt, ctx *context
 		receiving.Location().Add(uppaal.Location{0, 136}))
 	confirm := ctx.proc.AddTransition(receiving, received)
 	confirm.SetSync("receiver_confirm[" + channelVar + "]?")
+	if stmt.PayloadType() != nil {
+		t.addChanReceiverConfirmUpdates(confirm, channelVar, stmt.PayloadType(), stmt.ValueVal(), ctx)
+	}
 	confirm.SetSyncLocation(
 		receiving.Location().Add(uppaal.Location{4, 60}))
+	confirm.SetUpdateLocation(
+		receiving.Location().Add(uppaal.Location{4, 76}))
 
 	if t.config.GenerateChannelRelatedDeadlockQueries {
 		ctx.proc.AddQuery(uppaal.NewQuery(
@@ -267,6 +325,8 @@ func (t *translator) translateChanRangeStmt(stmt *ir.ChanRangeStmt, ctx *context
 			uppaal.NoChannelRelatedDeadlocks))
 	}
 
+	t.addGoroutineLeakQuery(receiving, "channel", stmt.Pos(), ctx)
+
 	bodyEnter, bodyExit, loopExit := t.translateLoopBody(stmt, body, received.Location().Add(uppaal.Location{0, 136}), ctx)
 
 	trans1 := ctx.proc.AddTransition(ctx.currentState, rangeEnter)
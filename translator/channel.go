@@ -3,6 +3,7 @@ package translator
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/arneph/toph/ir"
 	"github.com/arneph/toph/uppaal"
@@ -18,6 +19,104 @@ func (t *translator) channelCount() int {
 	return channelCount
 }
 
+// chanQueueCapacity returns the number of values that can be held by the
+// payload queue of a channel. The queue contains the values of buffered sends
+// and blocked senders.
+func (t *translator) chanQueueCapacity() int {
+	capacity := t.config.ContainerCapacity
+	for _, f := range t.program.Funcs() {
+		if t.isFuncUsed(f) {
+			capacity += t.callCount(f)
+		}
+	}
+	return capacity
+}
+
+// chanPayloadKinds returns whether any used function transfers values through
+// channels that are represented by Uppaal ints or fids respectively.
+func (t *translator) chanPayloadKinds() (usesInts, usesFids bool) {
+	addPayloadType := func(payloadType ir.Type) {
+		if payloadType == nil {
+			return
+		} else if t.uppaalReferenceTypeForIrType(payloadType) == "fid" {
+			usesFids = true
+		} else {
+			usesInts = true
+		}
+	}
+	for _, f := range t.program.Funcs() {
+		if !t.isFuncUsed(f) {
+			continue
+		}
+		f.Body().WalkStmts(func(stmt ir.Stmt, scope *ir.Scope) {
+			switch stmt := stmt.(type) {
+			case *ir.ChanCommOpStmt:
+				addPayloadType(stmt.PayloadType())
+			case *ir.SelectStmt:
+				for _, c := range stmt.Cases() {
+					addPayloadType(c.OpStmt().PayloadType())
+				}
+			case *ir.ChanRangeStmt:
+				addPayloadType(stmt.PayloadType())
+			}
+		})
+	}
+	return
+}
+
+// usesChanPayloads returns whether any used function transfers values through
+// channels.
+func (t *translator) usesChanPayloads() bool {
+	usesInts, usesFids := t.chanPayloadKinds()
+	return usesInts || usesFids
+}
+
+// usesChanReceiveOks returns whether any used function uses the ok result of
+// receive operations, which the Channel process then records in chan_received
+// for every completed receive operation.
+func (t *translator) usesChanReceiveOks() bool {
+	usesOks := false
+	for _, f := range t.program.Funcs() {
+		if !t.isFuncUsed(f) {
+			continue
+		}
+		f.Body().WalkStmts(func(stmt ir.Stmt, scope *ir.Scope) {
+			switch stmt := stmt.(type) {
+			case *ir.ChanCommOpStmt:
+				usesOks = usesOks || stmt.Ok() != nil
+			case *ir.SelectStmt:
+				for _, c := range stmt.Cases() {
+					usesOks = usesOks || c.OpStmt().Ok() != nil
+				}
+			}
+		})
+	}
+	return usesOks
+}
+
+// chanPayloadSuffix returns the suffix of the queue functions handling values
+// of the given type.
+func (t *translator) chanPayloadSuffix(payloadType ir.Type) string {
+	if t.uppaalReferenceTypeForIrType(payloadType) == "fid" {
+		return "_fid"
+	}
+	return ""
+}
+
+// chanSenderToken returns an expression uniquely identifying the current
+// process instance of the given function among all senders.
+func (t *translator) chanSenderToken(f *ir.Func) string {
+	offset := 0
+	for _, g := range t.program.Funcs() {
+		if g == f {
+			break
+		} else if t.isFuncUsed(g) {
+			offset += t.callCount(g)
+		}
+	}
+	return fmt.Sprintf("%d + pid", offset)
+}
+
 func (t *translator) addChannels() {
 	if t.channelCount() == 0 {
 		return
@@ -35,13 +134,8 @@ func (t *translator) addChannelProcess() {
 	// Parameters:
 	proc.AddParameter(fmt.Sprintf("int[0, %d] i", t.channelCount()-1))
 
-	// Queries:
-	if t.config.GenerateChannelSafetyQueries {
-		proc.AddQuery(uppaal.NewQuery(
-			"A[] (not out_of_resources) imply (not $.bad)",
-			"check Channel.bad state unreachable", "",
-			uppaal.ChannelSafety))
-	}
+	// Local Declarations:
+	proc.Declarations().AddVariable("buffer", "int", "0")
 
 	// States:
 	// Open
@@ -84,15 +178,15 @@ func (t *translator) addChannelProcess() {
 	confirmingClosed.SetType(uppaal.Committed)
 	confirmingClosed.SetLocationAndResetNameAndCommentLocation(uppaal.Location{442, -34})
 
-	// Bad
-	bad := proc.AddState("bad", uppaal.NoRenaming)
-	bad.SetLocation(uppaal.Location{102, -102})
-	bad.SetNameLocation(uppaal.Location{54, -134})
-
 	// Transitions:
 	// Open, Sender
 	trans1 := proc.AddTransition(idle, newSender)
 	trans1.SetSync("sender_trigger[i]?")
+	if t.usesChanPayloads() {
+		// Release the new sender if it does not need to block:
+		trans1.AddUpdate("chan_set_release(i, chan_queue_len[i] - 1)", true)
+		trans1.SetUpdateLocation(uppaal.Location{129, 322})
+	}
 	trans1.SetSyncLocation(uppaal.Location{129, 306})
 
 	trans2 := proc.AddTransition(newSender, idle)
@@ -115,6 +209,10 @@ func (t *translator) addChannelProcess() {
 	trans5.SetGuard("chan_counter[i] <= 0", true)
 	trans5.SetSync("receiver_confirm[i]!")
 	trans5.AddNail(uppaal.Location{204, 442})
+	if t.usesChanReceiveOks() {
+		trans5.AddUpdate("chan_received[i] = 1", true)
+		trans5.SetUpdateLocation(uppaal.Location{118, 474})
+	}
 	trans5.SetGuardLocation(uppaal.Location{118, 442})
 	trans5.SetSyncLocation(uppaal.Location{118, 458})
 
@@ -132,6 +230,16 @@ func (t *translator) addChannelProcess() {
 	trans8 := proc.AddTransition(newReceiver, confirmingB)
 	trans8.SetGuard("chan_counter[i] >= 0", true)
 	trans8.SetSync("receiver_confirm[i]!")
+	if t.usesChanReceiveOks() {
+		trans8.AddUpdate("chan_received[i] = 1", true)
+		trans8.SetUpdateLocation(uppaal.Location{446, 390})
+	}
+	if t.usesChanPayloads() {
+		// Release the oldest blocked sender, whose value moves into the
+		// buffer or gets received:
+		trans8.AddUpdate("chan_set_release(i, chan_buffer[i])", true)
+		trans8.SetUpdateLocation(uppaal.Location{446, 390})
+	}
 	trans8.SetGuardLocation(uppaal.Location{446, 358})
 	trans8.SetSyncLocation(uppaal.Location{446, 374})
 
@@ -148,17 +256,23 @@ func (t *translator) addChannelProcess() {
 
 	// Closing
 	trans11 := proc.AddTransition(idle, closing)
-	trans11.SetGuard("chan_counter[i] <= chan_buffer[i]", true)
 	trans11.SetSync("close[i]?")
-	trans11.AddUpdate("chan_buffer[i] = -1", true)
-	trans11.SetGuardLocation(uppaal.Location{276, 126})
-	trans11.SetSyncLocation(uppaal.Location{276, 142})
-	trans11.SetUpdateLocation(uppaal.Location{276, 158})
+	trans11.AddUpdate("buffer = chan_buffer[i]", false)
+	trans11.AddUpdate("\nchan_buffer[i] = -1", true)
+	if t.usesChanPayloads() {
+		// Release the newest blocked sender, if any:
+		trans11.AddUpdate("\nchan_set_release(i, chan_queue_len[i] - 1)", true)
+	}
+	trans11.SetSyncLocation(uppaal.Location{276, 126})
+	trans11.SetUpdateLocation(uppaal.Location{276, 142})
 
 	trans12 := proc.AddTransition(closing, closing)
 	trans12.SetGuard("chan_counter[i] < 0", true)
 	trans12.SetSync("receiver_confirm[i]!")
 	trans12.AddUpdate("chan_counter[i]++", true)
+	if t.usesChanReceiveOks() {
+		trans12.AddUpdate("\nchan_received[i] = 0", true)
+	}
 	trans12.AddNail(uppaal.Location{340, 51})
 	trans12.AddNail(uppaal.Location{340, 119})
 	trans12.SetGuardLocation(uppaal.Location{344, 68})
@@ -166,19 +280,24 @@ func (t *translator) addChannelProcess() {
 	trans12.SetUpdateLocation(uppaal.Location{344, 100})
 
 	trans13 := proc.AddTransition(closing, closed)
-	trans13.SetGuard("chan_counter[i] >= 0", true)
+	trans13.SetGuard("chan_counter[i] >= 0 && \nchan_counter[i] <= buffer", true)
 	trans13.SetGuardLocation(uppaal.Location{276, -2})
-	trans13.SetUpdateLocation(uppaal.Location{276, 14})
-
-	trans14 := proc.AddTransition(idle, bad)
-	trans14.SetGuard("chan_counter[i] > \nchan_buffer[i]", true)
-	trans14.SetSync("close[i]?")
-	trans14.AddUpdate("chan_buffer[i] = -1", true)
-	trans14.AddNail(uppaal.Location{272, 170})
-	trans14.AddNail(uppaal.Location{102, 170})
-	trans14.SetGuardLocation(uppaal.Location{106, 10})
-	trans14.SetSyncLocation(uppaal.Location{106, 42})
-	trans14.SetUpdateLocation(uppaal.Location{106, 58})
+
+	// Blocked senders panic:
+	trans14 := proc.AddTransition(closing, closing)
+	trans14.SetGuard("chan_counter[i] > buffer", true)
+	trans14.SetSync("sender_confirm[i]!")
+	trans14.AddUpdate("chan_counter[i]--", true)
+	if t.usesChanPayloads() {
+		// The released sender withdraws its value, release the next newest
+		// blocked sender:
+		trans14.AddUpdate("\nchan_set_release(i, chan_queue_len[i] - 2)", true)
+	}
+	trans14.AddNail(uppaal.Location{204, 119})
+	trans14.AddNail(uppaal.Location{204, 51})
+	trans14.SetGuardLocation(uppaal.Location{8, 68})
+	trans14.SetSyncLocation(uppaal.Location{8, 84})
+	trans14.SetUpdateLocation(uppaal.Location{8, 100})
 
 	// Closed
 	trans15 := proc.AddTransition(closed, confirmingClosed)
@@ -187,32 +306,31 @@ func (t *translator) addChannelProcess() {
 
 	trans16 := proc.AddTransition(confirmingClosed, closed)
 	trans16.SetSync("receiver_confirm[i]!")
-	trans16.AddUpdate("chan_counter[i] = (chan_counter[i] >= 0) ? chan_counter[i] : 0", true)
+	counterUpdate := "chan_counter[i] = (chan_counter[i] >= 0) ? chan_counter[i] : 0"
+	if t.usesChanReceiveOks() {
+		// Receivers get buffered values until the channel is drained:
+		trans16.AddUpdate("chan_received[i] = (chan_counter[i] >= 0) ? 1 : 0", true)
+		counterUpdate = "\n" + counterUpdate
+	}
+	trans16.AddUpdate(counterUpdate, true)
 	trans16.AddNail(uppaal.Location{408, -102})
 	trans16.AddNail(uppaal.Location{306, -102})
 	trans16.SetSyncLocation(uppaal.Location{298, -118})
 	trans16.SetUpdateLocation(uppaal.Location{298, -102})
-
-	trans17 := proc.AddTransition(closed, bad)
-	trans17.SetSync("sender_trigger[i]?")
-	trans17.AddNail(uppaal.Location{136, -34})
-	trans17.SetSyncLocation(uppaal.Location{129, -34})
-
-	trans18 := proc.AddTransition(closed, bad)
-	trans18.SetSync("close[i]?")
-	trans18.AddNail(uppaal.Location{238, -102})
-	trans18.SetSyncLocation(uppaal.Location{129, -118})
 }
 
 func (t *translator) addChannelDeclarations() {
 	t.system.Declarations().AddVariable("chan_count", "int", "0")
 	t.system.Declarations().AddArray("chan_counter", []int{t.channelCount()}, "int")
 	t.system.Declarations().AddArray("chan_buffer", []int{t.channelCount()}, "int")
-	t.system.Declarations().AddArray("sender_trigger", []int{t.channelCount()}, "chan")
-	t.system.Declarations().AddArray("sender_confirm", []int{t.channelCount()}, "chan")
-	t.system.Declarations().AddArray("receiver_trigger", []int{t.channelCount()}, "chan")
-	t.system.Declarations().AddArray("receiver_confirm", []int{t.channelCount()}, "chan")
-	t.system.Declarations().AddArray("close", []int{t.channelCount()}, "chan")
+	if t.usesChanReceiveOks() {
+		t.system.Declarations().AddArray("chan_received", []int{t.channelCount()}, "int")
+	}
+	t.system.Declarations().AddArray("sender_trigger", []int{t.channelCount()}, t.chanType())
+	t.system.Declarations().AddArray("sender_confirm", []int{t.channelCount()}, t.chanType())
+	t.system.Declarations().AddArray("receiver_trigger", []int{t.channelCount()}, t.chanType())
+	t.system.Declarations().AddArray("receiver_confirm", []int{t.channelCount()}, t.chanType())
+	t.system.Declarations().AddArray("close", []int{t.channelCount()}, t.chanType())
 	t.system.Declarations().AddSpaceBetweenVariables()
 
 	t.system.Declarations().AddFunc(fmt.Sprintf(
@@ -230,6 +348,18 @@ func (t *translator) addChannelDeclarations() {
 	return cid;
 }`, t.channelCount()))
 
+	t.system.Declarations().AddFunc(
+		`void chan_counter_update(int cid, int delta) {
+	if (cid < 0) {
+		return;
+	}
+	chan_counter[cid] += delta;
+}`)
+
+	if t.usesChanPayloads() {
+		t.addChannelPayloadDeclarations()
+	}
+
 	if t.config.GenerateIndividualResourceBoundQueries {
 		t.system.AddQuery(uppaal.NewQuery(
 			fmt.Sprintf("A[] chan_count < %d", t.channelCount()+1),
@@ -239,6 +369,129 @@ func (t *translator) addChannelDeclarations() {
 	}
 }
 
+// addChannelPayloadDeclarations adds a queue for the values transferred
+// through each channel. Every queued value is tagged with the token of its
+// sender. The Channel process stores the token of the sender that may
+// complete its operation in chan_release, ensuring that the released sender is
+// the one whose value got received or moved into the buffer.
+func (t *translator) addChannelPayloadDeclarations() {
+	type queue struct {
+		name, suffix, typ, zero string
+	}
+	var queues []queue
+	usesInts, usesFids := t.chanPayloadKinds()
+	if usesInts {
+		queues = append(queues, queue{"chan_queue_value", "", "int", "0"})
+	}
+	if usesFids {
+		queues = append(queues, queue{"chan_queue_fid", "_fid", "fid", t.translateValue(ir.FuncType.UninitializedValue())})
+	}
+	queues = append(queues, queue{"chan_queue_sender", "", "int", "0"})
+
+	for _, q := range queues {
+		t.system.Declarations().AddArray(q.name, []int{t.channelCount(), t.chanQueueCapacity()}, q.typ)
+	}
+	t.system.Declarations().AddArray("chan_queue_len", []int{t.channelCount()}, "int")
+	t.system.Declarations().AddArray("chan_release", []int{t.channelCount()}, "int")
+	t.system.Declarations().AddSpaceBetweenVariables()
+
+	var shiftCancelled, clearCancelled, shiftPopped, clearPopped strings.Builder
+	for _, q := range queues {
+		fmt.Fprintf(&shiftCancelled, "\n\t\t\t%[1]s[cid][j] = %[1]s[cid][i];", q.name)
+		fmt.Fprintf(&clearCancelled, "\n\t\t%s[cid][i] = %s;", q.name, q.zero)
+		fmt.Fprintf(&shiftPopped, "\n\t\t%[1]s[cid][i - 1] = %[1]s[cid][i];", q.name)
+		fmt.Fprintf(&clearPopped, "\n\t%s[cid][chan_queue_len[cid]] = %s;", q.name, q.zero)
+	}
+
+	for _, q := range queues[:len(queues)-1] {
+		t.system.Declarations().AddFunc(fmt.Sprintf(
+			`void chan_push%[1]s(int cid, %[2]s value, int sender) {
+	if (cid < 0) {
+		return;
+	} else if (chan_queue_len[cid] >= %[4]d) {
+		out_of_resources = true;
+		return;
+	}
+	%[3]s[cid][chan_queue_len[cid]] = value;
+	chan_queue_sender[cid][chan_queue_len[cid]] = sender;
+	chan_queue_len[cid]++;
+}`, q.suffix, q.typ, q.name, t.chanQueueCapacity()))
+
+		t.system.Declarations().AddFunc(fmt.Sprintf(
+			`%[2]s chan_front%[1]s(int cid, %[2]s zero) {
+	if (chan_queue_len[cid] == 0) {
+		return zero;
+	}
+	return %[3]s[cid][0];
+}`, q.suffix, q.typ, q.name))
+	}
+
+	t.system.Declarations().AddFunc(fmt.Sprintf(
+		`void chan_cancel(int cid, int sender) {
+	int i;
+	int j = 0;
+	if (cid < 0) {
+		return;
+	}
+	for (i = 0; i < chan_queue_len[cid]; i++) {
+		if (chan_queue_sender[cid][i] != sender) {%s
+			j++;
+		}
+	}
+	for (i = j; i < chan_queue_len[cid]; i++) {%s
+	}
+	chan_queue_len[cid] = j;
+}`, shiftCancelled.String(), clearCancelled.String()))
+
+	t.system.Declarations().AddFunc(fmt.Sprintf(
+		`void chan_pop(int cid) {
+	int i;
+	if (chan_queue_len[cid] == 0) {
+		return;
+	}
+	for (i = 1; i < chan_queue_len[cid]; i++) {%s
+	}
+	chan_queue_len[cid]--;%s
+}`, shiftPopped.String(), clearPopped.String()))
+
+	t.system.Declarations().AddFunc(
+		`void chan_set_release(int cid, int index) {
+	if (index < 0 || index >= chan_queue_len[cid]) {
+		chan_release[cid] = -1;
+		return;
+	}
+	chan_release[cid] = chan_queue_sender[cid][index];
+}`)
+}
+
+// chanSenderConfirmGuard returns the guard of a transition completing a send
+// operation with a payload on the given channel.
+func (t *translator) chanSenderConfirmGuard(channelVar string, ctx *context) string {
+	return fmt.Sprintf("chan_release[%s] == %s", channelVar, t.chanSenderToken(ctx.f))
+}
+
+// addChanReceiverOkUpdate adds the update storing whether the receive
+// operation on the channel with the given handle received a sent value in the
+// given ok variable to the given transition completing the operation.
+func (t *translator) addChanReceiverOkUpdate(trans *uppaal.Trans, channelVar string, ok *ir.Variable, ctx *context) {
+	okHandle, usesGlobals := t.translateVariable(ok, ctx)
+	trans.AddUpdate(fmt.Sprintf("%s = chan_received[%s]", okHandle, channelVar), usesGlobals)
+}
+
+// addChanReceiverConfirmUpdates adds the updates to a transition completing a
+// receive operation with a payload on the given channel. The received value
+// gets stored in receivedVal, unless it is nil.
+func (t *translator) addChanReceiverConfirmUpdates(trans *uppaal.Trans, channelVar string, payloadType ir.Type, receivedVal ir.LValue, ctx *context) {
+	if receivedVal != nil {
+		var rvs randomVariableSupplier
+		handle, usesGlobals := t.translateLValue(receivedVal, &rvs, ctx)
+		zero := t.translateValue(payloadType.UninitializedValue())
+		rvs.addToTrans(trans)
+		trans.AddUpdate(fmt.Sprintf("%s = chan_front%s(%s, %s)", handle, t.chanPayloadSuffix(payloadType), channelVar, zero), usesGlobals)
+	}
+	trans.AddUpdate(fmt.Sprintf("chan_pop(%s)", channelVar), true)
+}
+
 func (t *translator) addChannelProcessInstances() {
 	c := t.channelCount()
 	if c > 1 {
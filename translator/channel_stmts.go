@@ -2,7 +2,9 @@ package translator
 
 import (
 	"fmt"
+	"go/token"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 	"github.com/arneph/toph/uppaal"
 )
@@ -56,7 +58,15 @@ func (t *translator) translateChanCommOpStmt(stmt *ir.ChanCommOpStmt, ctx *conte
 		confirmChan = "receiver_confirm"
 		counterOp = "--"
 	default:
-		t.addWarning(fmt.Errorf("unsupported ChanCommOp: %v", stmt.Op()))
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "unsupported ChanCommOp: %v", stmt.Op()))
+	}
+
+	var payloadUpdate string
+	if stmt.Op() == ir.Send && stmt.PayloadType() != nil {
+		payloadHandle, _ := t.translateRValue(stmt.Payload(), &rvs, ctx)
+		payloadUpdate = fmt.Sprintf("chan_push%s(%s, %s, %s)",
+			t.chanPayloadSuffix(stmt.PayloadType()), channelVar, payloadHandle, t.chanSenderToken(ctx.f))
 	}
 
 	pending := ctx.proc.AddState(pendingName+"_"+name+"_", uppaal.Renaming)
@@ -64,11 +74,34 @@ func (t *translator) translateChanCommOpStmt(stmt *ir.ChanCommOpStmt, ctx *conte
 	pending.SetLocationAndResetNameAndCommentLocation(
 		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
 
+	t.addNilChannelBlock(ctx.currentState, pendingName+"_nil_"+name+"_", handle, &rvs, stmt.Pos(), ctx)
+
+	triggerGuard := handle + " >= 0"
+	var sendingClosed *uppaal.State
+	if stmt.Op() == ir.Send {
+		// Sending on a closed channel panics:
+		sendingClosed = t.addClosedChannelPanic("sending_closed_"+name+"_",
+			ctx.currentState.Location().Add(uppaal.Location{-136, 136}),
+			"check send on closed channel unreachable", stmt.Pos(), ctx)
+
+		sendClosed := ctx.proc.AddTransition(ctx.currentState, sendingClosed)
+		sendClosed.SetGuard(handle+" >= 0 && chan_buffer["+handle+"] < 0", true)
+		rvs.addToTrans(sendClosed)
+		sendClosed.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 48}))
+		sendClosed.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 64}))
+
+		triggerGuard += " && chan_buffer[" + handle + "] >= 0"
+	}
+
 	trigger := ctx.proc.AddTransition(ctx.currentState, pending)
+	trigger.SetGuard(triggerGuard, true)
 	rvs.addToTrans(trigger)
 	trigger.SetSync(triggerChan + "[" + handle + "]!")
 	trigger.AddUpdate(channelVar+" = "+handle, true)
 	trigger.AddUpdate("\nchan_counter["+channelVar+"]"+counterOp, true)
+	if payloadUpdate != "" {
+		trigger.AddUpdate("\n"+payloadUpdate, true)
+	}
 	trigger.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
 	trigger.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
 	trigger.SetSyncLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
@@ -81,8 +114,41 @@ func (t *translator) translateChanCommOpStmt(stmt *ir.ChanCommOpStmt, ctx *conte
 
 	confirm := ctx.proc.AddTransition(pending, confirmed)
 	confirm.SetSync(confirmChan + "[" + channelVar + "]?")
+	if stmt.Op() == ir.Send {
+		// Blocked senders get released with a panic if the channel gets closed:
+		confirmGuard := "chan_buffer[" + channelVar + "] >= 0"
+		panicGuard := "chan_buffer[" + channelVar + "] < 0"
+		if stmt.PayloadType() != nil {
+			confirmGuard += " && " + t.chanSenderConfirmGuard(channelVar, ctx)
+			panicGuard += " && " + t.chanSenderConfirmGuard(channelVar, ctx)
+		}
+		confirm.SetGuard(confirmGuard, true)
+
+		confirmClosed := ctx.proc.AddTransition(pending, sendingClosed)
+		confirmClosed.SetGuard(panicGuard, true)
+		confirmClosed.SetSync(confirmChan + "[" + channelVar + "]?")
+		if stmt.PayloadType() != nil {
+			confirmClosed.AddUpdate(fmt.Sprintf("chan_cancel(%s, %s)", channelVar, t.chanSenderToken(ctx.f)), true)
+		}
+		confirmClosed.AddNail(pending.Location().Sub(uppaal.Location{136, 0}))
+		confirmClosed.SetGuardLocation(pending.Location().Sub(uppaal.Location{132, -4}))
+		confirmClosed.SetSyncLocation(pending.Location().Sub(uppaal.Location{132, -20}))
+		confirmClosed.SetUpdateLocation(pending.Location().Sub(uppaal.Location{132, -36}))
+	} else {
+		if stmt.PayloadType() != nil {
+			receivedVal, _ := stmt.Payload().(ir.LValue)
+			t.addChanReceiverConfirmUpdates(confirm, channelVar, stmt.PayloadType(), receivedVal, ctx)
+		}
+		if stmt.Ok() != nil {
+			t.addChanReceiverOkUpdate(confirm, channelVar, stmt.Ok(), ctx)
+		}
+	}
+	confirm.SetGuardLocation(
+		pending.Location().Add(uppaal.Location{4, 44}))
 	confirm.SetSyncLocation(
 		pending.Location().Add(uppaal.Location{4, 60}))
+	confirm.SetUpdateLocation(
+		pending.Location().Add(uppaal.Location{4, 76}))
 
 	if t.config.GenerateChannelRelatedDeadlockQueries {
 		ctx.proc.AddQuery(uppaal.NewQuery(
@@ -92,22 +158,121 @@ func (t *translator) translateChanCommOpStmt(stmt *ir.ChanCommOpStmt, ctx *conte
 			uppaal.NoChannelRelatedDeadlocks))
 	}
 
+	t.addGoroutineLeakQuery(pending, "channel", stmt.Pos(), ctx)
+
 	ctx.currentState = confirmed
 	ctx.addLocation(pending.Location())
 	ctx.addLocation(confirmed.Location())
 }
 
+// addNilChannelBlock adds a transition from the given state to a new state
+// with the given name, taken if the channel with the given handle is nil. Like
+// all operations on nil channels, the process blocks forever in the new state.
+func (t *translator) addNilChannelBlock(from *uppaal.State, name, handle string, rvs *randomVariableSupplier, pos token.Pos, ctx *context) {
+	blocked := ctx.proc.AddState(name, uppaal.Renaming)
+	blocked.SetComment(t.program.FileSet().Position(pos).String())
+	blocked.SetLocationAndResetNameAndCommentLocation(
+		from.Location().Add(uppaal.Location{136, 136}))
+
+	block := ctx.proc.AddTransition(from, blocked)
+	block.SetGuard(handle+" < 0", true)
+	if rvs != nil {
+		rvs.addToTrans(block)
+	}
+	block.SetSelectLocation(from.Location().Add(uppaal.Location{140, 48}))
+	block.SetGuardLocation(from.Location().Add(uppaal.Location{140, 64}))
+
+	if t.config.GenerateChannelRelatedDeadlockQueries {
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not (deadlock and $."+blocked.Name()+"))",
+			"check deadlock with operation on nil channel unreachable",
+			t.program.FileSet().Position(pos).String(),
+			uppaal.NoChannelRelatedDeadlocks))
+	}
+
+	t.addGoroutineLeakQuery(blocked, "nil channel", pos, ctx)
+
+	ctx.addLocation(blocked.Location())
+}
+
+// addClosedChannelPanic adds a new committed state with the given name and
+// location, from which the current process panics because of an operation on
+// a closed channel.
+func (t *translator) addClosedChannelPanic(name string, loc uppaal.Location, queryDesc string, pos token.Pos, ctx *context) *uppaal.State {
+	panicking := ctx.proc.AddState(name, uppaal.Renaming)
+	panicking.SetComment(t.program.FileSet().Position(pos).String())
+	panicking.SetType(uppaal.Committed)
+	panicking.SetLocationAndResetNameAndCommentLocation(loc)
+	t.addPanicTransition(panicking, ctx)
+
+	if t.config.GenerateChannelSafetyQueries {
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not $."+panicking.Name()+")",
+			queryDesc,
+			t.program.FileSet().Position(pos).String(),
+			uppaal.ChannelSafety))
+	}
+
+	ctx.addLocation(panicking.Location())
+	return panicking
+}
+
+// addPanicTransition adds a transition from the given state to the exit of the
+// current function, raising a panic that unwinds deferred calls.
+func (t *translator) addPanicTransition(from *uppaal.State, ctx *context) {
+	panicking := ctx.proc.AddTransition(from, ctx.exitFuncState)
+	panicking.AddUpdate("internal_panic = true", false)
+	panicking.SetUpdateLocation(from.Location().Add(uppaal.Location{4, 48}))
+	panicking.AddNail(from.Location().Add(uppaal.Location{0, 68}))
+	panicking.AddNail(uppaal.Location{-68, from.Location().Y() + 68})
+	ctx.returnTransitions[panicking] = struct{}{}
+}
+
 func (t *translator) translateCloseChanStmt(stmt *ir.CloseChanStmt, ctx *context) {
 	var rvs randomVariableSupplier
 	handle, _ := t.translateLValue(stmt.Channel(), &rvs, ctx)
 	name := stmt.Channel().Name()
 
+	// Closing a nil channel panics:
+	closingNil := ctx.proc.AddState("closing_nil_"+name+"_", uppaal.Renaming)
+	closingNil.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	closingNil.SetType(uppaal.Committed)
+	closingNil.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{136, 136}))
+	t.addPanicTransition(closingNil, ctx)
+
+	closeNil := ctx.proc.AddTransition(ctx.currentState, closingNil)
+	closeNil.SetGuard(handle+" < 0", true)
+	rvs.addToTrans(closeNil)
+	closeNil.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{140, 48}))
+	closeNil.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{140, 64}))
+
+	if t.config.GenerateCloseOfNilChannelQueries {
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not $."+closingNil.Name()+")",
+			"check close of nil channel unreachable",
+			t.program.FileSet().Position(stmt.Pos()).String(),
+			uppaal.NoCloseOfNilChannel))
+	}
+
+	// Closing a closed channel panics:
+	closingClosed := t.addClosedChannelPanic("closing_closed_"+name+"_",
+		ctx.currentState.Location().Add(uppaal.Location{-136, 136}),
+		"check close of closed channel unreachable", stmt.Pos(), ctx)
+
+	closeClosed := ctx.proc.AddTransition(ctx.currentState, closingClosed)
+	closeClosed.SetGuard(handle+" >= 0 && chan_buffer["+handle+"] < 0", true)
+	rvs.addToTrans(closeClosed)
+	closeClosed.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 48}))
+	closeClosed.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 64}))
+
 	closed := ctx.proc.AddState("closed_"+name+"_", uppaal.Renaming)
 	closed.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
 	closed.SetLocationAndResetNameAndCommentLocation(
 		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
 
 	close := ctx.proc.AddTransition(ctx.currentState, closed)
+	close.SetGuard(handle+" >= 0 && chan_buffer["+handle+"] >= 0", true)
 	rvs.addToTrans(close)
 	close.SetSync("close[" + handle + "]!")
 	close.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
@@ -115,16 +280,25 @@ func (t *translator) translateCloseChanStmt(stmt *ir.CloseChanStmt, ctx *context
 	close.SetSyncLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
 
 	ctx.currentState = closed
+	ctx.addLocation(closingNil.Location())
 	ctx.addLocation(closed.Location())
 }
 
 type selectCaseInfo struct {
+	channelVar           string
 	channelVarAssignment string
 	counterForwardUpdate string
 	counterReverseUpdate string
+	payloadForwardUpdate string
+	payloadReverseUpdate string
 	possibleGuard        string
+	notNilGuard          string
+	triggerGuard         string
+	closedGuard          string
 	triggerChanSync      string
 	confirmChanSync      string
+	confirmGuard         string
+	closedConfirmGuard   string
 }
 
 func (t *translator) infoForSelectCase(index int, selectCase *ir.SelectCase, rvs *randomVariableSupplier, ctx *context) selectCaseInfo {
@@ -134,19 +308,35 @@ func (t *translator) infoForSelectCase(index int, selectCase *ir.SelectCase, rvs
 	channelVar := fmt.Sprintf("select_chan%d", index)
 	ctx.proc.Declarations().AddVariable(channelVar, "int", "0")
 
+	info.channelVar = channelVar
 	info.channelVarAssignment = channelVar + " = " + handle
 
 	var rangeGuard string
 	switch selectCase.OpStmt().Op() {
 	case ir.Send:
-		info.counterForwardUpdate = "chan_counter[" + channelVar + "]++"
-		info.counterReverseUpdate = "chan_counter[" + channelVar + "]--"
+		info.counterForwardUpdate = "chan_counter_update(" + channelVar + ", 1)"
+		info.counterReverseUpdate = "chan_counter_update(" + channelVar + ", -1)"
+		if selectCase.OpStmt().PayloadType() != nil {
+			payloadHandle, _ := t.translateRValue(selectCase.OpStmt().Payload(), rvs, ctx)
+			token := t.chanSenderToken(ctx.f)
+			info.payloadForwardUpdate = fmt.Sprintf("chan_push%s(%s, %s, %s)",
+				t.chanPayloadSuffix(selectCase.OpStmt().PayloadType()), channelVar, payloadHandle, token)
+			info.payloadReverseUpdate = fmt.Sprintf("chan_cancel(%s, %s)", channelVar, token)
+		}
+		// Blocked send cases get released with a panic if the channel gets
+		// closed:
+		info.confirmGuard = "chan_buffer[" + channelVar + "] >= 0"
+		info.closedConfirmGuard = "chan_buffer[" + channelVar + "] < 0"
+		if selectCase.OpStmt().PayloadType() != nil {
+			info.confirmGuard += " && " + t.chanSenderConfirmGuard(channelVar, ctx)
+			info.closedConfirmGuard += " && " + t.chanSenderConfirmGuard(channelVar, ctx)
+		}
 		rangeGuard = "chan_counter[" + channelVar + "] <= chan_buffer[" + channelVar + "]"
 		info.triggerChanSync = "sender_trigger[" + channelVar + "]!"
 		info.confirmChanSync = "sender_confirm[" + channelVar + "]?"
 	case ir.Receive:
-		info.counterForwardUpdate = "chan_counter[" + channelVar + "]--"
-		info.counterReverseUpdate = "chan_counter[" + channelVar + "]++"
+		info.counterForwardUpdate = "chan_counter_update(" + channelVar + ", -1)"
+		info.counterReverseUpdate = "chan_counter_update(" + channelVar + ", 1)"
 		rangeGuard = "chan_counter[" + channelVar + "] >= 0"
 		info.triggerChanSync = "receiver_trigger[" + channelVar + "]!"
 		info.confirmChanSync = "receiver_confirm[" + channelVar + "]?"
@@ -154,12 +344,66 @@ func (t *translator) infoForSelectCase(index int, selectCase *ir.SelectCase, rvs
 		panic("unexpected select case channel op")
 	}
 
+	// Cases on nil channels are never ready:
 	closedGuard := "chan_buffer[" + channelVar + "] < 0"
-	info.possibleGuard = closedGuard + " || " + rangeGuard
+	info.notNilGuard = channelVar + " >= 0"
+	info.possibleGuard = info.notNilGuard + " && (" + closedGuard + " || " + rangeGuard + ")"
+	info.triggerGuard = info.possibleGuard
+	if selectCase.OpStmt().Op() == ir.Send {
+		// Send cases on closed channels panic instead of triggering:
+		info.closedGuard = info.notNilGuard + " && " + closedGuard
+		info.triggerGuard = info.notNilGuard + " && !(" + closedGuard + ") && " + rangeGuard
+	}
 
 	return info
 }
 
+// addSelectCaseConfirmation adds the guard and updates for completing the
+// operation of the given select case, including the transfer of its payload,
+// to a transition entering the case.
+func (t *translator) addSelectCaseConfirmation(enteringCase *uppaal.Trans, selectCase *ir.SelectCase, info selectCaseInfo, ctx *context) {
+	opStmt := selectCase.OpStmt()
+	switch opStmt.Op() {
+	case ir.Send:
+		if enteringCase.Guard() != "" {
+			enteringCase.SetGuard(enteringCase.Guard()+" && "+info.confirmGuard, true)
+		} else {
+			enteringCase.SetGuard(info.confirmGuard, true)
+		}
+		enteringCase.SetGuardLocation(enteringCase.SyncLocation().Sub(uppaal.Location{0, 16}))
+	case ir.Receive:
+		if opStmt.PayloadType() != nil {
+			receivedVal, _ := opStmt.Payload().(ir.LValue)
+			t.addChanReceiverConfirmUpdates(enteringCase, info.channelVar, opStmt.PayloadType(), receivedVal, ctx)
+		}
+		if opStmt.Ok() != nil {
+			t.addChanReceiverOkUpdate(enteringCase, info.channelVar, opStmt.Ok(), ctx)
+		}
+	}
+}
+
+// addSelectCaseClosedConfirmation adds a transition from the given state to
+// the given panicking state, taken if the blocked send case with the given
+// index gets released because its channel got closed.
+func (t *translator) addSelectCaseClosedConfirmation(from, sendingClosed *uppaal.State, index int, caseInfos []selectCaseInfo, ctx *context) {
+	info := caseInfos[index]
+	confirmClosed := ctx.proc.AddTransition(from, sendingClosed)
+	confirmClosed.SetGuard(info.notNilGuard+" && "+info.closedConfirmGuard, true)
+	confirmClosed.SetSync(info.confirmChanSync)
+	// Revert all other counters and withdraw all offered values:
+	for j := range caseInfos {
+		if index != j {
+			confirmClosed.AddUpdate(caseInfos[j].counterReverseUpdate, true)
+		}
+		if caseInfos[j].payloadReverseUpdate != "" {
+			confirmClosed.AddUpdate(caseInfos[j].payloadReverseUpdate, true)
+		}
+	}
+	confirmClosed.SetGuardLocation(from.Location().Add(uppaal.Location{-132, 4}))
+	confirmClosed.SetSyncLocation(from.Location().Add(uppaal.Location{-132, 20}))
+	confirmClosed.SetUpdateLocation(from.Location().Add(uppaal.Location{-132, 36}))
+}
+
 func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 	// Generate select exit state:
 	exitSelect := ctx.proc.AddState("select_end_", uppaal.Renaming)
@@ -206,6 +450,8 @@ func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 				uppaal.NoChannelRelatedDeadlocks))
 		}
 
+		t.addGoroutineLeakQuery(pass2, "select", stmt.Pos(), ctx)
+
 		if len(stmt.Cases()) > 0 {
 			caseXs[0] = ctx.currentState.Location()[0] + 136
 		}
@@ -289,11 +535,18 @@ func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 	for i := range stmt.Cases() {
 		enteringPass1.AddUpdate(caseInfos[i].counterForwardUpdate, true)
 	}
+	// Offer all sent values when entering pass1:
+	for i := range stmt.Cases() {
+		if caseInfos[i].payloadForwardUpdate != "" {
+			enteringPass1.AddUpdate(caseInfos[i].payloadForwardUpdate, true)
+		}
+	}
 	enteringPass1.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
 	enteringPass1.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
 	enteringPass1.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
 
 	// Poll channels (pass 1):
+	sendingClosedStates := make([]*uppaal.State, len(stmt.Cases()))
 	for i, c := range stmt.Cases() {
 		triggeredCase := ctx.proc.AddState(fmt.Sprintf("select_case_%d_trigger_", i+1), uppaal.Renaming)
 		triggeredCase.SetComment(t.program.FileSet().Position(c.Pos()).String())
@@ -301,7 +554,7 @@ func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 			uppaal.Location{caseXs[i], ctx.currentState.Location()[1] + 272})
 
 		triggeringCase := ctx.proc.AddTransition(pass1, triggeredCase)
-		triggeringCase.SetGuard(caseInfos[i].possibleGuard, true)
+		triggeringCase.SetGuard(caseInfos[i].triggerGuard, true)
 		triggeringCase.SetGuardLocation(
 			triggeredCase.Location().Sub(uppaal.Location{
 				32 * (len(stmt.Cases()) - i), 32 * (len(stmt.Cases()) - i)}))
@@ -315,15 +568,43 @@ func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 		enteringCase.SetSyncLocation(
 			caseEnters[i].Location().Sub(uppaal.Location{
 				-4, 32 * (len(stmt.Cases()) - i)}))
+		t.addSelectCaseConfirmation(enteringCase, c, caseInfos[i], ctx)
 		// Revert all other counters when entering case:
 		for j := range stmt.Cases() {
 			if i != j {
 				enteringCase.AddUpdate(caseInfos[j].counterReverseUpdate, true)
+				if caseInfos[j].payloadReverseUpdate != "" {
+					enteringCase.AddUpdate(caseInfos[j].payloadReverseUpdate, true)
+				}
 			}
 		}
 		enteringCase.SetUpdateLocation(
 			caseEnters[i].Location().Sub(uppaal.Location{
 				-4, 32*(len(stmt.Cases())-i) - 16}))
+
+		if caseInfos[i].closedGuard == "" {
+			continue
+		}
+
+		// Send cases on closed channels panic:
+		sendingClosed := t.addClosedChannelPanic(fmt.Sprintf("select_case_%d_sending_closed_", i+1),
+			triggeredCase.Location().Add(uppaal.Location{68, -68}),
+			"check send on closed channel unreachable", c.Pos(), ctx)
+		sendingClosedStates[i] = sendingClosed
+
+		sendClosed := ctx.proc.AddTransition(pass1, sendingClosed)
+		sendClosed.SetGuard(caseInfos[i].closedGuard, true)
+		// Revert all counters and withdraw all offered values:
+		for j := range stmt.Cases() {
+			sendClosed.AddUpdate(caseInfos[j].counterReverseUpdate, true)
+			if caseInfos[j].payloadReverseUpdate != "" {
+				sendClosed.AddUpdate(caseInfos[j].payloadReverseUpdate, true)
+			}
+		}
+		sendClosed.SetGuardLocation(sendingClosed.Location().Sub(uppaal.Location{132, 36}))
+		sendClosed.SetUpdateLocation(sendingClosed.Location().Sub(uppaal.Location{132, 20}))
+
+		t.addSelectCaseClosedConfirmation(triggeredCase, sendingClosed, i, caseInfos, ctx)
 	}
 
 	exitingPass1Unsuccessful := ctx.proc.AddTransition(pass1, exitPass1Unsuccessful)
@@ -335,6 +616,9 @@ func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 		// Revert all counters when entering default case:
 		for i := range stmt.Cases() {
 			exitingPass1Unsuccessful.AddUpdate(caseInfos[i].counterReverseUpdate, true)
+			if caseInfos[i].payloadReverseUpdate != "" {
+				exitingPass1Unsuccessful.AddUpdate(caseInfos[i].payloadReverseUpdate, true)
+			}
 		}
 		exitingPass1Unsuccessful.SetUpdateLocation(
 			defaultEnter.Location().Sub(uppaal.Location{-4, len(stmt.Cases())*32 + 16}))
@@ -342,21 +626,31 @@ func (t *translator) translateSelectStmt(stmt *ir.SelectStmt, ctx *context) {
 	} else {
 		// Wait for channel (pass 2):
 		pass2 := exitPass1Unsuccessful
-		for i := range stmt.Cases() {
+		for i, c := range stmt.Cases() {
 			enteringCase := ctx.proc.AddTransition(pass2, caseEnters[i])
+			enteringCase.SetGuard(caseInfos[i].notNilGuard, false)
 			enteringCase.SetSync(caseInfos[i].confirmChanSync)
 			enteringCase.SetSyncLocation(
 				caseEnters[i].Location().Sub(uppaal.Location{
 					-4, 32 * (len(stmt.Cases()) - i)}))
+			enteringCase.SetGuardLocation(enteringCase.SyncLocation().Sub(uppaal.Location{0, 16}))
+			t.addSelectCaseConfirmation(enteringCase, c, caseInfos[i], ctx)
 			// Revert all other counters when entering case:
 			for j := range stmt.Cases() {
 				if i != j {
 					enteringCase.AddUpdate(caseInfos[j].counterReverseUpdate, true)
+					if caseInfos[j].payloadReverseUpdate != "" {
+						enteringCase.AddUpdate(caseInfos[j].payloadReverseUpdate, true)
+					}
 				}
 			}
 			enteringCase.SetUpdateLocation(
 				caseEnters[i].Location().Sub(uppaal.Location{
 					-4, 32*(len(stmt.Cases())-i) - 16}))
+
+			if sendingClosedStates[i] != nil {
+				t.addSelectCaseClosedConfirmation(pass2, sendingClosedStates[i], i, caseInfos, ctx)
+			}
 		}
 	}
 
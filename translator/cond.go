@@ -0,0 +1,182 @@
+package translator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/arneph/toph/ir"
+	"github.com/arneph/toph/uppaal"
+)
+
+func (t *translator) condCount() int {
+	condCount := t.completeFCG.TotalSpecialOpCount(ir.MakeCond)
+	if condCount < 1 {
+		condCount = 1
+	} else if condCount > t.config.MaxCondCount {
+		condCount = t.config.MaxCondCount
+	}
+	return condCount
+}
+
+func (t *translator) addConds() {
+	t.addCondProcess()
+	t.addCondDeclarations()
+	t.addCondProcessInstances()
+}
+
+func (t *translator) addCondProcess() {
+	proc := t.system.AddProcess("Cond")
+	t.condProcess = proc
+
+	// Parameters:
+	proc.AddParameter(fmt.Sprintf("int[0, %d] i", t.condCount()-1))
+
+	// Queries:
+	if t.config.GenerateCondSafetyQueries {
+		proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not $.bad)",
+			"check Cond.bad state unreachable", "",
+			uppaal.CondSafety))
+	}
+
+	// States:
+	idle := proc.AddState("idle", uppaal.NoRenaming)
+	idle.SetLocation(uppaal.Location{0, 0})
+	idle.SetNameLocation(uppaal.Location{17, -8})
+
+	proc.SetInitialState(idle)
+
+	signalling := proc.AddState("signalling", uppaal.NoRenaming)
+	signalling.SetType(uppaal.Committed)
+	signalling.SetLocation(uppaal.Location{272, 0})
+	signalling.SetNameLocation(uppaal.Location{289, -8})
+
+	broadcasting := proc.AddState("broadcasting", uppaal.NoRenaming)
+	broadcasting.SetType(uppaal.Committed)
+	broadcasting.SetLocation(uppaal.Location{0, 238})
+	broadcasting.SetNameLocation(uppaal.Location{17, 246})
+
+	bad := proc.AddState("bad", uppaal.NoRenaming)
+	bad.SetLocation(uppaal.Location{0, -204})
+	bad.SetNameLocation(uppaal.Location{17, -212})
+
+	// Transitions:
+	// Idle:
+	trans1 := proc.AddTransition(idle, idle)
+	trans1.SetGuard("cond_mutex[i] >= 0", true)
+	trans1.SetGuardLocation(uppaal.Location{-238, -48})
+	trans1.SetSync("cond_wait[i]?")
+	trans1.SetSyncLocation(uppaal.Location{-238, -32})
+	trans1.AddUpdate("cond_waiters[i]++", true)
+	trans1.SetUpdateLocation(uppaal.Location{-238, -16})
+	trans1.AddNail(uppaal.Location{-68, -34})
+	trans1.AddNail(uppaal.Location{-68, 34})
+
+	trans2 := proc.AddTransition(idle, idle)
+	trans2.SetGuard("cond_waiters[i] == 0", true)
+	trans2.SetGuardLocation(uppaal.Location{-238, 52})
+	trans2.SetSync("cond_signal[i]?")
+	trans2.SetSyncLocation(uppaal.Location{-238, 68})
+	trans2.AddNail(uppaal.Location{-34, 68})
+	trans2.AddNail(uppaal.Location{-34, 102})
+	trans2.AddNail(uppaal.Location{34, 102})
+	trans2.AddNail(uppaal.Location{34, 68})
+
+	// Idle, Signalling:
+	trans3 := proc.AddTransition(idle, signalling)
+	trans3.SetGuard("cond_waiters[i] > 0", true)
+	trans3.SetGuardLocation(uppaal.Location{76, -84})
+	trans3.SetSync("cond_signal[i]?")
+	trans3.SetSyncLocation(uppaal.Location{76, -68})
+	trans3.AddNail(uppaal.Location{34, -68})
+	trans3.AddNail(uppaal.Location{238, -68})
+
+	trans4 := proc.AddTransition(signalling, idle)
+	trans4.SetSync("cond_wake[i]!")
+	trans4.SetSyncLocation(uppaal.Location{76, 52})
+	trans4.AddUpdate("cond_waiters[i]--", true)
+	trans4.SetUpdateLocation(uppaal.Location{76, 68})
+	trans4.AddNail(uppaal.Location{238, 68})
+	trans4.AddNail(uppaal.Location{68, 68})
+
+	// Idle, Broadcasting:
+	trans5 := proc.AddTransition(idle, broadcasting)
+	trans5.SetSync("cond_broadcast[i]?")
+	trans5.SetSyncLocation(uppaal.Location{-170, 136})
+	trans5.AddNail(uppaal.Location{-34, 136})
+	trans5.AddNail(uppaal.Location{-34, 204})
+
+	trans6 := proc.AddTransition(broadcasting, broadcasting)
+	trans6.SetGuard("cond_waiters[i] > 0", true)
+	trans6.SetGuardLocation(uppaal.Location{-238, 290})
+	trans6.SetSync("cond_wake[i]!")
+	trans6.SetSyncLocation(uppaal.Location{-238, 306})
+	trans6.AddUpdate("cond_waiters[i]--", true)
+	trans6.SetUpdateLocation(uppaal.Location{-238, 322})
+	trans6.AddNail(uppaal.Location{-34, 306})
+	trans6.AddNail(uppaal.Location{34, 306})
+
+	trans7 := proc.AddTransition(broadcasting, idle)
+	trans7.SetGuard("cond_waiters[i] == 0", true)
+	trans7.SetGuardLocation(uppaal.Location{38, 136})
+	trans7.AddNail(uppaal.Location{34, 204})
+	trans7.AddNail(uppaal.Location{34, 136})
+
+	// Bad:
+	trans8 := proc.AddTransition(idle, bad)
+	trans8.SetGuard("cond_mutex[i] < 0", true)
+	trans8.SetGuardLocation(uppaal.Location{4, -120})
+	trans8.SetSync("cond_wait[i]?")
+	trans8.SetSyncLocation(uppaal.Location{4, -104})
+}
+
+func (t *translator) addCondDeclarations() {
+	t.system.Declarations().AddVariable("cond_count", "int", "0")
+	t.system.Declarations().AddArray("cond_mutex", []int{t.condCount()}, "int")
+	t.system.Declarations().AddArray("cond_rw_mutex", []int{t.condCount()}, "bool")
+	t.system.Declarations().AddArray("cond_read_locker", []int{t.condCount()}, "bool")
+	t.system.Declarations().AddArray("cond_waiters", []int{t.condCount()}, "int")
+	t.system.Declarations().AddArray("cond_wait", []int{t.condCount()}, t.chanType())
+	t.system.Declarations().AddArray("cond_wake", []int{t.condCount()}, t.chanType())
+	t.system.Declarations().AddArray("cond_signal", []int{t.condCount()}, t.chanType())
+	t.system.Declarations().AddArray("cond_broadcast", []int{t.condCount()}, t.chanType())
+	t.system.Declarations().AddSpaceBetweenVariables()
+
+	t.system.Declarations().AddFunc(fmt.Sprintf(
+		`int make_cond(int mid, bool rw, bool rl) {
+	int cvid;
+	if (cond_count >= %d) {
+		cond_count++;
+		out_of_resources = true;
+		return 0;
+	}
+	cvid = cond_count;
+	cond_count++;
+	cond_mutex[cvid] = mid;
+	cond_rw_mutex[cvid] = rw;
+	cond_read_locker[cvid] = rl;
+	cond_waiters[cvid] = 0;
+	return cvid;
+}`, t.condCount()))
+
+	if t.config.GenerateIndividualResourceBoundQueries {
+		t.system.AddQuery(uppaal.NewQuery(
+			fmt.Sprintf("A[] cond_count < %d", t.condCount()+1),
+			"check resource bound never reached through cond creation",
+			"",
+			uppaal.ResourceBoundUnreached))
+	}
+}
+
+func (t *translator) addCondProcessInstances() {
+	c := t.condCount()
+	if c > 1 {
+		c--
+	}
+	d := fmt.Sprintf("%d", int(math.Log10(float64(c))+1))
+	for i := 0; i < t.condCount(); i++ {
+		instName := fmt.Sprintf("%s%0"+d+"d", t.condProcess.Name(), i)
+		inst := t.system.AddProcessInstance(t.condProcess, instName)
+		inst.AddParameter(fmt.Sprintf("%d", i))
+	}
+}
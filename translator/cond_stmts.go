@@ -0,0 +1,261 @@
+package translator
+
+import (
+	"fmt"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/ir"
+	"github.com/arneph/toph/uppaal"
+)
+
+func (t *translator) translateMakeCondStmt(stmt *ir.MakeCondStmt, ctx *context) {
+	var rvs randomVariableSupplier
+	handle, usesGlobals := t.translateVariable(stmt.Cond(), ctx)
+	name := stmt.Cond().Name()
+	mutexHandle, mutexUsesGlobals := t.translateRValue(stmt.Mutex(), &rvs, ctx)
+
+	made := ctx.proc.AddState("made_"+name+"_", uppaal.Renaming)
+	made.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	made.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
+	make := ctx.proc.AddTransition(ctx.currentState, made)
+	isRW := stmt.Mutex().Type() == ir.RWMutexType
+	make.AddUpdate(fmt.Sprintf("%s = make_cond(%s, %t, %t)", handle, mutexHandle, isRW, stmt.UsesRLocker()),
+		usesGlobals || mutexUsesGlobals)
+	rvs.addToTrans(make)
+	make.SetSelectLocation(
+		ctx.currentState.Location().Add(uppaal.Location{4, 48}))
+	make.SetGuardLocation(
+		ctx.currentState.Location().Add(uppaal.Location{4, 64}))
+	make.SetUpdateLocation(
+		ctx.currentState.Location().Add(uppaal.Location{4, 80}))
+
+	ctx.currentState = made
+	ctx.addLocation(made.Location())
+}
+
+func (t *translator) translateCondOpStmt(stmt *ir.CondOpStmt, ctx *context) {
+	var rvs randomVariableSupplier
+	handle, _ := t.translateLValue(stmt.Cond(), &rvs, ctx)
+	name := stmt.Cond().Name()
+
+	switch stmt.Op() {
+	case ir.CondWait:
+		t.translateCondWait(stmt, handle, name, &rvs, ctx)
+		return
+	case ir.Signal, ir.Broadcast:
+	default:
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "unsupported CondOp: %v", stmt.Op()))
+		return
+	}
+
+	completed := ctx.proc.AddState(stmt.Op().String()+"_"+name+"_", uppaal.Renaming)
+	completed.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	completed.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
+	complete := ctx.proc.AddTransition(ctx.currentState, completed)
+	rvs.addToTrans(complete)
+	complete.SetGuard(handle+" >= 0", true)
+	complete.SetSync(fmt.Sprintf("cond_%s[%s]!", stmt.Op(), handle))
+	complete.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
+	complete.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
+	complete.SetSyncLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
+
+	// A cond that never got made (zero value without locker) has no waiters:
+	skip := ctx.proc.AddTransition(ctx.currentState, completed)
+	rvs.addToTrans(skip)
+	skip.SetGuard(handle+" < 0", true)
+	skip.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 48}))
+	skip.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 64}))
+	skip.AddNail(ctx.currentState.Location().Add(uppaal.Location{-34, 68}))
+
+	ctx.currentState = completed
+	ctx.addLocation(completed.Location())
+}
+
+func (t *translator) translateCondWait(stmt *ir.CondOpStmt, handle, name string, rvs *randomVariableSupplier, ctx *context) {
+	condVar := "op_cond"
+	mutexHandle := "cond_mutex[" + condVar + "]"
+	ctx.proc.Declarations().AddVariable(condVar, "int", "0")
+
+	// Register as waiter (committed, to atomically release the mutex):
+	registered := ctx.proc.AddState("registered_cond_wait_"+name+"_", uppaal.Renaming)
+	registered.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	registered.SetType(uppaal.Committed)
+	registered.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
+
+	register := ctx.proc.AddTransition(ctx.currentState, registered)
+	rvs.addToTrans(register)
+	register.SetGuard(handle+" >= 0", true)
+	register.SetSync("cond_wait[" + handle + "]!")
+	register.AddUpdate(condVar+" = "+handle, true)
+	register.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
+	register.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
+	register.SetSyncLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
+	register.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 96}))
+
+	// Waiting on a cond that never got made (zero value without locker) panics
+	// because of the nil locker:
+	nilLocker := ctx.proc.AddState("waiting_nil_locker_"+name+"_", uppaal.Renaming)
+	nilLocker.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	nilLocker.SetType(uppaal.Committed)
+	nilLocker.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{-136, 136}))
+	t.addPanicTransition(nilLocker, ctx)
+
+	waitNil := ctx.proc.AddTransition(ctx.currentState, nilLocker)
+	rvs.addToTrans(waitNil)
+	waitNil.SetGuard(handle+" < 0", true)
+	waitNil.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 48}))
+	waitNil.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 64}))
+
+	if t.config.GenerateCondSafetyQueries {
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not $."+nilLocker.Name()+")",
+			"check cond wait with nil locker unreachable",
+			t.program.FileSet().Position(stmt.Pos()).String(),
+			uppaal.CondSafety))
+	}
+
+	// Release mutex and wait for signal or broadcast:
+	waiting := ctx.proc.AddState("awaiting_cond_"+name+"_", uppaal.Renaming)
+	waiting.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	waiting.SetLocationAndResetNameAndCommentLocation(
+		registered.Location().Add(uppaal.Location{0, 136}))
+
+	mutexKinds := t.condMutexKinds(condVar)
+	for i, kind := range mutexKinds {
+		release := ctx.proc.AddTransition(registered, waiting)
+		release.SetGuard(mutexHandle+" >= 0 && "+kind.guard, true)
+		release.SetSync(kind.unlockSync)
+		release.SetGuardLocation(registered.Location().Add(uppaal.Location{4 + 136*i, 48}))
+		release.SetSyncLocation(registered.Location().Add(uppaal.Location{4 + 136*i, 64}))
+		if i > 0 {
+			release.AddNail(registered.Location().Add(uppaal.Location{136 * i, 68}))
+		}
+	}
+
+	noMutex := ctx.proc.AddTransition(registered, waiting)
+	noMutex.SetGuard(mutexHandle+" < 0", true)
+	noMutex.SetGuardLocation(registered.Location().Add(uppaal.Location{-132, 64}))
+	noMutex.AddNail(registered.Location().Add(uppaal.Location{-34, 68}))
+
+	if t.config.GenerateCondRelatedDeadlockQueries {
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not (deadlock and $."+waiting.Name()+"))",
+			"check deadlock with pending cond operation unreachable",
+			t.program.FileSet().Position(stmt.Pos()).String(),
+			uppaal.NoCondRelatedDeadlocks))
+	}
+
+	woken := ctx.proc.AddState("woken_cond_"+name+"_", uppaal.Renaming)
+	woken.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	woken.SetLocationAndResetNameAndCommentLocation(
+		waiting.Location().Add(uppaal.Location{0, 136}))
+
+	wake := ctx.proc.AddTransition(waiting, woken)
+	wake.SetSync("cond_wake[" + condVar + "]?")
+	wake.SetSyncLocation(waiting.Location().Add(uppaal.Location{4, 60}))
+
+	// Reacquire mutex:
+	relocking := ctx.proc.AddState("awaiting_write_lock_"+name+"_", uppaal.Renaming)
+	relocking.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	relocking.SetLocationAndResetNameAndCommentLocation(
+		woken.Location().Add(uppaal.Location{0, 136}))
+
+	for i, kind := range mutexKinds {
+		relockRegister := ctx.proc.AddTransition(woken, relocking)
+		relockRegister.SetGuard(kind.guard, true)
+		if kind.pendingCounter != "" {
+			relockRegister.AddUpdate(kind.pendingCounter+"++", true)
+		}
+		relockRegister.SetGuardLocation(woken.Location().Add(uppaal.Location{4 + 136*i, 44}))
+		relockRegister.SetUpdateLocation(woken.Location().Add(uppaal.Location{4 + 136*i, 60}))
+		if i > 0 {
+			relockRegister.AddNail(woken.Location().Add(uppaal.Location{136 * i, 68}))
+		}
+	}
+
+	if t.config.GenerateMutexRelatedDeadlockQueries {
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not (deadlock and $."+relocking.Name()+"))",
+			"check deadlock with pending mutex operation unreachable",
+			t.program.FileSet().Position(stmt.Pos()).String(),
+			uppaal.NoMutexRelatedDeadlocks))
+	}
+
+	t.addGoroutineLeakQuery(relocking, "mutex", stmt.Pos(), ctx)
+
+	relocked := ctx.proc.AddState("aquired_write_lock_"+name+"_", uppaal.Renaming)
+	relocked.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	relocked.SetLocationAndResetNameAndCommentLocation(
+		relocking.Location().Add(uppaal.Location{0, 136}))
+
+	for i, kind := range mutexKinds {
+		relock := ctx.proc.AddTransition(relocking, relocked)
+		relock.SetGuard(kind.guard, true)
+		relock.SetSync(kind.lockSync)
+		if kind.pendingCounter != "" {
+			relock.AddUpdate(kind.pendingCounter+"--", true)
+		}
+		relock.SetGuardLocation(relocking.Location().Add(uppaal.Location{4 + 136*i, 44}))
+		relock.SetSyncLocation(relocking.Location().Add(uppaal.Location{4 + 136*i, 60}))
+		relock.SetUpdateLocation(relocking.Location().Add(uppaal.Location{4 + 136*i, 76}))
+		if i > 0 {
+			relock.AddNail(relocking.Location().Add(uppaal.Location{136 * i, 68}))
+		}
+	}
+
+	ctx.currentState = relocked
+	ctx.addLocation(nilLocker.Location())
+	ctx.addLocation(registered.Location())
+	ctx.addLocation(waiting.Location())
+	ctx.addLocation(woken.Location())
+	ctx.addLocation(relocking.Location())
+	ctx.addLocation(relocked.Location())
+}
+
+// condMutexKind describes how a cond releases and reacquires its mutex.
+type condMutexKind struct {
+	guard          string
+	unlockSync     string
+	lockSync       string
+	pendingCounter string
+}
+
+// condMutexKinds returns the ways in which the given cond might release and
+// reacquire its mutex, depending on the type of the mutex and whether the cond
+// uses its read lock. Kinds only get returned if the corresponding mutex
+// template exists.
+func (t *translator) condMutexKinds(condVar string) []condMutexKind {
+	mutexHandle := "cond_mutex[" + condVar + "]"
+	rwHandle := "cond_rw_mutex[" + condVar + "]"
+	rlHandle := "cond_read_locker[" + condVar + "]"
+	usesRWMutex := t.isTypeUsed(ir.RWMutexType)
+	usesMutex := t.isTypeUsed(ir.MutexType)
+
+	var kinds []condMutexKind
+	if usesMutex {
+		kinds = append(kinds, condMutexKind{
+			guard:      "!" + rwHandle,
+			unlockSync: "unlock[" + mutexHandle + "]!",
+			lockSync:   "lock[" + mutexHandle + "]!",
+		})
+	}
+	if usesRWMutex {
+		kinds = append(kinds, condMutexKind{
+			guard:          rwHandle + " && !" + rlHandle,
+			unlockSync:     "rw_write_unlock[" + mutexHandle + "]!",
+			lockSync:       "rw_write_lock[" + mutexHandle + "]!",
+			pendingCounter: "rw_mutex_pending_writers[" + mutexHandle + "]",
+		}, condMutexKind{
+			guard:          rwHandle + " && " + rlHandle,
+			unlockSync:     "rw_read_unlock[" + mutexHandle + "]!",
+			lockSync:       "rw_read_lock[" + mutexHandle + "]!",
+			pendingCounter: "rw_mutex_pending_readers[" + mutexHandle + "]",
+		})
+	}
+	return kinds
+}
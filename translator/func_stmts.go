@@ -151,8 +151,14 @@ func (t *translator) translateCall(stmt *ir.CallStmt, info calleeInfo, ctx *cont
 			awaited.SetLocationAndResetNameAndCommentLocation(
 				started.Location().Add(uppaal.Location{0, 136}))
 			waitForRegularReturn := ctx.proc.AddTransition(started, awaited)
-			if !t.config.OptimizeIR || t.completeFCG.CanPanic(calleeFunc) {
+			canPanic := !t.config.OptimizeIR || t.completeFCG.CanPanic(calleeFunc)
+			canGoexit := !t.config.OptimizeIR || t.completeFCG.CanGoexit(calleeFunc)
+			if canPanic && canGoexit {
+				waitForRegularReturn.SetGuard(fmt.Sprintf("!external_panic_%[1]s[p] && !external_goexit_%[1]s[p]", calleeProc.Name()), false)
+			} else if canPanic {
 				waitForRegularReturn.SetGuard(fmt.Sprintf("!external_panic_%s[p]", calleeProc.Name()), false)
+			} else if canGoexit {
+				waitForRegularReturn.SetGuard(fmt.Sprintf("!external_goexit_%s[p]", calleeProc.Name()), false)
 			}
 			waitForRegularReturn.SetSync(fmt.Sprintf("sync_%s[p]?", calleeProc.Name()))
 
@@ -171,7 +177,7 @@ func (t *translator) translateCall(stmt *ir.CallStmt, info calleeInfo, ctx *cont
 			waitForRegularReturn.SetSyncLocation(started.Location().Add(uppaal.Location{4, 80}))
 			waitForRegularReturn.SetUpdateLocation(started.Location().Add(uppaal.Location{4, 96}))
 
-			if !t.config.OptimizeIR || t.completeFCG.CanPanic(calleeFunc) {
+			if canPanic {
 				waitForPanic := ctx.proc.AddTransition(started, ctx.exitFuncState)
 				waitForPanic.SetGuard(fmt.Sprintf("external_panic_%s[p]", calleeProc.Name()), false)
 				waitForPanic.SetSync(fmt.Sprintf("sync_%s[p]?", calleeProc.Name()))
@@ -184,6 +190,25 @@ func (t *translator) translateCall(stmt *ir.CallStmt, info calleeInfo, ctx *cont
 
 				ctx.returnTransitions[waitForPanic] = struct{}{}
 			}
+			if canGoexit {
+				// A panic in a deferred call of the callee during its goroutine
+				// exit takes precedence:
+				waitForGoexit := ctx.proc.AddTransition(started, ctx.exitFuncState)
+				if canPanic {
+					waitForGoexit.SetGuard(fmt.Sprintf("external_goexit_%[1]s[p] && !external_panic_%[1]s[p]", calleeProc.Name()), false)
+				} else {
+					waitForGoexit.SetGuard(fmt.Sprintf("external_goexit_%s[p]", calleeProc.Name()), false)
+				}
+				waitForGoexit.SetSync(fmt.Sprintf("sync_%s[p]?", calleeProc.Name()))
+				waitForGoexit.AddUpdate("internal_goexit = true", false)
+				waitForGoexit.SetGuardLocation(started.Location().Add(uppaal.Location{-132, 64}))
+				waitForGoexit.SetSyncLocation(started.Location().Add(uppaal.Location{-132, 80}))
+				waitForGoexit.SetUpdateLocation(started.Location().Add(uppaal.Location{-132, 96}))
+				waitForGoexit.AddNail(started.Location().Add(uppaal.Location{-34, 68}))
+				waitForGoexit.AddNail(uppaal.Location{-68, started.Location().Y() + 68})
+
+				ctx.returnTransitions[waitForGoexit] = struct{}{}
+			}
 
 			if info.endState == nil {
 				ctx.currentState = awaited
@@ -257,6 +282,9 @@ func (t *translator) translateDeferredCalls(proc *uppaal.Process, deferred *uppa
 		if !t.config.OptimizeIR || (t.completeFCG.CanPanic(callerFunc) && t.completeFCG.CanRecover(calleeFunc)) {
 			wait.AddUpdate(fmt.Sprintf("internal_panic = external_panic_%s[deferred_pid[deferred_count-1]]", calleeProc.Name()), false)
 		}
+		if !t.config.OptimizeIR || t.completeFCG.CanGoexit(calleeFunc) {
+			wait.AddUpdate(fmt.Sprintf("internal_goexit |= external_goexit_%s[deferred_pid[deferred_count-1]]", calleeProc.Name()), false)
+		}
 		wait.AddUpdate("deferred_count--", false)
 		wait.SetUpdateLocation(started.Location().Add(uppaal.Location{4, 48 + 32*i}))
 		wait.AddNail(started.Location().Add(uppaal.Location{0, 68}))
@@ -291,6 +319,17 @@ func (t *translator) translateReturnStmt(stmt *ir.ReturnStmt, ctx *context) {
 	ctx.returnTransitions[ret] = struct{}{}
 }
 
+func (t *translator) translateGoexitStmt(stmt *ir.GoexitStmt, ctx *context) {
+	exit := ctx.proc.AddTransition(ctx.currentState, ctx.exitFuncState)
+	exit.AddUpdate("internal_goexit = true", false)
+	exit.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
+	exit.AddNail(ctx.currentState.Location().Add(uppaal.Location{0, 68}))
+	exit.AddNail(uppaal.Location{-68, ctx.currentState.Location().Y() + 68})
+
+	ctx.currentState = ctx.exitFuncState
+	ctx.returnTransitions[exit] = struct{}{}
+}
+
 func (t *translator) translateRecoverStmt(stmt *ir.RecoverStmt, ctx *context) {
 	recovered := ctx.proc.AddState("attempted_recover_", uppaal.Renaming)
 	recovered.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
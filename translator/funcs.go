@@ -2,6 +2,7 @@ package translator
 
 import (
 	"fmt"
+	"go/token"
 	"math"
 
 	"github.com/arneph/toph/ir"
@@ -16,6 +17,9 @@ func (t translator) isFuncUsed(f *ir.Func) bool {
 }
 
 func (t translator) callCount(f *ir.Func) int {
+	if maxInstances := f.MaxInstances(); maxInstances > 0 {
+		return maxInstances
+	}
 	callCount := t.completeFCG.CalleeCount(f)
 	if callCount < 1 {
 		callCount = 1
@@ -57,17 +61,21 @@ func (t translator) addFuncDeclarations(f *ir.Func) {
 	proc := t.funcToProcess[f]
 
 	t.system.Declarations().AddVariable(proc.Name()+"_count", "int", "0")
-	t.system.Declarations().AddArray("async_"+proc.Name(), []int{t.callCount(f)}, "chan")
-	t.system.Declarations().AddArray("sync_"+proc.Name(), []int{t.callCount(f)}, "chan")
+	t.system.Declarations().AddArray("async_"+proc.Name(), []int{t.callCount(f)}, t.chanType())
+	t.system.Declarations().AddArray("sync_"+proc.Name(), []int{t.callCount(f)}, t.chanType())
 
 	if f.EnclosingFunc() != nil {
 		t.system.Declarations().AddArray("par_pid_"+proc.Name(), []int{t.callCount(f)}, "int")
 	}
 
-	externalPanicInit := ""
+	externalFlagsInit := ""
 	if !t.config.OptimizeIR || t.completeFCG.CanPanic(f) || t.completeFCG.CanRecover(f) {
 		t.system.Declarations().AddArray("external_panic_"+proc.Name(), []int{t.callCount(f)}, "bool")
-		externalPanicInit = fmt.Sprintf("\n    external_panic_%s[pid] = false;", proc.Name())
+		externalFlagsInit = fmt.Sprintf("\n    external_panic_%s[pid] = false;", proc.Name())
+	}
+	if !t.config.OptimizeIR || t.completeFCG.CanGoexit(f) {
+		t.system.Declarations().AddArray("external_goexit_"+proc.Name(), []int{t.callCount(f)}, "bool")
+		externalFlagsInit += fmt.Sprintf("\n    external_goexit_%s[pid] = false;", proc.Name())
 	}
 
 	for _, arg := range f.Args() {
@@ -95,7 +103,7 @@ func (t translator) addFuncDeclarations(f *ir.Func) {
 	pid = %[1]s_count;
 	%[1]s_count++;%[3]s
 	return pid;
-}`, proc.Name(), t.callCount(f), externalPanicInit))
+}`, proc.Name(), t.callCount(f), externalFlagsInit))
 	} else {
 		t.system.Declarations().AddFunc(
 			fmt.Sprintf(`int make_%[1]s(int par_pid) {
@@ -109,7 +117,7 @@ func (t translator) addFuncDeclarations(f *ir.Func) {
 	%[1]s_count++;
 	par_pid_%[1]s[pid] = par_pid;%[3]s
 	return pid;
-}`, proc.Name(), t.callCount(f), externalPanicInit))
+}`, proc.Name(), t.callCount(f), externalFlagsInit))
 	}
 	if t.config.GenerateIndividualResourceBoundQueries {
 		t.system.AddQuery(uppaal.NewQuery(
@@ -137,6 +145,9 @@ func (t *translator) translateFunc(f *ir.Func) {
 	if !t.config.OptimizeIR || t.completeFCG.CanPanic(f) {
 		proc.Declarations().AddVariable("internal_panic", "bool", "false")
 	}
+	if !t.config.OptimizeIR || t.completeFCG.CanGoexit(f) {
+		proc.Declarations().AddVariable("internal_goexit", "bool", "false")
+	}
 	if deferCount > 0 {
 		proc.Declarations().AddVariable("deferred_count", "int", "0")
 		proc.Declarations().AddArray("deferred_fid", []int{deferCount}, "int")
@@ -230,6 +241,10 @@ func (t *translator) translateFunc(f *ir.Func) {
 		finalize.AddUpdate(fmt.Sprintf("external_panic_%s[pid] |= internal_panic", proc.Name()), false)
 		finalize.SetUpdateLocation(uppaal.Location{4, endingY + 60})
 	}
+	if f != t.program.InitFunc() && (!t.config.OptimizeIR || t.completeFCG.CanGoexit(f)) {
+		finalize.AddUpdate(fmt.Sprintf("external_goexit_%s[pid] |= internal_goexit", proc.Name()), false)
+		finalize.SetUpdateLocation(uppaal.Location{4, endingY + 60})
+	}
 
 	endingY += 136
 
@@ -289,3 +304,49 @@ func (t *translator) translateFunc(f *ir.Func) {
 		endSync.SetSyncLocation(uppaal.Location{38, endingY + 64})
 	}
 }
+
+// addGoroutineLeakQuery adds a query to the process of the context checking
+// that no instance of the process is permanently stuck in the given waiting
+// state after the entry function returned. The init function process waits
+// in its ending state until all other goroutines terminated, therefore
+// goroutines only leak if no process can make progress while the init
+// function process is in its ending state. Goroutines that are only briefly
+// waiting when the entry function returns do not leak.
+func (t *translator) addGoroutineLeakQuery(waiting *uppaal.State, operation string, pos token.Pos, ctx *context) {
+	if !t.config.GenerateGoroutineLeakQueries || ctx.f == t.program.InitFunc() {
+		return
+	}
+	initInst := t.program.InitFunc().Handle()
+	ctx.proc.AddQuery(uppaal.NewQuery(
+		"A[] (not out_of_resources) imply (not ("+initInst+".ending and deadlock and $."+waiting.Name()+"))",
+		"check no goroutine leaked with pending "+operation+" operation",
+		t.program.FileSet().Position(pos).String(),
+		uppaal.NoGoroutineLeaks))
+}
+
+// addFatalError adds a transition from the given state to a new committed
+// state with the given name and location, taken if the given guard holds.
+// Fatal errors terminate the program and can not be recovered: the self loop
+// of the committed state prevents all other processes from making progress
+// without causing a deadlock.
+func (t *translator) addFatalError(from *uppaal.State, name, guard string, loc uppaal.Location, rvs *randomVariableSupplier, pos token.Pos, ctx *context) *uppaal.State {
+	fatal := ctx.proc.AddState(name, uppaal.Renaming)
+	fatal.SetComment(t.program.FileSet().Position(pos).String())
+	fatal.SetType(uppaal.Committed)
+	fatal.SetLocationAndResetNameAndCommentLocation(loc)
+
+	fail := ctx.proc.AddTransition(from, fatal)
+	fail.SetGuard(guard, true)
+	if rvs != nil {
+		rvs.addToTrans(fail)
+	}
+	fail.SetSelectLocation(fatal.Location().Sub(uppaal.Location{132, 88}))
+	fail.SetGuardLocation(fatal.Location().Sub(uppaal.Location{132, 72}))
+
+	terminated := ctx.proc.AddTransition(fatal, fatal)
+	terminated.AddNail(fatal.Location().Add(uppaal.Location{-17, 34}))
+	terminated.AddNail(fatal.Location().Add(uppaal.Location{17, 34}))
+
+	ctx.addLocation(fatal.Location())
+	return fatal
+}
@@ -3,6 +3,7 @@ package translator
 import (
 	"fmt"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 	"github.com/arneph/toph/uppaal"
 )
@@ -11,36 +12,66 @@ func (t *translator) translateMutexOpStmt(stmt *ir.MutexOpStmt, ctx *context) {
 	var rvs randomVariableSupplier
 	handle, _ := t.translateLValue(stmt.Mutex(), &rvs, ctx)
 	name := stmt.Mutex().Name()
+	if stmt.IsTry() {
+		t.translateMutexTryOpStmt(stmt, handle, name, &rvs, ctx)
+		return
+	}
 	var isLock bool
 	var registeredName, completedName, registerUpdate, sync, completeUpdate string
+	var fatalName, fatalGuard, fatalDesc string
 
 	mutexVar := "op_mutex"
 	assign := mutexVar + " = " + handle
 	ctx.proc.Declarations().AddVariable(mutexVar, "int", "0")
 
+	isRW := stmt.Mutex().Type() == ir.RWMutexType
 	switch stmt.Op() {
 	case ir.Lock:
 		isLock = true
 		registeredName = "awaiting_write_lock"
 		completedName = "aquired_write_lock"
-		registerUpdate = fmt.Sprintf("mutex_pending_writers[%s]++", mutexVar)
-		sync = fmt.Sprintf("write_lock[%s]!", mutexVar)
-		completeUpdate = fmt.Sprintf("mutex_pending_writers[%s]--", mutexVar)
+		if isRW {
+			registerUpdate = fmt.Sprintf("rw_mutex_pending_writers[%s]++", mutexVar)
+			sync = fmt.Sprintf("rw_write_lock[%s]!", mutexVar)
+			completeUpdate = fmt.Sprintf("rw_mutex_pending_writers[%s]--", mutexVar)
+		} else {
+			sync = fmt.Sprintf("lock[%s]!", mutexVar)
+		}
 	case ir.RLock:
+		if !isRW {
+			break
+		}
 		isLock = true
 		registeredName = "awaiting_read_lock"
 		completedName = "aquired_read_lock"
-		registerUpdate = fmt.Sprintf("mutex_pending_readers[%s]++", mutexVar)
-		sync = fmt.Sprintf("read_lock[%s]!", mutexVar)
-		completeUpdate = fmt.Sprintf("mutex_pending_readers[%s]--", mutexVar)
+		registerUpdate = fmt.Sprintf("rw_mutex_pending_readers[%s]++", mutexVar)
+		sync = fmt.Sprintf("rw_read_lock[%s]!", mutexVar)
+		completeUpdate = fmt.Sprintf("rw_mutex_pending_readers[%s]--", mutexVar)
 	case ir.RUnlock:
+		if !isRW {
+			break
+		}
 		completedName = "released_read_lock"
-		sync = fmt.Sprintf("read_unlock[%s]!", handle)
+		sync = fmt.Sprintf("rw_read_unlock[%s]!", handle)
+		fatalName = "read_unlocking_unlocked"
+		fatalGuard = fmt.Sprintf("rw_mutex_readers[%s] == 0", handle)
+		fatalDesc = "check read unlock of unlocked mutex unreachable"
 	case ir.Unlock:
 		completedName = "released_write_lock"
-		sync = fmt.Sprintf("write_unlock[%s]!", handle)
-	default:
-		t.addWarning(fmt.Errorf("unsupported MutexOp: %v", stmt.Op()))
+		fatalName = "unlocking_unlocked"
+		fatalDesc = "check unlock of unlocked mutex unreachable"
+		if isRW {
+			sync = fmt.Sprintf("rw_write_unlock[%s]!", handle)
+			fatalGuard = fmt.Sprintf("!rw_mutex_writer[%s]", handle)
+		} else {
+			sync = fmt.Sprintf("unlock[%s]!", handle)
+			fatalGuard = fmt.Sprintf("!mutex_locked[%s]", handle)
+		}
+	}
+	if sync == "" {
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "unsupported MutexOp on %v: %v", stmt.Mutex().Type(), stmt.Op()))
+		return
 	}
 
 	if isLock {
@@ -52,7 +83,9 @@ func (t *translator) translateMutexOpStmt(stmt *ir.MutexOpStmt, ctx *context) {
 		register := ctx.proc.AddTransition(ctx.currentState, registered)
 		rvs.addToTrans(register)
 		register.AddUpdate(assign, true)
-		register.AddUpdate(registerUpdate, true)
+		if registerUpdate != "" {
+			register.AddUpdate(registerUpdate, true)
+		}
 		register.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
 		register.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
 		register.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
@@ -65,6 +98,8 @@ func (t *translator) translateMutexOpStmt(stmt *ir.MutexOpStmt, ctx *context) {
 				uppaal.NoMutexRelatedDeadlocks))
 		}
 
+		t.addGoroutineLeakQuery(registered, "mutex", stmt.Pos(), ctx)
+
 		ctx.currentState = registered
 		ctx.addLocation(registered.Location())
 	}
@@ -74,11 +109,25 @@ func (t *translator) translateMutexOpStmt(stmt *ir.MutexOpStmt, ctx *context) {
 	completed.SetLocationAndResetNameAndCommentLocation(
 		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
 	complete := ctx.proc.AddTransition(ctx.currentState, completed)
+	if fatalName != "" {
+		// Unlocking an unlocked mutex is a fatal error:
+		fatal := t.addFatalError(ctx.currentState, fatalName+"_"+name+"_", fatalGuard,
+			ctx.currentState.Location().Add(uppaal.Location{136, 136}), &rvs, stmt.Pos(), ctx)
+		if t.config.GenerateMutexSafetyQueries {
+			ctx.proc.AddQuery(uppaal.NewQuery(
+				"A[] (not out_of_resources) imply (not $."+fatal.Name()+")",
+				fatalDesc,
+				t.program.FileSet().Position(stmt.Pos()).String(),
+				uppaal.MutexSafety))
+		}
+
+		complete.SetGuard("!("+fatalGuard+")", true)
+	}
 	if !isLock {
 		rvs.addToTrans(complete)
 	}
 	complete.SetSync(sync)
-	if isLock {
+	if completeUpdate != "" {
 		complete.AddUpdate(completeUpdate, true)
 	}
 	complete.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
@@ -89,3 +138,60 @@ func (t *translator) translateMutexOpStmt(stmt *ir.MutexOpStmt, ctx *context) {
 	ctx.currentState = completed
 	ctx.addLocation(completed.Location())
 }
+
+// translateMutexTryOpStmt translates TryLock and TryRLock operations. These
+// acquire the mutex if that is possible without blocking and otherwise fail
+// immediately. The result variable (if any) records the outcome.
+func (t *translator) translateMutexTryOpStmt(stmt *ir.MutexOpStmt, handle, name string, rvs *randomVariableSupplier, ctx *context) {
+	isRW := stmt.Mutex().Type() == ir.RWMutexType
+	var completedName, sync, freeGuard string
+	switch {
+	case stmt.Op() == ir.TryLock && !isRW:
+		completedName = "tried_write_lock"
+		sync = fmt.Sprintf("lock[%s]!", handle)
+		freeGuard = fmt.Sprintf("!mutex_locked[%s]", handle)
+	case stmt.Op() == ir.TryLock && isRW:
+		completedName = "tried_write_lock"
+		sync = fmt.Sprintf("rw_write_lock[%s]!", handle)
+		freeGuard = fmt.Sprintf("!rw_mutex_writer[%[1]s] && rw_mutex_readers[%[1]s] == 0 && rw_mutex_pending_writers[%[1]s] == 0", handle)
+	case stmt.Op() == ir.TryRLock && isRW:
+		completedName = "tried_read_lock"
+		sync = fmt.Sprintf("rw_read_lock[%s]!", handle)
+		freeGuard = fmt.Sprintf("!rw_mutex_writer[%[1]s] && rw_mutex_pending_writers[%[1]s] == 0", handle)
+	default:
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "unsupported MutexOp on %v: %v", stmt.Mutex().Type(), stmt.Op()))
+		return
+	}
+
+	completed := ctx.proc.AddState(completedName+"_"+name+"_", uppaal.Renaming)
+	completed.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	completed.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
+
+	succeed := ctx.proc.AddTransition(ctx.currentState, completed)
+	succeed.SetGuard(freeGuard, true)
+	rvs.addToTrans(succeed)
+	succeed.SetSync(sync)
+	succeed.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
+	succeed.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
+	succeed.SetSyncLocation(ctx.currentState.Location().Add(uppaal.Location{4, 80}))
+	succeed.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 96}))
+
+	fail := ctx.proc.AddTransition(ctx.currentState, completed)
+	fail.SetGuard("!("+freeGuard+")", true)
+	rvs.addToTrans(fail)
+	fail.SetSelectLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 48}))
+	fail.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 64}))
+	fail.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 96}))
+	fail.AddNail(ctx.currentState.Location().Add(uppaal.Location{-34, 68}))
+
+	if result := stmt.Result(); result != nil {
+		resultHandle, usesGlobals := t.translateVariable(result, ctx)
+		succeed.AddUpdate(resultHandle+" = 1", usesGlobals)
+		fail.AddUpdate(resultHandle+" = 0", usesGlobals)
+	}
+
+	ctx.currentState = completed
+	ctx.addLocation(completed.Location())
+}
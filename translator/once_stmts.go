@@ -80,6 +80,8 @@ func (t *translator) translateOnceDoStmt(stmt *ir.OnceDoStmt, ctx *context) {
 			uppaal.NoOnceRelatedDeadlocks))
 	}
 
+	t.addGoroutineLeakQuery(enter, "once", stmt.Pos(), ctx)
+
 	ctx.currentState = exit
 	ctx.addLocation(do.Location())
 	ctx.addLocation(exit.Location())
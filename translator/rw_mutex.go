@@ -0,0 +1,189 @@
+package translator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/arneph/toph/ir"
+	"github.com/arneph/toph/uppaal"
+)
+
+func (t *translator) rwMutexCount() int {
+	rwMutexCount := t.completeFCG.TotalTypeAllocations(ir.RWMutexType)
+	if rwMutexCount < 1 {
+		rwMutexCount = 1
+	} else if rwMutexCount > t.config.MaxMutexCount {
+		rwMutexCount = t.config.MaxMutexCount
+	}
+	return rwMutexCount
+}
+
+func (t *translator) addRWMutexes() {
+	t.addRWMutexProcess()
+	t.addRWMutexDeclarations()
+	t.addRWMutexProcessInstances()
+}
+
+// addRWMutexProcess adds the RWMutex template, which models the writer
+// preference of sync.RWMutex: once a writer is pending, new readers block
+// until the writer acquired and released the mutex. Readers blocked by a
+// writer acquire the mutex before the next writer does.
+func (t *translator) addRWMutexProcess() {
+	proc := t.system.AddProcess("RWMutex")
+	t.rwMutexProcess = proc
+
+	// Parameters:
+	proc.AddParameter(fmt.Sprintf("int[0, %d] i", t.rwMutexCount()-1))
+
+	// States:
+	idle := proc.AddState("idle", uppaal.NoRenaming)
+	idle.SetLocation(uppaal.Location{170, 306})
+	idle.SetNameLocation(uppaal.Location{187, 298})
+
+	proc.SetInitialState(idle)
+
+	writeLocked := proc.AddState("write_locked", uppaal.NoRenaming)
+	writeLocked.SetLocation(uppaal.Location{0, 306})
+	writeLocked.SetNameLocation(uppaal.Location{17, 298})
+
+	readLocked := proc.AddState("read_locked", uppaal.NoRenaming)
+	readLocked.SetLocation(uppaal.Location{544, 306})
+	readLocked.SetNameLocation(uppaal.Location{561, 298})
+
+	releasingReaders := proc.AddState("releasing_readers", uppaal.NoRenaming)
+	releasingReaders.SetType(uppaal.Committed)
+	releasingReaders.SetLocation(uppaal.Location{170, 476})
+	releasingReaders.SetNameLocation(uppaal.Location{74, 508})
+
+	// Transitions:
+	// Idle, Write locked:
+	trans1 := proc.AddTransition(idle, writeLocked)
+	trans1.SetSync("rw_write_lock[i]?")
+	trans1.SetSyncLocation(uppaal.Location{38, 222})
+	trans1.AddUpdate("rw_mutex_writer[i] = true", true)
+	trans1.SetUpdateLocation(uppaal.Location{38, 238})
+	trans1.AddNail(uppaal.Location{136, 238})
+	trans1.AddNail(uppaal.Location{34, 238})
+
+	// Write locked, Releasing readers (readers blocked by the writer go
+	// first):
+	trans2 := proc.AddTransition(writeLocked, releasingReaders)
+	trans2.SetSync("rw_write_unlock[i]?")
+	trans2.SetSyncLocation(uppaal.Location{38, 476})
+	trans2.AddUpdate("rw_mutex_writer[i] = false", true)
+	trans2.SetUpdateLocation(uppaal.Location{38, 492})
+	trans2.AddNail(uppaal.Location{0, 476})
+
+	trans3 := proc.AddTransition(releasingReaders, releasingReaders)
+	trans3.SetGuard("rw_mutex_pending_readers[i] > 0", true)
+	trans3.SetGuardLocation(uppaal.Location{208, 528})
+	trans3.SetSync("rw_read_lock[i]?")
+	trans3.SetSyncLocation(uppaal.Location{208, 544})
+	trans3.AddUpdate("rw_mutex_readers[i]++", true)
+	trans3.SetUpdateLocation(uppaal.Location{208, 560})
+	trans3.AddNail(uppaal.Location{136, 544})
+	trans3.AddNail(uppaal.Location{204, 544})
+
+	trans4 := proc.AddTransition(releasingReaders, idle)
+	trans4.SetGuard("rw_mutex_pending_readers[i] == 0 && \nrw_mutex_readers[i] == 0", true)
+	trans4.SetGuardLocation(uppaal.Location{38, 374})
+
+	trans5 := proc.AddTransition(releasingReaders, readLocked)
+	trans5.SetGuard("rw_mutex_pending_readers[i] == 0 && \nrw_mutex_readers[i] > 0", true)
+	trans5.SetGuardLocation(uppaal.Location{208, 444})
+	trans5.AddNail(uppaal.Location{544, 476})
+
+	// Idle, Read locked:
+	trans6 := proc.AddTransition(idle, readLocked)
+	trans6.SetGuard("rw_mutex_pending_writers[i] == 0", true)
+	trans6.SetGuardLocation(uppaal.Location{208, 206})
+	trans6.SetSync("rw_read_lock[i]?")
+	trans6.SetSyncLocation(uppaal.Location{208, 222})
+	trans6.AddUpdate("rw_mutex_readers[i]++", true)
+	trans6.SetUpdateLocation(uppaal.Location{208, 238})
+	trans6.AddNail(uppaal.Location{204, 238})
+	trans6.AddNail(uppaal.Location{510, 238})
+
+	trans7 := proc.AddTransition(readLocked, idle)
+	trans7.SetGuard("rw_mutex_readers[i] == 1", true)
+	trans7.SetGuardLocation(uppaal.Location{208, 358})
+	trans7.SetSync("rw_read_unlock[i]?")
+	trans7.SetSyncLocation(uppaal.Location{208, 374})
+	trans7.AddUpdate("rw_mutex_readers[i]--", true)
+	trans7.SetUpdateLocation(uppaal.Location{208, 390})
+	trans7.AddNail(uppaal.Location{510, 374})
+	trans7.AddNail(uppaal.Location{204, 374})
+
+	// Read locked:
+	trans8 := proc.AddTransition(readLocked, readLocked)
+	trans8.SetGuard("rw_mutex_pending_writers[i] == 0", true)
+	trans8.SetGuardLocation(uppaal.Location{650, 206})
+	trans8.SetSync("rw_read_lock[i]?")
+	trans8.SetSyncLocation(uppaal.Location{650, 222})
+	trans8.AddUpdate("rw_mutex_readers[i]++", true)
+	trans8.SetUpdateLocation(uppaal.Location{650, 238})
+	trans8.AddNail(uppaal.Location{612, 204})
+	trans8.AddNail(uppaal.Location{646, 204})
+	trans8.AddNail(uppaal.Location{646, 272})
+
+	trans9 := proc.AddTransition(readLocked, readLocked)
+	trans9.SetGuard("rw_mutex_readers[i] > 1", true)
+	trans9.SetGuardLocation(uppaal.Location{650, 358})
+	trans9.SetSync("rw_read_unlock[i]?")
+	trans9.SetSyncLocation(uppaal.Location{650, 374})
+	trans9.AddUpdate("rw_mutex_readers[i]--", true)
+	trans9.SetUpdateLocation(uppaal.Location{650, 390})
+	trans9.AddNail(uppaal.Location{612, 408})
+	trans9.AddNail(uppaal.Location{646, 408})
+	trans9.AddNail(uppaal.Location{646, 340})
+}
+
+func (t *translator) addRWMutexDeclarations() {
+	t.system.Declarations().AddVariable("rw_mutex_count", "int", "0")
+	t.system.Declarations().AddArray("rw_mutex_pending_readers", []int{t.rwMutexCount()}, "int")
+	t.system.Declarations().AddArray("rw_mutex_pending_writers", []int{t.rwMutexCount()}, "int")
+	t.system.Declarations().AddArray("rw_mutex_readers", []int{t.rwMutexCount()}, "int")
+	t.system.Declarations().AddArray("rw_mutex_writer", []int{t.rwMutexCount()}, "bool")
+	t.system.Declarations().AddArray("rw_read_lock", []int{t.rwMutexCount()}, t.chanType())
+	t.system.Declarations().AddArray("rw_read_unlock", []int{t.rwMutexCount()}, t.chanType())
+	t.system.Declarations().AddArray("rw_write_lock", []int{t.rwMutexCount()}, t.chanType())
+	t.system.Declarations().AddArray("rw_write_unlock", []int{t.rwMutexCount()}, t.chanType())
+	t.system.Declarations().AddSpaceBetweenVariables()
+
+	t.system.Declarations().AddFunc(fmt.Sprintf(
+		`int make_rw_mutex() {
+	int mid;
+	if (rw_mutex_count >= %d) {
+		rw_mutex_count++;
+		out_of_resources = true;
+		return 0;
+	}
+	mid = rw_mutex_count;
+	rw_mutex_count++;
+	rw_mutex_pending_readers[mid] = 0;
+	rw_mutex_pending_writers[mid] = 0;
+	rw_mutex_readers[mid] = 0;
+	rw_mutex_writer[mid] = false;
+	return mid;
+}`, t.rwMutexCount()))
+	if t.config.GenerateIndividualResourceBoundQueries {
+		t.system.AddQuery(uppaal.NewQuery(
+			fmt.Sprintf("A[] rw_mutex_count < %d", t.rwMutexCount()+1),
+			"check resource bound never reached through rw mutex creation",
+			"",
+			uppaal.ResourceBoundUnreached))
+	}
+}
+
+func (t *translator) addRWMutexProcessInstances() {
+	c := t.rwMutexCount()
+	if c > 1 {
+		c--
+	}
+	d := fmt.Sprintf("%d", int(math.Log10(float64(c))+1))
+	for i := 0; i < t.rwMutexCount(); i++ {
+		instName := fmt.Sprintf("%s%0"+d+"d", t.rwMutexProcess.Name(), i)
+		inst := t.system.AddProcessInstance(t.rwMutexProcess, instName)
+		inst.AddParameter(fmt.Sprintf("%d", i))
+	}
+}
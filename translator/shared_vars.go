@@ -0,0 +1,116 @@
+package translator
+
+import (
+	"fmt"
+
+	"github.com/arneph/toph/diag"
+	"github.com/arneph/toph/ir"
+	"github.com/arneph/toph/uppaal"
+)
+
+// isSharedVarChecked returns whether accesses of the given shared variable
+// get modeled. Annotated shared variables always get checked, inferred shared
+// variables only if several goroutines might access them.
+func (t *translator) isSharedVarChecked(v *ir.SharedVar) bool {
+	if v.IsAnnotated() {
+		return true
+	}
+	usingFuncs := 0
+	for _, f := range t.vi.FuncsUsingSharedVar(v) {
+		if !t.isFuncUsed(f) {
+			continue
+		}
+		usingFuncs++
+		if usingFuncs > 1 || t.callCount(f) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *translator) translateSharedVars() {
+	for _, v := range t.program.SharedVars() {
+		if t.isSharedVarChecked(v) {
+			t.checkedSharedVars[v] = true
+		}
+	}
+}
+
+// translateAccessStmt models an access of a shared variable as a transition
+// into an accessing state, incrementing the access counter of the accessed
+// instance, and a transition out of it, decrementing the counter. Goroutines
+// can only be in accessing states of the same instance at the same time if
+// no synchronization orders their accesses. Read-read conflicts are harmless,
+// therefore only writes get checked: writes leave through a racing state if
+// other accesses of the instance are ongoing.
+func (t *translator) translateAccessStmt(stmt *ir.AccessStmt, ctx *context) {
+	v := stmt.SharedVar()
+	if !t.checkedSharedVars[v] {
+		return
+	}
+	rvs := new(randomVariableSupplier)
+	accesses, usesGlobals := t.translateLValue(stmt.Counter(), rvs, ctx)
+	if len(rvs.randomVars) > 0 {
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, v.Name(), "can not record access of nondeterministically chosen instance: %s", v.Name()))
+		return
+	}
+
+	var accessingName, accessedName string
+	switch stmt.Kind() {
+	case ir.Read:
+		accessingName = "reading"
+		accessedName = "read"
+	case ir.Write:
+		accessingName = "writing"
+		accessedName = "wrote"
+	default:
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "unsupported AccessKind: %v", stmt.Kind()))
+		return
+	}
+
+	accessing := ctx.proc.AddState(accessingName+"_"+v.Name()+"_", uppaal.Renaming)
+	accessing.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	accessing.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
+	enter := ctx.proc.AddTransition(ctx.currentState, accessing)
+	enter.AddUpdate(fmt.Sprintf("%s++", accesses), usesGlobals)
+	enter.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 60}))
+
+	accessed := ctx.proc.AddState(accessedName+"_"+v.Name()+"_", uppaal.Renaming)
+	accessed.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	accessed.SetLocationAndResetNameAndCommentLocation(
+		accessing.Location().Add(uppaal.Location{0, 136}))
+	leave := ctx.proc.AddTransition(accessing, accessed)
+	leave.AddUpdate(fmt.Sprintf("%s--", accesses), usesGlobals)
+	leave.SetUpdateLocation(accessing.Location().Add(uppaal.Location{4, 60}))
+
+	if stmt.Kind() == ir.Write && t.config.GenerateDataRaceQueries {
+		leave.SetGuard(fmt.Sprintf("%s == 1", accesses), usesGlobals)
+		leave.SetGuardLocation(accessing.Location().Add(uppaal.Location{4, 76}))
+
+		racing := ctx.proc.AddState("racing_on_"+v.Name()+"_", uppaal.Renaming)
+		racing.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+		racing.SetType(uppaal.Committed)
+		racing.SetLocationAndResetNameAndCommentLocation(
+			accessing.Location().Add(uppaal.Location{136, 68}))
+		race := ctx.proc.AddTransition(accessing, racing)
+		race.SetGuard(fmt.Sprintf("%s > 1", accesses), usesGlobals)
+		race.SetGuardLocation(accessing.Location().Add(uppaal.Location{68, 16}))
+		resolve := ctx.proc.AddTransition(racing, accessed)
+		resolve.AddUpdate(fmt.Sprintf("%s--", accesses), usesGlobals)
+		resolve.SetUpdateLocation(racing.Location().Add(uppaal.Location{4, 60}))
+
+		ctx.proc.AddQuery(uppaal.NewQuery(
+			"A[] (not out_of_resources) imply (not $."+racing.Name()+")",
+			"check no data race on "+v.Name(),
+			t.program.FileSet().Position(stmt.Pos()).String(),
+			uppaal.NoDataRaces))
+		ctx.addLocation(racing.Location())
+	}
+
+	ctx.currentState = accessed
+	ctx.addLocation(accessing.Location())
+	ctx.addLocation(accessed.Location())
+}
@@ -1,14 +1,15 @@
 package translator
 
 import (
-	"fmt"
-
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 	"github.com/arneph/toph/uppaal"
 )
 
 func (t *translator) translateStmt(stmt ir.Stmt, ctx *context) {
 	switch stmt := stmt.(type) {
+	case *ir.AccessStmt:
+		t.translateAccessStmt(stmt, ctx)
 	case *ir.AssignStmt:
 		t.translateAssignStmt(stmt, ctx)
 	case *ir.CallStmt:
@@ -17,6 +18,8 @@ func (t *translator) translateStmt(stmt ir.Stmt, ctx *context) {
 		t.translateReturnStmt(stmt, ctx)
 	case *ir.RecoverStmt:
 		t.translateRecoverStmt(stmt, ctx)
+	case *ir.GoexitStmt:
+		t.translateGoexitStmt(stmt, ctx)
 	case *ir.IfStmt:
 		t.translateIfStmt(stmt, ctx)
 	case *ir.SwitchStmt:
@@ -47,14 +50,21 @@ func (t *translator) translateStmt(stmt ir.Stmt, ctx *context) {
 		t.translateSelectStmt(stmt, ctx)
 	case *ir.DeadEndStmt:
 		t.translateDeadEndStmt(stmt, ctx)
+	case *ir.SleepStmt:
+		t.translateSleepStmt(stmt, ctx)
 	case *ir.MutexOpStmt:
 		t.translateMutexOpStmt(stmt, ctx)
 	case *ir.WaitGroupOpStmt:
 		t.translateWaitGroupOpSmt(stmt, ctx)
 	case *ir.OnceDoStmt:
 		t.translateOnceDoStmt(stmt, ctx)
+	case *ir.MakeCondStmt:
+		t.translateMakeCondStmt(stmt, ctx)
+	case *ir.CondOpStmt:
+		t.translateCondOpStmt(stmt, ctx)
 	default:
-		t.addWarning(fmt.Errorf("ignoring %T statement", stmt))
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "ignoring %T statement", stmt))
 	}
 }
 
@@ -0,0 +1,94 @@
+package translator
+
+import (
+	"github.com/arneph/toph/ir"
+	"github.com/arneph/toph/uppaal"
+)
+
+func (t *translator) translateSleepStmt(stmt *ir.SleepStmt, ctx *context) {
+	// Each function process instance has its own clock, which only gets
+	// reset when the goroutine starts sleeping.
+	clock := "clk"
+	ctx.proc.Declarations().AddVariable(clock, "clock", "")
+
+	duration, usesGlobals := t.translateRValue(stmt.Duration(), nil, ctx)
+	constDuration, isConst := stmt.Duration().(ir.Value)
+
+	slept := ctx.proc.AddState("slept_", uppaal.Renaming)
+	slept.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+	slept.SetLocationAndResetNameAndCommentLocation(
+		ctx.currentState.Location().Add(uppaal.Location{0, 272}))
+
+	if !isConst || constDuration.Value() >= 0 {
+		sleeping := ctx.proc.AddState("sleeping_", uppaal.Renaming)
+		sleeping.SetComment(t.program.FileSet().Position(stmt.Pos()).String())
+		sleeping.SetLocationAndResetNameAndCommentLocation(
+			ctx.currentState.Location().Add(uppaal.Location{0, 136}))
+		sleeping.SetInvariant(clock + " <= " + duration)
+
+		start := ctx.proc.AddTransition(ctx.currentState, sleeping)
+		if !isConst {
+			start.SetGuard(duration+" >= 0", usesGlobals)
+			start.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{4, 48}))
+		}
+		start.AddUpdate(clock+" = 0", false)
+		start.SetUpdateLocation(ctx.currentState.Location().Add(uppaal.Location{4, 64}))
+
+		wake := ctx.proc.AddTransition(sleeping, slept)
+		wake.SetGuard(clock+" >= "+duration, usesGlobals)
+		wake.SetGuardLocation(sleeping.Location().Add(uppaal.Location{4, 48}))
+
+		ctx.addLocation(sleeping.Location())
+	}
+
+	// Durations that could not be determined (negative values) do not delay
+	// the goroutine.
+	if !isConst || constDuration.Value() < 0 {
+		skip := ctx.proc.AddTransition(ctx.currentState, slept)
+		if !isConst {
+			skip.SetGuard(duration+" < 0", usesGlobals)
+			skip.SetGuardLocation(ctx.currentState.Location().Add(uppaal.Location{-132, 136}))
+		}
+	}
+
+	ctx.currentState = slept
+	ctx.addLocation(slept.Location())
+}
+
+// chanType returns the type of Uppaal channels used for synchronization
+// between processes. In timed mode, channels are urgent, such that time can
+// not pass while processes are able to synchronize.
+func (t *translator) chanType() string {
+	if t.config.Timed {
+		return "urgent chan"
+	}
+	return "chan"
+}
+
+// MakeInternalTransitionsUrgent prevents time from passing while any process
+// of the given timed system can take a transition without synchronizing with
+// another process. Together with urgent channels, this ensures that
+// goroutines act without delay and that time only passes while all
+// goroutines are blocked or sleeping. Uppaal has no urgent transitions,
+// therefore this is modeled by adding a send on an urgent broadcast channel
+// without receivers to these transitions. Transitions leaving states with
+// invariants (sleeping goroutines) wait for clock guards and remain
+// unchanged. The function should only be called after optimizing the system,
+// since the added synchronizations prevent transitions from being merged.
+func MakeInternalTransitionsUrgent(sys *uppaal.System) {
+	sys.Declarations().AddVariable("hurry", "urgent broadcast chan", "")
+
+	for _, proc := range sys.Processes() {
+		for _, state := range proc.States() {
+			if state.Type() == uppaal.Committed || state.Invariant() != "" {
+				continue
+			}
+			for _, trans := range state.OutgoingTransitions() {
+				if trans.Sync() != "" {
+					continue
+				}
+				trans.SetSync("hurry!")
+			}
+		}
+	}
+}
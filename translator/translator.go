@@ -2,6 +2,7 @@ package translator
 
 import (
 	c "github.com/arneph/toph/config"
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 	"github.com/arneph/toph/ir/analyzer"
 	"github.com/arneph/toph/uppaal"
@@ -12,6 +13,7 @@ func TranslateProg(program *ir.Program, config *c.Config) (*uppaal.System, []err
 	t := new(translator)
 	t.program = program
 	t.funcToProcess = make(map[*ir.Func]*uppaal.Process)
+	t.checkedSharedVars = make(map[*ir.SharedVar]bool)
 	t.system = uppaal.NewSystem()
 	t.vi = analyzer.FindVarInfo(program)
 	t.tg = analyzer.BuildTypeGraph(program)
@@ -28,10 +30,14 @@ type translator struct {
 	program       *ir.Program
 	funcToProcess map[*ir.Func]*uppaal.Process
 
+	checkedSharedVars map[*ir.SharedVar]bool
+
 	system           *uppaal.System
 	channelProcess   *uppaal.Process
 	mutexProcess     *uppaal.Process
+	rwMutexProcess   *uppaal.Process
 	waitGroupProcess *uppaal.Process
+	condProcess      *uppaal.Process
 
 	vi *analyzer.VarInfo
 	tg *analyzer.TypeGraph
@@ -44,8 +50,8 @@ type translator struct {
 	warnings []error
 }
 
-func (t *translator) addWarning(err error) {
-	t.warnings = append(t.warnings, err)
+func (t *translator) addWarning(w *diag.Warning) {
+	t.warnings = append(t.warnings, w)
 }
 
 func (t *translator) translateProgram() {
@@ -72,6 +78,7 @@ func (t *translator) translateProgram() {
 	}
 
 	t.translateGlobalScope()
+	t.translateSharedVars()
 
 	for _, f := range t.program.Funcs() {
 		if !t.isFuncUsed(f) {
@@ -81,10 +81,14 @@ fid make_fid(int id, int par_pid) {
 			t.addChannels()
 		case ir.MutexType:
 			t.addMutexes()
+		case ir.RWMutexType:
+			t.addRWMutexes()
 		case ir.WaitGroupType:
 			t.addWaitGroups()
 		case ir.OnceType:
 			t.addOnces()
+		case ir.CondType:
+			t.addConds()
 		default:
 			panic(fmt.Errorf("unexpected ir.BasicType: %d", irType))
 		}
@@ -142,6 +146,8 @@ func (t *translator) addStructType(structType *ir.StructType) {
 		oldFieldHandle := fmt.Sprintf("%s_structs[old_sid].%s", structType.VariablePrefix(), field.Handle())
 		if field.RequiresDeepCopy() {
 			oldFieldHandle = t.translateCopyOfRValue(oldFieldHandle, field.Type())
+		} else if field.IsCounter() {
+			oldFieldHandle = initializedValue
 		}
 		fmt.Fprintf(&uninitializeFieldsStmts, "\t\t%s = %s;\n", fieldHandle, uninitializedValue)
 		fmt.Fprintf(&initializeFieldsStmts, "\t\t%s = %s;\n", fieldHandle, initializedValue)
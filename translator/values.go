@@ -67,6 +67,10 @@ func (t *translator) translateRValue(v ir.RValue, rvs *randomVariableSupplier, c
 		return t.translateContainerLength(v, rvs, ctx)
 	case *ir.ContainerAccess:
 		return t.translateContainerAccess(v, rvs, ctx)
+	case *ir.BinaryExpr:
+		return t.translateBinaryExpr(v, rvs, ctx)
+	case *ir.RandomInt:
+		return rvs.next(v.Min(), v.Max()), false
 	default:
 		panic(fmt.Errorf("unexpected %T rvalue type", v))
 	}
@@ -96,6 +100,8 @@ func (t *translator) translateValue(v ir.Value) string {
 	switch v {
 	case ir.InitializedMutex:
 		return "make_mutex()"
+	case ir.InitializedRWMutex:
+		return "make_rw_mutex()"
 	case ir.InitializedWaitGroup:
 		return "make_wait_group()"
 	case ir.InitializedOnce:
@@ -116,6 +122,12 @@ func (t *translator) translateValue(v ir.Value) string {
 	return v.String()
 }
 
+func (t *translator) translateBinaryExpr(e *ir.BinaryExpr, rvs *randomVariableSupplier, ctx *context) (string, bool) {
+	x, xUsesGlobals := t.translateRValue(e.X(), rvs, ctx)
+	y, yUsesGlobals := t.translateRValue(e.Y(), rvs, ctx)
+	return fmt.Sprintf("(%s %s %s)", x, e.Op(), y), xUsesGlobals || yUsesGlobals
+}
+
 func (t *translator) translateFieldSelection(fs *ir.FieldSelection, rvs *randomVariableSupplier, ctx *context) (string, bool) {
 	handle, usesGlobals := t.translateLValue(fs.StructVal(), rvs, ctx)
 	return fmt.Sprintf("%s_structs[%s].%s",
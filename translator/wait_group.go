@@ -35,14 +35,6 @@ func (t *translator) addWaitGroupProcess() {
 	// Parameters:
 	proc.AddParameter(fmt.Sprintf("int[0, %d] i", t.waitGroupCount()-1))
 
-	// Queries:
-	if t.config.GenerateWaitGroupSafetyQueries {
-		proc.AddQuery(uppaal.NewQuery(
-			"A[] (not out_of_resources) imply (not $.bad)",
-			"check WaitGroup.bad state unreachable", "",
-			uppaal.WaitGroupSafety))
-	}
-
 	// States:
 	idle := proc.AddState("idle", uppaal.NoRenaming)
 	idle.SetLocation(uppaal.Location{0, 0})
@@ -59,10 +51,6 @@ func (t *translator) addWaitGroupProcess() {
 	active.SetLocation(uppaal.Location{442, 0})
 	active.SetNameLocation(uppaal.Location{459, -8})
 
-	bad := proc.AddState("bad", uppaal.NoRenaming)
-	bad.SetLocation(uppaal.Location{238, -136})
-	bad.SetNameLocation(uppaal.Location{255, -144})
-
 	// Transitions:
 	// Idle:
 	trans1 := proc.AddTransition(idle, idle)
@@ -100,26 +88,14 @@ func (t *translator) addWaitGroupProcess() {
 	trans5.SetSyncLocation(uppaal.Location{276, 68})
 	trans5.AddNail(uppaal.Location{408, 68})
 	trans5.AddNail(uppaal.Location{272, 68})
-
-	// Bad:
-	trans6 := proc.AddTransition(idle, bad)
-	trans6.SetGuard("wait_group_waiters[i] > 0", true)
-	trans6.SetGuardLocation(uppaal.Location{38, -152})
-	trans6.SetSync("add[i]?")
-	trans6.SetSyncLocation(uppaal.Location{38, -136})
-	trans6.AddNail(uppaal.Location{0, -136})
-
-	trans7 := proc.AddTransition(adding, bad)
-	trans7.SetGuard("wait_group_counter[i] < 0", true)
-	trans7.SetGuardLocation(uppaal.Location{242, -110})
 }
 
 func (t *translator) addWaitGroupDeclarations() {
 	t.system.Declarations().AddVariable("wait_group_count", "int", "0")
 	t.system.Declarations().AddArray("wait_group_counter", []int{t.waitGroupCount()}, "int")
 	t.system.Declarations().AddArray("wait_group_waiters", []int{t.waitGroupCount()}, "int")
-	t.system.Declarations().AddArray("add", []int{t.waitGroupCount()}, "chan")
-	t.system.Declarations().AddArray("wait", []int{t.waitGroupCount()}, "chan")
+	t.system.Declarations().AddArray("add", []int{t.waitGroupCount()}, t.chanType())
+	t.system.Declarations().AddArray("wait", []int{t.waitGroupCount()}, t.chanType())
 	t.system.Declarations().AddSpaceBetweenVariables()
 
 	t.system.Declarations().AddFunc(fmt.Sprintf(
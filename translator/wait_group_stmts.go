@@ -3,6 +3,7 @@ package translator
 import (
 	"fmt"
 
+	"github.com/arneph/toph/diag"
 	"github.com/arneph/toph/ir"
 	"github.com/arneph/toph/uppaal"
 )
@@ -13,6 +14,7 @@ func (t *translator) translateWaitGroupOpSmt(stmt *ir.WaitGroupOpStmt, ctx *cont
 	name := stmt.WaitGroup().Name()
 	var isWait bool
 	var registeredName, completedName, registerUpdate, sync, completeUpdate string
+	var delta string
 
 	waitGroupVar := "op_wait_group"
 	assign := waitGroupVar + " = " + handle
@@ -20,7 +22,7 @@ func (t *translator) translateWaitGroupOpSmt(stmt *ir.WaitGroupOpStmt, ctx *cont
 
 	switch stmt.Op() {
 	case ir.Add:
-		delta, _ := t.translateRValue(stmt.Delta(), nil, ctx)
+		delta, _ = t.translateRValue(stmt.Delta(), nil, ctx)
 		completedName = "added_to_wait_group"
 		sync = fmt.Sprintf("add[%s]!", handle)
 		completeUpdate = fmt.Sprintf("wait_group_counter[%s] += %s", handle, delta)
@@ -32,7 +34,8 @@ func (t *translator) translateWaitGroupOpSmt(stmt *ir.WaitGroupOpStmt, ctx *cont
 		sync = fmt.Sprintf("wait[%s]?", waitGroupVar)
 		completeUpdate = fmt.Sprintf("wait_group_waiters[%s]--", waitGroupVar)
 	default:
-		t.addWarning(fmt.Errorf("unsupported WaitGroupOp: %v", stmt.Op()))
+		p := t.program.FileSet().Position(stmt.Pos())
+		t.addWarning(diag.Warningf(diag.UnsupportedStatement, p, "", "unsupported WaitGroupOp: %v", stmt.Op()))
 	}
 
 	if isWait {
@@ -57,6 +60,8 @@ func (t *translator) translateWaitGroupOpSmt(stmt *ir.WaitGroupOpStmt, ctx *cont
 				uppaal.NoWaitGroupRelatedDeadlocks))
 		}
 
+		t.addGoroutineLeakQuery(registered, "wait group", stmt.Pos(), ctx)
+
 		ctx.currentState = registered
 		ctx.addLocation(registered.Location())
 	}
@@ -66,6 +71,21 @@ func (t *translator) translateWaitGroupOpSmt(stmt *ir.WaitGroupOpStmt, ctx *cont
 	completed.SetLocationAndResetNameAndCommentLocation(
 		ctx.currentState.Location().Add(uppaal.Location{0, 136}))
 	complete := ctx.proc.AddTransition(ctx.currentState, completed)
+	if stmt.Op() == ir.Add {
+		// A negative counter is a fatal error:
+		fatalGuard := fmt.Sprintf("wait_group_counter[%s] + %s < 0", handle, delta)
+		fatal := t.addFatalError(ctx.currentState, "negative_wait_group_counter_"+name+"_", fatalGuard,
+			ctx.currentState.Location().Add(uppaal.Location{136, 136}), &rvs, stmt.Pos(), ctx)
+		if t.config.GenerateWaitGroupSafetyQueries {
+			ctx.proc.AddQuery(uppaal.NewQuery(
+				"A[] (not out_of_resources) imply (not $."+fatal.Name()+")",
+				"check negative wait group counter unreachable",
+				t.program.FileSet().Position(stmt.Pos()).String(),
+				uppaal.WaitGroupSafety))
+		}
+
+		complete.SetGuard("!("+fatalGuard+")", true)
+	}
 	if !isWait {
 		rvs.addToTrans(complete)
 	}
@@ -2,12 +2,13 @@ package uppaal
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 type variableInfo struct {
 	name         string
-	dimensions   []int
+	dimensions   []string
 	_type        string
 	initialValue string
 }
@@ -76,7 +77,10 @@ func (d *Declarations) AddArray(name string, dimensions []int, _type string) {
 		d.variableLookup[name] = i
 	}
 
-	d.variables[i].dimensions = dimensions
+	d.variables[i].dimensions = make([]string, len(dimensions))
+	for j, dim := range dimensions {
+		d.variables[i].dimensions[j] = strconv.Itoa(dim)
+	}
 	d.variables[i]._type = _type
 	d.variables[i].initialValue = ""
 }
@@ -123,7 +127,7 @@ func (d *Declarations) AsXTA() string {
 		}
 		fmt.Fprintf(&b, "%s %s", info._type, info.name)
 		for _, dim := range info.dimensions {
-			fmt.Fprintf(&b, "[%d]", dim)
+			fmt.Fprintf(&b, "[%s]", dim)
 		}
 		if info.initialValue != "" {
 			fmt.Fprintf(&b, " = %s", info.initialValue)
@@ -1,6 +1,8 @@
 package optimizer
 
 import (
+	"regexp"
+
 	"github.com/arneph/toph/uppaal"
 )
 
@@ -32,22 +34,44 @@ func reduceStatesInProcess(process *uppaal.Process) {
 		}
 	}
 
+	queriedStates := findQueriedStates(process)
 	for _, state := range sortedStates {
+		if queriedStates[state.Name()] {
+			continue
+		}
 		removeStateIfPossible(process, state)
 	}
 }
 
+var queriedStateRegexp = regexp.MustCompile(`\$\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// findQueriedStates returns the names of all states referenced by queries of
+// the given process. These states can not be removed.
+func findQueriedStates(process *uppaal.Process) map[string]bool {
+	queriedStates := make(map[string]bool)
+	for _, query := range process.Queries() {
+		for _, match := range queriedStateRegexp.FindAllStringSubmatch(query.Query(), -1) {
+			queriedStates[match[1]] = true
+		}
+	}
+	return queriedStates
+}
+
 func removeStateIfPossible(process *uppaal.Process, state *uppaal.State) {
 	// Check if state can be removed:
-	if state.IsInitialState() || state.Type() != uppaal.Normal {
+	if state.IsInitialState() || state.Type() != uppaal.Normal || state.Invariant() != "" {
 		return
 	}
 
+	// Guards of transitions leaving states with invariants can not be
+	// combined with later guards, since this could prevent the process from
+	// leaving the state in time.
 	oldTrans := append([]*uppaal.Trans{}, state.Transitions()...)
 	for _, trans := range oldTrans {
 		if trans.Start() == trans.End() ||
 			trans.Start().Type() != uppaal.Normal ||
 			trans.End().Type() != uppaal.Normal ||
+			trans.Start().Invariant() != "" ||
 			trans.Select() != "" {
 			return
 		}
@@ -0,0 +1,636 @@
+package uppaal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	identRegex      = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	trailingIdent   = regexp.MustCompile(`[A-Za-z_]\w*$`)
+	instanceRegex   = regexp.MustCompile(`(?s)^(\w+)\s*=\s*(\w+)\s*\((.*)\)\s*;$`)
+	stateRegex      = regexp.MustCompile(`(?s)^(\w+)\s*(?:\{(.*)\})?$`)
+	transRegex      = regexp.MustCompile(`(?s)^(\w+)?\s*->\s*(\w+)\s*\{(.*)\}$`)
+	labelRegex      = regexp.MustCompile(`(?s)^(\w+)\s+(.*)$`)
+	keywordRegex    = regexp.MustCompile(`^(\w+)`)
+	blockStartRegex = regexp.MustCompile(`(?s)(\)|^progress)\s*$`)
+	emptyTransRegex = regexp.MustCompile(`\btrans\s*$`)
+)
+
+// item is a top-level declaration or statement of Uppaal source text.
+type item struct {
+	text string
+	line int
+	// newlines is the number of line breaks between the preceding item and
+	// the item.
+	newlines int
+}
+
+func (it item) keyword() string {
+	return keywordRegex.FindString(it.text)
+}
+
+// splitItems splits the given Uppaal source text, starting at the given line,
+// into top-level items. Items end with a semicolon or, for function, process,
+// and progress measure definitions, with the closing brace of their body.
+// Comments between items get dropped, comments within items are kept.
+func splitItems(src string, line int) ([]item, error) {
+	var items []item
+	newlines := 0
+	for i := 0; i < len(src); {
+		switch {
+		case src[i] == '\n':
+			line++
+			newlines++
+			i++
+		case src[i] == ' ' || src[i] == '\t' || src[i] == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			if j := strings.IndexByte(src[i:], '\n'); j == -1 {
+				i = len(src)
+			} else {
+				i += j
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			j := strings.Index(src[i+2:], "*/")
+			if j == -1 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			n := strings.Count(src[i:i+j+4], "\n")
+			line += n
+			newlines += n
+			i += j + 4
+		default:
+			end, err := scanItem(src, i, line)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item{
+				text:     strings.TrimSpace(src[i:end]),
+				line:     line,
+				newlines: newlines,
+			})
+			line += strings.Count(src[i:end], "\n")
+			newlines = 0
+			i = end
+		}
+	}
+	return items, nil
+}
+
+// scanItem returns the end of the item starting at the given index.
+func scanItem(src string, start, line int) (end int, err error) {
+	depth := 0
+	block := false
+	for i := start; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\n':
+			line++
+		case strings.HasPrefix(src[i:], "//"):
+			if j := strings.IndexByte(src[i:], '\n'); j == -1 {
+				i = len(src)
+			} else {
+				i += j - 1
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			j := strings.Index(src[i+2:], "*/")
+			if j == -1 {
+				return 0, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+j+4], "\n")
+			i += j + 3
+		case c == '{' && depth == 0:
+			block = blockStartRegex.MatchString(src[start:i])
+			depth++
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			if depth < 0 {
+				return 0, fmt.Errorf("line %d: unexpected '%c'", line, c)
+			} else if depth == 0 && block && c == '}' {
+				return i + 1, nil
+			}
+		case c == ';' && depth == 0:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("line %d: unexpected end of input", line)
+}
+
+// splitList splits the given text at all separators outside of parentheses,
+// brackets, and braces and returns the trimmed, non-empty parts.
+func splitList(text string, sep byte) []string {
+	var parts []string
+	depth := 0
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) {
+			switch text[i] {
+			case '(', '[', '{':
+				depth++
+				continue
+			case ')', ']', '}':
+				depth--
+				continue
+			}
+			if text[i] != sep || depth > 0 {
+				continue
+			}
+		}
+		if part := strings.TrimSpace(text[start:i]); part != "" {
+			parts = append(parts, part)
+		}
+		start = i + 1
+	}
+	return parts
+}
+
+// matchingClose returns the index of the parenthesis, bracket, or brace
+// closing the one at the given index, or -1 if it is not closed.
+func matchingClose(text string, open int) int {
+	depth := 0
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parse adds the declarations in the given source text, starting at the given
+// line, to the declarations. A leading line comment becomes the header
+// comment of the declarations.
+func (d *Declarations) parse(src string, line int) error {
+	d.parseHeaderComment(src)
+	items, err := splitItems(src, line)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		if err := d.parseItem(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseHeaderComment sets the header comment of the declarations to the
+// leading line comment of the given source text, if any.
+func (d *Declarations) parseHeaderComment(src string) {
+	trimmed := strings.TrimSpace(src)
+	if !strings.HasPrefix(trimmed, "//") {
+		return
+	}
+	comment := strings.TrimPrefix(trimmed, "//")
+	if i := strings.IndexByte(comment, '\n'); i != -1 {
+		comment = comment[:i]
+	}
+	d.headerComment = strings.TrimSpace(comment)
+}
+
+// parseItem adds the type, variable, or function declaration of the given
+// item to the declarations. Constants get added like types, since type
+// declarations precede variable declarations and can depend on constants.
+// Blank lines between type or variable declarations are kept.
+func (d *Declarations) parseItem(it item) error {
+	if k := it.keyword(); k == "typedef" || k == "const" {
+		if len(d.types) > 0 {
+			for i := 1; i < it.newlines; i++ {
+				d.AddSpaceBetweenTypes()
+			}
+		}
+		d.AddType(it.text)
+		return nil
+	} else if strings.HasSuffix(it.text, "}") {
+		d.AddFunc(it.text)
+		return nil
+	}
+
+	infos, err := parseVariableDecl(strings.TrimSuffix(it.text, ";"))
+	if err != nil {
+		return fmt.Errorf("line %d: %v", it.line, err)
+	}
+	if len(d.variables) > 0 {
+		for i := 1; i < it.newlines; i++ {
+			d.AddSpaceBetweenVariables()
+		}
+	}
+	for _, info := range infos {
+		if i, ok := d.variableLookup[info.name]; ok {
+			d.variables[i] = info
+			continue
+		}
+		d.variableLookup[info.name] = len(d.variables)
+		d.variables = append(d.variables, info)
+	}
+	return nil
+}
+
+// parseVariableDecl parses a declaration of one or more variables of the same
+// type, for example "int[0, 3] a, b[2] = {1, 2}".
+func parseVariableDecl(text string) ([]variableInfo, error) {
+	var infos []variableInfo
+	var typ string
+	for i, part := range splitList(text, ',') {
+		var info variableInfo
+		if j := findInitializer(part); j != -1 {
+			info.initialValue = strings.TrimSpace(part[j+1:])
+			part = strings.TrimSpace(part[:j])
+			if info.initialValue == "" {
+				return nil, fmt.Errorf("expected initial value in declaration: %s", text)
+			}
+		}
+		for strings.HasSuffix(part, "]") {
+			j := strings.LastIndexByte(part, '[')
+			for j != -1 && matchingClose(part, j) != len(part)-1 {
+				j = strings.LastIndexByte(part[:j], '[')
+			}
+			if j == -1 {
+				return nil, fmt.Errorf("unbalanced brackets in declaration: %s", text)
+			}
+			dim := strings.TrimSpace(part[j+1 : len(part)-1])
+			info.dimensions = append([]string{dim}, info.dimensions...)
+			part = strings.TrimSpace(part[:j])
+		}
+		info.name = trailingIdent.FindString(part)
+		if info.name == "" {
+			return nil, fmt.Errorf("expected variable name in declaration: %s", text)
+		}
+		if i == 0 {
+			typ = strings.TrimSpace(strings.TrimSuffix(part, info.name))
+			if typ == "" {
+				return nil, fmt.Errorf("expected type in declaration: %s", text)
+			}
+		} else if part != info.name {
+			return nil, fmt.Errorf("unexpected declaration: %s", part)
+		}
+		info._type = typ
+		infos = append(infos, info)
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("empty declaration")
+	}
+	return infos, nil
+}
+
+// findInitializer returns the index of the '=' separating the declared
+// variable from its initial value, or -1 if the declaration has none.
+func findInitializer(decl string) int {
+	depth := 0
+	for i := 0; i < len(decl); i++ {
+		switch decl[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '=':
+			if depth > 0 {
+				continue
+			} else if i+1 < len(decl) && decl[i+1] == '=' {
+				i++
+				continue
+			} else if i > 0 && strings.IndexByte("<>!=", decl[i-1]) != -1 {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseXTA parses the xta (file format) representation of a system. The
+// parser does not resolve names, so all guards and updates are assumed to use
+// global variables. Comments outside of function bodies get dropped, except
+// for the header comments of declarations. Queries are not part of the xta
+// format and can be parsed separately with ParseQ.
+func ParseXTA(xta string) (*System, error) {
+	s := NewSystem()
+	s.decls.parseHeaderComment(xta)
+	if err := s.parseItems(xta, 1, true); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseItems parses the given xta source text, starting at the given line,
+// and adds all found declarations, process definitions (if allowed), process
+// instances, system processes, and progress measures to the system.
+func (s *System) parseItems(src string, line int, allowProcesses bool) error {
+	items, err := splitItems(src, line)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		switch it.keyword() {
+		case "process":
+			if !allowProcesses {
+				return fmt.Errorf("line %d: unexpected process definition", it.line)
+			}
+			err = s.parseProcess(it)
+		case "system":
+			err = s.parseSystemProcesses(it)
+		case "progress":
+			err = s.parseProgressMeasures(it)
+		default:
+			if instanceRegex.MatchString(it.text) {
+				err = s.parseProcessInstance(it)
+			} else {
+				err = s.decls.parseItem(it)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *System) parseProcess(it item) error {
+	i := strings.IndexByte(it.text, '(')
+	j := -1
+	if i != -1 {
+		j = matchingClose(it.text, i)
+	}
+	k := strings.IndexByte(it.text[j+1:], '{') + j + 1
+	if i == -1 || j == -1 || k == j || !strings.HasSuffix(it.text, "}") {
+		return fmt.Errorf("line %d: malformed process definition", it.line)
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(it.text[:i], "process"))
+	if !identRegex.MatchString(name) {
+		return fmt.Errorf("line %d: invalid process name: %q", it.line, name)
+	} else if _, ok := s.processes[name]; ok {
+		return fmt.Errorf("line %d: duplicate process: %s", it.line, name)
+	} else if _, ok := s.instances[name]; ok {
+		return fmt.Errorf("line %d: duplicate process: %s", it.line, name)
+	}
+	proc := s.AddProcess(name)
+	for _, param := range splitList(it.text[i+1:j], ',') {
+		proc.AddParameter(param)
+	}
+
+	// Processes without transitions have an empty trans section:
+	body := emptyTransRegex.ReplaceAllString(it.text[k+1:len(it.text)-1], "")
+	line := it.line + strings.Count(it.text[:k+1], "\n")
+	proc.decls.parseHeaderComment(body)
+	items, err := splitItems(body, line)
+	if err != nil {
+		return err
+	}
+	inDecls := true
+	for _, it := range items {
+		keyword := it.keyword()
+		if keyword == "state" {
+			inDecls = false
+		}
+		if inDecls {
+			if err := proc.decls.parseItem(it); err != nil {
+				return err
+			}
+			continue
+		}
+		list := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(it.text, keyword), ";"))
+		switch keyword {
+		case "state":
+			err = proc.parseStates(list, it.line)
+		case "commit", "urgent":
+			t := Committed
+			if keyword == "urgent" {
+				t = Urgent
+			}
+			for _, name := range splitList(list, ',') {
+				state, ok := proc.stateLookup[name]
+				if !ok {
+					return fmt.Errorf("line %d: unknown state: %s", it.line, name)
+				}
+				state.SetType(t)
+			}
+		case "init":
+			state, ok := proc.stateLookup[list]
+			if !ok {
+				return fmt.Errorf("line %d: unknown state: %s", it.line, list)
+			}
+			proc.SetInitialState(state)
+		case "trans":
+			err = proc.parseTransitions(list, it.line)
+		default:
+			err = fmt.Errorf("line %d: unexpected %q in process %s", it.line, keyword, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if proc.initialState == nil {
+		return fmt.Errorf("line %d: process %s has no initial state", it.line, name)
+	}
+	return nil
+}
+
+func (p *Process) parseStates(list string, line int) error {
+	for _, part := range splitList(list, ',') {
+		m := stateRegex.FindStringSubmatch(part)
+		if m == nil {
+			return fmt.Errorf("line %d: malformed state: %s", line, part)
+		} else if _, ok := p.stateLookup[m[1]]; ok {
+			return fmt.Errorf("line %d: duplicate state: %s", line, m[1])
+		}
+		state := p.AddState(m[1], NoRenaming)
+		state.SetInvariant(strings.TrimSpace(m[2]))
+	}
+	return nil
+}
+
+// parseTransitions parses the given transitions. Transitions without a start
+// state start at the start state of the preceding transition.
+func (p *Process) parseTransitions(list string, line int) error {
+	var start *State
+	for _, part := range splitList(list, ',') {
+		m := transRegex.FindStringSubmatch(part)
+		if m == nil {
+			return fmt.Errorf("line %d: malformed transition: %s", line, part)
+		}
+		if m[1] != "" {
+			var ok bool
+			start, ok = p.stateLookup[m[1]]
+			if !ok {
+				return fmt.Errorf("line %d: unknown state: %s", line, m[1])
+			}
+		} else if start == nil {
+			return fmt.Errorf("line %d: transition without start state: %s", line, part)
+		}
+		end, ok := p.stateLookup[m[2]]
+		if !ok {
+			return fmt.Errorf("line %d: unknown state: %s", line, m[2])
+		}
+		trans := p.AddTransition(start, end)
+		for _, label := range splitList(m[3], ';') {
+			l := labelRegex.FindStringSubmatch(label)
+			if l == nil {
+				return fmt.Errorf("line %d: malformed transition label: %s", line, label)
+			}
+			if err := trans.setLabel(l[1], strings.TrimSpace(l[2])); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setLabel sets the label of the given kind, using the keywords of the xta
+// format or the label kinds of the xml format.
+func (t *Trans) setLabel(kind, text string) error {
+	switch kind {
+	case "select":
+		t.AddSelect(text)
+	case "guard":
+		t.SetGuard(text, true)
+	case "sync", "synchronisation":
+		t.SetSync(text)
+	case "assign", "assignment":
+		t.AddUpdate(text, true)
+	default:
+		return fmt.Errorf("unsupported transition label: %s", kind)
+	}
+	return nil
+}
+
+func (s *System) parseProcessInstance(it item) error {
+	m := instanceRegex.FindStringSubmatch(it.text)
+	proc, ok := s.processes[m[2]]
+	if !ok {
+		return fmt.Errorf("line %d: unknown process: %s", it.line, m[2])
+	} else if _, ok := s.instances[m[1]]; ok {
+		return fmt.Errorf("line %d: duplicate process instance: %s", it.line, m[1])
+	}
+	inst := s.AddProcessInstance(proc, m[1])
+	for _, param := range splitList(m[3], ',') {
+		inst.AddParameter(param)
+	}
+	return nil
+}
+
+// parseSystemProcesses adds instances for all processes listed in the system
+// statement that do not refer to declared process instances. Priorities
+// between the listed processes get dropped.
+func (s *System) parseSystemProcesses(it item) error {
+	list := strings.TrimSuffix(strings.TrimPrefix(it.text, "system"), ";")
+	for _, group := range splitList(list, ',') {
+		for _, name := range splitList(group, '<') {
+			if _, ok := s.instances[name]; ok {
+				continue
+			}
+			proc, ok := s.processes[name]
+			if !ok {
+				return fmt.Errorf("line %d: unknown process: %s", it.line, name)
+			}
+			s.AddProcessInstance(proc, name)
+		}
+	}
+	return nil
+}
+
+func (s *System) parseProgressMeasures(it item) error {
+	i := strings.IndexByte(it.text, '{')
+	if i == -1 || !strings.HasSuffix(it.text, "}") {
+		return fmt.Errorf("line %d: malformed progress measures", it.line)
+	}
+	for _, measure := range splitList(it.text[i+1:len(it.text)-1], ';') {
+		s.AddProgressMeasure(measure)
+	}
+	return nil
+}
+
+// ParseQ parses the q (file format) representation of queries. Each query
+// consists of a line with the query expression, preceded by an optional
+// comment. Comments in the format generated by Query.AsQ get parsed into the
+// description, source location, and category of the query. Queries with other
+// comments use the comment as description and ReachabilityRequirements as
+// category.
+func ParseQ(q string) ([]*Query, error) {
+	var queries []*Query
+	var comment strings.Builder
+	line := 1
+	for i := 0; i < len(q); {
+		switch {
+		case q[i] == '\n':
+			line++
+			i++
+		case q[i] == ' ' || q[i] == '\t' || q[i] == '\r':
+			i++
+		case strings.HasPrefix(q[i:], "/*"):
+			j := strings.Index(q[i+2:], "*/")
+			if j == -1 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			comment.WriteString(q[i+2:i+2+j] + "\n")
+			line += strings.Count(q[i:i+j+4], "\n")
+			i += j + 4
+		default:
+			j := strings.IndexByte(q[i:], '\n')
+			if j == -1 {
+				j = len(q) - i
+			}
+			text := strings.TrimSpace(q[i : i+j])
+			i += j
+			if strings.HasPrefix(text, "//") {
+				comment.WriteString(strings.TrimPrefix(text, "//") + "\n")
+				continue
+			}
+			queries = append(queries, parseQuery(text, comment.String()))
+			comment.Reset()
+		}
+	}
+	return queries, nil
+}
+
+// parseQuery returns a query with the given query expression and the
+// description, source location, and category from the given comment.
+func parseQuery(query, comment string) *Query {
+	var description, location string
+	category := ReachabilityRequirements
+	var other []string
+	for _, line := range strings.Split(strings.TrimSpace(comment), "\n") {
+		line = strings.TrimSpace(line)
+		key, value := line, ""
+		if i := strings.Index(line, ": "); i != -1 {
+			key, value = line[:i], strings.TrimSpace(line[i+2:])
+		}
+		switch key {
+		case "description":
+			description = value
+		case "location":
+			location = value
+		case "category":
+			if c, ok := parseQueryCategory(value); ok {
+				category = c
+			} else {
+				other = append(other, line)
+			}
+		case "number":
+		default:
+			if line != "" {
+				other = append(other, line)
+			}
+		}
+	}
+	if description == "" {
+		description = strings.Join(other, "\n")
+	}
+	return NewQuery(query, description, location, category)
+}
+
+func parseQueryCategory(str string) (QueryCategory, bool) {
+	for c := ResourceBoundUnreached; c <= ReachabilityRequirements; c++ {
+		if strings.EqualFold(c.String(), str) {
+			return c, true
+		}
+	}
+	return 0, false
+}
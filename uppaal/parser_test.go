@@ -0,0 +1,165 @@
+package uppaal
+
+import (
+	"strings"
+	"testing"
+)
+
+// newTestSystem returns a system using most features of the file formats.
+// Declarations, states, and transitions are deliberately not added in
+// alphabetical order.
+func newTestSystem() *System {
+	sys := NewSystem()
+	sys.Declarations().AddType("typedef int[0, 3] counter_t;")
+	sys.Declarations().AddVariable("x", "int", "0")
+	sys.Declarations().AddVariable("c", "counter_t", "")
+	sys.Declarations().AddArray("ch", []int{4}, "chan")
+	sys.Declarations().AddVariable("b", "bool", "false")
+	sys.Declarations().AddFunc("int inc(int i) {\n\treturn i + 1;\n}")
+
+	proc := sys.AddProcess("Worker")
+	proc.AddParameter("int id")
+	proc.Declarations().AddVariable("y", "int", "0")
+	proc.Declarations().AddVariable("a", "int", "id")
+	zeta := proc.AddState("zeta", NoRenaming)
+	zeta.SetComment("start")
+	mid := proc.AddState("mid", NoRenaming)
+	mid.SetType(Committed)
+	alpha := proc.AddState("alpha", NoRenaming)
+	alpha.SetInvariant("y <= 5")
+	end := proc.AddState("end", NoRenaming)
+	proc.SetInitialState(zeta)
+
+	t1 := proc.AddTransition(zeta, mid)
+	t1.AddSelect("i : int[0, 3]")
+	t1.SetGuard("x < 3", true)
+	t1.SetSync("ch[i]!")
+	t1.AddUpdate("x = inc(x)", true)
+	t1.AddUpdate("y = i", false)
+	t2 := proc.AddTransition(mid, alpha)
+	t2.AddUpdate("b = true", true)
+	proc.AddTransition(zeta, alpha).SetSync("ch[id]?")
+	proc.AddTransition(alpha, end).SetGuard("b", true)
+	proc.AddTransition(alpha, zeta)
+
+	main := sys.AddProcess("Main")
+	m0 := main.AddState("m0", NoRenaming)
+	main.SetInitialState(m0)
+
+	w := sys.AddProcessInstance(proc, "worker")
+	w.AddParameter("2")
+	sys.AddProcessInstance(main, "main")
+
+	sys.AddQuery(NewQuery("A[] not deadlock", "no deadlocks", "main.go:3:1", NoChannelRelatedDeadlocks))
+	sys.AddQuery(NewQuery("E<> worker.end", "end reachable", "main.go:7:2", ReachabilityRequirements))
+	return sys
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	sys := newTestSystem()
+	xml := sys.AsXML()
+	parsed, err := ParseXML(xml)
+	if err != nil {
+		t.Fatalf("ParseXML failed: %v", err)
+	}
+	if got := parsed.AsXML(); got != xml {
+		t.Errorf("xml changed after ParseXML:\ngot:\n%s\nwant:\n%s", got, xml)
+	}
+	if got, want := parsed.AsXTA(), sys.AsXTA(); got != want {
+		t.Errorf("xta changed after ParseXML:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := parsed.AsQ(), sys.AsQ(); got != want {
+		t.Errorf("queries changed after ParseXML:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestXTARoundTrip(t *testing.T) {
+	parsed, err := ParseXML(newTestSystem().AsXML())
+	if err != nil {
+		t.Fatalf("ParseXML failed: %v", err)
+	}
+	xta := parsed.AsXTA()
+	reparsed, err := ParseXTA(xta)
+	if err != nil {
+		t.Fatalf("ParseXTA failed: %v", err)
+	}
+	if got := reparsed.AsXTA(); got != xta {
+		t.Errorf("xta changed after ParseXTA:\ngot:\n%s\nwant:\n%s", got, xta)
+	}
+}
+
+func TestElementOrder(t *testing.T) {
+	sys, err := ParseXTA(newTestSystem().AsXTA())
+	if err != nil {
+		t.Fatalf("ParseXTA failed: %v", err)
+	}
+	var proc *Process
+	for _, p := range sys.Processes() {
+		if p.Name() == "Worker" {
+			proc = p
+		}
+	}
+	if proc == nil {
+		t.Fatalf("process Worker missing")
+	}
+	var states []string
+	for _, state := range proc.States() {
+		states = append(states, state.Name())
+	}
+	if got, want := strings.Join(states, " "), "zeta mid alpha end"; got != want {
+		t.Errorf("got states %q, want %q", got, want)
+	}
+	xta := proc.AsXTA()
+	transitions := []string{"zeta -> mid", "mid -> alpha", "zeta -> alpha", "alpha -> end", "alpha -> zeta"}
+	prev := -1
+	for _, trans := range transitions {
+		i := strings.Index(xta, trans)
+		if i == -1 {
+			t.Fatalf("transition %s missing:\n%s", trans, xta)
+		} else if i < prev {
+			t.Errorf("transition %s out of order:\n%s", trans, xta)
+		}
+		prev = i
+	}
+	decls := sys.Declarations().AsXTA()
+	prev = -1
+	for _, decl := range []string{"counter_t;", "int x", "counter_t c", "chan ch[4]", "bool b", "int inc("} {
+		i := strings.Index(decls, decl)
+		if i == -1 {
+			t.Fatalf("declaration %s missing:\n%s", decl, decls)
+		} else if i < prev {
+			t.Errorf("declaration %s out of order:\n%s", decl, decls)
+		}
+		prev = i
+	}
+}
+
+func TestParseQ(t *testing.T) {
+	sys := newTestSystem()
+	queries, err := ParseQ(sys.AsQ())
+	if err != nil {
+		t.Fatalf("ParseQ failed: %v", err)
+	}
+	want := sys.AllQueries()
+	if len(queries) != len(want) {
+		t.Fatalf("got %d queries, want %d", len(queries), len(want))
+	}
+	for i, q := range queries {
+		if q.Query() != want[i].Query() || q.Description() != want[i].Description() ||
+			q.SourceLocation() != want[i].SourceLocation() || q.Category() != want[i].Category() {
+			t.Errorf("query %d: got %v, want %v", i, *q, *want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"process P() {\nstate a;\ntrans a -> b {};\n}",
+		"process P() {\nstate a;\n}",
+		"process P() {\nstate a;\ninit a;\n}\nq = Q();",
+	} {
+		if _, err := ParseXTA(src); err == nil {
+			t.Errorf("expected error for:\n%s", src)
+		}
+	}
+}
@@ -24,10 +24,12 @@ type Process struct {
 
 	decls Declarations
 
-	initialState     *State
-	states           map[*State]struct{}
+	initialState *State
+	// states and transitions are in the order they were added in, such that
+	// the file representations of the process are stable.
+	states           []*State
 	stateLookup      map[string]*State
-	transitions      map[*Trans]struct{}
+	transitions      []*Trans
 	transitionLookup map[*State]map[*State][]*Trans
 
 	queries []*Query
@@ -38,9 +40,7 @@ func newProcess(name string) *Process {
 	p.name = name
 	p.decls.initDeclarations("Place local declarations here.")
 	p.initialState = nil
-	p.states = make(map[*State]struct{})
 	p.stateLookup = make(map[string]*State)
-	p.transitions = make(map[*Trans]struct{})
 	p.transitionLookup = make(map[*State]map[*State][]*Trans)
 
 	return p
@@ -73,7 +73,7 @@ func (p *Process) InitialState() *State {
 
 // SetInitialState changes the initial state of the process to the given state.
 func (p *Process) SetInitialState(state *State) {
-	_, ok := p.states[state]
+	ok := p.hasState(state)
 	if state != nil && !ok {
 		panic("tried to set unknown state as initial state")
 	}
@@ -86,19 +86,19 @@ func (p *Process) SetInitialState(state *State) {
 	}
 }
 
-// States returns all states of the process.
+// States returns all states of the process, in the order they were added in.
 func (p *Process) States() []*State {
-	var states []*State
-	for state := range p.states {
-		states = append(states, state)
-	}
-	return states
+	return append([]*State(nil), p.states...)
+}
+
+func (p *Process) hasState(state *State) bool {
+	return p.stateLookup[state.name] == state
 }
 
 // StatesWithType returns all states with the given state type.
 func (p *Process) StatesWithType(t StateType) []*State {
 	var filteredStates []*State
-	for state := range p.states {
+	for _, state := range p.states {
 		if state.Type() != t {
 			continue
 		}
@@ -129,7 +129,7 @@ func (p *Process) AddState(name string, opt RenamingOption) *State {
 
 	state := newState(name)
 
-	p.states[state] = struct{}{}
+	p.states = append(p.states, state)
 	p.stateLookup[name] = state
 	p.transitionLookup[state] = make(map[*State][]*Trans)
 
@@ -138,7 +138,7 @@ func (p *Process) AddState(name string, opt RenamingOption) *State {
 
 // RemoveState removes the given state from the process.
 func (p *Process) RemoveState(state *State) {
-	if _, ok := p.states[state]; !ok {
+	if !p.hasState(state) {
 		panic("tried to remove unknown state")
 	} else if len(state.Transitions()) > 0 {
 		panic("tried to remove state with remaining transitions")
@@ -148,7 +148,12 @@ func (p *Process) RemoveState(state *State) {
 		p.initialState = nil
 	}
 
-	delete(p.states, state)
+	for i, s := range p.states {
+		if s == state {
+			p.states = append(p.states[:i], p.states[i+1:]...)
+			break
+		}
+	}
 	delete(p.stateLookup, state.name)
 	delete(p.transitionLookup, state)
 }
@@ -162,15 +167,15 @@ func (p *Process) TransitionLookup() map[*State]map[*State][]*Trans {
 // AddTransition adds a transition betweent the given start and end state to
 // the process and returns the new transition.
 func (p *Process) AddTransition(start, end *State) *Trans {
-	if _, ok := p.states[start]; !ok {
+	if !p.hasState(start) {
 		panic("tried to add transition with unknown start state")
-	} else if _, ok := p.states[end]; !ok {
+	} else if !p.hasState(end) {
 		panic("tried to add transition with unknown end state")
 	}
 
 	trans := newTrans(start, end)
 
-	p.transitions[trans] = struct{}{}
+	p.transitions = append(p.transitions, trans)
 	p.transitionLookup[start][end] = append(p.transitionLookup[start][end], trans)
 
 	start.transitions = append(start.transitions, trans)
@@ -183,11 +188,18 @@ func (p *Process) AddTransition(start, end *State) *Trans {
 
 // RemoveTransition removes the given transition from the process.
 func (p *Process) RemoveTransition(trans *Trans) {
-	if _, ok := p.transitions[trans]; !ok {
+	index := -1
+	for i, t := range p.transitions {
+		if t == trans {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
 		panic("tried to remove unknown transition")
 	}
 
-	delete(p.transitions, trans)
+	p.transitions = append(p.transitions[:index], p.transitions[index+1:]...)
 	for i, t := range p.transitionLookup[trans.start][trans.end] {
 		if t == trans {
 			p.transitionLookup[trans.start][trans.end] = append(p.transitionLookup[trans.start][trans.end][:i], p.transitionLookup[trans.start][trans.end][i+1:]...)
@@ -222,13 +234,16 @@ func (p *Process) AsXTA() string {
 	s += p.decls.AsXTA() + "\n\n"
 	s += "state\n"
 	first := true
-	for state := range p.states {
+	for _, state := range p.states {
 		if first {
 			first = false
 		} else {
 			s += ",\n"
 		}
 		s += "    " + state.Name()
+		if state.invariant != "" {
+			s += " { " + state.invariant + " }"
+		}
 	}
 	s += ";\n"
 	for _, stateType := range []StateType{Committed, Urgent} {
@@ -256,18 +271,12 @@ func (p *Process) AsXTA() string {
 	s += "init\n"
 	s += "    " + p.initialState.Name() + ";\n"
 	s += "trans\n"
-	i := 0
-	for _, ends := range p.transitionLookup {
-		for _, transitions := range ends {
-			for _, trans := range transitions {
-				s += "    " + trans.AsXTA()
-				if i < len(p.transitions)-1 {
-					s += ",\n"
-				} else {
-					s += ";\n"
-				}
-				i++
-			}
+	for i, trans := range p.transitions {
+		s += "    " + trans.AsXTA()
+		if i < len(p.transitions)-1 {
+			s += ",\n"
+		} else {
+			s += ";\n"
 		}
 	}
 	s += "}"
@@ -277,15 +286,18 @@ func (p *Process) AsXTA() string {
 // AsUGI returns the ugi (file format) representation of the process.
 func (p *Process) AsUGI() string {
 	s := "process " + p.name + " graphinfo {\n"
-	for state := range p.states {
+	for _, state := range p.states {
 		s += state.AsUGI()
 	}
-	for start, ends := range p.transitionLookup {
-		for end, transitions := range ends {
-			for index, trans := range transitions {
-				s += trans.AsUGI(start.location, end.location, index)
+	for _, trans := range p.transitions {
+		index := 0
+		for _, t := range p.transitionLookup[trans.start][trans.end] {
+			if t == trans {
+				break
 			}
+			index++
 		}
+		s += trans.AsUGI(trans.start.location, trans.end.location, index)
 	}
 	s += "}"
 	return s
@@ -295,7 +307,9 @@ func (p *Process) asXML(b *strings.Builder, indent string) {
 	b.WriteString(indent + "<template>\n")
 	b.WriteString(indent + "    <name>" + p.name + "</name>\n")
 	if len(p.params) > 0 {
-		b.WriteString(indent + "    <parameter>" + strings.Join(p.params, ", ") + "</parameter>\n")
+		b.WriteString(indent + "    <parameter>")
+		xml.EscapeText(b, []byte(strings.Join(p.params, ", ")))
+		b.WriteString("</parameter>\n")
 	}
 	b.WriteString(indent + "    <declaration>")
 	xml.EscapeText(b, []byte(p.decls.AsXTA()))
@@ -303,7 +317,7 @@ func (p *Process) asXML(b *strings.Builder, indent string) {
 
 	stateIndices := make(map[*State]int, len(p.states))
 	stateCount := 0
-	for state := range p.states {
+	for _, state := range p.states {
 		stateIndex := stateCount
 		stateIndices[state] = stateIndex
 		stateCount++
@@ -311,6 +325,12 @@ func (p *Process) asXML(b *strings.Builder, indent string) {
 			indent, stateIndex, state.location.X(), state.location.Y())
 		fmt.Fprintf(b, "%s        <name x=\"%d\" y=\"%d\">%s</name>\n",
 			indent, state.nameLocation.X(), state.nameLocation.Y(), state.name)
+		if state.invariant != "" {
+			fmt.Fprintf(b, "%s        <label kind=\"invariant\" x=\"%d\" y=\"%d\">",
+				indent, state.invariantLocation.X(), state.invariantLocation.Y())
+			xml.EscapeText(b, []byte(state.invariant))
+			b.WriteString("</label>\n")
+		}
 		if state.comment != "" {
 			fmt.Fprintf(b, "%s    <label kind=\"comments\" x=\"%d\" y=\"%d\">",
 				indent, state.commentLocation.X(), state.commentLocation.Y())
@@ -326,7 +346,7 @@ func (p *Process) asXML(b *strings.Builder, indent string) {
 	}
 	fmt.Fprintf(b, "%s    <init ref=\"id%d\"/>\n", indent, stateIndices[p.initialState])
 
-	for transition := range p.transitions {
+	for _, transition := range p.transitions {
 		srcIndex := stateIndices[transition.start]
 		tgtIndex := stateIndices[transition.end]
 
@@ -337,7 +357,7 @@ func (p *Process) asXML(b *strings.Builder, indent string) {
 			fmt.Fprintf(b, "%s        <label kind=\"select\" x=\"%d\" y=\"%d\">",
 				indent, transition.selectLocation.X(), transition.selectLocation.Y())
 			xml.EscapeText(b, []byte(transition.selectStmts))
-			b.WriteString(indent + "</label>\n")
+			b.WriteString("</label>\n")
 		}
 		if transition.guardExpr != "" {
 			fmt.Fprintf(b, "%s        <label kind=\"guard\" x=\"%d\" y=\"%d\">",
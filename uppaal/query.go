@@ -18,6 +18,8 @@ const (
 	MutexSafety
 	// WaitGroupSafety verifies the system never performs disallowed wait group operations.
 	WaitGroupSafety
+	// CondSafety verifies the system never performs disallowed cond operations.
+	CondSafety
 	// NoChannelRelatedDeadlocks verifies the system is never stuck waiting on a channel operation.
 	NoChannelRelatedDeadlocks
 	// NoMutexRelatedDeadlocks verifies the system is never stuck waiting on a mutex operation.
@@ -26,10 +28,18 @@ const (
 	NoWaitGroupRelatedDeadlocks
 	// NoOnceRelatedDeadlocks verifies the system is never stuck waiting on a once operation.
 	NoOnceRelatedDeadlocks
+	// NoCondRelatedDeadlocks verifies the system is never stuck waiting on a cond operation.
+	NoCondRelatedDeadlocks
 	// NoFunctionCallsWithNilVariable verifies the system is never attempting to call a nil (-1) function variable.
 	NoFunctionCallsWithNilVariable
+	// NoCloseOfNilChannel verifies the system never attempts to close a nil (-1) channel.
+	NoCloseOfNilChannel
 	// NoGoroutineExitWithPanic verifies the system never exits a panicking goroutine.
 	NoGoroutineExitWithPanic
+	// NoGoroutineLeaks verifies no goroutine is stuck waiting on an operation when the entry function returns.
+	NoGoroutineLeaks
+	// NoDataRaces verifies no two goroutines access a shared variable concurrently with at least one write.
+	NoDataRaces
 	// ReachabilityRequirements are user generated and verify that a certain state is or is not reachable.
 	ReachabilityRequirements
 )
@@ -44,6 +54,8 @@ func (c QueryCategory) String() string {
 		return "mutex safety"
 	case WaitGroupSafety:
 		return "wait group safety"
+	case CondSafety:
+		return "cond safety"
 	case NoChannelRelatedDeadlocks:
 		return "no channel related deadlocks"
 	case NoMutexRelatedDeadlocks:
@@ -52,10 +64,18 @@ func (c QueryCategory) String() string {
 		return "no wait group related deadlocks"
 	case NoOnceRelatedDeadlocks:
 		return "no once related deadlocks"
+	case NoCondRelatedDeadlocks:
+		return "no cond related deadlocks"
 	case NoFunctionCallsWithNilVariable:
 		return "no function calls with nil variable"
+	case NoCloseOfNilChannel:
+		return "no close of nil channel"
 	case NoGoroutineExitWithPanic:
 		return "no goroutine exit with panic"
+	case NoGoroutineLeaks:
+		return "no goroutine leaks"
+	case NoDataRaces:
+		return "no data races"
 	case ReachabilityRequirements:
 		return "reachability requirements"
 	default:
@@ -19,14 +19,16 @@ type State struct {
 	name      string
 	isInitial bool
 	stateType StateType
+	invariant string
 	comment   string
 
 	transitions []*Trans
 
 	// All locations are absolute. AsGUI() translates to relative coordinates.
-	location        Location
-	nameLocation    Location
-	commentLocation Location
+	location          Location
+	nameLocation      Location
+	invariantLocation Location
+	commentLocation   Location
 }
 
 func newState(name string) *State {
@@ -34,10 +36,12 @@ func newState(name string) *State {
 	s.name = name
 	s.isInitial = false
 	s.stateType = Normal
+	s.invariant = ""
 	s.comment = ""
 	s.transitions = nil
 	s.location = Location{}
 	s.nameLocation = Location{}
+	s.invariantLocation = Location{}
 	s.commentLocation = Location{}
 
 	return s
@@ -63,6 +67,16 @@ func (s *State) SetType(t StateType) {
 	s.stateType = t
 }
 
+// Invariant returns the invariant of the state.
+func (s *State) Invariant() string {
+	return s.invariant
+}
+
+// SetInvariant sets the invariant of the state.
+func (s *State) SetInvariant(invariant string) {
+	s.invariant = invariant
+}
+
 // Comment returns the comment for the state.
 func (s *State) Comment() string {
 	return s.comment
@@ -120,6 +134,16 @@ func (s *State) SetNameLocation(nameLocation Location) {
 	s.nameLocation = nameLocation
 }
 
+// InvariantLocation returns the location of the invariant label of the state.
+func (s *State) InvariantLocation() Location {
+	return s.invariantLocation
+}
+
+// SetInvariantLocation sets the location of the invariant label of the state.
+func (s *State) SetInvariantLocation(invariantLocation Location) {
+	s.invariantLocation = invariantLocation
+}
+
 // CommentLocation returns the location of the comment label of the state.
 func (s *State) CommentLocation() Location {
 	return s.commentLocation
@@ -132,9 +156,11 @@ func (s *State) SetCommentLocation(commentLocation Location) {
 
 // SetLocationAndResetNameAndCommentLocation sets the location of the state and sets the
 // location of the name label of the state to the default, below the state.
+// The invariant label gets placed above the state.
 func (s *State) SetLocationAndResetNameAndCommentLocation(location Location) {
 	s.location = location
 	s.nameLocation = location.Add(Location{4, 16})
+	s.invariantLocation = location.Add(Location{4, -32})
 	s.commentLocation = location.Add(Location{4, 34})
 }
 
@@ -104,6 +104,20 @@ func (s *System) AddQuery(query *Query) {
 	s.queries = append(s.queries, query)
 }
 
+// AllQueries returns all system queries followed by the process specific
+// queries for all process instances, in the order they appear in the q file.
+func (s *System) AllQueries() []*Query {
+	queries := append([]*Query(nil), s.queries...)
+	for _, inst := range s.sortedInstances() {
+		proc := s.processes[inst.Process().Name()]
+
+		for _, procQuery := range proc.queries {
+			queries = append(queries, procQuery.Substitute(inst.Name()))
+		}
+	}
+	return queries
+}
+
 // AsXTA returns the xta (file format) representation of the system.
 func (s *System) AsXTA() string {
 	str := s.decls.AsXTA() + "\n\n"
@@ -156,21 +170,8 @@ func (s *System) AsUGI() string {
 func (s *System) AsQ() string {
 	var str string
 
-	queryNumber := 1
-	for _, query := range s.queries {
-		str += query.AsQ(queryNumber)
-		queryNumber++
-	}
-
-	sortedInstances := s.sortedInstances()
-	for _, inst := range sortedInstances {
-		proc := s.processes[inst.Process().Name()]
-
-		for _, procQuery := range proc.queries {
-			instQuery := procQuery.Substitute(inst.Name())
-			str += instQuery.AsQ(queryNumber)
-			queryNumber++
-		}
+	for i, query := range s.AllQueries() {
+		str += query.AsQ(i + 1)
 	}
 
 	return str
@@ -193,50 +194,42 @@ func (s *System) AsXML() string {
 		b.WriteString("\n")
 	}
 
-	b.WriteString("    <system>\n")
+	var sys strings.Builder
 	sortedInstances := s.sortedInstances()
 	for _, inst := range sortedInstances {
 		if inst.CanSkipDeclaration() {
 			continue
 		}
-		b.WriteString(inst.AsXTA() + "\n")
+		sys.WriteString(inst.AsXTA() + "\n")
 	}
 	if len(sortedInstances) > 0 {
-		b.WriteString("system ")
+		sys.WriteString("system ")
 		first := true
 		for _, inst := range sortedInstances {
 			if first {
 				first = false
 			} else {
-				b.WriteString(", ")
+				sys.WriteString(", ")
 			}
-			b.WriteString(inst.Name())
+			sys.WriteString(inst.Name())
 		}
-		b.WriteString(";\n")
+		sys.WriteString(";\n")
 	}
 	if len(s.progressMeasures) > 0 {
-		b.WriteString("progress{\n")
+		sys.WriteString("progress{\n")
 		for _, measure := range s.progressMeasures {
-			b.WriteString("    " + measure + ";\n")
+			sys.WriteString("    " + measure + ";\n")
 		}
-		b.WriteString("}\n")
+		sys.WriteString("}\n")
 	}
+	b.WriteString("    <system>\n")
+	xml.EscapeText(&b, []byte(sys.String()))
 	b.WriteString("</system>\n")
 
 	b.WriteString("    <queries>\n")
-	queryNumber := 1
-	for _, query := range s.queries {
-		query.asXML(&b, queryNumber, "        ")
+	for i, query := range s.AllQueries() {
+		query.asXML(&b, i+1, "        ")
 		b.WriteString("\n")
-		queryNumber++
-	}
-	for _, inst := range sortedInstances {
-		for _, procQuery := range inst.Process().Queries() {
-			instQuery := procQuery.Substitute(inst.Name())
-			instQuery.asXML(&b, queryNumber, "        ")
-			b.WriteString("\n")
-			queryNumber++
-		}
 	}
 	b.WriteString("    </queries>\n")
 
@@ -0,0 +1,193 @@
+package uppaal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+type xmlNTA struct {
+	Declaration string        `xml:"declaration"`
+	Templates   []xmlTemplate `xml:"template"`
+	System      string        `xml:"system"`
+	Queries     []xmlQuery    `xml:"queries>query"`
+}
+
+type xmlTemplate struct {
+	Name        string          `xml:"name"`
+	Parameter   string          `xml:"parameter"`
+	Declaration string          `xml:"declaration"`
+	Locations   []xmlLocation   `xml:"location"`
+	Init        xmlRef          `xml:"init"`
+	Transitions []xmlTransition `xml:"transition"`
+}
+
+type xmlLocation struct {
+	ID        string     `xml:"id,attr"`
+	X         int        `xml:"x,attr"`
+	Y         int        `xml:"y,attr"`
+	Name      *xmlLabel  `xml:"name"`
+	Labels    []xmlLabel `xml:"label"`
+	Committed *struct{}  `xml:"committed"`
+	Urgent    *struct{}  `xml:"urgent"`
+}
+
+type xmlTransition struct {
+	Source xmlRef     `xml:"source"`
+	Target xmlRef     `xml:"target"`
+	Labels []xmlLabel `xml:"label"`
+	Nails  []xmlLabel `xml:"nail"`
+}
+
+type xmlLabel struct {
+	Kind string `xml:"kind,attr"`
+	X    int    `xml:"x,attr"`
+	Y    int    `xml:"y,attr"`
+	Text string `xml:",chardata"`
+}
+
+func (l xmlLabel) location() Location {
+	return Location{l.X, l.Y}
+}
+
+type xmlRef struct {
+	Ref string `xml:"ref,attr"`
+}
+
+type xmlQuery struct {
+	Formula string `xml:"formula"`
+	Comment string `xml:"comment"`
+}
+
+// ParseXML parses the xml (file format) representation of a system, including
+// the locations of states, labels, and nails, and the queries. Queries get
+// parsed like in ParseQ. Declarations in the system section get added to the
+// global declarations. Unnamed locations get named "L" followed by a number.
+// Like ParseXTA, the parser assumes that all guards and updates use global
+// variables.
+func ParseXML(src string) (*System, error) {
+	var nta xmlNTA
+	if err := xml.Unmarshal([]byte(src), &nta); err != nil {
+		return nil, err
+	}
+
+	s := NewSystem()
+	if err := s.decls.parse(nta.Declaration, 1); err != nil {
+		return nil, fmt.Errorf("global declarations: %v", err)
+	}
+	for _, template := range nta.Templates {
+		if err := s.parseTemplate(template); err != nil {
+			return nil, fmt.Errorf("template %s: %v", template.Name, err)
+		}
+	}
+	if err := s.parseItems(nta.System, 1, false); err != nil {
+		return nil, fmt.Errorf("system declarations: %v", err)
+	}
+	for _, query := range nta.Queries {
+		s.AddQuery(parseQuery(strings.TrimSpace(query.Formula), query.Comment))
+	}
+	return s, nil
+}
+
+func (s *System) parseTemplate(template xmlTemplate) error {
+	name := strings.TrimSpace(template.Name)
+	if !identRegex.MatchString(name) {
+		return fmt.Errorf("invalid name: %q", name)
+	} else if _, ok := s.processes[name]; ok {
+		return fmt.Errorf("duplicate template")
+	}
+	proc := s.AddProcess(name)
+	for _, param := range splitList(template.Parameter, ',') {
+		proc.AddParameter(param)
+	}
+	if err := proc.decls.parse(template.Declaration, 1); err != nil {
+		return err
+	}
+
+	// Add named locations first, such that unnamed locations get renamed to
+	// avoid naming conflicts:
+	states := make(map[string]*State)
+	for _, named := range []bool{true, false} {
+		for _, location := range template.Locations {
+			stateName := ""
+			if location.Name != nil {
+				stateName = strings.TrimSpace(location.Name.Text)
+			}
+			if (stateName != "") != named {
+				continue
+			} else if _, ok := states[location.ID]; ok {
+				return fmt.Errorf("duplicate location id: %s", location.ID)
+			} else if _, ok := proc.stateLookup[stateName]; ok && named {
+				return fmt.Errorf("duplicate location: %s", stateName)
+			}
+			var state *State
+			if named {
+				state = proc.AddState(stateName, NoRenaming)
+			} else {
+				state = proc.AddState("L", Renaming)
+			}
+			states[location.ID] = state
+			state.SetLocationAndResetNameAndCommentLocation(Location{location.X, location.Y})
+			if location.Name != nil {
+				state.SetNameLocation(location.Name.location())
+			}
+			for _, label := range location.Labels {
+				switch label.Kind {
+				case "invariant":
+					state.SetInvariant(strings.TrimSpace(label.Text))
+					state.SetInvariantLocation(label.location())
+				case "comments":
+					state.SetComment(strings.TrimSpace(label.Text))
+					state.SetCommentLocation(label.location())
+				default:
+					return fmt.Errorf("unsupported location label: %s", label.Kind)
+				}
+			}
+			if location.Committed != nil {
+				state.SetType(Committed)
+			} else if location.Urgent != nil {
+				state.SetType(Urgent)
+			}
+		}
+	}
+	init, ok := states[template.Init.Ref]
+	if !ok {
+		return fmt.Errorf("unknown initial location: %q", template.Init.Ref)
+	}
+	proc.SetInitialState(init)
+
+	for _, transition := range template.Transitions {
+		start, ok := states[transition.Source.Ref]
+		if !ok {
+			return fmt.Errorf("unknown location: %q", transition.Source.Ref)
+		}
+		end, ok := states[transition.Target.Ref]
+		if !ok {
+			return fmt.Errorf("unknown location: %q", transition.Target.Ref)
+		}
+		trans := proc.AddTransition(start, end)
+		for _, label := range transition.Labels {
+			text := strings.TrimSpace(label.Text)
+			if label.Kind == "comments" || text == "" {
+				continue
+			}
+			if err := trans.setLabel(label.Kind, text); err != nil {
+				return err
+			}
+			switch label.Kind {
+			case "select":
+				trans.SetSelectLocation(label.location())
+			case "guard":
+				trans.SetGuardLocation(label.location())
+			case "synchronisation":
+				trans.SetSyncLocation(label.location())
+			case "assignment":
+				trans.SetUpdateLocation(label.location())
+			}
+		}
+		for _, nail := range transition.Nails {
+			trans.AddNail(nail.location())
+		}
+	}
+	return nil
+}